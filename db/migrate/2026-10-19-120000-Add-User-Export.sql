@@ -0,0 +1,3 @@
+/* Record personal data exports on users */
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS export_token text;
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS export_at timestamp;
@@ -0,0 +1,2 @@
+/* Record scheduled account deletions on users */
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS delete_at timestamp;
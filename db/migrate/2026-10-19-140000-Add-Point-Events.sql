@@ -0,0 +1,20 @@
+/* Record every change to user points in a ledger */
+CREATE TABLE IF NOT EXISTS point_events (
+id SERIAL NOT NULL,
+created_at timestamp,
+user_id integer,
+delta integer,
+reason text,
+story_id integer,
+comment_id integer,
+reversed_id integer
+);
+CREATE INDEX IF NOT EXISTS point_events_user_id ON point_events (user_id);
+
+/* Each event may be reversed only once */
+CREATE UNIQUE INDEX IF NOT EXISTS point_events_reversed_id ON point_events (reversed_id);
+
+/* Existing points are carried into the ledger as an opening balance */
+INSERT INTO point_events (created_at, user_id, delta, reason)
+SELECT now(), id, coalesce(points,0), 'opening balance' FROM users
+WHERE NOT EXISTS (SELECT 1 FROM point_events WHERE point_events.user_id = users.id);
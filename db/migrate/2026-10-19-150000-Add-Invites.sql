@@ -0,0 +1,14 @@
+/* Record invites, and the user who invited each user */
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS invited_by integer;
+
+CREATE TABLE IF NOT EXISTS invites (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+user_id integer,
+token text,
+email text,
+used_at timestamp,
+used_by integer
+);
+CREATE UNIQUE INDEX IF NOT EXISTS invites_token ON invites (token);
@@ -0,0 +1,6 @@
+/* Add the profile fields shown in the about section for users */
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS title text;
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS text text;
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS website text;
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS github text;
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS mastodon text;
@@ -0,0 +1,2 @@
+/* Record the time sessions were last revoked on users */
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS sessions_at timestamp;
@@ -0,0 +1,4 @@
+/* Record suspensions and shadow bans on users */
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS suspended_until timestamp;
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS suspended_reason text;
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS shadow_banned boolean DEFAULT false;
@@ -0,0 +1,5 @@
+/* Allow moderators to lock story threads, merge duplicate stories and suspend comments */
+ALTER TABLE IF EXISTS stories ADD COLUMN IF NOT EXISTS locked boolean DEFAULT false;
+ALTER TABLE IF EXISTS stories ADD COLUMN IF NOT EXISTS merged_into integer;
+ALTER TABLE IF EXISTS comments ADD COLUMN IF NOT EXISTS status integer DEFAULT 100;
+UPDATE comments SET status=100 WHERE status IS NULL;
@@ -0,0 +1,19 @@
+/* Keep previous versions of stories and comments when they are edited */
+CREATE TABLE IF NOT EXISTS revisions (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+story_id integer,
+comment_id integer,
+user_id integer,
+user_name text,
+name text,
+summary text,
+url text,
+text text
+);
+ALTER TABLE revisions OWNER TO gohackernews_server;
+CREATE INDEX IF NOT EXISTS revisions_story_id ON revisions (story_id);
+CREATE INDEX IF NOT EXISTS revisions_comment_id ON revisions (comment_id);
+ALTER TABLE IF EXISTS stories ADD COLUMN IF NOT EXISTS edited_at timestamp;
+ALTER TABLE IF EXISTS comments ADD COLUMN IF NOT EXISTS edited_at timestamp;
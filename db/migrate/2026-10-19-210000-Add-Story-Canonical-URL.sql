@@ -0,0 +1,3 @@
+/* Store the canonical form of story urls to find duplicates, existing stories are filled in by a scheduled task */
+ALTER TABLE IF EXISTS stories ADD COLUMN IF NOT EXISTS canonical_url text;
+CREATE UNIQUE INDEX IF NOT EXISTS stories_canonical_url ON stories (canonical_url) WHERE canonical_url <> '';
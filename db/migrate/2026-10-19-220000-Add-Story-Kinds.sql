@@ -0,0 +1,8 @@
+/* Store the kind of story (0 link, 1 show, 2 ask, 3 video, 4 job, 5 event) instead of using title prefixes */
+ALTER TABLE IF EXISTS stories ADD COLUMN IF NOT EXISTS kind integer DEFAULT 0;
+UPDATE stories SET kind = 1, name = btrim(substr(name, 6)) WHERE kind = 0 AND name ILIKE 'Show:%';
+UPDATE stories SET kind = 2, name = btrim(substr(name, 5)) WHERE kind = 0 AND name ILIKE 'Ask:%';
+UPDATE stories SET kind = 3, name = btrim(substr(name, 7)) WHERE kind = 0 AND name ILIKE 'Video:%';
+UPDATE stories SET kind = 4, name = btrim(substr(name, 8)) WHERE kind = 0 AND name ILIKE 'Hiring:%';
+UPDATE stories SET kind = 5, name = btrim(substr(name, 7)) WHERE kind = 0 AND name ILIKE 'Event:%';
+CREATE INDEX IF NOT EXISTS stories_kind ON stories (kind);
@@ -0,0 +1,22 @@
+/* Add a job board with structured jobs which expire unless renewed */
+CREATE TABLE IF NOT EXISTS jobs (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+status integer,
+name text,
+text text,
+company text,
+company_url text,
+location text,
+remote integer DEFAULT 0,
+salary_min integer DEFAULT 0,
+salary_max integer DEFAULT 0,
+currency text,
+url text,
+user_id integer,
+user_name text,
+expires_at timestamp
+);
+ALTER TABLE jobs OWNER TO gohackernews_server;
+CREATE INDEX IF NOT EXISTS jobs_expires_at ON jobs (expires_at);
@@ -0,0 +1,18 @@
+/* Add a ledger of payments taken through Stripe Checkout */
+CREATE TABLE IF NOT EXISTS payments (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+status integer DEFAULT 0,
+user_id integer,
+product text,
+item_id integer,
+amount integer DEFAULT 0,
+currency text,
+session_id text,
+payment_intent text,
+event_id text,
+fulfilled_at timestamp
+);
+ALTER TABLE payments OWNER TO gohackernews_server;
+CREATE UNIQUE INDEX IF NOT EXISTS payments_session_id ON payments (session_id) WHERE session_id <> '';
@@ -0,0 +1,19 @@
+/* Add sponsored placements of stories and jobs, paid for through Stripe Checkout */
+CREATE TABLE IF NOT EXISTS sponsorships (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+status integer DEFAULT 0,
+user_id integer,
+story_id integer DEFAULT 0,
+job_id integer DEFAULT 0,
+name text,
+url text,
+days integer DEFAULT 0,
+starts_at timestamp,
+ends_at timestamp,
+impressions integer DEFAULT 0,
+clicks integer DEFAULT 0
+);
+ALTER TABLE sponsorships OWNER TO gohackernews_server;
+CREATE INDEX IF NOT EXISTS sponsorships_status ON sponsorships (status);
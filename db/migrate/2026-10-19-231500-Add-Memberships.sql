@@ -0,0 +1,21 @@
+/* Add supporter memberships renewed by Stripe subscriptions, and supporter badges on users and their content */
+CREATE TABLE IF NOT EXISTS memberships (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+status integer DEFAULT 0,
+user_id integer,
+tier integer DEFAULT 0,
+subscription_id text,
+customer_id text,
+renews_at timestamp,
+cancelling boolean DEFAULT false,
+event_at timestamp
+);
+ALTER TABLE memberships OWNER TO gohackernews_server;
+CREATE INDEX IF NOT EXISTS memberships_user_id ON memberships (user_id);
+CREATE INDEX IF NOT EXISTS memberships_subscription_id ON memberships (subscription_id);
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS supporter boolean DEFAULT false;
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS supporter_tier integer DEFAULT 0;
+ALTER TABLE IF EXISTS stories ADD COLUMN IF NOT EXISTS user_supporter integer DEFAULT 0;
+ALTER TABLE IF EXISTS comments ADD COLUMN IF NOT EXISTS user_supporter integer DEFAULT 0;
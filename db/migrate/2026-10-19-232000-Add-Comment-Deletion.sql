@@ -0,0 +1,3 @@
+/* Keep deleted comments as tombstones so that their replies are kept */
+ALTER TABLE IF EXISTS comments ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+CREATE INDEX IF NOT EXISTS comments_deleted_at ON comments (deleted_at) WHERE deleted_at IS NOT NULL;
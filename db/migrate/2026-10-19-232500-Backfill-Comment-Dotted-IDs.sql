@@ -0,0 +1,8 @@
+/* Set dotted_ids on comments to the ids of their ancestors, e.g. 1.5. for a reply to 5 which replies to 1 */
+WITH RECURSIVE threads AS (
+SELECT id, ''::text AS dotted_ids FROM comments WHERE parent_id IS NULL OR parent_id = 0
+UNION ALL
+SELECT comments.id, threads.dotted_ids || threads.id::text || '.' FROM comments JOIN threads ON comments.parent_id = threads.id
+)
+UPDATE comments SET dotted_ids = threads.dotted_ids FROM threads WHERE comments.id = threads.id AND comments.dotted_ids IS DISTINCT FROM threads.dotted_ids;
+CREATE INDEX IF NOT EXISTS comments_story_id_dotted_ids ON comments (story_id, dotted_ids text_pattern_ops);
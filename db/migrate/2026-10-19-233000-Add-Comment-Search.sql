@@ -0,0 +1,3 @@
+/* Add a full text index on comment text for searching comments */
+CREATE INDEX IF NOT EXISTS comments_text_search ON comments USING gin (to_tsvector('english', text));
+CREATE INDEX IF NOT EXISTS comments_created_at ON comments (created_at);
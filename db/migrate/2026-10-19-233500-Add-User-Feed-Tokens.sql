@@ -0,0 +1,3 @@
+/* Add tokens identifying users in the urls of their private feeds */
+ALTER TABLE IF EXISTS users ADD COLUMN IF NOT EXISTS feed_token text;
+CREATE UNIQUE INDEX IF NOT EXISTS users_feed_token ON users (feed_token) WHERE feed_token <> '';
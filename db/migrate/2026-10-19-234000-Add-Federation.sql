@@ -0,0 +1,29 @@
+/* Add the followers of the site on the fediverse, the queue of activities delivered to them, and when stories were published to them */
+CREATE TABLE IF NOT EXISTS followers (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+actor_id text,
+inbox text,
+shared_inbox text,
+follow_id text
+);
+ALTER TABLE followers OWNER TO gohackernews_server;
+CREATE UNIQUE INDEX IF NOT EXISTS followers_actor_id ON followers (actor_id);
+
+CREATE TABLE IF NOT EXISTS deliveries (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+inbox text,
+activity text,
+attempts integer DEFAULT 0,
+next_at timestamp,
+delivered_at timestamp,
+failed_at timestamp,
+error text
+);
+ALTER TABLE deliveries OWNER TO gohackernews_server;
+CREATE INDEX IF NOT EXISTS deliveries_next_at ON deliveries (next_at) WHERE delivered_at IS NULL AND failed_at IS NULL;
+
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS federated_at timestamp;
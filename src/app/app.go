@@ -1,15 +1,28 @@
 package app
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/fragmenta/assets"
 	"github.com/fragmenta/server/config"
 	"github.com/fragmenta/server/log"
 
+	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/activitypub"
+	"github.com/kennygrant/gohackernews/src/lib/karma"
 	"github.com/kennygrant/gohackernews/src/lib/mail"
 	"github.com/kennygrant/gohackernews/src/lib/mail/adapters/sendgrid"
+	"github.com/kennygrant/gohackernews/src/lib/password"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+	"github.com/kennygrant/gohackernews/src/memberships"
+	"github.com/kennygrant/gohackernews/src/payments"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
 )
 
 // appAssets holds a reference to our assets for use in asset setup
@@ -49,6 +62,24 @@ func Setup() {
 	// Setup our authentication and authorisation
 	SetupAuth()
 
+	// Setup the karma policy for user privileges
+	SetupKarma()
+
+	// Setup the password policy for users
+	SetupPasswords()
+
+	// Setup the time authors may edit their stories and comments
+	SetupEditWindows()
+
+	// Setup the time jobs are listed for
+	SetupJobs()
+
+	// Setup stripe and the products which may be bought
+	SetupPayments()
+
+	// Setup the key the site signs activities with on the fediverse
+	SetupFederation()
+
 	// Setup our router and handlers
 	SetupRoutes()
 
@@ -82,3 +113,136 @@ func SetupMail() {
 	mail.Production = config.Production()
 	mail.Service = sendgrid.New(config.Get("mail_from"), config.Get("mail_secret"))
 }
+
+// SetupKarma loads the karma policy for privileges and vote costs from config.
+func SetupKarma() {
+	policy, err := karma.Load(config.Get)
+	if err != nil {
+		log.Fatal(log.V{"msg": "unable to load karma policy", "error": err})
+		os.Exit(1)
+	}
+	karma.Current = policy
+}
+
+// SetupPasswords loads the password strength policy and hash cost from config.
+func SetupPasswords() {
+	policy, err := password.Load(config.Get)
+	if err != nil {
+		log.Fatal(log.V{"msg": "unable to load password policy", "error": err})
+		os.Exit(1)
+	}
+	password.Current = policy
+}
+
+// SetupEditWindows loads the time authors may edit their stories and comments,
+// and the time admins may restore deleted comments, from config.
+func SetupEditWindows() {
+	var err error
+	stories.EditWindow, err = configMinutes("story_edit_minutes", stories.EditWindow)
+	if err == nil {
+		comments.EditWindow, err = configMinutes("comment_edit_minutes", comments.EditWindow)
+	}
+	if err == nil {
+		comments.RestoreWindow, err = configMinutes("comment_restore_minutes", comments.RestoreWindow)
+	}
+	if err != nil {
+		log.Fatal(log.V{"msg": "unable to load edit windows", "error": err})
+		os.Exit(1)
+	}
+}
+
+// SetupJobs loads the number of days jobs are listed for from config.
+func SetupJobs() {
+	s := config.Get("job_days")
+	if s == "" {
+		return
+	}
+	days, err := strconv.Atoi(s)
+	if err != nil || days < 1 {
+		log.Fatal(log.V{"msg": "unable to load job lifetime", "job_days": s})
+		os.Exit(1)
+	}
+	jobs.Lifetime = time.Duration(days) * 24 * time.Hour
+}
+
+// SetupFederation sets up the activitypub client with the private key of the actor for the site,
+// read from the pem file at activitypub_key in config. Without a key the site is not federated.
+func SetupFederation() {
+	path := config.Get("activitypub_key")
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(log.V{"msg": "unable to read activitypub key", "error": err})
+		os.Exit(1)
+	}
+
+	key, err := activitypub.ParsePrivateKey(data)
+	if err != nil {
+		log.Fatal(log.V{"msg": "unable to load activitypub key", "error": err})
+		os.Exit(1)
+	}
+
+	activitypub.Default = activitypub.New(config.Get("root_url")+"/actor#main-key", key)
+}
+
+// SetupPayments sets up the stripe client from config, and registers the products which
+// may be bought. Products without a stripe price in config are free.
+func SetupPayments() {
+	stripe.Default = stripe.New(config.Get("stripe_secret"), config.Get("stripe_webhook_secret"))
+
+	payments.Register(&payments.Product{
+		Name:        jobs.TableName,
+		Description: "job listing",
+		Price:       config.Get("stripe_price_jobs"),
+		Find: func(id int64) (payments.Item, error) {
+			return jobs.Find(id)
+		},
+	})
+
+	payments.Register(&payments.Product{
+		Name:        sponsorships.TableName,
+		Description: "sponsored placement",
+		Price:       config.Get("stripe_price_sponsorships"),
+		Find: func(id int64) (payments.Item, error) {
+			return sponsorships.Find(id)
+		},
+	})
+
+	// Each tier of membership is a product with its own recurring price
+	for _, tier := range users.Tiers() {
+		tier := tier
+		name := users.TierName(tier)
+		payments.Register(&payments.Product{
+			Name:        name,
+			Description: users.TierDisplay(tier) + " membership",
+			Price:       config.Get("stripe_price_" + name),
+			Mode:        stripe.ModeSubscription,
+			Find: func(id int64) (payments.Item, error) {
+				m, err := memberships.Find(id)
+				if err != nil {
+					return nil, err
+				}
+				if m.Tier != tier {
+					return nil, fmt.Errorf("app: membership %d is not of tier %s", id, name)
+				}
+				return m, nil
+			},
+		})
+	}
+}
+
+// configMinutes returns the duration in minutes set in config for key, or d if not set.
+func configMinutes(key string, d time.Duration) (time.Duration, error) {
+	s := config.Get(key)
+	if s == "" {
+		return d, nil
+	}
+	m, err := strconv.Atoi(s)
+	if err != nil || m < 0 {
+		return 0, fmt.Errorf("app: invalid value for %s:%s", key, s)
+	}
+	return time.Duration(m) * time.Minute, nil
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/fragmenta/auth/can"
 	"github.com/fragmenta/server/config"
 
+	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/stories"
 	"github.com/kennygrant/gohackernews/src/users"
 )
 
@@ -91,3 +93,90 @@ func TestAuth(t *testing.T) {
 	}
 
 }
+
+// TestPermissions tests each role against each action in the permission matrix.
+func TestPermissions(t *testing.T) {
+
+	SetupAuth()
+
+	// Resources owned by the reader (id 3)
+	reader := users.MockReader()
+	story := stories.New()
+	story.ID = 1
+	story.UserID = reader.ID
+	comment := comments.New()
+	comment.ID = 1
+	comment.UserID = reader.ID
+	other := &users.User{Role: users.Reader}
+	other.ID = 4
+	other.TableName = users.TableName
+
+	roles := map[string]*users.User{
+		"anon":   users.MockAnon(),
+		"reader": reader,
+		"editor": users.MockEditor(),
+		"admin":  users.MockAdmin(),
+	}
+
+	tests := []struct {
+		action  string
+		do      func(u *users.User) error
+		allowed []string
+	}{
+		{"create story", func(u *users.User) error { return can.Create(stories.New(), u) }, []string{"reader", "editor", "admin"}},
+		{"update story", func(u *users.User) error { return can.Update(story, u) }, []string{"reader", "editor", "admin"}},
+		{"destroy story", func(u *users.User) error { return can.Destroy(story, u) }, []string{"admin"}},
+		{"create comment", func(u *users.User) error { return can.Create(comments.New(), u) }, []string{"reader", "editor", "admin"}},
+		{"update comment", func(u *users.User) error { return can.Update(comment, u) }, []string{"reader", "editor", "admin"}},
+		{"destroy comment", func(u *users.User) error { return can.Destroy(comment, u) }, []string{"admin"}},
+		{"update other user", func(u *users.User) error { return can.Update(other, u) }, []string{"admin"}},
+		{"list users", func(u *users.User) error { return can.List(other, u) }, []string{"admin"}},
+		{"manage users", func(u *users.User) error { return can.Manage(other, u) }, []string{"admin"}},
+	}
+
+	for _, test := range tests {
+		for name, u := range roles {
+			allowed := false
+			for _, a := range test.allowed {
+				if a == name {
+					allowed = true
+				}
+			}
+			err := test.do(u)
+			if allowed && err != nil {
+				t.Errorf("app: %s should be allowed to %s :%s", name, test.action, err)
+			}
+			if !allowed && err == nil {
+				t.Errorf("app: %s should not be allowed to %s", name, test.action)
+			}
+		}
+	}
+
+	// Readers may update only the stories and comments they own
+	if can.Update(story, users.MockEditor()) != nil || can.Update(story, &users.User{Role: users.Reader}) == nil {
+		t.Errorf("app: readers may update stories they do not own")
+	}
+
+	// Only editors and admins may moderate
+	for name, u := range roles {
+		if u.CanModerate() != (name == "editor" || name == "admin") {
+			t.Errorf("app: wrong moderation permission for %s", name)
+		}
+	}
+
+	// Moderators may suspend, but may not change content, points or ownership
+	for _, p := range append(stories.AllowedParamsModerator(), comments.AllowedParamsModerator()...) {
+		if p != "status" {
+			t.Errorf("app: moderators should not be allowed to set %s", p)
+		}
+	}
+
+	// Only admins see content by shadow banned users
+	author := &users.User{ShadowBanned: true}
+	author.ID = 99
+	for name, u := range roles {
+		if u.CanSee(author) != (name == "admin") {
+			t.Errorf("app: wrong shadow ban visibility for %s", name)
+		}
+	}
+}
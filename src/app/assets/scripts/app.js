@@ -14,9 +14,9 @@ DOM.Ready(function() {
 
 });
 
-// Perform AJAX post on click on method=post|delete anchors
-function ActivateMethodLinks() {
-    DOM.On('a[method="post"], a[method="delete"]', 'click', function(e) {
+// Perform AJAX post on click on method=post|delete anchors, within scope if given
+function ActivateMethodLinks(scope) {
+    DOM.On(scoped(scope, 'a[method="post"], a[method="delete"]'), 'click', function(e) {
         var link = this;
 
         // Confirm action before delete
@@ -67,7 +67,7 @@ function ActivateMethodLinks() {
         return false;
     });
 
-    DOM.On('a[method="back"]', 'click', function(e) {
+    DOM.On(scoped(scope, 'a[method="back"]'), 'click', function(e) {
         history.back(); // go back one step in history
         e.preventDefault();
         return false;
@@ -78,11 +78,11 @@ function ActivateMethodLinks() {
 
 // Insert an input into every form with js to include the csrf token.
 // this saves us having to insert tokens into every form.
-function ActivateForms() {
+function ActivateForms(scope) {
     // Get authenticity token from head of page
     var token = authenticityToken();
 
-    DOM.Each('form', function(f) {
+    DOM.Each(scoped(scope, 'form'), function(f) {
 
         // Create an input element 
         var csrf = document.createElement("input");
@@ -103,8 +103,8 @@ function ActivateFilterFields() {
 }
 
 // Show/Hide elements with selector in attribute href - do this with a hidden class name
-function ActivateShowlinks() {
-    DOM.On('.show', 'click', function(e) {
+function ActivateShowlinks(scope) {
+    DOM.On(scoped(scope, '.show'), 'click', function(e) {
         e.preventDefault();
         var selector = this.getAttribute('data-show');
         if (selector == "") {
@@ -123,6 +123,16 @@ function ActivateShowlinks() {
     });
 }
 
+// Return the selector sel limited to elements within the scope selector, if any
+function scoped(scope, sel) {
+    if (scope === undefined) {
+        return sel;
+    }
+    return sel.split(',').map(function(s) {
+        return scope + ' ' + s.trim();
+    }).join(', ');
+}
+
 function authenticityToken() {
     // Collect the authenticity token from meta tags in header
     var meta = DOM.First("meta[name='authenticity_token']")
@@ -6,6 +6,9 @@ import (
 	"github.com/fragmenta/server/config"
 
 	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/memberships"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
 	"github.com/kennygrant/gohackernews/src/stories"
 	"github.com/kennygrant/gohackernews/src/users"
 )
@@ -39,6 +42,42 @@ func SetupAuth() {
 	can.Authorise(users.Reader, can.CreateResource, stories.TableName)
 	can.AuthoriseOwner(users.Reader, can.UpdateResource, stories.TableName)
 
+	// Readers may post jobs, and only employers may edit their own jobs
+	can.Authorise(users.Reader, can.CreateResource, jobs.TableName)
+	can.AuthoriseOwner(users.Reader, can.UpdateResource, jobs.TableName)
+
+	// Readers may sponsor their own stories and jobs, and see reports for their sponsorships
+	can.Authorise(users.Reader, can.CreateResource, sponsorships.TableName)
+	can.AuthoriseOwner(users.Reader, can.ShowResource, sponsorships.TableName)
+
+	// Readers may become supporters, and cancel their own memberships
+	can.Authorise(users.Reader, can.CreateResource, memberships.TableName)
+	can.AuthoriseOwner(users.Reader, can.UpdateResource, memberships.TableName)
+
+	// Editors (moderators) may edit their user, but no others, so they cannot change roles or points
+	can.AuthoriseOwner(users.Editor, can.UpdateResource, users.TableName)
+
+	// Editors may add comments and stories, and show or update any comment or story
+	// the params they may change are limited by AllowedParamsModerator
+	can.Authorise(users.Editor, can.CreateResource, comments.TableName)
+	can.Authorise(users.Editor, can.ShowResource, comments.TableName)
+	can.Authorise(users.Editor, can.UpdateResource, comments.TableName)
+	can.Authorise(users.Editor, can.CreateResource, stories.TableName)
+	can.Authorise(users.Editor, can.ShowResource, stories.TableName)
+	can.Authorise(users.Editor, can.UpdateResource, stories.TableName)
+
+	// Editors may post jobs, but like readers may only edit their own
+	can.Authorise(users.Editor, can.CreateResource, jobs.TableName)
+	can.AuthoriseOwner(users.Editor, can.UpdateResource, jobs.TableName)
+
+	// Editors may sponsor their own stories and jobs, but only admins review sponsorships
+	can.Authorise(users.Editor, can.CreateResource, sponsorships.TableName)
+	can.AuthoriseOwner(users.Editor, can.ShowResource, sponsorships.TableName)
+
+	// Editors may become supporters, and cancel their own memberships
+	can.Authorise(users.Editor, can.CreateResource, memberships.TableName)
+	can.AuthoriseOwner(users.Editor, can.UpdateResource, memberships.TableName)
+
 	// Anon may create users
 	can.AuthoriseOwner(users.Anon, can.CreateResource, users.TableName)
 
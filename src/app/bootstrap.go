@@ -235,6 +235,12 @@ func runMigrations(projectPath string) error {
 	// Sort the list alphabetically
 	sort.Strings(files)
 
+	// The database and tables must be created before any later migrations are applied,
+	// the create tables migration contains the full schema, so later migrations are guarded
+	sort.SliceStable(files, func(i, j int) bool {
+		return migrationOrder(files[i]) < migrationOrder(files[j])
+	})
+
 	for _, file := range files {
 		filename := path.Base(file)
 
@@ -273,6 +279,18 @@ func runMigrations(projectPath string) error {
 	return nil
 }
 
+// migrationOrder returns the order in which bootstrap migrations should run,
+// create database first, then create tables, followed by all other migrations.
+func migrationOrder(file string) int {
+	switch {
+	case strings.Contains(file, createDatabaseMigrationName):
+		return 0
+	case strings.Contains(file, createTablesMigrationName):
+		return 1
+	}
+	return 2
+}
+
 // Oh, we need to write the full list of migrations, not just one migration version
 
 // Update the database with a line recording what we have done
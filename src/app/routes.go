@@ -9,7 +9,11 @@ import (
 
 	// Resource Actions
 	commentactions "github.com/kennygrant/gohackernews/src/comments/actions"
+	followeractions "github.com/kennygrant/gohackernews/src/followers/actions"
+	jobactions "github.com/kennygrant/gohackernews/src/jobs/actions"
 	"github.com/kennygrant/gohackernews/src/lib/session"
+	membershipactions "github.com/kennygrant/gohackernews/src/memberships/actions"
+	sponsorshipactions "github.com/kennygrant/gohackernews/src/sponsorships/actions"
 	storyactions "github.com/kennygrant/gohackernews/src/stories/actions"
 	stripeactions "github.com/kennygrant/gohackernews/src/stripe/actions"
 	useractions "github.com/kennygrant/gohackernews/src/users/actions"
@@ -34,33 +38,86 @@ func SetupRoutes() *mux.Mux {
 	router.Get("/stripe/pay", stripeactions.HandleShowPay)
 	router.Get("/stripe/thanks", stripeactions.HandleShowPayThanks)
 	router.Get("/stripe/cancel", stripeactions.HandleShowPayCancel)
+	router.Post("/stripe/checkout", stripeactions.HandleCheckout)
+	router.Post("/stripe/webhook", stripeactions.HandleWebhook)
+	router.Get("/stripe/payments", stripeactions.HandleIndex)
+
+	// Add activitypub routes for the actor which represents the site
+	router.Get("/.well-known/webfinger", followeractions.HandleWebFinger)
+	router.Get("/actor", followeractions.HandleActor)
+	router.Get("/actor/outbox", followeractions.HandleOutbox)
+	router.Get("/actor/followers", followeractions.HandleFollowers)
+	router.Get("/actor/stories/{id:[0-9]+}", followeractions.HandleStory)
+	router.Post("/actor/inbox", followeractions.HandleInbox)
 
 	// Add story routes
-	router.Get("/go-jobs", storyactions.HandleJobs)
-	router.Get("/index{format:(.xml)?}", storyactions.HandleIndex)
+	router.Get("/go-jobs{format:(.xml)?}", storyactions.HandleJobs)
+	router.Get("/show{format:(.xml|.atom|.json)?}", storyactions.HandleListShow)
+	router.Get("/ask{format:(.xml|.atom|.json)?}", storyactions.HandleListAsk)
+	router.Get("/videos{format:(.xml|.atom|.json)?}", storyactions.HandleListVideos)
+	router.Get("/events{format:(.xml|.atom|.json)?}", storyactions.HandleListEvents)
+	router.Get("/index{format:(.xml|.atom|.json)?}", storyactions.HandleIndex)
 	router.Get("/stories/create", storyactions.HandleCreateShow)
 	router.Post("/stories/create", storyactions.HandleCreate)
-	router.Get("/stories/code{format:(.xml)?}", storyactions.HandleListCode)
-	router.Get("/stories/upvoted{format:(.xml)?}", storyactions.HandleListUpvoted)
+	router.Get("/stories/code{format:(.xml|.atom|.json)?}", storyactions.HandleListCode)
+	router.Get("/stories/flagged", storyactions.HandleFlagged)
+	router.Get("/stories/metadata", storyactions.HandleMetadata)
+	router.Get("/stories/upvoted{format:(.xml|.atom|.json)?}", storyactions.HandleListUpvoted)
 	router.Get("/stories/{id:[0-9]+}/update", storyactions.HandleUpdateShow)
 	router.Post("/stories/{id:[0-9]+}/update", storyactions.HandleUpdate)
 	router.Post("/stories/{id:[0-9]+}/destroy", storyactions.HandleDestroy)
 	router.Post("/stories/{id:[0-9]+}/upvote", storyactions.HandleUpvote)
 	router.Post("/stories/{id:[0-9]+}/downvote", storyactions.HandleDownvote)
 	router.Post("/stories/{id:[0-9]+}/flag", storyactions.HandleFlag)
+	router.Post("/stories/{id:[0-9]+}/flags/resolve", storyactions.HandleResolveFlags)
+	router.Post("/stories/{id:[0-9]+}/lock", storyactions.HandleLock)
+	router.Post("/stories/{id:[0-9]+}/merge", storyactions.HandleMerge)
+	router.Get("/stories/{id:[0-9]+}/history", storyactions.HandleHistory)
+	router.Post("/stories/{id:[0-9]+}/revisions/{revision_id:[0-9]+}/restore", storyactions.HandleRestore)
 	router.Get("/stories/{id:[0-9]+}", storyactions.HandleShow)
-	router.Get("/stories{format:(.xml)?}", storyactions.HandleIndex)
+	router.Get("/stories{format:(.xml|.atom|.json)?}", storyactions.HandleIndex)
 	router.Get("/sitemap.xml", storyactions.HandleSiteMap)
 
-	router.Get("/comments", commentactions.HandleIndex)
+	// Add job routes
+	router.Get("/jobs{format:(.xml|.atom|.json)?}", jobactions.HandleIndex)
+	router.Get("/jobs/stories{format:(.xml|.atom|.json)?}", storyactions.HandleListJobs)
+	router.Get("/jobs/create", jobactions.HandleCreateShow)
+	router.Post("/jobs/create", jobactions.HandleCreate)
+	router.Get("/jobs/{id:[0-9]+}/update", jobactions.HandleUpdateShow)
+	router.Post("/jobs/{id:[0-9]+}/update", jobactions.HandleUpdate)
+	router.Post("/jobs/{id:[0-9]+}/renew", jobactions.HandleRenew)
+	router.Post("/jobs/{id:[0-9]+}/destroy", jobactions.HandleDestroy)
+	router.Get("/jobs/{id:[0-9]+}", jobactions.HandleShow)
+
+	// Add sponsorship routes
+	router.Get("/sponsorships", sponsorshipactions.HandleIndex)
+	router.Get("/sponsorships/create", sponsorshipactions.HandleCreateShow)
+	router.Post("/sponsorships/create", sponsorshipactions.HandleCreate)
+	router.Post("/sponsorships/{id:[0-9]+}/approve", sponsorshipactions.HandleApprove)
+	router.Post("/sponsorships/{id:[0-9]+}/reject", sponsorshipactions.HandleReject)
+	router.Get("/sponsorships/{id:[0-9]+}/click", sponsorshipactions.HandleClick)
+	router.Get("/sponsorships/{id:[0-9]+}", sponsorshipactions.HandleShow)
+
+	// Add membership routes
+	router.Get("/memberships", membershipactions.HandleIndex)
+	router.Post("/memberships/create", membershipactions.HandleCreate)
+	router.Post("/memberships/{id:[0-9]+}/cancel", membershipactions.HandleCancel)
+
+	router.Get("/comments{format:(.xml|.atom|.json)?}", commentactions.HandleIndex)
 	router.Get("/comments/create", commentactions.HandleCreateShow)
+	router.Get("/comments/flagged", commentactions.HandleFlagged)
 	router.Post("/comments/create", commentactions.HandleCreate)
 	router.Get("/comments/{id:[0-9]+}/update", commentactions.HandleUpdateShow)
 	router.Post("/comments/{id:[0-9]+}/update", commentactions.HandleUpdate)
 	router.Post("/comments/{id:[0-9]+}/destroy", commentactions.HandleDestroy)
+	router.Post("/comments/{id:[0-9]+}/restore", commentactions.HandleUndelete)
 	router.Post("/comments/{id:[0-9]+}/upvote", commentactions.HandleUpvote)
 	router.Post("/comments/{id:[0-9]+}/downvote", commentactions.HandleDownvote)
 	router.Post("/comments/{id:[0-9]+}/flag", commentactions.HandleFlag)
+	router.Post("/comments/{id:[0-9]+}/flags/resolve", commentactions.HandleResolveFlags)
+	router.Get("/comments/{id:[0-9]+}/history", commentactions.HandleHistory)
+	router.Post("/comments/{id:[0-9]+}/revisions/{revision_id:[0-9]+}/restore", commentactions.HandleRestore)
+	router.Get("/comments/{id:[0-9]+}/replies", commentactions.HandleReplies)
 	router.Get("/comments/{id:[0-9]+}", commentactions.HandleShow)
 
 	router.Get("/users", useractions.HandleIndex)
@@ -68,8 +125,31 @@ func SetupRoutes() *mux.Mux {
 	router.Post("/users/create", useractions.HandleCreate)
 	router.Get("/users/{id:[0-9]+}/update", useractions.HandleUpdateShow)
 	router.Post("/users/{id:[0-9]+}/update", useractions.HandleUpdate)
+	router.Get("/users/{id:[0-9]+}/password", useractions.HandlePasswordChangeShow)
+	router.Post("/users/{id:[0-9]+}/password", useractions.HandlePasswordChange)
 	router.Post("/users/{id:[0-9]+}/destroy", useractions.HandleDestroy)
-	router.Get("/users/{id:[0-9]+}", useractions.HandleShow)
+	router.Get("/users/{id:[0-9]+}/delete", useractions.HandleDeleteShow)
+	router.Post("/users/{id:[0-9]+}/delete", useractions.HandleDelete)
+	router.Post("/users/{id:[0-9]+}/delete/cancel", useractions.HandleDeleteCancel)
+	router.Post("/users/{id:[0-9]+}/feeds/token", useractions.HandleFeedToken)
+	router.Post("/users/{id:[0-9]+}/purge", useractions.HandlePurge)
+	router.Get("/users/{id:[0-9]+}/export", useractions.HandleExportShow)
+	router.Post("/users/{id:[0-9]+}/export", useractions.HandleExport)
+	router.Get("/users/{id:[0-9]+}/export/download", useractions.HandleExportDownload)
+	router.Get("/users/{id:[0-9]+}/privileges", useractions.HandlePrivileges)
+	router.Get("/users/{id:[0-9]+}/points", useractions.HandlePoints)
+	router.Post("/users/points/{id:[0-9]+}/reverse", useractions.HandleReverse)
+	router.Get("/users/reconcile", useractions.HandleReconcileShow)
+	router.Post("/users/reconcile", useractions.HandleReconcile)
+	router.Post("/users/{id:[0-9]+}/ban", useractions.HandleBanInvited)
+	router.Post("/users/{id:[0-9]+}/suspend", useractions.HandleSuspend)
+	router.Post("/users/{id:[0-9]+}/unsuspend", useractions.HandleUnsuspend)
+	router.Post("/users/{id:[0-9]+}/shadowban", useractions.HandleShadowBan)
+	router.Get("/users/invites", useractions.HandleInvitesShow)
+	router.Post("/users/invites", useractions.HandleInviteCreate)
+	router.Get("/users/invites/tree", useractions.HandleInviteTree)
+	router.Get("/users/{id:[0-9]+}/avatar.svg", useractions.HandleAvatar)
+	router.Get("/users/{id:[0-9]+}{format:(.json)?}", useractions.HandleShow)
 	router.Get("/u/{name:.*}", useractions.HandleShowName)
 	router.Get("/users/login", useractions.HandleLoginShow)
 	router.Post("/users/login", useractions.HandleLogin)
@@ -4,8 +4,12 @@ import (
 	"time"
 
 	"github.com/fragmenta/server/config"
+	"github.com/kennygrant/gohackernews/src/comments/actions"
+	"github.com/kennygrant/gohackernews/src/followers/actions"
 	"github.com/kennygrant/gohackernews/src/lib/twitter"
+	"github.com/kennygrant/gohackernews/src/sponsorships/actions"
 	"github.com/kennygrant/gohackernews/src/stories/actions"
+	"github.com/kennygrant/gohackernews/src/users/actions"
 )
 
 // SetupServices sets up external services from our config file
@@ -30,6 +34,28 @@ func SetupServices() {
 
 		ScheduleAt(storyactions.TweetTopStory, tweetTime, tweetInterval)
 	}
+
+	// Remove expired user data exports every hour
+	ScheduleAt(useractions.PurgeExports, now.Add(time.Minute), time.Hour)
+
+	// Set canonical urls on older stories every hour until all are set
+	ScheduleAt(storyactions.CanonicaliseStories, now.Add(time.Minute), time.Hour)
+
+	// Delete accounts whose deletion grace period has passed every hour
+	ScheduleAt(useractions.DeleteScheduledUsers, now.Add(time.Minute), time.Hour)
+
+	// Remove comments deleted before the restore window every hour
+	ScheduleAt(commentactions.PurgeDeletedComments, now.Add(time.Minute), time.Hour)
+
+	// Start and finish sponsored placements on schedule every ten minutes
+	ScheduleAt(sponsorshipactions.ScheduleSponsorships, now.Add(time.Minute), 10*time.Minute)
+
+	// Publish front page stories to followers on the fediverse if we have a key, and deliver them every minute
+	if config.Get("activitypub_key") != "" {
+		ScheduleAt(followeractions.PublishStories, now.Add(time.Minute), 10*time.Minute)
+		ScheduleAt(followeractions.DeliverActivities, now.Add(time.Minute), time.Minute)
+		ScheduleAt(followeractions.PurgeDeliveries, now.Add(time.Minute), time.Hour)
+	}
 	/*
 		// Set up mail
 		if config.Get("mail_secret") != "" {
@@ -9,6 +9,7 @@ import (
 	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
+	"github.com/kennygrant/gohackernews/src/lib/diff"
 	"github.com/kennygrant/gohackernews/src/lib/helpers"
 )
 
@@ -56,6 +57,7 @@ func SetupView() {
 	defer log.Time(time.Now(), log.V{"msg": "Finished loading templates"})
 
 	view.Helpers["markup"] = helpers.Markup
+	view.Helpers["diff"] = diff.HTML
 	view.Helpers["timeago"] = helpers.TimeAgo
 	view.Helpers["root_url"] = helpers.RootURL
 
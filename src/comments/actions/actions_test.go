@@ -7,12 +7,16 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/fragmenta/auth/can"
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/query"
 
 	"github.com/kennygrant/gohackernews/src/comments"
 	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+	"github.com/kennygrant/gohackernews/src/users"
 )
 
 // names is used to test setting and getting the first string field of the comment.
@@ -38,7 +42,7 @@ func TestSetup(t *testing.T) {
 
 	// FIXME - Need to write routes out here again, but without pkg prefix
 	// Any neat way to do this instead? We'd need a separate routes package under app...
-	router.Add("/comments", nil)
+	router.Add("/comments{format:(.xml|.atom|.json)?}", nil)
 	router.Add("/comments/create", nil)
 	router.Add("/comments/create", nil).Post()
 	router.Add("/comments/login", nil)
@@ -48,6 +52,10 @@ func TestSetup(t *testing.T) {
 	router.Add("/comments/{id:\\d+}/update", nil)
 	router.Add("/comments/{id:\\d+}/update", nil).Post()
 	router.Add("/comments/{id:\\d+}/destroy", nil).Post()
+	router.Add("/comments/{id:\\d+}/restore", nil).Post()
+	router.Add("/comments/{id:\\d+}/replies", nil)
+	router.Add("/comments/{id:\\d+}/history", nil)
+	router.Add("/comments/{id:\\d+}/upvote", nil).Post()
 	router.Add("/comments/{id:\\d+}", nil)
 
 	// Delete all comments to ensure we get consistent results
@@ -181,6 +189,33 @@ func TestListComments(t *testing.T) {
 
 }
 
+// Test GET /comments with filters and its feeds
+func TestListCommentsFiltered(t *testing.T) {
+
+	tests := map[string]string{
+		"/comments?q=comment&user=admin&points=1&sort=top": "foo bar comment",
+		"/comments?q=missing":                              "No comments match these filters.",
+		"/comments?from=2000-01-01&to=2000-01-02":          "No comments match these filters.",
+		"/comments.xml?q=comment":                          "<rss version=\"2.0\"",
+		"/comments.atom?q=comment":                         "<feed xmlns=\"http://www.w3.org/2005/Atom\">",
+		"/comments.json?q=comment":                         "https://jsonfeed.org/version/1.1",
+	}
+
+	for path, pattern := range tests {
+		r := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+
+		err := HandleIndex(w, r)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("commentactions: error handling HandleIndex for %s %s", path, err)
+		}
+
+		if !strings.Contains(w.Body.String(), pattern) {
+			t.Fatalf("commentactions: unexpected response for HandleIndex %s expected:%s got:%s", path, pattern, w.Body.String())
+		}
+	}
+}
+
 // Test of GET /comments/1
 func TestShowComments(t *testing.T) {
 
@@ -209,6 +244,72 @@ func TestShowComments(t *testing.T) {
 	}
 }
 
+// Test GET /comments/1/replies and GET /comments/2 for a reply to comment 1
+func TestRepliesComments(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("story_id", "1")
+	form.Add("parent_id", "1")
+	form.Add("text", "foo bar reply")
+	r := httptest.NewRequest("POST", "/comments/create", strings.NewReader(form.Encode()))
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	err := resource.AddUserSessionCookie(w, r, 1)
+	if err != nil {
+		t.Fatalf("commentactions: error setting session %s", err)
+	}
+
+	err = HandleCreate(w, r)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("commentactions: error handling HandleCreate for reply %s", err)
+	}
+
+	reply, err := comments.Find(2)
+	if err != nil || reply.ParentID != 1 || reply.DottedIDs != "1." {
+		t.Fatalf("commentactions: error with reply values: %v %v", err, reply)
+	}
+
+	// The fragment of replies contains the reply
+	r = httptest.NewRequest("GET", "/comments/1/replies", nil)
+	w = httptest.NewRecorder()
+	err = resource.AddUserSessionCookie(w, r, 1)
+	if err != nil {
+		t.Fatalf("commentactions: error setting session %s", err)
+	}
+	r.URL.RawQuery += "&offset=0"
+
+	err = HandleReplies(w, r)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("commentactions: error handling HandleReplies %s", err)
+	}
+	if !strings.Contains(w.Body.String(), "foo bar reply") {
+		t.Fatalf("commentactions: unexpected response for HandleReplies got:%s", w.Body.String())
+	}
+
+	// Replies are shown in the thread of their story, rather than as comments posted on it
+	if strings.Contains(w.Body.String(), "comment posted on") {
+		t.Fatalf("commentactions: replies rendered without their story got:%s", w.Body.String())
+	}
+
+	// The permalink of the reply shows its parent for context
+	r = httptest.NewRequest("GET", "/comments/2", nil)
+	w = httptest.NewRecorder()
+	err = resource.AddUserSessionCookie(w, r, 1)
+	if err != nil {
+		t.Fatalf("commentactions: error setting session %s", err)
+	}
+
+	err = HandleShow(w, r)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("commentactions: error handling HandleShow for reply %s", err)
+	}
+	pattern := `<ol class="ancestors">`
+	if !strings.Contains(w.Body.String(), pattern) || !strings.Contains(w.Body.String(), "foo bar comment") {
+		t.Fatalf("commentactions: unexpected response for HandleShow expected:%s got:%s", pattern, w.Body.String())
+	}
+}
+
 // Test GET /comments/123/update
 func TestShowUpdateComments(t *testing.T) {
 
@@ -277,6 +378,42 @@ func TestUpdateComments(t *testing.T) {
 
 }
 
+// TestModerateComments tests moderators may suspend comments by others after the edit window has closed
+func TestModerateComments(t *testing.T) {
+
+	// Make user 2 a moderator, and let the edit window for the comment pass
+	can.Authorise(users.Editor, can.UpdateResource, comments.TableName)
+	query.ExecSQL("update users set role=$1 where id=2;", users.Editor)
+	defer query.ExecSQL("update users set role=0 where id=2;")
+	query.ExecSQL("update comments set created_at=$1, user_id=1 where id=1;", query.TimeString(time.Now().UTC().Add(-comments.EditWindow-time.Hour)))
+
+	form := url.Values{}
+	form.Add("status", fmt.Sprintf("%d", status.Suspended))
+	form.Add("text", "moderated")
+	body := strings.NewReader(form.Encode())
+
+	r := httptest.NewRequest("POST", "/comments/1/update", body)
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	err := resource.AddUserSessionCookie(w, r, 2)
+	if err != nil {
+		t.Fatalf("commentactions: error setting session %s", err)
+	}
+
+	err = HandleUpdate(w, r)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("commentactions: moderator could not update old comment %s %d", err, w.Code)
+	}
+
+	// Only the status is changed by moderators
+	comment, err := comments.Find(1)
+	if err != nil || comment.Status != status.Suspended || comment.Text == "moderated" {
+		t.Fatalf("commentactions: error with moderated comment values: %v", comment)
+	}
+	comment.Update(map[string]string{"status": fmt.Sprintf("%d", status.Published)})
+}
+
 // Test of POST /comments/123/destroy
 func TestDeleteComments(t *testing.T) {
 
@@ -316,4 +453,56 @@ func TestDeleteComments(t *testing.T) {
 		t.Fatalf("commentactions: unexpected response for HandleDestroy as anon, expected failure")
 	}
 
+	// Test the comment is kept, but deleted
+	comment, err := comments.Find(1)
+	if err != nil || !comment.Deleted() {
+		t.Fatalf("commentactions: comment not deleted %v", err)
+	}
+
+	// The history of the deleted comment is not shown to other users
+	r = httptest.NewRequest("GET", "/comments/1/history", nil)
+	w = httptest.NewRecorder()
+	err = resource.AddUserSessionCookie(w, r, 2)
+	if err != nil {
+		t.Fatalf("commentactions: error setting session %s", err)
+	}
+	err = HandleHistory(w, r)
+	if err == nil {
+		t.Fatalf("commentactions: history of deleted comment shown")
+	}
+
+	// The deleted comment may not be voted on
+	r = httptest.NewRequest("POST", "/comments/1/upvote", nil)
+	w = httptest.NewRecorder()
+	err = resource.AddUserSessionCookie(w, r, 2)
+	if err != nil {
+		t.Fatalf("commentactions: error setting session %s", err)
+	}
+	err = HandleUpvote(w, r)
+	if err == nil {
+		t.Fatalf("commentactions: deleted comment upvoted")
+	}
+
+}
+
+// Test of POST /comments/123/restore
+func TestUndeleteComments(t *testing.T) {
+
+	r := httptest.NewRequest("POST", "/comments/1/restore", nil)
+	w := httptest.NewRecorder()
+
+	err := resource.AddUserSessionCookie(w, r, 1)
+	if err != nil {
+		t.Fatalf("commentactions: error setting session %s", err)
+	}
+
+	err = HandleUndelete(w, r)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("commentactions: error handling HandleUndelete %s", err)
+	}
+
+	comment, err := comments.Find(1)
+	if err != nil || comment.Deleted() {
+		t.Fatalf("commentactions: comment not restored %v", err)
+	}
 }
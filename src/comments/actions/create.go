@@ -11,6 +11,7 @@ import (
 	"github.com/fragmenta/view"
 
 	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/lib/karma"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/stories"
 )
@@ -52,7 +53,8 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 
 	// Check permissions - if not logged in and above 0 points, redirect
 	if !currentUser.CanComment() {
-		return server.NotAuthorizedError(nil, "Sorry", "You need to be registered and have more than 0 points to comment.")
+		msg := fmt.Sprintf("You need to be registered and have more than %d points to comment.", karma.Current.Threshold(karma.Comment))
+		return server.NotAuthorizedError(nil, "Sorry", msg)
 	}
 
 	// Get Params
@@ -79,6 +81,11 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 		return server.NotFoundError(err)
 	}
 
+	// Locked threads accept no new comments
+	if story.Locked {
+		return server.NotAuthorizedError(nil, "Thread Locked", "Sorry, this thread has been locked by the moderators.")
+	}
+
 	// Clean params according to role
 	accepted := comments.AllowedParams()
 	if currentUser.Admin() {
@@ -87,7 +94,7 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 	commentParams := comment.ValidateParams(params.Map(), accepted)
 
 	// Find the parent to set dotted id
-	// these are of the form xx.xx. with a trailing dot
+	// these are the ids of ancestors of the form xx.xx. with a trailing dot
 	// this saves us from saving twice on create
 	parentID := params.GetInt("parent_id")
 	if parentID > 0 {
@@ -96,7 +103,10 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 		if e != nil {
 			return server.NotFoundError(err)
 		}
-		commentParams["dotted_ids"] = fmt.Sprintf(parent.DottedIDs + ".")
+		if parent.Deleted() {
+			return server.NotAuthorizedError(nil, "Comment Deleted", "Sorry, you cannot reply to a deleted comment.")
+		}
+		commentParams["dotted_ids"] = parent.DottedIDsFor()
 	}
 
 	// Set other params from story/user details
@@ -104,6 +114,7 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 	commentParams["story_name"] = story.Name
 	commentParams["user_id"] = fmt.Sprintf("%d", currentUser.ID)
 	commentParams["user_name"] = currentUser.Name
+	commentParams["user_supporter"] = fmt.Sprintf("%d", currentUser.SupporterTier)
 	commentParams["points"] = "1"
 
 	ID, err := comment.Create(commentParams)
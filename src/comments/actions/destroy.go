@@ -6,12 +6,14 @@ import (
 	"github.com/fragmenta/auth/can"
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
 
 	"github.com/kennygrant/gohackernews/src/comments"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 )
 
-// HandleDestroy responds to /comments/n/destroy by deleting the comment.
+// HandleDestroy responds to /comments/n/destroy by deleting the comment. Deleted comments are
+// kept as tombstones while they have replies, and may be restored until the restore window passes.
 func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
 
 	// Fetch the  params
@@ -33,15 +35,22 @@ func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	// Authorise destroy comment
-	err = can.Destroy(comment, session.CurrentUser(w, r))
+	currentUser := session.CurrentUser(w, r)
+	err = can.Destroy(comment, currentUser)
 	if err != nil {
 		return server.NotAuthorizedError(err)
 	}
 
-	// Destroy the comment
-	comment.Destroy()
+	// Delete the comment
+	err = comment.Delete()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "comment deleted", "comment_id": comment.ID, "user_id": currentUser.ID})
 
-	// Redirect to comments root
-	return server.Redirect(w, r, comment.IndexURL())
+	// Redirect to the story
+	return server.Redirect(w, r, comment.StoryURL())
 
 }
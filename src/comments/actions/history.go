@@ -0,0 +1,108 @@
+package commentactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/revisions"
+)
+
+// HandleHistory responds to GET /comments/{id}/history
+// by showing the changes made to a comment since it was posted.
+func HandleHistory(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the comment
+	comment, err := comments.Find(params.GetInt(comments.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise access - as for showing the comment
+	currentUser := session.CurrentUser(w, r)
+	err = authoriseShow(comment, currentUser)
+	if err != nil {
+		return err
+	}
+
+	// The history of deleted comments is seen only by admins, until they are purged
+	if comment.Deleted() && !currentUser.Admin() {
+		return server.NotFoundError(nil)
+	}
+
+	results, err := revisions.FindAll(revisions.ForComment(comment.ID))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("comment", comment)
+	view.AddKey("changes", revisions.Changes(results, revisions.FromComment(comment)))
+	view.AddKey("meta_title", "Comment history")
+	view.AddKey("currentUser", currentUser)
+	view.Template("comments/views/history.html.got")
+	return view.Render()
+}
+
+// HandleRestore responds to POST /comments/{id}/revisions/{revision_id}/restore
+// by restoring the comment to the revision given, the version replaced is kept as a revision.
+func HandleRestore(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the comment
+	comment, err := comments.Find(params.GetInt(comments.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Find the revision, which must belong to this comment
+	revision, err := revisions.Find(params.GetInt("revision_id"))
+	if err != nil || revision.CommentID != comment.ID {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may restore revisions
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	commentParams := revision.CommentParams()
+	err = revisions.RecordComment(comment, commentParams, currentUser)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	err = comment.Update(commentParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "comment revision restored", "comment_id": comment.ID, "revision_id": revision.ID, "admin_id": currentUser.ID})
+
+	return server.Redirect(w, r, comment.ShowURL())
+}
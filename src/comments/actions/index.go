@@ -1,57 +1,217 @@
 package commentactions
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
 	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/config"
 	"github.com/fragmenta/view"
 
 	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/lib/feed"
+	"github.com/kennygrant/gohackernews/src/lib/helpers"
 	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
 )
 
-// HandleIndex displays a list of comments.
+const listLimit = 50
+
+// dateFormat is the format of the from and to date filters
+const dateFormat = "2006-01-02"
+
+// HandleIndex displays the latest comments across the site, filtered by the params
+// q (text search), user (user name), u (user id), story (story id), from and to (dates)
+// and points (minimum points), sorted by sort (new or top). Responds to GET /comments and its feeds
 func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 
+	// No Authorisation - anyone can view comments
+
 	// Get the params
 	params, err := mux.Params(r)
 	if err != nil {
 		return server.InternalError(err)
 	}
 
-	// Build a query
-	q := comments.Query().Order("created_at desc").Limit(100)
+	// Build a query, requiring points to be over 0 to avoid spam
+	q := comments.Query().Where("points > 0").Where("deleted_at IS NULL").Limit(listLimit)
 
-	// Require points to be over 0 to avoid spam
-	q.Where("points > 0")
+	// Hide comments by shadow banned users from everyone else
+	currentUser := session.CurrentUser(w, r)
+	currentUser.WhereVisible(q)
 
-	// Filter on user id - we only show the actual user's comments
+	// Filter on the params given - we only show the actual user's comments
 	// so not a nested view as in HN
-	userID := params.GetInt("u")
-	if userID > 0 {
-		q.Where("user_id=?", userID)
+	filter := params.Get("q")
+	userName := params.Get("user")
+	from := params.Get("from")
+	to := params.Get("to")
+	filterComments(q, filter, userName, params.GetInt("u"), params.GetInt("story"), from, to, params.GetInt("points"))
+
+	// Name the list after the user or story followed, if any
+	title, err := commentsTitle(userName, params.GetInt("u"), params.GetInt("story"), currentUser)
+	if err != nil {
+		return server.NotFoundError(err)
 	}
 
-	// Filter if requested
-	filter := params.Get("filter")
-	if len(filter) > 0 {
-		q.Where("name ILIKE ?", filter)
+	// Order by newest by default, or by points
+	sort := params.Get("sort")
+	if sort == comments.SortTop {
+		q.Order("points desc, created_at desc, id desc")
+	} else {
+		sort = comments.SortNew
+		q.Order("created_at desc, id desc")
+	}
+
+	// Set the offset in pages if we have one
+	page := int(params.GetInt("page"))
+	if page > 0 {
+		q.Offset(listLimit * page)
 	}
 
-	// Fetch the comments
-	results, err := comments.FindAll(q)
+	// Fetch the comments as a list, not as a tree
+	results, err := comments.FindList(q)
 	if err != nil {
 		return server.InternalError(err)
 	}
 
-	// Get current user
-	currentUser := session.CurrentUser(w, r)
+	// Serve a feed of the comments if requested
+	if format := feed.Negotiate(w, r); format != "" {
+		return renderCommentsFeed(w, r, format, title, results)
+	}
 
 	// Render the template
 	view := view.NewRenderer(w, r)
+	view.Template("comments/views/index.html.got")
+	view.AddKey("page", page)
+	view.AddKey("more", len(results) == listLimit)
 	view.AddKey("filter", filter)
+	view.AddKey("user", userName)
+	view.AddKey("story", params.GetInt("story"))
+	view.AddKey("from", from)
+	view.AddKey("to", to)
+	view.AddKey("points", params.GetInt("points"))
+	view.AddKey("sort", sort)
+	view.AddKey("prevURL", pageURL(r, page-1))
+	view.AddKey("nextURL", pageURL(r, page+1))
 	view.AddKey("comments", results)
+	view.AddKey("meta_title", title)
+	view.AddKey("meta_desc", config.Get("meta_desc"))
+	view.AddKey("meta_rss", feed.Path(r, feed.RSS))
+	view.AddKey("meta_atom", feed.Path(r, feed.Atom))
+	view.AddKey("meta_json", feed.Path(r, feed.JSON))
 	view.AddKey("currentUser", currentUser)
 	return view.Render()
 }
+
+// filterComments restricts the query to comments matching the text search, user, story,
+// dates and minimum points given. Invalid dates are ignored.
+func filterComments(q *query.Query, filter, userName string, userID, storyID int64, from, to string, points int64) {
+
+	// Search the text of comments using the full text index
+	if len(filter) > 0 {
+		q.Where("to_tsvector('english', comments.text) @@ plainto_tsquery('english', ?)", filter)
+	}
+
+	if len(userName) > 0 {
+		q.Where("comments.user_name=?", userName)
+	}
+
+	if userID > 0 {
+		q.Where("comments.user_id=?", userID)
+	}
+
+	if storyID > 0 {
+		q.Where("comments.story_id=?", storyID)
+	}
+
+	// Dates include the whole of the days given
+	t, err := time.Parse(dateFormat, from)
+	if err == nil {
+		q.Where("comments.created_at >= ?", query.TimeString(t))
+	}
+
+	t, err = time.Parse(dateFormat, to)
+	if err == nil {
+		q.Where("comments.created_at < ?", query.TimeString(t.AddDate(0, 0, 1)))
+	}
+
+	if points > 0 {
+		q.Where("comments.points >= ?", points)
+	}
+}
+
+// pageURL returns the url of this page of the list with the same filters.
+func pageURL(r *http.Request, page int) string {
+	q := r.URL.Query()
+	q.Del("page")
+	if page > 0 {
+		q.Set("page", fmt.Sprintf("%d", page))
+	}
+	u := url.URL{Path: "/comments", RawQuery: q.Encode()}
+	return u.String()
+}
+
+// commentsTitle returns the title of the list of comments by this user or on this story.
+// The user and the story must be visible to the current user.
+func commentsTitle(userName string, userID, storyID int64, currentUser *users.User) (string, error) {
+	title := fmt.Sprintf("Latest comments - %s", config.Get("meta_title"))
+
+	if storyID > 0 {
+		results, err := stories.FindAll(currentUser.WhereVisible(stories.Where("id=?", storyID)))
+		if err != nil || len(results) == 0 {
+			return "", fmt.Errorf("commentactions: story %d not found", storyID)
+		}
+		title = fmt.Sprintf("Comments on %s - %s", results[0].Name, config.Get("meta_title"))
+	}
+
+	if userID > 0 {
+		user, err := users.Find(userID)
+		if err != nil || !currentUser.CanSee(user) {
+			return "", fmt.Errorf("commentactions: user %d not found", userID)
+		}
+		userName = user.Name
+	}
+
+	if len(userName) > 0 {
+		title = fmt.Sprintf("Comments by %s - %s", userName, config.Get("meta_title"))
+	}
+
+	return title, nil
+}
+
+// renderCommentsFeed writes these comments as a feed in the format given.
+func renderCommentsFeed(w http.ResponseWriter, r *http.Request, format, title string, results []*comments.Comment) error {
+	f := &feed.Feed{
+		Root:        config.Get("root_url"),
+		Title:       title,
+		Description: config.Get("meta_desc"),
+		Path:        "/comments",
+		Updated:     time.Now(),
+	}
+
+	for i, c := range results {
+		if i == 0 || c.UpdatedAt.After(f.Updated) {
+			f.Updated = c.UpdatedAt
+		}
+		f.Items = append(f.Items, &feed.Item{
+			ID:           c.ShowURL(),
+			Title:        fmt.Sprintf("%s on %s", c.UserName, c.StoryName),
+			URL:          c.ShowURL(),
+			Text:         c.Text,
+			HTML:         string(helpers.Markup(c.Text)),
+			AuthorName:   c.UserName,
+			AuthorPath:   fmt.Sprintf("/users/%d", c.UserID),
+			CommentsPath: c.ContextURL(),
+			Published:    c.CreatedAt,
+			Updated:      c.UpdatedAt,
+		})
+	}
+
+	return feed.Render(w, r, f, format)
+}
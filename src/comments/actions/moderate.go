@@ -0,0 +1,121 @@
+package commentactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// HandleFlagged responds to GET /comments/flagged
+// by showing moderators the comments with unresolved flags.
+func HandleFlagged(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise - only moderators may see flagged comments
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.CanModerate() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	results, err := comments.FindAll(comments.Flagged().Limit(100))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("comments", results)
+	view.AddKey("meta_title", "Flagged comments")
+	view.AddKey("currentUser", currentUser)
+	view.Template("comments/views/flagged.html.got")
+	return view.Render()
+}
+
+// HandleResolveFlags responds to POST /comments/{id}/flags/resolve
+// by upholding the flags (suspending the comment) or dismissing them (restoring the points they removed).
+func HandleResolveFlags(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the comment
+	comment, err := comments.Find(params.GetInt(comments.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only moderators may resolve flags
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.CanModerate() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	action := params.Get("action")
+	switch action {
+	case "uphold":
+		err = comment.Update(map[string]string{"status": fmt.Sprintf("%d", status.Suspended)})
+		if err != nil {
+			return server.InternalError(err)
+		}
+	case "dismiss":
+		// Give back the points taken from the comment and its author by the flags
+		points, err := comment.FlagPoints()
+		if err != nil {
+			return server.InternalError(err)
+		}
+		if points < 0 {
+			err = comment.Update(map[string]string{"points": fmt.Sprintf("%d", comment.Points-points)})
+			if err != nil {
+				return server.InternalError(err)
+			}
+			err = users.AddPoints(comment.UserID, -points, users.ReasonReversal, comment.StoryID, comment.ID)
+			if err != nil {
+				return server.InternalError(err)
+			}
+		}
+	default:
+		return server.NotAuthorizedError(nil, "Resolve Failed", "Sorry, flags must be upheld or dismissed")
+	}
+
+	err = comment.ClearFlags()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	err = updateCommentsRank(comment.StoryID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Adjust the story comment count
+	story, err := stories.Find(comment.StoryID)
+	if err == nil {
+		err = updateStoryCommentCount(story)
+		if err != nil {
+			return server.InternalError(err)
+		}
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "comment flags resolved", "comment_id": comment.ID, "action": action, "moderator_id": currentUser.ID})
+
+	return server.Redirect(w, r, "/comments/flagged")
+}
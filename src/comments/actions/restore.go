@@ -0,0 +1,69 @@
+package commentactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+)
+
+// HandleUndelete responds to POST /comments/n/restore by restoring a deleted comment.
+func HandleUndelete(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the comment
+	comment, err := comments.Find(params.GetInt(comments.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may restore comments
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	if !comment.Restorable() {
+		return server.NotAuthorizedError(nil, "Sorry", "This comment may no longer be restored.")
+	}
+
+	err = comment.Restore()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "comment restored", "comment_id": comment.ID, "user_id": currentUser.ID})
+
+	// Redirect to the comment on the story
+	return server.Redirect(w, r, fmt.Sprintf("%s#comment%d", comment.StoryURL(), comment.ID))
+}
+
+// PurgeDeletedComments removes comments deleted before the restore window,
+// it is called at intervals by the app.
+func PurgeDeletedComments() {
+	count, err := comments.PurgeDeleted()
+	if err != nil {
+		log.Error(log.V{"msg": "deleted comment purge failed", "error": err})
+		return
+	}
+	if count > 0 {
+		log.Info(log.V{"msg": "deleted comments purged", "count": count})
+	}
+}
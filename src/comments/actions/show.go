@@ -1,6 +1,7 @@
 package commentactions
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/fragmenta/auth/can"
@@ -11,9 +12,11 @@ import (
 	"github.com/kennygrant/gohackernews/src/comments"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/lib/status"
+	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
 )
 
-// HandleShow displays a single comment.
+// HandleShow displays the permalink page of a comment, with its ancestors for context and its replies.
 func HandleShow(w http.ResponseWriter, r *http.Request) error {
 
 	// Fetch the  params
@@ -30,19 +33,135 @@ func HandleShow(w http.ResponseWriter, r *http.Request) error {
 
 	// Authorise access
 	currentUser := session.CurrentUser(w, r)
+	err = authoriseShow(comment, currentUser)
+	if err != nil {
+		return err
+	}
 
-	// Authorise access - for now all stories are visible, later might control on draft/published
-	if comment.Status < status.None { // status.Published
-		err = can.Show(comment, currentUser)
-		if err != nil {
-			return server.NotAuthorizedError(err)
-		}
+	// Find the story, which allows replies
+	story, err := stories.Find(comment.StoryID)
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Find the ancestors of the comment which this user may see
+	ancestors, err := comments.FindAncestors(comment, currentUser.WhereVisible(comments.Query()))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the replies, excluding those under 0, long threads are continued on request
+	sort := comments.ValidSort(params.Get("sort"))
+	q := comment.RepliesQuery().Where("points > 0")
+	currentUser.WhereVisible(q)
+	comment.Children, err = comments.FindTree(q, sort)
+	if err != nil {
+		return server.InternalError(err)
+	}
+	comments.Trim([]*comments.Comment{comment}, comments.RepliesPerComment, comments.ThreadDepth)
+
+	// Deleted comments are shown as tombstones while they have replies, admins may restore them
+	if comment.Deleted() && len(comment.Children) == 0 && !currentUser.Admin() {
+		return server.NotFoundError(nil)
 	}
 
 	// Render the template
 	view := view.NewRenderer(w, r)
-	view.CacheKey(comment.CacheKey())
+	view.CacheKey(comment.CacheKey() + sort)
+	view.AddKey("comment", comment)
+	view.AddKey("ancestors", ancestors)
+	view.AddKey("story", story)
+	view.AddKey("permalink", true)
+	view.AddKey("sort", sort)
+	view.AddKey("meta_title", fmt.Sprintf("Comment on %s", story.Name))
+	view.AddKey("currentUser", currentUser)
+	return view.Render()
+}
+
+// HandleReplies responds to GET /comments/{id}/replies?offset=10 with a fragment containing
+// the replies to a comment which follow those already shown.
+func HandleReplies(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the comment
+	comment, err := comments.Find(params.GetInt(comments.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise access
+	currentUser := session.CurrentUser(w, r)
+	err = authoriseShow(comment, currentUser)
+	if err != nil {
+		return err
+	}
+
+	// Find the story, which allows replies to the replies loaded
+	story, err := stories.Find(comment.StoryID)
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Find the replies, excluding those under 0
+	sort := comments.ValidSort(params.Get("sort"))
+	q := comment.RepliesQuery().Where("points > 0")
+	currentUser.WhereVisible(q)
+	comment.Children, err = comments.FindTree(q, sort)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Show the next replies after those at the offset, and continue them to the depth left on the story page
+	offset := int(params.GetInt("offset"))
+	if offset < 0 || offset > len(comment.Children) {
+		offset = len(comment.Children)
+	}
+	comment.Children = comment.Children[offset:]
+	comments.Trim([]*comments.Comment{comment}, comments.RepliesPerComment, comments.ThreadDepth-int(comment.Level()))
+	if comment.ContinueThread {
+		comment.Children = nil
+	}
+
+	// Render the fragment without a layout
+	view := view.NewRenderer(w, r)
+	view.Layout("")
+	view.Template("comments/views/replies.html.got")
 	view.AddKey("comment", comment)
+	view.AddKey("story", story)
+	view.AddKey("replies", comment.Children)
+	view.AddKey("more", comment.MoreReplies > 0)
+	view.AddKey("moreURL", fmt.Sprintf("/comments/%d/replies?offset=%d&sort=%s", comment.ID, offset+len(comment.Children), sort))
+	view.AddKey("sort", sort)
 	view.AddKey("currentUser", currentUser)
 	return view.Render()
 }
+
+// authoriseShow returns an error if this user may not see this comment.
+func authoriseShow(comment *comments.Comment, currentUser *users.User) error {
+
+	// Authorise access - for now all stories are visible, later might control on draft/published
+	if comment.Status < status.None { // status.Published
+		err := can.Show(comment, currentUser)
+		if err != nil {
+			return server.NotAuthorizedError(err)
+		}
+	}
+
+	// Suspended comments are seen only by moderators
+	if comment.Status == status.Suspended && !currentUser.CanModerate() {
+		return server.NotFoundError(nil)
+	}
+
+	// Comments by shadow banned users are not found by anyone else
+	author, err := users.Find(comment.UserID)
+	if err == nil && !currentUser.CanSee(author) {
+		return server.NotFoundError(nil)
+	}
+
+	return nil
+}
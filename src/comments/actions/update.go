@@ -1,6 +1,7 @@
 package commentactions
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/fragmenta/auth/can"
@@ -9,7 +10,9 @@ import (
 	"github.com/fragmenta/view"
 
 	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/lib/helpers"
 	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/revisions"
 )
 
 // HandleUpdateShow renders the form to update a comment.
@@ -34,6 +37,18 @@ func HandleUpdateShow(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err)
 	}
 
+	// Authors may only edit within the edit window, admins are exempt, as are moderators
+	// who may only change the status of comments by others
+	moderating := currentUser.Editor() && comment.UserID != currentUser.ID
+	if !currentUser.Admin() && !moderating && !comment.Editable() {
+		return editWindowClosed()
+	}
+
+	// Deleted comments must be restored before they are edited
+	if comment.Deleted() {
+		return server.NotFoundError(nil)
+	}
+
 	// Render the template
 	view := view.NewRenderer(w, r)
 	view.AddKey("currentUser", currentUser)
@@ -69,13 +84,33 @@ func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err)
 	}
 
-	// Clean params according to role
+	// Authors may only edit within the edit window, admins are exempt, as are moderators
+	// who may only change the status of comments by others
+	moderating := currentUser.Editor() && comment.UserID != currentUser.ID
+	if !currentUser.Admin() && !moderating && !comment.Editable() {
+		return editWindowClosed()
+	}
+
+	// Deleted comments must be restored before they are edited
+	if comment.Deleted() {
+		return server.NotFoundError(nil)
+	}
+
+	// Clean params according to role, moderators may only change the status of comments by others
 	accepted := comments.AllowedParams()
 	if currentUser.Admin() {
 		accepted = comments.AllowedParamsAdmin()
+	} else if moderating {
+		accepted = comments.AllowedParamsModerator()
 	}
 	commentParams := comment.ValidateParams(params.Map(), accepted)
 
+	// Keep the previous version if the comment text has changed
+	err = revisions.RecordComment(comment, commentParams, currentUser)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
 	err = comment.Update(commentParams)
 	if err != nil {
 		return server.InternalError(err)
@@ -84,3 +119,9 @@ func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
 	// Redirect to comment
 	return server.Redirect(w, r, comment.ShowURL())
 }
+
+// editWindowClosed returns the error shown to authors who try to edit a comment after the edit window.
+func editWindowClosed() error {
+	msg := fmt.Sprintf("Sorry, comments may only be edited for %s after they are posted.", helpers.Duration(comments.EditWindow))
+	return server.NotAuthorizedError(nil, "Editing Closed", msg)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/fragmenta/server"
 
 	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/lib/karma"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/stories"
 	"github.com/kennygrant/gohackernews/src/users"
@@ -36,6 +37,11 @@ func HandleFlag(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return server.NotFoundError(err)
 	}
+
+	// Deleted comments may not be voted on or flagged
+	if comment.Deleted() {
+		return server.NotFoundError(nil)
+	}
 	user := session.CurrentUser(w, r)
 	ip := getUserIP(r)
 
@@ -44,19 +50,29 @@ func HandleFlag(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err, "Flag Failed", "Sorry you are not allowed to flag twice, nice try!")
 	}
 
-	// Authorise upvote on comment for this user - our rules are:
+	// Authorise flag on comment for this user - our rules are:
 	if !user.CanFlag() {
 		return server.NotAuthorizedError(err, "Flag Failed", "Sorry, you can't flag yet")
 	}
+	if !user.Admin() && !user.CanFlagToday() {
+		return server.NotAuthorizedError(err, "Flag Failed", "Sorry, you have used all your flags for today")
+	}
 
 	// CURRENT User burns points for flagging
-	err = adjustUserPoints(user, -1)
+	err = users.AddPoints(user.ID, -karma.Current.FlagCost, users.ReasonFlagCost, comment.StoryID, comment.ID)
+	if err != nil {
+		return server.InternalError(err, "Flag Failed", "Sorry could not adjust user points")
+	}
+
+	// Record the flag separately, so that moderators can resolve it
+	delta := -karma.Current.FlagPenalty
+	err = recordCommentFlag(comment, user, ip, delta)
 	if err != nil {
 		return err
 	}
 
 	// Adjust the comment vote
-	err = addCommentVote(comment, user, ip, -5)
+	err = addCommentVote(comment, user, ip, delta, users.ReasonCommentFlagged)
 	if err != nil {
 		return err
 	}
@@ -100,6 +116,11 @@ func HandleDownvote(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return server.NotFoundError(err)
 	}
+
+	// Deleted comments may not be voted on or flagged
+	if comment.Deleted() {
+		return server.NotFoundError(nil)
+	}
 	user := session.CurrentUser(w, r)
 	ip := getUserIP(r)
 
@@ -110,19 +131,22 @@ func HandleDownvote(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
-	// Authorise upvote on comment for this user - our rules are:
+	// Authorise downvote on comment for this user - our rules are:
 	if !user.CanDownvote() {
 		return server.NotAuthorizedError(err, "Vote Failed", "Sorry, you can't downvote yet")
 	}
+	if !user.Admin() && !user.CanVoteToday() {
+		return server.NotAuthorizedError(err, "Vote Failed", "Sorry, you have used all your votes for today")
+	}
 
 	// CURRENT User burns points for downvoting
-	err = adjustUserPoints(user, -1)
+	err = users.AddPoints(user.ID, -karma.Current.DownvoteCost, users.ReasonDownvoteCost, comment.StoryID, comment.ID)
 	if err != nil {
-		return err
+		return server.InternalError(err, "Vote Failed", "Sorry could not adjust user points")
 	}
 
 	// Adjust points on comment and add to the vote table
-	err = addCommentVote(comment, user, ip, -1)
+	err = addCommentVote(comment, user, ip, -karma.Current.DownvotePenalty, users.ReasonCommentDownvoted)
 	if err != nil {
 		return err
 	}
@@ -163,6 +187,11 @@ func HandleUpvote(w http.ResponseWriter, r *http.Request) error {
 		return server.NotFoundError(err)
 	}
 
+	// Deleted comments may not be voted on or flagged
+	if comment.Deleted() {
+		return server.NotFoundError(nil)
+	}
+
 	user := session.CurrentUser(w, r)
 	ip := getUserIP(r)
 
@@ -177,9 +206,12 @@ func HandleUpvote(w http.ResponseWriter, r *http.Request) error {
 	if !user.CanUpvote() {
 		return server.NotAuthorizedError(err, "Vote Failed", "Sorry, you can't upvote yet")
 	}
+	if !user.Admin() && !user.CanVoteToday() {
+		return server.NotAuthorizedError(err, "Vote Failed", "Sorry, you have used all your votes for today")
+	}
 
 	// Adjust points on comment and add to the vote table
-	err = addCommentVote(comment, user, ip, +1)
+	err = addCommentVote(comment, user, ip, karma.Current.UpvoteReward, users.ReasonCommentUpvoted)
 	if err != nil {
 		return err
 	}
@@ -188,7 +220,8 @@ func HandleUpvote(w http.ResponseWriter, r *http.Request) error {
 }
 
 // addCommentVote adjusts the comment points, and adds a vote record for this user
-func addCommentVote(comment *comments.Comment, user *users.User, ip string, delta int64) error {
+// the author of the comment gains delta points for the reason given
+func addCommentVote(comment *comments.Comment, user *users.User, ip string, delta int64, reason string) error {
 
 	if comment.Points < -5 && delta < 0 {
 		return server.InternalError(nil, "Vote Failed", "Comment is already hidden")
@@ -201,28 +234,12 @@ func addCommentVote(comment *comments.Comment, user *users.User, ip string, delt
 	}
 
 	// Update the *comment* user points by delta
-	commentUser, err := users.Find(comment.UserID)
-	if err != nil {
-		return err
-	}
-	err = adjustUserPoints(commentUser, delta)
-	if err != nil {
-		return err
-	}
-
-	return recordCommentVote(comment, user, ip, delta)
-}
-
-// removeUserPoints removes these points from this user
-func adjustUserPoints(user *users.User, delta int64) error {
-
-	// Update the user points
-	err := user.Update(map[string]string{"points": fmt.Sprintf("%d", user.Points+delta)})
+	err = users.AddPoints(comment.UserID, delta, reason, comment.StoryID, comment.ID)
 	if err != nil {
 		return server.InternalError(err, "Vote Failed", "Sorry could not adjust user points")
 	}
 
-	return nil
+	return recordCommentVote(comment, user, ip, delta)
 }
 
 // recordCommentVote adds a vote record for this user
@@ -239,6 +256,16 @@ func recordCommentVote(comment *comments.Comment, user *users.User, ip string, d
 	return nil
 }
 
+// recordCommentFlag adds a flag record for this user
+func recordCommentFlag(comment *comments.Comment, user *users.User, ip string, delta int64) error {
+	_, err := query.Exec("insert into flags VALUES(now(),$1,NULL,$2,$3,$4)", comment.ID, user.ID, ip, delta)
+	if err != nil {
+		return server.InternalError(err, "Flag Failed", "Sorry your flag failed to record")
+	}
+
+	return nil
+}
+
 // commentHasUserVote returns true if we already have a vote for this comment from this user
 func commentHasUserVote(comment *comments.Comment, user *users.User) bool {
 	// Query votes table for rows with userId and commentId
@@ -272,9 +299,9 @@ func updateCommentsRank(storyID int64) error {
 }
 
 // updateStoryCommentCount updates a story for new comment counts
-// discounting comments under 0 points
+// discounting comments under 0 points and deleted comments
 func updateStoryCommentCount(story *stories.Story) error {
-	commentCount, err := comments.Query().Where("story_id=?", story.ID).Where("points > 0").Count()
+	commentCount, err := comments.Query().Where("story_id=?", story.ID).Where("points > 0 AND deleted_at IS NULL").Count()
 	if err != nil {
 		return err
 	}
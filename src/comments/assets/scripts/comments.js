@@ -1 +1,42 @@
-/* JS for comments */
\ No newline at end of file
+/* JS for comments */
+DOM.Ready(function() {
+
+    // Load more replies in place of links with a data-replies url
+    ActivateLoadReplies();
+
+});
+
+// Replace load-replies links with the replies they link to, within scope if given
+function ActivateLoadReplies(scope) {
+    DOM.On(scoped(scope, 'a.load-replies'), 'click', function(e) {
+        e.preventDefault();
+        var link = this;
+        var url = link.getAttribute('data-replies');
+        if (url === null || DOM.HasClass(link, 'disabled')) {
+            window.location = link.getAttribute('href');
+            return false;
+        }
+        DOM.AddClass(link, 'disabled');
+
+        DOM.Get(url, function(request) {
+            // Insert the replies in a container, and activate links within it
+            ActivateLoadReplies.count = (ActivateLoadReplies.count || 0) + 1;
+            var container = document.createElement('div');
+            container.id = 'replies' + ActivateLoadReplies.count;
+            container.className = 'replies';
+            container.innerHTML = request.responseText;
+            link.parentNode.replaceChild(container, link);
+
+            var sel = '#' + container.id;
+            ActivateMethodLinks(sel);
+            ActivateShowlinks(sel);
+            ActivateForms(sel);
+            ActivateLoadReplies(sel);
+        }, function(request) {
+            // Fall back to the permalink page of the comment
+            window.location = link.getAttribute('href');
+        });
+
+        return false;
+    });
+}
@@ -10,6 +10,10 @@ import (
 	"github.com/kennygrant/gohackernews/src/lib/status"
 )
 
+// EditWindow is the time after posting during which authors may edit their comments,
+// it is replaced on startup by the value of comment_edit_minutes in config.
+var EditWindow = 3 * time.Hour
+
 // Comment handles saving and retreiving comments from the database
 type Comment struct {
 	// resource.Base defines behaviour and fields shared between all resources
@@ -25,10 +29,19 @@ type Comment struct {
 	// Any child comments (may be empty)
 	Children []*Comment
 
+	// The number of replies not shown in Children, which may be loaded separately
+	MoreReplies int64
+	// ContinueThread is set when replies are not shown as the thread is too deep
+	ContinueThread bool
+
 	// Score of the comment (raw points and calculated rank with gravity)
 	Points int64
 	Rank   int64
 
+	// Counts of up and down votes, set only for the controversial sort
+	Upvotes   int64
+	Downvotes int64
+
 	// The main story text
 	Text string
 
@@ -37,8 +50,15 @@ type Comment struct {
 	UserID  int64
 
 	// Denormalised attributes from joins
-	StoryName string
-	UserName  string
+	StoryName     string
+	UserName      string
+	UserSupporter int64
+
+	// EditedAt is the time the text was last changed
+	EditedAt time.Time
+
+	// DeletedAt is the time the comment was deleted, deleted comments are kept while they have replies
+	DeletedAt time.Time
 }
 
 // Level returns the nesting level of this comment, based on dotted_ids
@@ -64,10 +84,15 @@ func (c *Comment) StoryURL() string {
 	return fmt.Sprintf("/stories/%d", c.StoryID)
 }
 
-// Editable returns true if this comment is editable.
-// Comments are editable if less than 3 hours old.
+// Editable returns true if this comment is editable by its author.
+// Comments are editable if they were posted within the EditWindow.
 func (c *Comment) Editable() bool {
-	return time.Now().Sub(c.CreatedAt) < time.Hour*3
+	return time.Now().Sub(c.CreatedAt) < EditWindow
+}
+
+// Edited returns true if this comment has been changed since it was posted.
+func (c *Comment) Edited() bool {
+	return !c.EditedAt.IsZero()
 }
 
 // OwnedBy returns true if this user id owns this comment.
@@ -2,7 +2,9 @@
 package comments
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/kennygrant/gohackernews/src/lib/resource"
 )
@@ -134,6 +136,70 @@ func TestDestroyComments(t *testing.T) {
 
 }
 
+// Test Delete, Restore and PurgeDeleted methods
+func TestDeleteComments(t *testing.T) {
+
+	parentID, err := New().Create(map[string]string{"user_name": testName, "status": "100", "points": "1", "text": "parent"})
+	if err != nil {
+		t.Fatalf("comments: Delete create comment failed :%s", err)
+	}
+	childID, err := New().Create(map[string]string{"user_name": testName, "status": "100", "points": "1", "parent_id": fmt.Sprintf("%d", parentID)})
+	if err != nil {
+		t.Fatalf("comments: Delete create reply failed :%s", err)
+	}
+
+	parent, err := Find(parentID)
+	if err != nil {
+		t.Fatalf("comments: Delete find comment failed :%s", err)
+	}
+
+	// Deleted comments may be restored
+	err = parent.Delete()
+	if err != nil || !parent.Restorable() {
+		t.Fatalf("comments: Delete comment failed :%v", err)
+	}
+	err = parent.Restore()
+	if err != nil {
+		t.Fatalf("comments: Restore comment failed :%s", err)
+	}
+	parent, err = Find(parentID)
+	if err != nil || parent.Deleted() {
+		t.Fatalf("comments: Restore comment not restored :%v", err)
+	}
+
+	// Once the restore window has passed, deleted comments with replies are kept without their text
+	err = parent.Delete()
+	if err != nil {
+		t.Fatalf("comments: Delete comment failed :%s", err)
+	}
+	window := RestoreWindow
+	RestoreWindow = -time.Minute
+	defer func() { RestoreWindow = window }()
+
+	count, err := PurgeDeleted()
+	if err != nil || count != 0 {
+		t.Fatalf("comments: PurgeDeleted removed comment with replies :%v", err)
+	}
+	parent, err = Find(parentID)
+	if err != nil || parent.Text != "" {
+		t.Fatalf("comments: PurgeDeleted kept text of deleted comment :%v", err)
+	}
+
+	// Once the replies are deleted too, both are removed
+	child, err := Find(childID)
+	if err != nil {
+		t.Fatalf("comments: Delete find reply failed :%s", err)
+	}
+	err = child.Delete()
+	if err != nil {
+		t.Fatalf("comments: Delete reply failed :%s", err)
+	}
+	count, err = PurgeDeleted()
+	if err != nil || count != 2 {
+		t.Fatalf("comments: PurgeDeleted removed %d comments :%v", count, err)
+	}
+}
+
 // TestAllowedParams should always return some params
 func TestAllowedParams(t *testing.T) {
 	if len(AllowedParams()) == 0 {
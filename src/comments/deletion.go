@@ -0,0 +1,91 @@
+package comments
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+)
+
+// This file contains functions related to deleting comments. Deleted comments are kept
+// as tombstones so that their replies stay in place, and may be restored by admins
+// until the RestoreWindow has passed.
+
+// RestoreWindow is the time after deletion during which admins may restore comments,
+// it is replaced on startup by the value of comment_restore_minutes in config.
+var RestoreWindow = 30 * 24 * time.Hour
+
+// Deleted returns true if this comment has been deleted.
+func (c *Comment) Deleted() bool {
+	return !c.DeletedAt.IsZero()
+}
+
+// Restorable returns true if this comment was deleted within the RestoreWindow.
+func (c *Comment) Restorable() bool {
+	return c.Deleted() && time.Now().Sub(c.DeletedAt) < RestoreWindow
+}
+
+// Delete marks this comment deleted, and updates the comment count of its story.
+func (c *Comment) Delete() error {
+	now := time.Now().UTC()
+	_, err := query.Exec("update comments set deleted_at=$1 where id=$2 and deleted_at IS NULL", query.TimeString(now), c.ID)
+	if err != nil {
+		return err
+	}
+	c.DeletedAt = now
+	return UpdateStoryCount(c.StoryID)
+}
+
+// Restore reverses the deletion of this comment, and updates the comment count of its story.
+func (c *Comment) Restore() error {
+	_, err := query.Exec("update comments set deleted_at=NULL where id=$1", c.ID)
+	if err != nil {
+		return err
+	}
+	c.DeletedAt = time.Time{}
+	return UpdateStoryCount(c.StoryID)
+}
+
+// UpdateStoryCount sets the comment count of this story,
+// discounting comments under 0 points and deleted comments.
+func UpdateStoryCount(storyID int64) error {
+	sql := "update stories set comment_count=(select count(*) from comments where story_id=$1 and points > 0 and deleted_at IS NULL) where id=$1"
+	_, err := query.Exec(sql, storyID)
+	return err
+}
+
+// PurgeDeleted removes comments deleted before the RestoreWindow. Comments without replies
+// are removed with their votes, flags and revisions, those with replies are kept as tombstones
+// but their text is removed. It returns the number of comments removed.
+func PurgeDeleted() (int64, error) {
+	before := query.TimeString(time.Now().UTC().Add(-RestoreWindow))
+
+	// Removing a comment may leave its deleted parent without replies, so repeat until none are left
+	var count int64
+	for {
+		ids := query.New(TableName, KeyName).Select("select id from comments").Where("deleted_at < ?", before).Where("NOT EXISTS (select 1 from comments replies where replies.parent_id=comments.id)").ResultIDs()
+		if len(ids) == 0 {
+			break
+		}
+
+		for _, table := range []string{"votes", "flags", "revisions"} {
+			err := query.New(table, "comment_id").WhereIn("comment_id", ids).Delete()
+			if err != nil {
+				return count, err
+			}
+		}
+
+		err := query.New(TableName, KeyName).WhereIn("id", ids).Delete()
+		if err != nil {
+			return count, err
+		}
+		count += int64(len(ids))
+	}
+
+	_, err := query.Exec("delete from revisions where comment_id in (select id from comments where deleted_at < $1)", before)
+	if err != nil {
+		return count, err
+	}
+
+	_, err = query.Exec("update comments set text='' where deleted_at < $1 and text <> ''", before)
+	return count, err
+}
@@ -0,0 +1,29 @@
+package comments
+
+import (
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+// This file contains functions used by moderators to resolve flags on comments.
+
+// Flagged returns a query for comments which have unresolved flags.
+func Flagged() *query.Query {
+	return Query().Where("id IN (select comment_id from flags where comment_id IS NOT NULL)").Order("created_at desc, id desc")
+}
+
+// FlagPoints returns the total points removed from this comment by flags which have not been resolved.
+func (c *Comment) FlagPoints() (int64, error) {
+	results, err := query.New("flags", "comment_id").Select("select coalesce(sum(points),0) as points from flags").Where("comment_id=?", c.ID).Results()
+	if err != nil || len(results) == 0 {
+		return 0, err
+	}
+	return resource.ValidateInt(results[0]["points"]), nil
+}
+
+// ClearFlags removes the flags on this comment once they have been resolved.
+func (c *Comment) ClearFlags() error {
+	_, err := query.Exec("delete from flags where comment_id=$1", c.ID)
+	return err
+}
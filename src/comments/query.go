@@ -28,6 +28,12 @@ func AllowedParams() []string {
 	return []string{"text", "parent_id"}
 }
 
+// AllowedParamsModerator returns an array of allowed param keys for moderators
+// on comments by others, who may suspend comments but not change their text, points or ownership.
+func AllowedParamsModerator() []string {
+	return []string{"status"}
+}
+
 // NewWithColumns creates a new comment instance and fills it with data from the database cols provided.
 func NewWithColumns(cols map[string]interface{}) *Comment {
 
@@ -45,6 +51,9 @@ func NewWithColumns(cols map[string]interface{}) *Comment {
 	comment.Text = resource.ValidateString(cols["text"])
 	comment.UserID = resource.ValidateInt(cols["user_id"])
 	comment.UserName = resource.ValidateString(cols["user_name"])
+	comment.UserSupporter = resource.ValidateInt(cols["user_supporter"])
+	comment.EditedAt = resource.ValidateTime(cols["edited_at"])
+	comment.DeletedAt = resource.ValidateTime(cols["deleted_at"])
 
 	return comment
 }
@@ -79,54 +88,24 @@ func Find(id int64) (*Comment, error) {
 	return NewWithColumns(result), nil
 }
 
-// FindAll returns all results for this query
+// FindAll returns all results for this query as a tree of comments,
+// the comments at each level are in the order of the query.
 func FindAll(q *query.Query) ([]*Comment, error) {
+	return FindTree(q, "")
+}
 
-	// Fetch query.Results from query
+// FindList returns all results for this query as a list of comments in the order of the query,
+// without arranging them into a tree.
+func FindList(q *query.Query) ([]*Comment, error) {
 	results, err := q.Results()
 	if err != nil {
 		return nil, err
 	}
-
-	// Construct an array of comments constructed from the results
-	// We do things a little differently, as we have a tree of comments
-	// root comments are added to the list, others are held in another list
-	// and added as children to rootComments
-
-	var rootComments, childComments []*Comment
-	for _, cols := range results {
-		c := NewWithColumns(cols)
-		if c.Root() {
-			rootComments = append(rootComments, c)
-		} else {
-			childComments = append(childComments, c)
-		}
+	list := make([]*Comment, len(results))
+	for i, cols := range results {
+		list[i] = NewWithColumns(cols)
 	}
-
-	// Now walk through child comments, assigning them to their parent
-
-	// Walk through comments, adding those with no parent id to comments list
-	// and others to the parent comment in root comments
-	for _, c := range childComments {
-		found := false
-		for _, p := range rootComments {
-			if p.ID == c.ParentID {
-				p.Children = append(p.Children, c)
-				found = true
-				break
-			}
-		}
-		if !found {
-			for _, p := range childComments {
-				if p.ID == c.ParentID {
-					p.Children = append(p.Children, c)
-					break
-				}
-			}
-		}
-	}
-
-	return rootComments, nil
+	return list, nil
 }
 
 // Query returns a new query for comments with a default order.
@@ -0,0 +1,131 @@
+package comments
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/query"
+)
+
+// This file contains functions for showing long threads in parts. Story pages show a page of
+// root comments at a time, with a limited number of replies to each comment and a limited depth,
+// the rest are loaded on request or shown on the permalink page of a comment.
+
+var (
+	// CommentsPerPage is the number of root comments shown on each page of a story
+	CommentsPerPage = 50
+
+	// RepliesPerComment is the number of replies shown before the rest are loaded on request
+	RepliesPerComment = 10
+
+	// ThreadDepth is the number of levels of a thread shown before it is continued on another page
+	ThreadDepth = 6
+)
+
+// DottedIDsFor returns the dotted ids of a reply to this comment, the ids of its ancestors
+// from the root, each followed by a dot, e.g. 1.5. for a reply to comment 5 which replies to 1.
+func (c *Comment) DottedIDsFor() string {
+	return fmt.Sprintf("%s%d.", c.DottedIDs, c.ID)
+}
+
+// AncestorIDs returns the ids of the ancestors of this comment from the root to the parent.
+func (c *Comment) AncestorIDs() []int64 {
+	var ids []int64
+	for _, s := range strings.Split(c.DottedIDs, ".") {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err == nil && id > 0 && id != c.ID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ParentURL returns the permalink of the parent of this comment.
+func (c *Comment) ParentURL() string {
+	return fmt.Sprintf("/comments/%d", c.ParentID)
+}
+
+// ContextURL returns the url of the whole thread containing this comment,
+// which is the permalink of its root comment.
+func (c *Comment) ContextURL() string {
+	ids := c.AncestorIDs()
+	if len(ids) == 0 {
+		return fmt.Sprintf("%s#comment%d", c.StoryURL(), c.ID)
+	}
+	return fmt.Sprintf("/comments/%d#comment%d", ids[0], c.ID)
+}
+
+// MoreRepliesURL returns the url of the fragment containing the replies to this comment
+// which follow those shown.
+func (c *Comment) MoreRepliesURL() string {
+	return fmt.Sprintf("/comments/%d/replies?offset=%d", c.ID, len(c.Children))
+}
+
+// RepliesQuery returns a query for all the replies to this comment and their replies.
+func (c *Comment) RepliesQuery() *query.Query {
+	return Where("story_id=?", c.StoryID).Where("dotted_ids LIKE ?", c.DottedIDsFor()+"%")
+}
+
+// FindAncestors returns the ancestors of this comment from the root to the parent,
+// using the query given to select those which may be shown.
+func FindAncestors(c *Comment, q *query.Query) ([]*Comment, error) {
+	ids := c.AncestorIDs()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	results, err := q.WhereIn("id", ids).Results()
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[int64]*Comment, len(results))
+	for _, cols := range results {
+		a := NewWithColumns(cols)
+		index[a.ID] = a
+	}
+
+	var ancestors []*Comment
+	for _, id := range ids {
+		if a := index[id]; a != nil {
+			ancestors = append(ancestors, a)
+		}
+	}
+	return ancestors, nil
+}
+
+// Page returns the page of comments from this list with the page number given (from 0),
+// and true if there are more comments on later pages.
+func Page(list []*Comment, page, perPage int) ([]*Comment, bool) {
+	start := page * perPage
+	if page < 0 || start >= len(list) {
+		return nil, false
+	}
+	end := start + perPage
+	if end >= len(list) {
+		return list[start:], false
+	}
+	return list[start:end], true
+}
+
+// Trim limits the replies shown to each comment in this tree, and the depth shown. Replies beyond
+// the limit are counted in MoreReplies, and comments at the maximum depth with replies are marked
+// to ContinueThread. The depth includes the level of the comments in the list.
+func Trim(list []*Comment, replies, depth int) {
+	for _, c := range list {
+		if len(c.Children) == 0 {
+			continue
+		}
+		if depth <= 1 {
+			c.Children = nil
+			c.ContinueThread = true
+			continue
+		}
+		if len(c.Children) > replies {
+			c.MoreReplies = int64(len(c.Children) - replies)
+			c.Children = c.Children[:replies]
+		}
+		Trim(c.Children, replies, depth-1)
+	}
+}
@@ -0,0 +1,236 @@
+package comments
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+// This file contains functions for arranging comments into a sorted tree.
+
+// Sort modes for comment trees, selected with the sort param on stories.
+const (
+	SortTop           = "top"
+	SortNew           = "new"
+	SortOld           = "old"
+	SortControversial = "controversial"
+)
+
+// Sorts returns the sort modes which may be selected, the first is the default.
+func Sorts() []string {
+	return []string{SortTop, SortNew, SortOld, SortControversial}
+}
+
+// ValidSort returns the sort mode s if valid, or the default sort mode.
+func ValidSort(s string) string {
+	for _, v := range Sorts() {
+		if s == v {
+			return s
+		}
+	}
+	return SortTop
+}
+
+// FindTree returns the comments for this query as a tree, with the comments at each level
+// in the order of the sort mode given.
+func FindTree(q *query.Query, mode string) ([]*Comment, error) {
+
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*Comment, len(results))
+	for i, cols := range results {
+		list[i] = NewWithColumns(cols)
+	}
+
+	if mode == SortControversial {
+		err = countVotes(list)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return BuildTree(list, mode), nil
+}
+
+// BuildTree arranges these comments into a tree and returns the roots. Comments whose parent is
+// missing (because it was removed or excluded by the query) are attached to their nearest ancestor
+// in the list, or else become roots. Deleted comments are kept as tombstones only while they have
+// replies. Comments at each level are sorted by mode, or left in the order given if mode is empty.
+// The time taken is linear in the number of comments, plus sorting.
+func BuildTree(list []*Comment, mode string) []*Comment {
+
+	index := make(map[int64]*Comment, len(list))
+	for _, c := range list {
+		c.Children = nil
+		index[c.ID] = c
+	}
+
+	var roots []*Comment
+	for _, c := range list {
+		parent := findAncestor(index, c)
+		if parent == nil {
+			roots = append(roots, c)
+		} else {
+			parent.Children = append(parent.Children, c)
+		}
+	}
+
+	// Deleted comments are shown only while they have replies
+	roots = pruneDeleted(roots)
+
+	less := lessFunc(mode)
+	if less == nil {
+		return roots
+	}
+
+	sortComments(roots, less)
+	for _, c := range list {
+		sortComments(c.Children, less)
+	}
+
+	return roots
+}
+
+// findAncestor returns the nearest ancestor of c in the index, or nil if it has none.
+func findAncestor(index map[int64]*Comment, c *Comment) *Comment {
+	if c.Root() {
+		return nil
+	}
+
+	parent := index[c.ParentID]
+	if parent != nil && parent != c {
+		return parent
+	}
+
+	// Walk up the dotted ids towards the root, skipping this comment and its parent
+	ids := strings.Split(c.DottedIDs, ".")
+	for i := len(ids) - 1; i >= 0; i-- {
+		id, err := strconv.ParseInt(ids[i], 10, 64)
+		if err != nil || id == c.ID || id == c.ParentID {
+			continue
+		}
+		if ancestor := index[id]; ancestor != nil {
+			return ancestor
+		}
+	}
+
+	return nil
+}
+
+// pruneDeleted removes deleted comments without replies from this list and their descendants.
+func pruneDeleted(list []*Comment) []*Comment {
+	kept := list[:0]
+	for _, c := range list {
+		c.Children = pruneDeleted(c.Children)
+		if c.Deleted() && len(c.Children) == 0 {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// sortComments sorts a list of sibling comments in place.
+func sortComments(list []*Comment, less func(a, b *Comment) bool) {
+	if len(list) < 2 {
+		return
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return less(list[i], list[j])
+	})
+}
+
+// lessFunc returns a function ordering comments for this sort mode, or nil to keep the order given.
+func lessFunc(mode string) func(a, b *Comment) bool {
+	switch mode {
+	case SortTop:
+		return lessTop
+	case SortNew:
+		return func(a, b *Comment) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+			return a.ID > b.ID
+		}
+	case SortOld:
+		return func(a, b *Comment) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			return a.ID < b.ID
+		}
+	case SortControversial:
+		return func(a, b *Comment) bool {
+			ca, cb := a.Controversy(), b.Controversy()
+			if ca != cb {
+				return ca > cb
+			}
+			return lessTop(a, b)
+		}
+	}
+	return nil
+}
+
+// lessTop orders comments as Order does, by rank, then points, then newest first.
+func lessTop(a, b *Comment) bool {
+	if a.Rank != b.Rank {
+		return a.Rank > b.Rank
+	}
+	if a.Points != b.Points {
+		return a.Points > b.Points
+	}
+	return a.ID > b.ID
+}
+
+// Controversy returns a score which is highest for comments with many votes, evenly split
+// between up and down votes. Comments with only up or only down votes score 0.
+func (c *Comment) Controversy() float64 {
+	if c.Upvotes <= 0 || c.Downvotes <= 0 {
+		return 0
+	}
+	total := float64(c.Upvotes + c.Downvotes)
+	balance := float64(c.Downvotes) / float64(c.Upvotes)
+	if c.Downvotes > c.Upvotes {
+		balance = float64(c.Upvotes) / float64(c.Downvotes)
+	}
+	return math.Pow(total, balance)
+}
+
+// countVotes sets the number of up and down votes on each of these comments.
+func countVotes(list []*Comment) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	index := make(map[int64]*Comment, len(list))
+	ids := make([]int64, len(list))
+	for i, c := range list {
+		index[c.ID] = c
+		ids[i] = c.ID
+	}
+
+	sql := `select id, (select count(*) from votes where votes.comment_id=comments.id and votes.points > 0) as upvotes,
+	(select count(*) from votes where votes.comment_id=comments.id and votes.points < 0) as downvotes from comments`
+	results, err := query.New(TableName, KeyName).Select(sql).WhereIn("id", ids).Results()
+	if err != nil {
+		return err
+	}
+
+	for _, cols := range results {
+		c := index[resource.ValidateInt(cols["id"])]
+		if c != nil {
+			c.Upvotes = resource.ValidateInt(cols["upvotes"])
+			c.Downvotes = resource.ValidateInt(cols["downvotes"])
+		}
+	}
+
+	return nil
+}
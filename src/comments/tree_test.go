@@ -0,0 +1,190 @@
+package comments
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// testComment returns a comment with this id under the parent given (nil for a root comment).
+func testComment(id int64, parent *Comment, rank int64, created time.Time) *Comment {
+	c := New()
+	c.ID = id
+	c.Rank = rank
+	c.Points = rank
+	c.CreatedAt = created
+	if parent != nil {
+		c.ParentID = parent.ID
+		c.DottedIDs = parent.DottedIDsFor()
+	}
+	return c
+}
+
+// treeIDs returns the ids of comments in the tree in display order, with children in brackets.
+func treeIDs(list []*Comment) string {
+	s := ""
+	for _, c := range list {
+		s += fmt.Sprintf(" %d", c.ID)
+		if len(c.Children) > 0 {
+			s += " [" + treeIDs(c.Children) + " ]"
+		}
+	}
+	return s
+}
+
+// TestBuildTree tests trees are built and sorted, and orphans are kept.
+func TestBuildTree(t *testing.T) {
+	now := time.Now()
+	c1 := testComment(1, nil, 5, now.Add(-3*time.Hour))
+	c2 := testComment(2, nil, 10, now.Add(-2*time.Hour))
+	c3 := testComment(3, c1, 1, now.Add(-time.Hour))
+	c4 := testComment(4, c1, 8, now)
+	c5 := testComment(5, c3, 1, now)
+	c6 := testComment(6, c5, 1, now)
+
+	// c5 is missing, so c6 is attached to c3
+	tests := map[string]string{
+		SortTop: " 2 1 [ 4 3 [ 6 ] ]",
+		SortNew: " 2 1 [ 4 3 [ 6 ] ]",
+		SortOld: " 1 [ 3 [ 6 ] 4 ] 2",
+	}
+	for mode, expected := range tests {
+		got := treeIDs(BuildTree([]*Comment{c6, c4, c3, c2, c1}, mode))
+		if got != expected {
+			t.Fatalf("comments: wrong tree for sort %s expected:%s got:%s", mode, expected, got)
+		}
+	}
+
+	// Without a sort mode the query order is kept
+	got := treeIDs(BuildTree([]*Comment{c1, c2, c3, c4, c6}, ""))
+	if got != " 1 [ 3 [ 6 ] 4 ] 2" {
+		t.Fatalf("comments: wrong tree for query order got:%s", got)
+	}
+
+	// Without any ancestors present, orphans become roots
+	got = treeIDs(BuildTree([]*Comment{c6, c4}, SortOld))
+	if got != " 4 6" {
+		t.Fatalf("comments: wrong tree for orphans got:%s", got)
+	}
+
+	// Deleted comments are kept only while they have replies
+	c3.DeletedAt, c4.DeletedAt = now, now
+	got = treeIDs(BuildTree([]*Comment{c1, c2, c3, c4, c6}, SortOld))
+	if got != " 1 [ 3 [ 6 ] ] 2" {
+		t.Fatalf("comments: wrong tree for deleted comments got:%s", got)
+	}
+	c3.DeletedAt, c4.DeletedAt = time.Time{}, time.Time{}
+
+	// Comments evenly split between up and down votes are most controversial
+	c1.Upvotes, c1.Downvotes = 10, 9
+	c2.Upvotes, c2.Downvotes = 20, 1
+	got = treeIDs(BuildTree([]*Comment{c1, c2}, SortControversial))
+	if got != " 1 2" {
+		t.Fatalf("comments: wrong tree for controversial got:%s", got)
+	}
+
+	if ValidSort("new") != SortNew || ValidSort("bogus") != SortTop {
+		t.Fatalf("comments: invalid sort accepted")
+	}
+}
+
+// TestThreads tests threads are paged and trimmed, and ancestors found from dotted ids.
+func TestThreads(t *testing.T) {
+	now := time.Now()
+	c1 := testComment(1, nil, 1, now)
+	c2 := testComment(2, c1, 1, now)
+	c3 := testComment(3, c2, 1, now)
+	c4 := testComment(4, c1, 1, now)
+	c5 := testComment(5, c1, 1, now)
+
+	if c3.DottedIDs != "1.2." || fmt.Sprint(c3.AncestorIDs()) != "[1 2]" || c1.AncestorIDs() != nil {
+		t.Fatalf("comments: wrong ancestors for %s got:%v", c3.DottedIDs, c3.AncestorIDs())
+	}
+	if c3.ContextURL() != "/comments/1#comment3" || c3.ParentURL() != "/comments/2" {
+		t.Fatalf("comments: wrong urls got:%s %s", c3.ContextURL(), c3.ParentURL())
+	}
+
+	list := []*Comment{c1, c2, c3, c4, c5}
+	page, more := Page(list, 1, 2)
+	if treeIDs(page) != " 3 4" || !more {
+		t.Fatalf("comments: wrong page got:%s %v", treeIDs(page), more)
+	}
+	page, more = Page(list, 2, 2)
+	if treeIDs(page) != " 5" || more {
+		t.Fatalf("comments: wrong last page got:%s %v", treeIDs(page), more)
+	}
+	page, _ = Page(list, 3, 2)
+	if page != nil {
+		t.Fatalf("comments: wrong page beyond the end got:%s", treeIDs(page))
+	}
+
+	// c1 shows one of its three replies, and c2 is continued on its own page
+	roots := BuildTree(list, SortOld)
+	Trim(roots, 1, 2)
+	if treeIDs(roots) != " 1 [ 2 ]" || c1.MoreReplies != 2 || !c2.ContinueThread || c1.ContinueThread {
+		t.Fatalf("comments: wrong trimmed tree got:%s more:%d", treeIDs(roots), c1.MoreReplies)
+	}
+	if c1.MoreRepliesURL() != "/comments/1/replies?offset=1" {
+		t.Fatalf("comments: wrong more replies url got:%s", c1.MoreRepliesURL())
+	}
+}
+
+// benchmarkComments returns n comments in a thread where one in ten comments is a root
+// and the others reply to a random earlier comment, shuffled as a query might return them.
+func benchmarkComments(n int) []*Comment {
+	r := rand.New(rand.NewSource(1))
+	now := time.Now()
+	list := make([]*Comment, n)
+	for i := range list {
+		var parent *Comment
+		if i%10 != 0 {
+			parent = list[r.Intn(i)]
+		}
+		list[i] = testComment(int64(i+1), parent, r.Int63n(100), now.Add(time.Duration(i)*time.Second))
+		list[i].Upvotes, list[i].Downvotes = r.Int63n(10), r.Int63n(10)
+	}
+	r.Shuffle(n, func(i, j int) {
+		list[i], list[j] = list[j], list[i]
+	})
+	return list
+}
+
+// benchmarkTree builds a tree from n comments with this sort mode.
+func benchmarkTree(b *testing.B, n int, mode string) {
+	list := benchmarkComments(n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		BuildTree(list, mode)
+	}
+}
+
+func BenchmarkBuildTree10k(b *testing.B) {
+	benchmarkTree(b, 10000, "")
+}
+
+func BenchmarkBuildTreeTop10k(b *testing.B) {
+	benchmarkTree(b, 10000, SortTop)
+}
+
+func BenchmarkBuildTreeNew10k(b *testing.B) {
+	benchmarkTree(b, 10000, SortNew)
+}
+
+func BenchmarkBuildTreeControversial10k(b *testing.B) {
+	benchmarkTree(b, 10000, SortControversial)
+}
+
+// BenchmarkBuildTreeDeep10k builds a single thread 10k comments deep.
+func BenchmarkBuildTreeDeep10k(b *testing.B) {
+	list := make([]*Comment, 10000)
+	var parent *Comment
+	for i := range list {
+		list[i] = testComment(int64(i+1), parent, 1, time.Now())
+		parent = list[i]
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		BuildTree(list, SortTop)
+	}
+}
@@ -0,0 +1,140 @@
+// Package deliveries represents the queue of activities to be delivered to the inboxes of remote servers,
+// which are tried again with increasing delays until they are delivered or we give up.
+package deliveries
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/activitypub"
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+// MaxAttempts is the number of times delivery is tried before we give up.
+var MaxAttempts int64 = 8
+
+// RetryDelay is the delay after the first failure, which doubles after each failure thereafter.
+var RetryDelay = time.Minute
+
+// Delivery is an activity queued for delivery to an inbox.
+type Delivery struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	// Inbox is the url the activity is posted to
+	Inbox string
+
+	// Activity is the activity as json
+	Activity string
+
+	// Attempts is the number of times delivery has failed, NextAt the time it will next be tried
+	Attempts int64
+	NextAt   time.Time
+
+	// DeliveredAt is set when the inbox accepts the activity, FailedAt when we give up
+	DeliveredAt time.Time
+	FailedAt    time.Time
+
+	// Error is the last error returned by the inbox
+	Error string
+}
+
+// Enqueue adds the activity to the queue for each of these inboxes, to be delivered as soon as possible.
+func Enqueue(activity interface{}, inboxes ...string) error {
+	data, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	now := query.TimeString(time.Now().UTC())
+	for _, inbox := range inboxes {
+		params := map[string]string{
+			"inbox":    inbox,
+			"activity": string(data),
+			"next_at":  now,
+		}
+		_, err = New().Create(params)
+		if err != nil {
+			return fmt.Errorf("deliveries: error queueing delivery to %s :%s", inbox, err)
+		}
+	}
+	return nil
+}
+
+// Pending returns a query for deliveries which are due to be tried at this time, oldest first.
+func Pending(now time.Time) *query.Query {
+	return Where("delivered_at IS NULL AND failed_at IS NULL AND next_at <= ?", query.TimeString(now.UTC())).Order("next_at asc, id asc")
+}
+
+// Deliver tries up to limit deliveries due at this time, sending each with send, and returns the number delivered and failed.
+// Deliveries which fail are tried again later, unless the inbox refused them with an error which will not change.
+func Deliver(now time.Time, limit int, send func(inbox string, activity []byte) error) (int, int, error) {
+	pending, err := FindAll(Pending(now).Limit(limit))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	delivered, failed := 0, 0
+	for _, d := range pending {
+		err = send(d.Inbox, []byte(d.Activity))
+		if err == nil {
+			delivered++
+			err = d.Delivered()
+		} else {
+			failed++
+			retry := true
+			if e, ok := err.(*activitypub.StatusError); ok {
+				retry = e.Temporary()
+			}
+			err = d.Failed(err, retry)
+		}
+		if err != nil {
+			return delivered, failed, err
+		}
+	}
+
+	return delivered, failed, nil
+}
+
+// Delivered records that the inbox accepted this activity.
+func (d *Delivery) Delivered() error {
+	now := query.TimeString(time.Now().UTC())
+	return d.Update(map[string]string{"delivered_at": now, "error": ""})
+}
+
+// Failed records that delivery failed with this error, and schedules it to be tried again after a delay
+// which doubles with each attempt. If retry is false, or the attempts are used up, we give up.
+func (d *Delivery) Failed(err error, retry bool) error {
+	now := time.Now().UTC()
+	d.Attempts++
+	params := map[string]string{
+		"attempts": fmt.Sprintf("%d", d.Attempts),
+		"error":    err.Error(),
+		"next_at":  query.TimeString(now.Add(Backoff(d.Attempts))),
+	}
+	if !retry || d.Attempts >= MaxAttempts {
+		params["failed_at"] = query.TimeString(now)
+	}
+	return d.Update(params)
+}
+
+// Backoff returns the delay before trying again after this many attempts have failed.
+func Backoff(attempts int64) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	if attempts > 16 {
+		attempts = 16
+	}
+	return RetryDelay << uint(attempts-1)
+}
+
+// Purge removes deliveries which were delivered or given up on before this time.
+func Purge(before time.Time) error {
+	t := query.TimeString(before.UTC())
+	_, err := query.Exec("delete from deliveries where delivered_at < $1 or failed_at < $1", t)
+	return err
+}
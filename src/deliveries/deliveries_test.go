@@ -0,0 +1,102 @@
+// Tests for the deliveries package
+package deliveries
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/activitypub"
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("deliveries: Setup db failed %s", err)
+	}
+
+	query.ExecSQL("delete from deliveries;")
+}
+
+func TestBackoff(t *testing.T) {
+	tests := map[int64]time.Duration{
+		0:  0,
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		5:  16 * time.Minute,
+		40: RetryDelay << 15,
+	}
+	for attempts, delay := range tests {
+		if Backoff(attempts) != delay {
+			t.Fatalf("deliveries: wrong backoff for %d got:%s", attempts, Backoff(attempts))
+		}
+	}
+}
+
+// Test activities are delivered, tried again after failures, and given up on
+func TestDeliver(t *testing.T) {
+	activity := map[string]string{"type": "Create"}
+	err := Enqueue(activity, "https://remote.example/inbox", "https://other.example/inbox", "https://gone.example/inbox")
+	if err != nil {
+		t.Fatalf("deliveries: Enqueue failed :%s", err)
+	}
+
+	// Inboxes which fail temporarily are tried again, those which are gone are not
+	sent := map[string]int{}
+	send := func(inbox string, data []byte) error {
+		sent[inbox]++
+		if string(data) != `{"type":"Create"}` {
+			t.Fatalf("deliveries: wrong activity sent %s", data)
+		}
+		switch inbox {
+		case "https://other.example/inbox":
+			return errors.New("connection refused")
+		case "https://gone.example/inbox":
+			return &activitypub.StatusError{URL: inbox, Code: http.StatusGone}
+		}
+		return nil
+	}
+
+	now := time.Now()
+	delivered, failed, err := Deliver(now, 10, send)
+	if err != nil || delivered != 1 || failed != 2 {
+		t.Fatalf("deliveries: Deliver wrong counts %d %d %v", delivered, failed, err)
+	}
+
+	// Nothing is due until the retry delay has passed
+	delivered, failed, err = Deliver(now, 10, send)
+	if err != nil || delivered != 0 || failed != 0 {
+		t.Fatalf("deliveries: Deliver retried too soon %d %d %v", delivered, failed, err)
+	}
+
+	// Only the failed delivery is tried again, until the attempts are used up
+	for i := int64(1); i < MaxAttempts; i++ {
+		now = time.Now().Add(Backoff(i) + time.Second)
+		delivered, failed, err = Deliver(now, 10, send)
+		if err != nil || delivered != 0 || failed != 1 {
+			t.Fatalf("deliveries: Deliver retry %d wrong counts %d %d %v", i, delivered, failed, err)
+		}
+	}
+	if sent["https://remote.example/inbox"] != 1 || sent["https://gone.example/inbox"] != 1 || sent["https://other.example/inbox"] != int(MaxAttempts) {
+		t.Fatalf("deliveries: wrong attempts %v", sent)
+	}
+
+	d, err := FindFirst("inbox=?", "https://other.example/inbox")
+	if err != nil || d.Attempts != MaxAttempts || d.FailedAt.IsZero() || d.Error != "connection refused" {
+		t.Fatalf("deliveries: failed delivery not given up %v %v", d, err)
+	}
+
+	// Finished deliveries are purged
+	err = Purge(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("deliveries: Purge failed :%s", err)
+	}
+	results, err := FindAll(Query())
+	if err != nil || len(results) != 0 {
+		t.Fatalf("deliveries: Purge left deliveries %d %v", len(results), err)
+	}
+}
@@ -0,0 +1,94 @@
+package deliveries
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "deliveries"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "created_at desc, id desc"
+)
+
+// NewWithColumns creates a new delivery instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Delivery {
+
+	delivery := New()
+	delivery.ID = resource.ValidateInt(cols["id"])
+	delivery.CreatedAt = resource.ValidateTime(cols["created_at"])
+	delivery.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	delivery.Inbox = resource.ValidateString(cols["inbox"])
+	delivery.Activity = resource.ValidateString(cols["activity"])
+	delivery.Attempts = resource.ValidateInt(cols["attempts"])
+	delivery.NextAt = resource.ValidateTime(cols["next_at"])
+	delivery.DeliveredAt = resource.ValidateTime(cols["delivered_at"])
+	delivery.FailedAt = resource.ValidateTime(cols["failed_at"])
+	delivery.Error = resource.ValidateString(cols["error"])
+
+	return delivery
+}
+
+// New creates and initialises a new delivery instance.
+func New() *Delivery {
+	delivery := &Delivery{}
+	delivery.CreatedAt = time.Now()
+	delivery.UpdatedAt = time.Now()
+	delivery.TableName = TableName
+	delivery.KeyName = KeyName
+	return delivery
+}
+
+// FindFirst fetches a single delivery record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Delivery, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single delivery record from the database by id.
+func Find(id int64) (*Delivery, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all delivery records matching this query from the database.
+func FindAll(q *query.Query) ([]*Delivery, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of deliveries constructed from the results
+	var deliveries []*Delivery
+	for _, cols := range results {
+		d := NewWithColumns(cols)
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, nil
+}
+
+// Query returns a new query for deliveries with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for deliveries with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
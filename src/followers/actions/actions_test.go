@@ -0,0 +1,391 @@
+package followeractions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+	"github.com/fragmenta/server/config"
+
+	"github.com/kennygrant/gohackernews/src/deliveries"
+	"github.com/kennygrant/gohackernews/src/followers"
+	"github.com/kennygrant/gohackernews/src/lib/activitypub"
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+// siteKey is the key of the site actor, remoteKey the key of alice on the remote server.
+var (
+	siteKey, _   = rsa.GenerateKey(rand.Reader, 2048)
+	remoteKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+)
+
+// received holds the activities delivered to inboxes on the remote server, by path.
+var received = struct {
+	sync.Mutex
+	activities map[string][]*activitypub.Activity
+}{activities: map[string][]*activitypub.Activity{}}
+
+// remote is a local stand-in for a Mastodon server, with an actor alice whose server accepts
+// deliveries to her inbox and the shared inbox only if they are signed by the site.
+var remote = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/users/alice":
+		pem, _ := activitypub.PublicKeyPEM(&remoteKey.PublicKey)
+		id := "http://" + r.Host + "/users/alice"
+		w.Header().Set("Content-Type", activitypub.ContentType)
+		json.NewEncoder(w).Encode(&activitypub.Actor{
+			ID:        id,
+			Type:      "Person",
+			Inbox:     id + "/inbox",
+			Endpoints: &activitypub.Endpoints{SharedInbox: "http://" + r.Host + "/inbox"},
+			PublicKey: activitypub.PublicKey{ID: id + "#main-key", Owner: id, PublicKeyPem: pem},
+		})
+	case "/users/alice/inbox", "/inbox":
+		body, _ := io.ReadAll(r.Body)
+		if activitypub.Verify(r, body, &siteKey.PublicKey, time.Now()) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		activity, err := activitypub.ParseActivity(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received.Lock()
+		received.activities[r.URL.Path] = append(received.activities[r.URL.Path], activity)
+		received.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	case "/down":
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}))
+
+// aliceID returns the id of alice on the remote server.
+func aliceID() string {
+	return remote.URL + "/users/alice"
+}
+
+// receivedAt returns the activities received at this path on the remote server.
+func receivedAt(path string) []*activitypub.Activity {
+	received.Lock()
+	defer received.Unlock()
+	return received.activities[path]
+}
+
+// signedInboxRequest returns a request to the site inbox with this activity, signed by alice with key.
+func signedInboxRequest(t *testing.T, activity interface{}, key *rsa.PrivateKey) *http.Request {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		t.Fatalf("followeractions: error encoding activity %s", err)
+	}
+	r := httptest.NewRequest("POST", actorURL("/inbox"), bytes.NewReader(body))
+	r.Header.Set("Content-Type", activitypub.ContentType)
+	err = activitypub.Sign(r, aliceID()+"#main-key", key, body)
+	if err != nil {
+		t.Fatalf("followeractions: error signing activity %s", err)
+	}
+	return r
+}
+
+// TestSetup performs setup for integration tests
+// using the test database, a key for the site actor, and a stand-in for a remote server
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(3)
+	if err != nil {
+		fmt.Printf("followers: Setup db failed %s", err)
+	}
+
+	// Load config for the root url of the site
+	c := config.New()
+	c.Load(filepath.Join("..", "..", "..", "secrets", "fragmenta.json"))
+	c.Mode = config.ModeTest
+	config.Current = c
+
+	router := mux.New()
+	mux.SetDefault(router)
+
+	router.Add("/.well-known/webfinger", nil)
+	router.Add("/actor", nil)
+	router.Add("/actor/outbox", nil)
+	router.Add("/actor/followers", nil)
+	router.Add("/actor/stories/{id:\\d+}", nil)
+	router.Add("/actor/inbox", nil).Post()
+
+	// Sign as the site actor, allowing requests to the remote server on the loopback address
+	activitypub.Default = activitypub.New(actorURL("#main-key"), siteKey)
+	activitypub.Default.HTTP = &http.Client{Timeout: time.Second}
+	activitypub.Default.AllowURL = func(string) bool { return true }
+
+	// Delete all followers, deliveries and stories to ensure we get consistent results
+	query.ExecSQL("delete from followers;")
+	query.ExecSQL("delete from deliveries;")
+	query.ExecSQL("delete from stories;")
+
+	// Delete all users to ensure we get consistent results
+	_, err = query.ExecSQL("delete from users;")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(1,'example@example.com','admin',100,100,100,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+
+	// Insert a link and a discussion on the front page, and a story without points
+	_, err = query.ExecSQL("INSERT INTO stories (id,created_at,updated_at,status,name,kind,url,rank,user_id,user_name,points) VALUES(1,now(),now(),100,'Go 2 released #golang','https://go.dev/blog/go2',0,10,1,'admin',10);")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO stories (id,created_at,updated_at,status,name,kind,summary,rank,user_id,user_name,points) VALUES(2,now(),now(),100,'Ask: Which <router> do you use?',2,'Looking for advice',9,1,'admin',5);")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO stories (id,created_at,updated_at,status,name,kind,url,rank,user_id,user_name,points) VALUES(3,now(),now(),100,'Spam','https://spam.example',0,0,1,'admin',0);")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+}
+
+// Test GET /.well-known/webfinger finds only the site actor
+func TestWebFinger(t *testing.T) {
+	host := siteHost()
+
+	tests := map[string]bool{
+		"acct:news@" + host:  true,
+		"news@" + host:       true,
+		actorURL(""):         true,
+		"acct:admin@" + host: false,
+		"acct:news@remote":   false,
+		"":                   false,
+	}
+
+	for resource, found := range tests {
+		r := httptest.NewRequest("GET", "/.well-known/webfinger?resource="+url.QueryEscape(resource), nil)
+		w := httptest.NewRecorder()
+
+		err := HandleWebFinger(w, r)
+		if !found {
+			if err == nil {
+				t.Fatalf("followeractions: found webfinger for %s", resource)
+			}
+			continue
+		}
+
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("followeractions: error handling HandleWebFinger %s %v", resource, err)
+		}
+		wf := &activitypub.WebFinger{}
+		err = json.Unmarshal(w.Body.Bytes(), wf)
+		if err != nil || wf.Subject != "acct:news@"+host || wf.Links[0].Href != actorURL("") {
+			t.Fatalf("followeractions: unexpected webfinger for %s got:%s", resource, w.Body.String())
+		}
+	}
+}
+
+// Test GET /actor publishes the key and inboxes of the site
+func TestShowActor(t *testing.T) {
+	r := httptest.NewRequest("GET", "/actor", nil)
+	r.Header.Set("Accept", activitypub.LDContentType)
+	w := httptest.NewRecorder()
+
+	err := HandleActor(w, r)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("followeractions: error handling HandleActor %s", err)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), activitypub.ContentType) {
+		t.Fatalf("followeractions: wrong content type for actor %s", w.Header().Get("Content-Type"))
+	}
+
+	actor := &activitypub.Actor{}
+	err = json.Unmarshal(w.Body.Bytes(), actor)
+	if err != nil || actor.ID != actorURL("") || actor.Inbox != actorURL("/inbox") || actor.PreferredUsername != "news" {
+		t.Fatalf("followeractions: unexpected actor got:%s", w.Body.String())
+	}
+	key, err := activitypub.ParsePublicKey([]byte(actor.PublicKey.PublicKeyPem))
+	if err != nil || !key.Equal(&siteKey.PublicKey) || actor.PublicKey.ID != activitypub.Default.KeyID {
+		t.Fatalf("followeractions: unexpected actor key %v", err)
+	}
+
+	// Browsers are sent to the home page
+	r = httptest.NewRequest("GET", "/actor", nil)
+	r.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	err = HandleActor(w, r)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("followeractions: unexpected response for html actor %d %v", w.Code, err)
+	}
+}
+
+// Test GET /actor/outbox lists the front page as notes and articles
+func TestShowOutbox(t *testing.T) {
+	r := httptest.NewRequest("GET", "/actor/outbox", nil)
+	w := httptest.NewRecorder()
+
+	err := HandleOutbox(w, r)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("followeractions: error handling HandleOutbox %s", err)
+	}
+
+	var outbox struct {
+		TotalItems   int64                  `json:"totalItems"`
+		OrderedItems []activitypub.Activity `json:"orderedItems"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &outbox)
+	if err != nil || outbox.TotalItems != 2 || len(outbox.OrderedItems) != 2 {
+		t.Fatalf("followeractions: unexpected outbox got:%s", w.Body.String())
+	}
+
+	note, _ := outbox.OrderedItems[0].Object.(map[string]interface{})
+	article, _ := outbox.OrderedItems[1].Object.(map[string]interface{})
+	if outbox.OrderedItems[0].Type != activitypub.TypeCreate || note["type"] != activitypub.TypeNote || note["url"] != "https://go.dev/blog/go2" || article["type"] != activitypub.TypeArticle {
+		t.Fatalf("followeractions: unexpected outbox items got:%s", w.Body.String())
+	}
+
+	// Content is escaped, and tags link to the stories with that tag
+	content := fmt.Sprint(note["content"]) + fmt.Sprint(article["content"])
+	if !strings.Contains(content, `rel="tag">#golang</a>`) || strings.Contains(content, "<router>") || fmt.Sprint(article["name"]) != "Ask: Which <router> do you use?" {
+		t.Fatalf("followeractions: unexpected content got:%s", content)
+	}
+
+	// Objects may be fetched by id
+	r = httptest.NewRequest("GET", "/actor/stories/1", nil)
+	r.Header.Set("Accept", activitypub.ContentType)
+	w = httptest.NewRecorder()
+	err = HandleStory(w, r)
+	if err != nil || !strings.Contains(w.Body.String(), `"id":"`+actorURL("/stories/1")+`"`) {
+		t.Fatalf("followeractions: error handling HandleStory %v %s", err, w.Body.String())
+	}
+}
+
+// Test POST /actor/inbox with a signed follow records the follower and accepts the follow
+func TestInboxFollow(t *testing.T) {
+	follow := &activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      aliceID() + "#follows/1",
+		Type:    activitypub.TypeFollow,
+		Actor:   aliceID(),
+		Object:  actorURL(""),
+	}
+
+	// Unsigned activities, or those signed with another key, are refused
+	r := signedInboxRequest(t, follow, remoteKey)
+	r.Header.Del("Signature")
+	err := HandleInbox(httptest.NewRecorder(), r)
+	if err == nil {
+		t.Fatalf("followeractions: inbox accepted unsigned activity")
+	}
+	err = HandleInbox(httptest.NewRecorder(), signedInboxRequest(t, follow, siteKey))
+	if err == nil {
+		t.Fatalf("followeractions: inbox accepted activity signed with wrong key")
+	}
+
+	// Activities for other actors than the signer are refused
+	forged := *follow
+	forged.Actor = remote.URL + "/users/bob"
+	err = HandleInbox(httptest.NewRecorder(), signedInboxRequest(t, &forged, remoteKey))
+	if err == nil {
+		t.Fatalf("followeractions: inbox accepted activity for another actor")
+	}
+
+	w := httptest.NewRecorder()
+	err = HandleInbox(w, signedInboxRequest(t, follow, remoteKey))
+	if err != nil || w.Code != http.StatusAccepted {
+		t.Fatalf("followeractions: error handling HandleInbox %s", err)
+	}
+
+	follower, err := followers.FindActor(aliceID())
+	if err != nil || follower.DeliveryInbox() != remote.URL+"/inbox" || follower.FollowID != follow.ID {
+		t.Fatalf("followeractions: follower not recorded %v %v", follower, err)
+	}
+
+	// The accept is queued, and delivered to the inbox of alice signed by the site
+	DeliverActivities()
+	accepts := receivedAt("/users/alice/inbox")
+	if len(accepts) != 1 || accepts[0].Type != activitypub.TypeAccept || accepts[0].ObjectID() != follow.ID {
+		t.Fatalf("followeractions: accept not delivered %v", accepts)
+	}
+}
+
+// Test front page stories are published to followers, and deliveries which fail are tried again
+func TestPublishStories(t *testing.T) {
+	PublishStories()
+
+	count, err := deliveries.Query().Where("inbox=?", remote.URL+"/inbox").Count()
+	if err != nil || count != 2 {
+		t.Fatalf("followeractions: stories not queued %d %v", count, err)
+	}
+
+	// Stories are published once
+	PublishStories()
+	count, err = deliveries.Query().Where("inbox=?", remote.URL+"/inbox").Count()
+	if err != nil || count != 2 {
+		t.Fatalf("followeractions: stories queued twice %d", count)
+	}
+
+	// A delivery to a server which is down is tried again later
+	err = deliveries.Enqueue(map[string]string{"type": "Create"}, remote.URL+"/down")
+	if err != nil {
+		t.Fatalf("followeractions: error queueing delivery %s", err)
+	}
+
+	DeliverActivities()
+	creates := receivedAt("/inbox")
+	if len(creates) != 2 || creates[0].Type != activitypub.TypeCreate || creates[0].Actor != actorURL("") {
+		t.Fatalf("followeractions: stories not delivered %v", creates)
+	}
+
+	d, err := deliveries.FindFirst("inbox=?", remote.URL+"/down")
+	if err != nil || d.Attempts != 1 || !d.FailedAt.IsZero() || !d.NextAt.After(time.Now()) {
+		t.Fatalf("followeractions: failed delivery not scheduled again %v %v", d, err)
+	}
+}
+
+// Test POST /actor/inbox with an undo of a follow removes the follower
+func TestInboxUndo(t *testing.T) {
+	undo := &activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      aliceID() + "#follows/1/undo",
+		Type:    activitypub.TypeUndo,
+		Actor:   aliceID(),
+		Object: &activitypub.Activity{
+			ID:     aliceID() + "#follows/1",
+			Type:   activitypub.TypeFollow,
+			Actor:  aliceID(),
+			Object: actorURL(""),
+		},
+	}
+
+	w := httptest.NewRecorder()
+	err := HandleInbox(w, signedInboxRequest(t, undo, remoteKey))
+	if err != nil || w.Code != http.StatusAccepted {
+		t.Fatalf("followeractions: error handling HandleInbox %s", err)
+	}
+
+	_, err = followers.FindActor(aliceID())
+	if err == nil {
+		t.Fatalf("followeractions: follower not removed after undo")
+	}
+
+	// Followers are counted publicly
+	r := httptest.NewRequest("GET", "/actor/followers", nil)
+	w = httptest.NewRecorder()
+	err = HandleFollowers(w, r)
+	if err != nil || !strings.Contains(w.Body.String(), `"totalItems":0`) {
+		t.Fatalf("followeractions: error handling HandleFollowers %v %s", err, w.Body.String())
+	}
+}
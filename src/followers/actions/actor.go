@@ -0,0 +1,143 @@
+package followeractions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/config"
+
+	"github.com/kennygrant/gohackernews/src/followers"
+	"github.com/kennygrant/gohackernews/src/lib/activitypub"
+)
+
+// actorPath is the path of the actor which represents the site on the fediverse.
+const actorPath = "/actor"
+
+// actorURL returns the absolute url of this path under the actor.
+func actorURL(path string) string {
+	return config.Get("root_url") + actorPath + path
+}
+
+// actorName returns the user name of the actor, e.g. news for @news@golangnews.com
+func actorName() string {
+	name := config.Get("activitypub_name")
+	if name == "" {
+		return "news"
+	}
+	return name
+}
+
+// siteHost returns the host of the site from the root url.
+func siteHost() string {
+	u, err := url.Parse(config.Get("root_url"))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// HandleWebFinger responds to GET /.well-known/webfinger?resource=acct:news@golangnews.com
+// with the actor for the site, so that it may be followed as @news@golangnews.com
+func HandleWebFinger(w http.ResponseWriter, r *http.Request) error {
+
+	// No authorisation - the actor is public
+
+	if !activitypub.Default.Enabled() {
+		return server.NotFoundError(nil)
+	}
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Only the actor of the site is known, by account or by id
+	resource := params.Get("resource")
+	if resource != actorURL("") {
+		name, host, err := activitypub.ParseAccount(resource)
+		if err != nil || name != actorName() || host != siteHost() {
+			return server.NotFoundError(err)
+		}
+	}
+
+	account := activitypub.Account(actorName(), siteHost())
+	w.Header().Set("Content-Type", activitypub.JRDContentType+"; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	return json.NewEncoder(w).Encode(activitypub.NewWebFinger(account, actorURL(""), config.Get("root_url")+"/"))
+}
+
+// HandleActor responds to GET /actor with the actor document for the site,
+// browsers are sent to the home page.
+func HandleActor(w http.ResponseWriter, r *http.Request) error {
+
+	// No authorisation - the actor is public
+
+	if !activitypub.Default.Enabled() {
+		return server.NotFoundError(nil)
+	}
+
+	if !activitypub.Accepts(r.Header.Get("Accept")) {
+		return server.Redirect(w, r, "/")
+	}
+
+	key, err := activitypub.PublicKeyPEM(&activitypub.Default.Key.PublicKey)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	actor := &activitypub.Actor{
+		Context:           []string{activitypub.Context, activitypub.SecurityContext},
+		ID:                actorURL(""),
+		Type:              activitypub.TypeService,
+		PreferredUsername: actorName(),
+		Name:              config.Get("meta_title"),
+		Summary:           config.Get("meta_desc"),
+		URL:               config.Get("root_url") + "/",
+		Inbox:             actorURL("/inbox"),
+		Outbox:            actorURL("/outbox"),
+		Followers:         actorURL("/followers"),
+		Endpoints:         &activitypub.Endpoints{SharedInbox: actorURL("/inbox")},
+		Icon:              &activitypub.Image{Type: "Image", MediaType: "image/png", URL: config.Get("root_url") + "/favicon.png"},
+		PublicKey: activitypub.PublicKey{
+			ID:           activitypub.Default.KeyID,
+			Owner:        actorURL(""),
+			PublicKeyPem: key,
+		},
+	}
+
+	return renderActivityJSON(w, actor)
+}
+
+// HandleFollowers responds to GET /actor/followers with the number of followers of the site,
+// the followers themselves are not listed.
+func HandleFollowers(w http.ResponseWriter, r *http.Request) error {
+
+	// No authorisation - the count is public
+
+	if !activitypub.Default.Enabled() {
+		return server.NotFoundError(nil)
+	}
+
+	count, err := followers.Count()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	return renderActivityJSON(w, &activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         actorURL("/followers"),
+		Type:       activitypub.TypeOrderedCollection,
+		TotalItems: count,
+	})
+}
+
+// renderActivityJSON writes this document as activity json.
+func renderActivityJSON(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", activitypub.ContentType+"; charset=utf-8")
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,130 @@
+package followeractions
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/deliveries"
+	"github.com/kennygrant/gohackernews/src/followers"
+	"github.com/kennygrant/gohackernews/src/lib/activitypub"
+)
+
+// maxInboxBytes limits the size of activities we read.
+const maxInboxBytes = 256 * 1024
+
+// HandleInbox responds to POST /actor/inbox from remote servers. Activities are accepted only
+// with a valid http signature by the actor who sent them. Follows of the site are recorded
+// and accepted, Undo of a follow removes the follower, and other activities are ignored.
+// There is no authenticity token or session, the signature authenticates the request.
+func HandleInbox(w http.ResponseWriter, r *http.Request) error {
+
+	if !activitypub.Default.Enabled() {
+		return server.NotFoundError(nil)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInboxBytes))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	activity, err := activitypub.ParseActivity(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil
+	}
+
+	// Authorise the activity with the signature of the actor who sent it
+	actor, err := activitypub.Default.VerifyRequest(r, body)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+	if actor.ID != activity.Actor {
+		return server.NotAuthorizedError(fmt.Errorf("followeractions: activity by %s signed by %s", activity.Actor, actor.ID))
+	}
+
+	switch activity.Type {
+	case activitypub.TypeFollow:
+		err = follow(actor, activity)
+	case activitypub.TypeUndo:
+		err = undo(actor, activity)
+	default:
+		log.Info(log.V{"msg": "activitypub: activity ignored", "actor": actor.ID, "type": activity.Type})
+	}
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+// follow records the actor as a follower if they follow the site, and queues the Accept of their follow.
+func follow(actor *activitypub.Actor, activity *activitypub.Activity) error {
+	if activity.ObjectID() != actorURL("") {
+		log.Info(log.V{"msg": "activitypub: follow of unknown actor ignored", "actor": actor.ID, "object": activity.ObjectID()})
+		return nil
+	}
+
+	// Inboxes must be public https urls, as we deliver to them later
+	if !activitypub.Default.Allowed(actor.Inbox) {
+		log.Info(log.V{"msg": "activitypub: follow with invalid inbox ignored", "actor": actor.ID, "inbox": actor.Inbox})
+		return nil
+	}
+	sharedInbox := ""
+	if actor.Endpoints != nil && activitypub.Default.Allowed(actor.Endpoints.SharedInbox) {
+		sharedInbox = actor.Endpoints.SharedInbox
+	}
+
+	follower, err := followers.Follow(actor.ID, actor.Inbox, sharedInbox, activity.ID)
+	if err != nil {
+		return err
+	}
+
+	// The follow we accept is sent back without its context
+	activity.Context = nil
+	accept := &activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      actorURL(fmt.Sprintf("#accepts/%d", follower.ID)),
+		Type:    activitypub.TypeAccept,
+		Actor:   actorURL(""),
+		Object:  activity,
+	}
+	err = deliveries.Enqueue(accept, actor.Inbox)
+	if err != nil {
+		return err
+	}
+
+	log.Info(log.V{"msg": "activitypub: follower added", "actor": actor.ID})
+	return nil
+}
+
+// undo removes the actor from the followers if the activity undone is their follow of the site.
+func undo(actor *activitypub.Actor, activity *activitypub.Activity) error {
+	follower, err := followers.FindActor(actor.ID)
+	if err != nil {
+		// Actors who do not follow the site have nothing to undo
+		return nil
+	}
+
+	// The follow undone may be embedded or given by id
+	undone, err := activity.ObjectActivity()
+	if err == nil {
+		if undone.Type != activitypub.TypeFollow || undone.Actor != actor.ID {
+			return nil
+		}
+	} else if activity.ObjectID() != follower.FollowID {
+		return nil
+	}
+
+	err = followers.Unfollow(actor.ID)
+	if err != nil {
+		return err
+	}
+
+	log.Info(log.V{"msg": "activitypub: follower removed", "actor": actor.ID})
+	return nil
+}
@@ -0,0 +1,161 @@
+package followeractions
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/config"
+
+	"github.com/kennygrant/gohackernews/src/lib/activitypub"
+	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// outboxLimit is the number of stories from the front page listed in the outbox.
+const outboxLimit = 20
+
+// frontPage returns a query for the stories on the front page visible to everyone, in the order shown there.
+func frontPage() *query.Query {
+	q := stories.Query().Where("points > 0").Order("rank desc, points desc, id desc")
+	return users.WherePublic(q)
+}
+
+// HandleOutbox responds to GET /actor/outbox with the stories on the front page, as Create activities.
+func HandleOutbox(w http.ResponseWriter, r *http.Request) error {
+
+	// No authorisation - the front page is public
+
+	if !activitypub.Default.Enabled() {
+		return server.NotFoundError(nil)
+	}
+
+	results, err := stories.FindAll(frontPage().Limit(outboxLimit))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	outbox := &activitypub.OrderedCollection{
+		Context:      activitypub.Context,
+		ID:           actorURL("/outbox"),
+		Type:         activitypub.TypeOrderedCollection,
+		TotalItems:   int64(len(results)),
+		OrderedItems: []interface{}{},
+	}
+	for _, story := range results {
+		activity := storyActivity(story)
+		activity.Context = nil
+		outbox.OrderedItems = append(outbox.OrderedItems, activity)
+	}
+
+	return renderActivityJSON(w, outbox)
+}
+
+// HandleStory responds to GET /actor/stories/{id} with the object published for a story,
+// browsers are sent to the story.
+func HandleStory(w http.ResponseWriter, r *http.Request) error {
+
+	// No authorisation - only stories visible to everyone are found
+
+	if !activitypub.Default.Enabled() {
+		return server.NotFoundError(nil)
+	}
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the story, if it is visible to everyone
+	q := users.WherePublic(stories.Where("id=?", params.GetInt(stories.KeyName)))
+	results, err := stories.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+	if len(results) == 0 {
+		return server.NotFoundError(nil)
+	}
+	story := results[0]
+
+	if !activitypub.Accepts(r.Header.Get("Accept")) {
+		return server.Redirect(w, r, story.CanonicalURL())
+	}
+
+	object := storyObject(story)
+	object.Context = activitypub.Context
+	return renderActivityJSON(w, object)
+}
+
+// storyActivity returns the Create activity which publishes this story.
+func storyActivity(story *stories.Story) *activitypub.Activity {
+	object := storyObject(story)
+	published := object.Published
+	return &activitypub.Activity{
+		Context:   activitypub.Context,
+		ID:        object.ID + "/activity",
+		Type:      activitypub.TypeCreate,
+		Actor:     actorURL(""),
+		Object:    object,
+		Published: &published,
+		To:        object.To,
+		Cc:        object.Cc,
+	}
+}
+
+// storyObject returns the object which represents this story, an Article for discussions
+// such as Ask and Show, which are read here, or a Note linking to the url for other stories.
+func storyObject(story *stories.Story) *activitypub.Object {
+	root := config.Get("root_url")
+	discussURL := root + story.CanonicalURL()
+
+	object := &activitypub.Object{
+		ID:           actorURL(fmt.Sprintf("/stories/%d", story.ID)),
+		Type:         activitypub.TypeNote,
+		AttributedTo: actorURL(""),
+		URL:          story.DestinationURL(),
+		Published:    story.CreatedAt.UTC(),
+		To:           []string{activitypub.Public},
+		Cc:           []string{actorURL("/followers")},
+	}
+	if object.URL == "" {
+		object.URL = discussURL
+	} else if strings.HasPrefix(object.URL, "/") {
+		object.URL = root + object.URL
+	}
+
+	var content bytes.Buffer
+	name := strings.TrimSpace(story.NameDisplay())
+	if story.KindInfo().Discussion {
+		object.Type = activitypub.TypeArticle
+		object.Name = name
+		object.URL = discussURL
+	} else {
+		fmt.Fprintf(&content, `<p><a href="%s">%s</a></p>`, template.HTMLEscapeString(object.URL), template.HTMLEscapeString(name))
+	}
+	if story.Summary != "" {
+		fmt.Fprintf(&content, "<p>%s</p>", template.HTMLEscapeString(story.Summary))
+	}
+	fmt.Fprintf(&content, `<p>Posted by %s · <a href="%s">discuss</a>`, template.HTMLEscapeString(story.UserName), template.HTMLEscapeString(discussURL))
+
+	// Hashtags link to the stories with that tag
+	for _, tag := range story.Tags() {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		href := fmt.Sprintf("%s/stories?tag=%s", root, url.QueryEscape(tag))
+		fmt.Fprintf(&content, ` <a href="%s" class="hashtag" rel="tag">#%s</a>`, template.HTMLEscapeString(href), template.HTMLEscapeString(tag))
+		object.Tag = append(object.Tag, activitypub.Tag{Type: activitypub.TypeHashtag, Href: href, Name: "#" + tag})
+	}
+	content.WriteString("</p>")
+
+	object.Content = content.String()
+	return object
+}
@@ -0,0 +1,92 @@
+package followeractions
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/deliveries"
+	"github.com/kennygrant/gohackernews/src/followers"
+	"github.com/kennygrant/gohackernews/src/lib/activitypub"
+	"github.com/kennygrant/gohackernews/src/stories"
+)
+
+// publishLimit is the number of stories published to followers each time PublishStories is called.
+const publishLimit = 3
+
+// deliveryLimit is the number of deliveries tried each time DeliverActivities is called.
+const deliveryLimit = 100
+
+// deliveryRetention is the time finished deliveries are kept for before they are purged.
+const deliveryRetention = 7 * 24 * time.Hour
+
+// PublishStories queues delivery of the popular stories on the front page which have not been published yet
+// to the inboxes of followers. It is called regularly by the app.
+func PublishStories() {
+	if !activitypub.Default.Enabled() {
+		return
+	}
+
+	// Don't publish old stories, or those already published
+	q := frontPage().Where("points > 2").Where("created_at > current_timestamp - interval '3 days'")
+	q.Where("federated_at IS NULL").Limit(publishLimit)
+
+	results, err := stories.FindAll(q)
+	if err != nil {
+		log.Error(log.V{"msg": "activitypub: error finding stories to publish", "error": err})
+		return
+	}
+	if len(results) == 0 {
+		return
+	}
+
+	inboxes, err := followers.Inboxes()
+	if err != nil {
+		log.Error(log.V{"msg": "activitypub: error finding inboxes", "error": err})
+		return
+	}
+
+	for _, story := range results {
+		err = PublishStory(story, inboxes)
+		if err != nil {
+			log.Error(log.V{"msg": "activitypub: error publishing story", "story_id": story.ID, "error": err})
+			return
+		}
+	}
+	log.Info(log.V{"msg": "activitypub: published stories", "stories": len(results), "inboxes": len(inboxes)})
+}
+
+// PublishStory queues delivery of this story to these inboxes, and records that it has been published.
+func PublishStory(story *stories.Story, inboxes []string) error {
+	err := deliveries.Enqueue(storyActivity(story), inboxes...)
+	if err != nil {
+		return err
+	}
+	return story.Update(map[string]string{"federated_at": query.TimeString(time.Now().UTC())})
+}
+
+// DeliverActivities delivers the activities which are due to remote inboxes, signed by the site.
+// It is called regularly by the app, deliveries which fail are tried again later.
+func DeliverActivities() {
+	if !activitypub.Default.Enabled() {
+		return
+	}
+
+	delivered, failed, err := deliveries.Deliver(time.Now(), deliveryLimit, activitypub.Default.Post)
+	if err != nil {
+		log.Error(log.V{"msg": "activitypub: delivery failed", "error": err})
+		return
+	}
+	if delivered > 0 || failed > 0 {
+		log.Info(log.V{"msg": "activitypub: deliveries", "delivered": delivered, "failed": failed})
+	}
+}
+
+// PurgeDeliveries removes deliveries which finished more than a week ago.
+func PurgeDeliveries() {
+	err := deliveries.Purge(time.Now().Add(-deliveryRetention))
+	if err != nil {
+		log.Error(log.V{"msg": "activitypub: error purging deliveries", "error": err})
+	}
+}
@@ -0,0 +1,75 @@
+// Package followers represents the actors on other servers in the fediverse who follow the site,
+// and to whose inboxes the stories on the front page are delivered.
+package followers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+// Follower is a remote actor who follows the site, such as a user on a Mastodon server.
+type Follower struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	// ActorID is the id of the remote actor, e.g. https://mastodon.social/users/alice
+	ActorID string
+
+	// Inbox is the inbox of the actor, SharedInbox the inbox shared by everyone on their server (if any)
+	Inbox       string
+	SharedInbox string
+
+	// FollowID is the id of the Follow activity, which is referred to when we accept it
+	FollowID string
+}
+
+// DeliveryInbox returns the inbox public activities should be delivered to for this follower,
+// their shared inbox if they have one, so that each server receives an activity once.
+func (f *Follower) DeliveryInbox() string {
+	if f.SharedInbox != "" {
+		return f.SharedInbox
+	}
+	return f.Inbox
+}
+
+// Follow records that this actor follows the site, or updates their inboxes and follow if they already did.
+func Follow(actorID, inbox, sharedInbox, followID string) (*Follower, error) {
+	if actorID == "" || inbox == "" {
+		return nil, fmt.Errorf("followers: invalid follower %s", actorID)
+	}
+
+	now := query.TimeString(time.Now().UTC())
+	sql := `insert into followers (created_at, updated_at, actor_id, inbox, shared_inbox, follow_id) values ($1, $1, $2, $3, $4, $5)
+	on conflict (actor_id) do update set updated_at=$1, inbox=$3, shared_inbox=$4, follow_id=$5`
+	_, err := query.Exec(sql, now, actorID, inbox, sharedInbox, followID)
+	if err != nil {
+		return nil, err
+	}
+
+	return FindActor(actorID)
+}
+
+// Unfollow removes this actor from the followers of the site, if they follow it.
+func Unfollow(actorID string) error {
+	_, err := query.Exec("delete from followers where actor_id=$1", actorID)
+	return err
+}
+
+// Inboxes returns the inboxes public activities should be delivered to,
+// with each shared inbox listed once for all the followers who share it.
+func Inboxes() ([]string, error) {
+	results, err := query.New(TableName, KeyName).Select("select distinct coalesce(nullif(shared_inbox,''), inbox) as inbox from followers").Order("inbox asc").Results()
+	if err != nil {
+		return nil, err
+	}
+
+	var inboxes []string
+	for _, cols := range results {
+		inboxes = append(inboxes, resource.ValidateString(cols["inbox"]))
+	}
+	return inboxes, nil
+}
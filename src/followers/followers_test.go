@@ -0,0 +1,70 @@
+// Tests for the followers package
+package followers
+
+import (
+	"testing"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("followers: Setup db failed %s", err)
+	}
+
+	query.ExecSQL("delete from followers;")
+}
+
+// Test actors follow and unfollow, and shared inboxes receive activities once
+func TestFollow(t *testing.T) {
+	alice, err := Follow("https://remote.example/users/alice", "https://remote.example/users/alice/inbox", "https://remote.example/inbox", "https://remote.example/follows/1")
+	if err != nil {
+		t.Fatalf("followers: Follow failed :%s", err)
+	}
+	if alice.DeliveryInbox() != "https://remote.example/inbox" || alice.FollowID != "https://remote.example/follows/1" {
+		t.Fatalf("followers: Follow wrong follower %v", alice)
+	}
+
+	_, err = Follow("https://remote.example/users/bob", "https://remote.example/users/bob/inbox", "https://remote.example/inbox", "https://remote.example/follows/2")
+	if err != nil {
+		t.Fatalf("followers: Follow failed :%s", err)
+	}
+	carol, err := Follow("https://other.example/carol", "https://other.example/carol/inbox", "", "https://other.example/follows/3")
+	if err != nil || carol.DeliveryInbox() != "https://other.example/carol/inbox" {
+		t.Fatalf("followers: Follow failed :%v", err)
+	}
+
+	// Following again updates the follower
+	again, err := Follow("https://remote.example/users/alice", "https://remote.example/users/alice/inbox", "https://remote.example/inbox", "https://remote.example/follows/4")
+	if err != nil || again.ID != alice.ID || again.FollowID != "https://remote.example/follows/4" {
+		t.Fatalf("followers: Follow again failed %v %v", again, err)
+	}
+
+	count, err := Count()
+	if err != nil || count != 3 {
+		t.Fatalf("followers: wrong count %d %v", count, err)
+	}
+
+	inboxes, err := Inboxes()
+	if err != nil || len(inboxes) != 2 || inboxes[0] != "https://other.example/carol/inbox" || inboxes[1] != "https://remote.example/inbox" {
+		t.Fatalf("followers: wrong inboxes %v %v", inboxes, err)
+	}
+
+	err = Unfollow("https://remote.example/users/alice")
+	if err != nil {
+		t.Fatalf("followers: Unfollow failed :%s", err)
+	}
+	_, err = FindActor("https://remote.example/users/alice")
+	if err == nil {
+		t.Fatalf("followers: found follower after unfollow")
+	}
+
+	// Followers must have an inbox
+	_, err = Follow("https://remote.example/users/dave", "", "", "")
+	if err == nil {
+		t.Fatalf("followers: Follow accepted follower without inbox")
+	}
+}
@@ -0,0 +1,101 @@
+package followers
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "followers"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "created_at desc, id desc"
+)
+
+// NewWithColumns creates a new follower instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Follower {
+
+	follower := New()
+	follower.ID = resource.ValidateInt(cols["id"])
+	follower.CreatedAt = resource.ValidateTime(cols["created_at"])
+	follower.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	follower.ActorID = resource.ValidateString(cols["actor_id"])
+	follower.Inbox = resource.ValidateString(cols["inbox"])
+	follower.SharedInbox = resource.ValidateString(cols["shared_inbox"])
+	follower.FollowID = resource.ValidateString(cols["follow_id"])
+
+	return follower
+}
+
+// New creates and initialises a new follower instance.
+func New() *Follower {
+	follower := &Follower{}
+	follower.CreatedAt = time.Now()
+	follower.UpdatedAt = time.Now()
+	follower.TableName = TableName
+	follower.KeyName = KeyName
+	return follower
+}
+
+// FindFirst fetches a single follower record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Follower, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single follower record from the database by id.
+func Find(id int64) (*Follower, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindActor fetches the follower with this actor id.
+func FindActor(actorID string) (*Follower, error) {
+	return FindFirst("actor_id=?", actorID)
+}
+
+// FindAll fetches all follower records matching this query from the database.
+func FindAll(q *query.Query) ([]*Follower, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of followers constructed from the results
+	var followers []*Follower
+	for _, cols := range results {
+		f := NewWithColumns(cols)
+		followers = append(followers, f)
+	}
+
+	return followers, nil
+}
+
+// Count returns the number of followers of the site.
+func Count() (int64, error) {
+	return Query().Count()
+}
+
+// Query returns a new query for followers with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for followers with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
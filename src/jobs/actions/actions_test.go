@@ -0,0 +1,332 @@
+package jobactions
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+// names is used to test setting and getting the first string field of the job.
+var names = []string{"Go developer", "Senior Go developer"}
+
+// testSetup performs setup for integration tests
+// using the test database, real views, and mock authorisation
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(3)
+	if err != nil {
+		fmt.Printf("jobs: Setup db failed %s", err)
+	}
+
+	// Set up mock auth, readers may edit only their own jobs as in app
+	resource.SetupAuthorisation()
+	can.Authorise(10, can.CreateResource, jobs.TableName)
+	can.AuthoriseOwner(10, can.UpdateResource, jobs.TableName)
+
+	// Load templates for rendering
+	resource.SetupView(3)
+
+	router := mux.New()
+	mux.SetDefault(router)
+
+	router.Add("/jobs{format:(.xml|.atom|.json)?}", nil)
+	router.Add("/jobs/create", nil)
+	router.Add("/jobs/create", nil).Post()
+	router.Add("/jobs/{id:\\d+}/update", nil)
+	router.Add("/jobs/{id:\\d+}/update", nil).Post()
+	router.Add("/jobs/{id:\\d+}/renew", nil).Post()
+	router.Add("/jobs/{id:\\d+}/destroy", nil).Post()
+	router.Add("/jobs/{id:\\d+}", nil)
+
+	// Delete all jobs to ensure we get consistent results
+	query.ExecSQL("delete from jobs;")
+	query.ExecSQL("ALTER SEQUENCE jobs_id_seq RESTART WITH 1;")
+
+	// Delete all users to ensure we get consistent results
+	_, err = query.ExecSQL("delete from users;")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	// Insert a test admin user, and two readers who post jobs
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(1,'example@example.com','admin',100,100,100,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(2,'example2@example.com','employer',100,100,10,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(3,'example3@example.com','reader',100,100,10,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+
+	query.ExecSQL("ALTER SEQUENCE users_id_seq RESTART WITH 4;")
+}
+
+// Test GET /jobs/create
+func TestShowCreateJobs(t *testing.T) {
+
+	// Setup request and recorder
+	r := httptest.NewRequest("GET", "/jobs/create", nil)
+	w := httptest.NewRecorder()
+
+	// Set up session cookie for the employer above
+	err := resource.AddUserSessionCookie(w, r, 2)
+	if err != nil {
+		t.Fatalf("jobactions: error setting session %s", err)
+	}
+
+	// Run the handler
+	err = HandleCreateShow(w, r)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("jobactions: error handling HandleCreateShow %s", err)
+	}
+
+	// Test the body for a known pattern
+	pattern := "resource-update-form"
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("jobactions: unexpected response for HandleCreateShow expected:%s got:%s", pattern, w.Body.String())
+	}
+
+}
+
+// Test POST /jobs/create
+func TestCreateJobs(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("name", names[0])
+	form.Add("company", "Example")
+	form.Add("location", "Berlin")
+	form.Add("remote", "2")
+	form.Add("salary_min", "80000")
+	form.Add("salary_max", "100000")
+	form.Add("currency", "EUR")
+	form.Add("user_id", "3")
+	body := strings.NewReader(form.Encode())
+
+	r := httptest.NewRequest("POST", "/jobs/create", body)
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	// Set up session cookie for the employer
+	err := resource.AddUserSessionCookie(w, r, 2)
+	if err != nil {
+		t.Fatalf("jobactions: error setting session %s", err)
+	}
+
+	// Run the handler to create the job
+	err = HandleCreate(w, r)
+	if err != nil {
+		t.Fatalf("jobactions: error handling HandleCreate %s", err)
+	}
+
+	// Test we get a redirect after create (to the job concerned)
+	if w.Code != http.StatusFound {
+		t.Fatalf("jobactions: unexpected response code for HandleCreate expected:%d got:%d", http.StatusFound, w.Code)
+	}
+
+	// Check the job belongs to the employer, whatever the params said
+	allJobs, err := jobs.FindAll(jobs.Query().Order("id desc"))
+	if err != nil || len(allJobs) == 0 {
+		t.Fatalf("jobactions: error finding created job %s", err)
+	}
+	newJob := allJobs[0]
+	if newJob.ID != 1 || newJob.Name != names[0] || newJob.UserID != 2 || newJob.Expired() {
+		t.Fatalf("jobactions: error with created job values: %v %s %d", newJob.ID, newJob.Name, newJob.UserID)
+	}
+}
+
+// Test GET /jobs with filters
+func TestListJobs(t *testing.T) {
+
+	filters := map[string]bool{
+		"/jobs":                 true,
+		"/jobs?q=developer":     true,
+		"/jobs?location=berlin": true,
+		"/jobs?remote=2":        true,
+		"/jobs?salary=90000":    true,
+		"/jobs?location=Paris":  false,
+		"/jobs?salary=150000":   false,
+		"/jobs?q=rust&remote=1": false,
+		"/jobs?q=%25":           false,
+	}
+
+	for path, found := range filters {
+		r := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+
+		err := HandleIndex(w, r)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("jobactions: error handling HandleIndex %s", err)
+		}
+
+		if strings.Contains(w.Body.String(), names[0]) != found {
+			t.Errorf("jobactions: unexpected response for HandleIndex %s expected job:%v", path, found)
+		}
+	}
+}
+
+// Test GET /jobs.json
+func TestListJobsJSON(t *testing.T) {
+
+	r := httptest.NewRequest("GET", "/jobs.json", nil)
+	w := httptest.NewRecorder()
+
+	err := HandleIndex(w, r)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("jobactions: error handling HandleIndex json %s", err)
+	}
+
+	for _, pattern := range []string{"jsonfeed.org/version/1.1", names[0], `"remote":"Remote"`, `"salary_min":80000`} {
+		if !strings.Contains(w.Body.String(), pattern) {
+			t.Fatalf("jobactions: unexpected response for HandleIndex json expected:%s got:%s", pattern, w.Body.String())
+		}
+	}
+}
+
+// Test GET /jobs.xml and /jobs.atom
+func TestListJobsFeeds(t *testing.T) {
+
+	for path, pattern := range map[string]string{"/jobs.xml": "<rss", "/jobs.atom": "<feed"} {
+		r := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+
+		err := HandleIndex(w, r)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("jobactions: error handling HandleIndex %s %s", path, err)
+		}
+
+		for _, p := range []string{pattern, names[0]} {
+			if !strings.Contains(w.Body.String(), p) {
+				t.Fatalf("jobactions: unexpected response for HandleIndex %s expected:%s got:%s", path, p, w.Body.String())
+			}
+		}
+	}
+}
+
+// Test GET /jobs/1
+func TestShowJobs(t *testing.T) {
+
+	r := httptest.NewRequest("GET", "/jobs/1", nil)
+	w := httptest.NewRecorder()
+
+	err := HandleShow(w, r)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("jobactions: error handling HandleShow %s", err)
+	}
+
+	pattern := "EUR 80k–100k"
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("jobactions: unexpected response for HandleShow expected:%s got:%s", pattern, w.Body.String())
+	}
+}
+
+// Test POST /jobs/1/update is allowed only for the employer
+func TestUpdateJobs(t *testing.T) {
+
+	for _, id := range []int{3, 2} {
+		form := url.Values{}
+		form.Add("name", names[1])
+		form.Add("expires_at", "2100-01-01 00:00:00")
+		body := strings.NewReader(form.Encode())
+
+		r := httptest.NewRequest("POST", "/jobs/1/update", body)
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		err := resource.AddUserSessionCookie(w, r, id)
+		if err != nil {
+			t.Fatalf("jobactions: error setting session %s", err)
+		}
+
+		err = HandleUpdate(w, r)
+
+		// Other readers may not edit the job
+		if id == 3 {
+			if err == nil {
+				t.Fatalf("jobactions: reader allowed to update job of another user")
+			}
+			continue
+		}
+
+		if err != nil || w.Code != http.StatusFound {
+			t.Fatalf("jobactions: error handling HandleUpdate %s", err)
+		}
+	}
+
+	job, err := jobs.Find(1)
+	if err != nil || job.Name != names[1] || job.ExpiresAt.Year() == 2100 {
+		t.Fatalf("jobactions: error with updated job %v %v", job, err)
+	}
+}
+
+// Test POST /jobs/1/renew
+func TestRenewJobs(t *testing.T) {
+
+	renew := func() error {
+		r := httptest.NewRequest("POST", "/jobs/1/renew", nil)
+		w := httptest.NewRecorder()
+		err := resource.AddUserSessionCookie(w, r, 2)
+		if err != nil {
+			t.Fatalf("jobactions: error setting session %s", err)
+		}
+		return HandleRenew(w, r)
+	}
+
+	// New jobs may not be renewed
+	if renew() == nil {
+		t.Fatalf("jobactions: new job renewed")
+	}
+
+	// Expired jobs may be renewed, and are listed again
+	job, _ := jobs.Find(1)
+	job.Update(map[string]string{"expires_at": "2001-01-01 00:00:00"})
+	err := renew()
+	if err != nil {
+		t.Fatalf("jobactions: error handling HandleRenew %s", err)
+	}
+
+	job, err = jobs.Find(1)
+	if err != nil || job.Expired() {
+		t.Fatalf("jobactions: job not renewed %v", err)
+	}
+}
+
+// Test POST /jobs/1/destroy is allowed only for admins
+func TestDestroyJobs(t *testing.T) {
+
+	for _, id := range []int{2, 1} {
+		r := httptest.NewRequest("POST", "/jobs/1/destroy", nil)
+		w := httptest.NewRecorder()
+
+		err := resource.AddUserSessionCookie(w, r, id)
+		if err != nil {
+			t.Fatalf("jobactions: error setting session %s", err)
+		}
+
+		err = HandleDestroy(w, r)
+		if id == 2 && err == nil {
+			t.Fatalf("jobactions: employer allowed to destroy job")
+		}
+		if id == 1 && (err != nil || w.Code != http.StatusFound) {
+			t.Fatalf("jobactions: error handling HandleDestroy %s", err)
+		}
+	}
+
+	_, err := jobs.Find(1)
+	if err == nil {
+		t.Fatalf("jobactions: job not destroyed")
+	}
+}
@@ -0,0 +1,113 @@
+package jobactions
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/helpers"
+	"github.com/kennygrant/gohackernews/src/lib/karma"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+	"github.com/kennygrant/gohackernews/src/payments"
+)
+
+// HandleCreateShow serves the create form via GET for jobs.
+func HandleCreateShow(w http.ResponseWriter, r *http.Request) error {
+
+	job := jobs.New()
+
+	// Authorise
+	currentUser := session.CurrentUser(w, r)
+	err := can.Create(job, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.Template("jobs/views/create.html.got")
+	view.AddKey("job", job)
+	view.AddKey("lifetime", helpers.Duration(jobs.Lifetime))
+	view.AddKey("paid", payments.Enabled(jobs.TableName))
+	view.AddKey("currentUser", currentUser)
+	return view.Render()
+}
+
+// HandleCreate handles the POST of the create form for jobs
+func HandleCreate(w http.ResponseWriter, r *http.Request) error {
+
+	job := jobs.New()
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise
+	currentUser := session.CurrentUser(w, r)
+	err = can.Create(job, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Employers need the same points as those who submit stories
+	if !currentUser.CanSubmit() {
+		msg := fmt.Sprintf("You need to be registered and have more than %d points to post jobs.", karma.Current.Threshold(karma.Submit))
+		return server.NotAuthorizedError(nil, "Sorry", msg)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Validate the params, removing any we don't accept
+	jobParams := job.ValidateParams(params.Map(), jobs.AllowedParams())
+	err = jobs.Validate(jobParams)
+	if err != nil {
+		return server.NotAuthorizedError(err, "Invalid Job", err.Error())
+	}
+
+	// Set a few params to known good values
+	jobParams["status"] = fmt.Sprintf("%d", status.Published)
+	jobParams["user_id"] = fmt.Sprintf("%d", currentUser.ID)
+	jobParams["user_name"] = currentUser.Name
+	jobParams["expires_at"] = jobs.ExpiresParam()
+
+	// If jobs are paid for, they are listed only once payment is received
+	if payments.Enabled(jobs.TableName) {
+		jobParams["status"] = fmt.Sprintf("%d", status.Draft)
+		jobParams["expires_at"] = query.TimeString(time.Now().UTC())
+	}
+
+	ID, err := job.Create(jobParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log creation
+	log.Info(log.V{"msg": "Created job", "job_id": ID, "user_id": currentUser.ID})
+
+	// Redirect to the new job, or to payment for it
+	job, err = jobs.Find(ID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	if job.AwaitingPayment() {
+		return server.Redirect(w, r, job.PayURL())
+	}
+
+	return server.Redirect(w, r, job.CanonicalURL())
+}
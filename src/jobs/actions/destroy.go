@@ -0,0 +1,53 @@
+package jobactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+)
+
+// HandleDestroy responds to /jobs/n/destroy by deleting the job.
+func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the job
+	job, err := jobs.Find(params.GetInt(jobs.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise destroy job
+	currentUser := session.CurrentUser(w, r)
+	err = can.Destroy(job, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Destroy the job
+	err = job.Destroy()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	log.Info(log.V{"msg": "Destroyed job", "job_id": job.ID, "user_id": currentUser.ID})
+
+	// Redirect to jobs root
+	return server.Redirect(w, r, job.IndexURL())
+}
@@ -0,0 +1,218 @@
+package jobactions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/config"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/feed"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/lib/stats"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+)
+
+const listLimit = 50
+
+// HandleIndex displays the jobs which are currently listed, filtered by
+// the params q, location, remote and salary. Responds to GET /jobs, and the feeds /jobs.xml, /jobs.atom and /jobs.json
+func HandleIndex(w http.ResponseWriter, r *http.Request) error {
+
+	// No Authorisation - anyone can view jobs
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	stats.RegisterHit(r)
+
+	// Build a query for jobs which have not expired
+	q := jobs.Listed().Limit(listLimit)
+	filterJobs(q, params.Get("q"), params.Get("location"), params.GetInt("remote"), params.GetInt("salary"))
+
+	// Set the offset in pages if we have one
+	page := int(params.GetInt("page"))
+	if page > 0 {
+		q.Offset(listLimit * page)
+	}
+
+	// Fetch the jobs
+	results, err := jobs.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Serve a feed of the jobs if requested, the json feed includes the structured fields of jobs
+	switch format := feed.Negotiate(w, r); format {
+	case feed.JSON:
+		return renderJobsJSON(w, results)
+	case feed.RSS, feed.Atom:
+		return renderJobsFeed(w, r, format, results)
+	}
+
+	// Fetch live sponsored jobs for the marked slot on the first page, counting an impression for each
+	var sponsored []*sponsorships.Sponsorship
+	if page == 0 {
+		sponsored, err = sponsorships.FindAll(sponsorships.PinnedJobs().Limit(sponsorships.Slots))
+		if err != nil {
+			return server.InternalError(err)
+		}
+		err = sponsorships.CountImpressions(sponsored)
+		if err != nil {
+			log.Error(log.V{"msg": "sponsorship impressions failed", "error": err})
+		}
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.Template("jobs/views/index.html.got")
+	view.AddKey("page", page)
+	view.AddKey("jobs", results)
+	view.AddKey("sponsored", sponsored)
+	view.AddKey("filter", params.Get("q"))
+	view.AddKey("location", params.Get("location"))
+	view.AddKey("remote", params.GetInt("remote"))
+	view.AddKey("salary", params.GetInt("salary"))
+	view.AddKey("remoteOptions", jobs.RemoteOptions())
+	view.AddKey("meta_title", "Go Jobs - Companies hiring programmers and using Go")
+	view.AddKey("meta_desc", "Jobs for Go hackers")
+	view.AddKey("meta_keywords", "jobs careers "+config.Get("meta_keywords"))
+	view.AddKey("meta_rss", feed.Path(r, feed.RSS))
+	view.AddKey("meta_atom", feed.Path(r, feed.Atom))
+	view.AddKey("meta_json", feed.Path(r, feed.JSON))
+	view.AddKey("currentUser", session.CurrentUser(w, r))
+	return view.Render()
+}
+
+// filterJobs restricts the query to jobs matching the text filter, location, remote option and salary.
+func filterJobs(q *query.Query, filter, location string, remote, salary int64) {
+
+	// Replace special characters with escaped sequence for wildcard searches
+	escape := strings.NewReplacer("_", "\\_", "%", "\\%")
+
+	if len(filter) > 0 {
+		wildcard := "%" + escape.Replace(filter) + "%"
+		q.Where("(jobs.name ILIKE ? OR jobs.company ILIKE ? OR jobs.text ILIKE ?)", wildcard, wildcard, wildcard)
+	}
+
+	if len(location) > 0 {
+		q.Where("jobs.location ILIKE ?", "%"+escape.Replace(location)+"%")
+	}
+
+	// Remote 1 selects hybrid or remote jobs, 2 only remote jobs
+	if remote > jobs.OnSite {
+		q.Where("jobs.remote >= ?", remote)
+	}
+
+	// Salary selects jobs which may pay at least this much
+	if salary > 0 {
+		q.Where("GREATEST(jobs.salary_min, jobs.salary_max) >= ?", salary)
+	}
+}
+
+// jobsFeed is a JSON Feed (https://jsonfeed.org/version/1.1) of jobs.
+type jobsFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jobsFeedItem `json:"items"`
+}
+
+// jobsFeedItem is a single job in the feed, with the structured fields under _job.
+type jobsFeedItem struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url"`
+	ExternalURL   string    `json:"external_url,omitempty"`
+	Title         string    `json:"title"`
+	ContentText   string    `json:"content_text"`
+	DatePublished time.Time `json:"date_published"`
+	DateModified  time.Time `json:"date_modified"`
+	Job           jobJSON   `json:"_job"`
+}
+
+// jobJSON is the public representation of the structured fields of a job.
+type jobJSON struct {
+	Company    string    `json:"company"`
+	CompanyURL string    `json:"company_url,omitempty"`
+	Location   string    `json:"location"`
+	Remote     string    `json:"remote"`
+	SalaryMin  int64     `json:"salary_min,omitempty"`
+	SalaryMax  int64     `json:"salary_max,omitempty"`
+	Currency   string    `json:"currency,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// renderJobsFeed writes these jobs as an rss or atom feed.
+func renderJobsFeed(w http.ResponseWriter, r *http.Request, format string, results []*jobs.Job) error {
+	f := &feed.Feed{
+		Root:        config.Get("root_url"),
+		Title:       "Go Jobs",
+		Description: "Jobs for Go hackers",
+		Path:        "/jobs",
+	}
+	for _, j := range results {
+		if j.UpdatedAt.After(f.Updated) {
+			f.Updated = j.UpdatedAt
+		}
+		f.Items = append(f.Items, &feed.Item{
+			ID:          j.ShowURL(),
+			Title:       j.Name + " at " + j.Company,
+			URL:         j.CanonicalURL(),
+			ExternalURL: j.URL,
+			Text:        fmt.Sprintf("%s (%s) %s", j.Location, j.RemoteDisplay(), j.Text),
+			Published:   j.CreatedAt,
+			Updated:     j.UpdatedAt,
+		})
+	}
+	return feed.Render(w, r, f, format)
+}
+
+// renderJobsJSON writes these jobs as a json feed.
+func renderJobsJSON(w http.ResponseWriter, results []*jobs.Job) error {
+	root := config.Get("root_url")
+	feed := jobsFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       "Go Jobs",
+		HomePageURL: root + "/jobs",
+		FeedURL:     root + "/jobs.json",
+		Items:       []jobsFeedItem{},
+	}
+	for _, j := range results {
+		item := jobsFeedItem{
+			ID:            root + j.ShowURL(),
+			URL:           root + j.CanonicalURL(),
+			ExternalURL:   j.URL,
+			Title:         j.Name + " at " + j.Company,
+			ContentText:   j.Text,
+			DatePublished: j.CreatedAt,
+			DateModified:  j.UpdatedAt,
+			Job: jobJSON{
+				Company:    j.Company,
+				CompanyURL: j.CompanyURL,
+				Location:   j.Location,
+				Remote:     j.RemoteDisplay(),
+				ExpiresAt:  j.ExpiresAt,
+			},
+		}
+		if j.SalaryMin > 0 || j.SalaryMax > 0 {
+			item.Job.SalaryMin = j.SalaryMin
+			item.Job.SalaryMax = j.SalaryMax
+			item.Job.Currency = j.Currency
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+	return json.NewEncoder(w).Encode(feed)
+}
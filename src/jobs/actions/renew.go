@@ -0,0 +1,65 @@
+package jobactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/helpers"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/payments"
+)
+
+// HandleRenew responds to POST /jobs/{id}/renew by listing the job for another lifetime.
+func HandleRenew(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the job
+	job, err := jobs.Find(params.GetInt(jobs.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only the employer who posted the job (or an admin) may renew it
+	currentUser := session.CurrentUser(w, r)
+	err = can.Update(job, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Jobs may only be renewed as they near expiry, so they cannot be kept at the top
+	if !job.Renewable() {
+		msg := fmt.Sprintf("Sorry, jobs may be renewed in the last %s before they expire.", helpers.Duration(jobs.Lifetime/4))
+		return server.NotAuthorizedError(nil, "Not Renewable Yet", msg)
+	}
+
+	// If jobs are paid for, they are renewed once payment is received
+	if payments.Enabled(jobs.TableName) {
+		return server.Redirect(w, r, job.PayURL())
+	}
+
+	err = job.Renew()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	log.Info(log.V{"msg": "Renewed job", "job_id": job.ID, "user_id": currentUser.ID})
+
+	return server.Redirect(w, r, job.CanonicalURL())
+}
@@ -0,0 +1,51 @@
+package jobactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/config"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+	"github.com/kennygrant/gohackernews/src/payments"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+)
+
+// HandleShow displays a single job, expired jobs are still shown
+// so that links to them keep working, but are marked as closed.
+func HandleShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the job
+	job, err := jobs.Find(params.GetInt(jobs.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Suspended jobs are seen only by moderators and the employer
+	currentUser := session.CurrentUser(w, r)
+	if job.Status < status.Published && !currentUser.CanModerate() && !job.OwnedBy(currentUser.ID) {
+		return server.NotFoundError(nil)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.Template("jobs/views/show.html.got")
+	view.AddKey("job", job)
+	view.AddKey("meta_title", fmt.Sprintf("%s at %s - %s", job.Name, job.Company, config.Get("meta_title")))
+	view.AddKey("meta_desc", fmt.Sprintf("%s at %s, %s %s", job.Name, job.Company, job.Location, job.RemoteDisplay()))
+	view.AddKey("meta_keywords", fmt.Sprintf("jobs %s %s", job.Company, config.Get("meta_keywords")))
+	view.AddKey("sponsor", payments.Enabled(sponsorships.TableName))
+	view.AddKey("currentUser", currentUser)
+	return view.Render()
+}
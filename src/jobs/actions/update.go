@@ -0,0 +1,91 @@
+package jobactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+)
+
+// HandleUpdateShow renders the form to update a job.
+func HandleUpdateShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the job
+	job, err := jobs.Find(params.GetInt(jobs.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise update job - only the employer who posted it (or an admin) may edit
+	currentUser := session.CurrentUser(w, r)
+	err = can.Update(job, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.Template("jobs/views/update.html.got")
+	view.AddKey("job", job)
+	view.AddKey("currentUser", currentUser)
+	return view.Render()
+}
+
+// HandleUpdate handles the POST of the form to update a job
+func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the job
+	job, err := jobs.Find(params.GetInt(jobs.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update job - only the employer who posted it (or an admin) may edit
+	currentUser := session.CurrentUser(w, r)
+	err = can.Update(job, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Validate the params, removing any we don't accept
+	accepted := jobs.AllowedParams()
+	if currentUser.Admin() {
+		accepted = jobs.AllowedParamsAdmin()
+	}
+	jobParams := job.ValidateParams(params.Map(), accepted)
+	err = jobs.Validate(jobParams)
+	if err != nil {
+		return server.NotAuthorizedError(err, "Invalid Job", err.Error())
+	}
+
+	err = job.Update(jobParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to job
+	return server.Redirect(w, r, job.CanonicalURL())
+}
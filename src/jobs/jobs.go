@@ -0,0 +1,237 @@
+// Package jobs represents job listings posted by employers
+package jobs
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/model/file"
+	"github.com/fragmenta/view/helpers"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+)
+
+// Lifetime is the time a job is listed after it is posted or renewed,
+// it is replaced on startup by the value of job_days in config.
+var Lifetime = 30 * 24 * time.Hour
+
+// Remote values valid in the remote field of jobs.
+const (
+	OnSite = 0
+	Hybrid = 1
+	Remote = 2
+)
+
+// Limits on the length of job fields.
+const (
+	MaxName = 100
+	MaxText = 10000
+)
+
+// Currencies are the currencies salaries may be given in.
+var Currencies = []string{"USD", "EUR", "GBP", "CAD", "AUD", "CHF", "SEK", "JPY", "INR"}
+
+// Job is a position offered by an employer, listed until it expires.
+type Job struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	// status.ResourceStatus defines a status field and associated behaviour
+	status.ResourceStatus
+
+	// Name is the title of the position
+	Name string
+	Text string
+
+	Company    string
+	CompanyURL string
+
+	// Location is where the job is based, Remote is one of OnSite, Hybrid or Remote
+	Location string
+	Remote   int64
+
+	// Salary range per year in Currency, zero if not given
+	SalaryMin int64
+	SalaryMax int64
+	Currency  string
+
+	// URL is where applications are made, if empty applications go to the employer by email
+	URL string
+
+	// The employer who posted the job, only they may edit it
+	UserID   int64
+	UserName string
+
+	// ExpiresAt is the time the job stops being listed unless renewed
+	ExpiresAt time.Time
+}
+
+// CanonicalURL is the canonical URL of the job on this site including a slug for seo
+func (j *Job) CanonicalURL() string {
+	return fmt.Sprintf("/jobs/%d-%s", j.ID, file.SanitizeName(j.Name+" "+j.Company))
+}
+
+// RenewURL returns the url used to renew this job.
+func (j *Job) RenewURL() string {
+	return fmt.Sprintf("/jobs/%d/renew", j.ID)
+}
+
+// PayURL returns the url of the page used to pay for this job.
+func (j *Job) PayURL() string {
+	return fmt.Sprintf("/stripe/pay?product=%s&item_id=%d", TableName, j.ID)
+}
+
+// AwaitingPayment returns true if this job will be listed once it is paid for.
+func (j *Job) AwaitingPayment() bool {
+	return j.Status == status.Draft
+}
+
+// Payable returns true if the job may be paid for now, to list it or to renew it.
+func (j *Job) Payable() bool {
+	return j.AwaitingPayment() || (j.Status >= status.Published && j.Renewable())
+}
+
+// Expired returns true if this job is no longer listed.
+func (j *Job) Expired() bool {
+	return !j.ExpiresAt.After(time.Now())
+}
+
+// Renewable returns true if the employer may renew this job now. Jobs may be renewed
+// once less than a quarter of their lifetime is left, or after they have expired.
+func (j *Job) Renewable() bool {
+	return time.Until(j.ExpiresAt) < Lifetime/4
+}
+
+// OwnedBy returns true if this user id owns this job.
+func (j *Job) OwnedBy(uid int64) bool {
+	return uid == j.UserID
+}
+
+// RemoteOptions returns an array of remote values for a select.
+func RemoteOptions() []helpers.Option {
+	return []helpers.Option{
+		{Id: OnSite, Name: "On site"},
+		{Id: Hybrid, Name: "Hybrid"},
+		{Id: Remote, Name: "Remote"},
+	}
+}
+
+// RemoteOptions returns an array of remote values for a select for this job.
+func (j *Job) RemoteOptions() []helpers.Option {
+	return RemoteOptions()
+}
+
+// RemoteDisplay returns a description of where the job is done.
+func (j *Job) RemoteDisplay() string {
+	for _, o := range RemoteOptions() {
+		if o.Id == j.Remote {
+			return o.Name
+		}
+	}
+	return ""
+}
+
+// SalaryDisplay returns the salary range for display, or "" if none was given.
+func (j *Job) SalaryDisplay() string {
+	switch {
+	case j.SalaryMin > 0 && j.SalaryMax > j.SalaryMin:
+		return fmt.Sprintf("%s %s–%s", j.Currency, thousands(j.SalaryMin), thousands(j.SalaryMax))
+	case j.SalaryMin > 0:
+		return fmt.Sprintf("%s %s+", j.Currency, thousands(j.SalaryMin))
+	case j.SalaryMax > 0:
+		return fmt.Sprintf("up to %s %s", j.Currency, thousands(j.SalaryMax))
+	}
+	return ""
+}
+
+// thousands returns n formatted as 85k for round thousands, or in full otherwise.
+func thousands(n int64) string {
+	if n >= 1000 && n%1000 == 0 {
+		return fmt.Sprintf("%dk", n/1000)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// Validate checks the job params, normalising them where possible,
+// and returns an error suitable for showing to the employer.
+func Validate(params map[string]string) error {
+
+	if v, ok := params["name"]; ok {
+		v = strings.TrimSpace(v)
+		if len(v) < 3 || len(v) > MaxName {
+			return fmt.Errorf("Sorry, the job title must be between 3 and %d characters", MaxName)
+		}
+		params["name"] = v
+	}
+
+	if v, ok := params["company"]; ok {
+		v = strings.TrimSpace(v)
+		if v == "" || len(v) > MaxName {
+			return fmt.Errorf("Sorry, the company name must be between 1 and %d characters", MaxName)
+		}
+		params["company"] = v
+	}
+
+	if len(params["text"]) > MaxText {
+		return fmt.Errorf("Sorry, the job description must be less than %d characters", MaxText)
+	}
+
+	for _, key := range []string{"url", "company_url"} {
+		if v, ok := params[key]; ok {
+			v = strings.TrimSpace(v)
+			if v != "" {
+				u, err := url.Parse(v)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("Sorry, links must be full http or https urls")
+				}
+			}
+			params[key] = v
+		}
+	}
+
+	if v, ok := params["remote"]; ok {
+		r, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || r < OnSite || r > Remote {
+			return fmt.Errorf("Sorry, that remote option is not valid")
+		}
+	}
+
+	var salary [2]int64
+	for i, key := range []string{"salary_min", "salary_max"} {
+		if v, ok := params[key]; ok {
+			v = strings.Replace(strings.TrimSpace(v), ",", "", -1)
+			if v == "" {
+				v = "0"
+			}
+			s, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || s < 0 {
+				return fmt.Errorf("Sorry, salaries must be whole numbers")
+			}
+			params[key] = v
+			salary[i] = s
+		}
+	}
+	if salary[0] > 0 && salary[1] > 0 && salary[1] < salary[0] {
+		return fmt.Errorf("Sorry, the maximum salary must be more than the minimum")
+	}
+
+	if v, ok := params["currency"]; ok {
+		v = strings.ToUpper(strings.TrimSpace(v))
+		valid := v == ""
+		for _, c := range Currencies {
+			if c == v {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("Sorry, salaries must be in one of %s", strings.Join(Currencies, ", "))
+		}
+		params["currency"] = v
+	}
+
+	return nil
+}
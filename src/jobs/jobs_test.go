@@ -0,0 +1,228 @@
+// Tests for the jobs package
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+var testName = "foo"
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("jobs: Setup db failed %s", err)
+	}
+}
+
+// Test Create method
+func TestCreateJobs(t *testing.T) {
+	jobParams := map[string]string{
+		"name":       testName,
+		"company":    "Example",
+		"status":     "100",
+		"expires_at": ExpiresParam(),
+	}
+
+	id, err := New().Create(jobParams)
+	if err != nil {
+		t.Fatalf("jobs: Create job failed :%s", err)
+	}
+
+	job, err := Find(id)
+	if err != nil {
+		t.Fatalf("jobs: Create job find failed")
+	}
+
+	if job.Name != testName {
+		t.Fatalf("jobs: Create job name failed expected:%s got:%s", testName, job.Name)
+	}
+
+	if job.Expired() {
+		t.Fatalf("jobs: Create job expired on creation")
+	}
+}
+
+// Test Index (List) method
+func TestListJobs(t *testing.T) {
+
+	// Get all listed jobs (we should have at least one)
+	results, err := FindAll(Listed())
+	if err != nil {
+		t.Fatalf("jobs: List no job found :%s", err)
+	}
+
+	if len(results) < 1 {
+		t.Fatalf("jobs: List no jobs found :%s", err)
+	}
+
+}
+
+// Test expired jobs are no longer listed, and may be renewed
+func TestExpireJobs(t *testing.T) {
+
+	job, err := FindFirst("name=?", testName)
+	if err != nil {
+		t.Fatalf("jobs: Expire no job found :%s", err)
+	}
+
+	if job.Renewable() {
+		t.Fatalf("jobs: new job is renewable")
+	}
+
+	err = job.Update(map[string]string{"expires_at": "2001-01-01 00:00:00"})
+	if err != nil {
+		t.Fatalf("jobs: Expire job failed :%s", err)
+	}
+
+	results, err := FindAll(Listed().Where("id=?", job.ID))
+	if err != nil || len(results) > 0 {
+		t.Fatalf("jobs: expired job still listed :%v", err)
+	}
+
+	job, err = Find(job.ID)
+	if err != nil || !job.Expired() || !job.Renewable() {
+		t.Fatalf("jobs: expired job not renewable :%v", err)
+	}
+
+	err = job.Renew()
+	if err != nil {
+		t.Fatalf("jobs: Renew job failed :%s", err)
+	}
+
+	results, err = FindAll(Listed().Where("id=?", job.ID))
+	if err != nil || len(results) != 1 {
+		t.Fatalf("jobs: renewed job not listed :%v", err)
+	}
+}
+
+// Test Update method
+func TestUpdateJobs(t *testing.T) {
+
+	// Get the last job (created in TestCreateJobs above)
+	job, err := FindFirst("name=?", testName)
+	if err != nil {
+		t.Fatalf("jobs: Update no job found :%s", err)
+	}
+
+	name := "bar"
+	jobParams := map[string]string{"name": name}
+	err = job.Update(jobParams)
+	if err != nil {
+		t.Fatalf("jobs: Update job failed :%s", err)
+	}
+
+	// Fetch the job again from db
+	job, err = Find(job.ID)
+	if err != nil {
+		t.Fatalf("jobs: Update job fetch failed :%s", job.Name)
+	}
+
+	if job.Name != name {
+		t.Fatalf("jobs: Update job failed :%s", job.Name)
+	}
+
+}
+
+// Test Destroy method
+func TestDestroyJobs(t *testing.T) {
+
+	results, err := FindAll(Query())
+	if err != nil || len(results) == 0 {
+		t.Fatalf("jobs: Destroy no job found :%s", err)
+	}
+	job := results[0]
+	count := len(results)
+
+	err = job.Destroy()
+	if err != nil {
+		t.Fatalf("jobs: Destroy job failed :%s", err)
+	}
+
+	// Check new length of jobs returned
+	results, err = FindAll(Query())
+	if err != nil {
+		t.Fatalf("jobs: Destroy error getting results :%s", err)
+	}
+
+	// length should be one less than previous
+	if len(results) != count-1 {
+		t.Fatalf("jobs: Destroy job count wrong :%d", len(results))
+	}
+
+}
+
+// Test job params are validated and normalised
+func TestValidate(t *testing.T) {
+	params := map[string]string{
+		"name":       " Go developer ",
+		"company":    "Example",
+		"url":        "https://example.com/jobs/1",
+		"remote":     "2",
+		"salary_min": "80,000",
+		"salary_max": "",
+		"currency":   "eur",
+	}
+	err := Validate(params)
+	if err != nil {
+		t.Fatalf("jobs: Validate failed :%s", err)
+	}
+	if params["name"] != "Go developer" || params["salary_min"] != "80000" || params["salary_max"] != "0" || params["currency"] != "EUR" {
+		t.Fatalf("jobs: Validate wrong params got:%v", params)
+	}
+
+	invalid := []map[string]string{
+		{"name": "Go"},
+		{"company": ""},
+		{"url": "javascript:alert(1)"},
+		{"company_url": "example.com"},
+		{"remote": "3"},
+		{"salary_min": "lots"},
+		{"salary_min": "90000", "salary_max": "50000"},
+		{"currency": "XYZ"},
+	}
+	for _, p := range invalid {
+		if Validate(p) == nil {
+			t.Errorf("jobs: Validate accepted invalid params %v", p)
+		}
+	}
+}
+
+// Test the display of salaries and expiry
+func TestDisplay(t *testing.T) {
+	job := New()
+	if job.SalaryDisplay() != "" {
+		t.Fatalf("jobs: unexpected salary for new job %s", job.SalaryDisplay())
+	}
+	job.SalaryMin, job.SalaryMax = 80000, 100000
+	if job.SalaryDisplay() != "USD 80k–100k" {
+		t.Fatalf("jobs: wrong salary display got:%s", job.SalaryDisplay())
+	}
+	job.SalaryMax = 0
+	if job.SalaryDisplay() != "USD 80k+" {
+		t.Fatalf("jobs: wrong salary display got:%s", job.SalaryDisplay())
+	}
+
+	job.ExpiresAt = time.Now().Add(Lifetime)
+	if job.Expired() || job.Renewable() {
+		t.Fatalf("jobs: new job expired or renewable")
+	}
+	job.ExpiresAt = time.Now().Add(Lifetime / 8)
+	if job.Expired() || !job.Renewable() {
+		t.Fatalf("jobs: job near expiry not renewable")
+	}
+}
+
+// TestAllowedParams should always return some params
+func TestAllowedParams(t *testing.T) {
+	if len(AllowedParams()) == 0 {
+		t.Fatalf("jobs: no allowed params")
+	}
+	for _, p := range AllowedParams() {
+		if p == "user_id" || p == "expires_at" || p == "status" {
+			t.Fatalf("jobs: employers may set %s", p)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "jobs"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "created_at desc, id desc"
+)
+
+// AllowedParams returns the cols editable by employers
+func AllowedParams() []string {
+	return []string{"name", "text", "company", "company_url", "location", "remote", "salary_min", "salary_max", "currency", "url"}
+}
+
+// AllowedParamsAdmin returns the cols editable by admins
+func AllowedParamsAdmin() []string {
+	return append(AllowedParams(), "status", "expires_at", "user_id", "user_name")
+}
+
+// NewWithColumns creates a new job instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Job {
+
+	job := New()
+	job.ID = resource.ValidateInt(cols["id"])
+	job.CreatedAt = resource.ValidateTime(cols["created_at"])
+	job.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	job.Status = resource.ValidateInt(cols["status"])
+	job.Name = resource.ValidateString(cols["name"])
+	job.Text = resource.ValidateString(cols["text"])
+	job.Company = resource.ValidateString(cols["company"])
+	job.CompanyURL = resource.ValidateString(cols["company_url"])
+	job.Location = resource.ValidateString(cols["location"])
+	job.Remote = resource.ValidateInt(cols["remote"])
+	job.SalaryMin = resource.ValidateInt(cols["salary_min"])
+	job.SalaryMax = resource.ValidateInt(cols["salary_max"])
+	job.Currency = resource.ValidateString(cols["currency"])
+	job.URL = resource.ValidateString(cols["url"])
+	job.UserID = resource.ValidateInt(cols["user_id"])
+	job.UserName = resource.ValidateString(cols["user_name"])
+	job.ExpiresAt = resource.ValidateTime(cols["expires_at"])
+
+	return job
+}
+
+// New creates and initialises a new job instance.
+func New() *Job {
+	job := &Job{}
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = time.Now()
+	job.TableName = TableName
+	job.KeyName = KeyName
+	job.Status = status.Published
+	job.Currency = "USD"
+	return job
+}
+
+// FindFirst fetches a single job record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Job, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single job record from the database by id.
+func Find(id int64) (*Job, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all job records matching this query from the database.
+func FindAll(q *query.Query) ([]*Job, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of jobs constructed from the results
+	var jobs []*Job
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		jobs = append(jobs, p)
+	}
+
+	return jobs, nil
+}
+
+// Query returns a new query for jobs with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for jobs with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
+
+// Published returns a query for all jobs with status >= published.
+func Published() *query.Query {
+	return Query().Where("status>=?", status.Published)
+}
+
+// Listed returns a query for published jobs which have not expired.
+func Listed() *query.Query {
+	return Published().Where("expires_at > ?", query.TimeString(time.Now().UTC()))
+}
+
+// Renew lists the job for another Lifetime from now.
+func (j *Job) Renew() error {
+	expires := time.Now().UTC().Add(Lifetime)
+	return j.Update(map[string]string{"expires_at": query.TimeString(expires)})
+}
+
+// Fulfil lists the job for another Lifetime once it has been paid for, publishing new jobs
+// and extending jobs which are still listed from the time they would have expired.
+func (j *Job) Fulfil() error {
+	start := time.Now().UTC()
+	if j.Status >= status.Published && j.ExpiresAt.After(start) {
+		start = j.ExpiresAt
+	}
+	params := map[string]string{
+		"status":     fmt.Sprintf("%d", status.Published),
+		"expires_at": query.TimeString(start.Add(Lifetime)),
+	}
+	return j.Update(params)
+}
+
+// ExpiresParam returns the expires_at param for a job posted now.
+func ExpiresParam() string {
+	return query.TimeString(time.Now().UTC().Add(Lifetime))
+}
@@ -0,0 +1,230 @@
+// Package activitypub implements the parts of ActivityPub we use to publish stories to the fediverse:
+// the JSON types of actors, activities and collections, WebFinger, HTTP signatures,
+// and a client which delivers signed activities to remote inboxes.
+package activitypub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of ActivityPub documents.
+const ContentType = "application/activity+json"
+
+// LDContentType is the JSON-LD media type remote servers may also ask for.
+const LDContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+
+// JRDContentType is the media type of WebFinger responses.
+const JRDContentType = "application/jrd+json"
+
+// Contexts of the documents we publish.
+const (
+	Context         = "https://www.w3.org/ns/activitystreams"
+	SecurityContext = "https://w3id.org/security/v1"
+)
+
+// Public is the special collection addressing an activity to everyone.
+const Public = "https://www.w3.org/ns/activitystreams#Public"
+
+// Types of activity and object.
+const (
+	TypeService           = "Service"
+	TypeCreate            = "Create"
+	TypeFollow            = "Follow"
+	TypeAccept            = "Accept"
+	TypeUndo              = "Undo"
+	TypeNote              = "Note"
+	TypeArticle           = "Article"
+	TypeHashtag           = "Hashtag"
+	TypeOrderedCollection = "OrderedCollection"
+)
+
+// Accepts returns true if this Accept header asks for an ActivityPub document.
+func Accepts(accept string) bool {
+	return strings.Contains(accept, ContentType) || strings.Contains(accept, "application/ld+json")
+}
+
+// Actor is a person or service which sends and receives activities.
+type Actor struct {
+	Context           interface{} `json:"@context,omitempty"`
+	ID                string      `json:"id"`
+	Type              string      `json:"type"`
+	PreferredUsername string      `json:"preferredUsername,omitempty"`
+	Name              string      `json:"name,omitempty"`
+	Summary           string      `json:"summary,omitempty"`
+	URL               string      `json:"url,omitempty"`
+	Inbox             string      `json:"inbox"`
+	Outbox            string      `json:"outbox,omitempty"`
+	Followers         string      `json:"followers,omitempty"`
+	Endpoints         *Endpoints  `json:"endpoints,omitempty"`
+	Icon              *Image      `json:"icon,omitempty"`
+	PublicKey         PublicKey   `json:"publicKey"`
+}
+
+// SharedInbox returns the shared inbox of this actor if it has one, or its own inbox.
+func (a *Actor) SharedInbox() string {
+	if a.Endpoints != nil && a.Endpoints.SharedInbox != "" {
+		return a.Endpoints.SharedInbox
+	}
+	return a.Inbox
+}
+
+// Endpoints holds the shared inbox of an actor, which receives public activities for everyone on a server.
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+// Image is an icon of an actor.
+type Image struct {
+	Type      string `json:"type"`
+	MediaType string `json:"mediaType,omitempty"`
+	URL       string `json:"url"`
+}
+
+// PublicKey is the key used to verify the signatures of an actor.
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// Activity is an action by an actor on an object. Object is the id of the object,
+// or the object itself, which for an Undo is the activity undone.
+type Activity struct {
+	Context   interface{} `json:"@context,omitempty"`
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Actor     string      `json:"actor"`
+	Object    interface{} `json:"object"`
+	Published *time.Time  `json:"published,omitempty"`
+	To        []string    `json:"to,omitempty"`
+	Cc        []string    `json:"cc,omitempty"`
+}
+
+// ObjectID returns the id of the object of this activity, whether it is embedded or not.
+func (a *Activity) ObjectID() string {
+	switch o := a.Object.(type) {
+	case string:
+		return o
+	case map[string]interface{}:
+		id, _ := o["id"].(string)
+		return id
+	case *Activity:
+		return o.ID
+	case *Object:
+		return o.ID
+	}
+	return ""
+}
+
+// ObjectActivity returns the activity embedded as the object of this one, such as the Follow undone by an Undo.
+func (a *Activity) ObjectActivity() (*Activity, error) {
+	switch o := a.Object.(type) {
+	case *Activity:
+		return o, nil
+	case map[string]interface{}:
+		data, err := json.Marshal(o)
+		if err != nil {
+			return nil, err
+		}
+		activity := &Activity{}
+		err = json.Unmarshal(data, activity)
+		if err != nil {
+			return nil, err
+		}
+		return activity, nil
+	}
+	return nil, errors.New("activitypub: object is not an embedded activity")
+}
+
+// ParseActivity decodes an activity received in an inbox, which must have an id, type and actor.
+func ParseActivity(data []byte) (*Activity, error) {
+	activity := &Activity{}
+	err := json.Unmarshal(data, activity)
+	if err != nil {
+		return nil, err
+	}
+	if activity.ID == "" || activity.Type == "" || activity.Actor == "" {
+		return nil, errors.New("activitypub: invalid activity")
+	}
+	return activity, nil
+}
+
+// Object is a Note or Article published by an actor.
+type Object struct {
+	Context      interface{} `json:"@context,omitempty"`
+	ID           string      `json:"id"`
+	Type         string      `json:"type"`
+	AttributedTo string      `json:"attributedTo"`
+	Name         string      `json:"name,omitempty"`
+	Summary      string      `json:"summary,omitempty"`
+	Content      string      `json:"content"`
+	URL          string      `json:"url,omitempty"`
+	Published    time.Time   `json:"published"`
+	Updated      *time.Time  `json:"updated,omitempty"`
+	To           []string    `json:"to,omitempty"`
+	Cc           []string    `json:"cc,omitempty"`
+	Tag          []Tag       `json:"tag,omitempty"`
+}
+
+// Tag is a hashtag on an object.
+type Tag struct {
+	Type string `json:"type"`
+	Href string `json:"href,omitempty"`
+	Name string `json:"name"`
+}
+
+// OrderedCollection is a list of items, such as an outbox, newest first.
+// Items may be omitted to publish only the total, as for a list of followers.
+type OrderedCollection struct {
+	Context      interface{}   `json:"@context,omitempty"`
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	TotalItems   int64         `json:"totalItems"`
+	OrderedItems []interface{} `json:"orderedItems,omitempty"`
+}
+
+// WebFinger is the JSON resource descriptor returned by WebFinger for an account.
+type WebFinger struct {
+	Subject string   `json:"subject"`
+	Aliases []string `json:"aliases,omitempty"`
+	Links   []Link   `json:"links"`
+}
+
+// Link is a link of a WebFinger resource.
+type Link struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// Account returns the acct: resource for a user name on this host.
+func Account(name, host string) string {
+	return fmt.Sprintf("acct:%s@%s", name, host)
+}
+
+// ParseAccount returns the user name and host of an acct: resource, which may omit the scheme or start with @.
+func ParseAccount(resource string) (name, host string, err error) {
+	resource = strings.TrimPrefix(strings.TrimPrefix(resource, "acct:"), "@")
+	parts := strings.Split(resource, "@")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("activitypub: invalid account %q", resource)
+	}
+	return parts[0], strings.ToLower(parts[1]), nil
+}
+
+// NewWebFinger returns the WebFinger response for an account whose actor is at actorID,
+// with the profile page at profileURL.
+func NewWebFinger(account, actorID, profileURL string) *WebFinger {
+	return &WebFinger{
+		Subject: account,
+		Aliases: []string{actorID},
+		Links: []Link{
+			{Rel: "self", Type: ContentType, Href: actorID},
+			{Rel: "http://webfinger.net/rel/profile-page", Type: "text/html", Href: profileURL},
+		},
+	}
+}
@@ -0,0 +1,99 @@
+// Tests for the activitypub package
+package activitypub
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseAccount(t *testing.T) {
+	tests := []struct {
+		resource string
+		name     string
+		host     string
+	}{
+		{"acct:news@golangnews.com", "news", "golangnews.com"},
+		{"@news@GolangNews.com", "news", "golangnews.com"},
+		{"news@golangnews.com", "news", "golangnews.com"},
+		{"acct:golangnews.com", "", ""},
+		{"acct:@golangnews.com", "", ""},
+		{"https://golangnews.com/actor", "", ""},
+	}
+
+	for _, tt := range tests {
+		name, host, err := ParseAccount(tt.resource)
+		if tt.name == "" {
+			if err == nil {
+				t.Fatalf("activitypub: expected error for %s", tt.resource)
+			}
+			continue
+		}
+		if err != nil || name != tt.name || host != tt.host {
+			t.Fatalf("activitypub: wrong account for %s got:%s %s %v", tt.resource, name, host, err)
+		}
+	}
+
+	if Account("news", "golangnews.com") != "acct:news@golangnews.com" {
+		t.Fatalf("activitypub: wrong account")
+	}
+}
+
+func TestParseActivity(t *testing.T) {
+	data := []byte(`{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id": "https://remote.example/users/alice#follows/1/undo",
+		"type": "Undo",
+		"actor": "https://remote.example/users/alice",
+		"object": {
+			"id": "https://remote.example/users/alice#follows/1",
+			"type": "Follow",
+			"actor": "https://remote.example/users/alice",
+			"object": "https://golangnews.com/actor"
+		}
+	}`)
+
+	activity, err := ParseActivity(data)
+	if err != nil {
+		t.Fatalf("activitypub: error parsing activity %s", err)
+	}
+	if activity.Type != TypeUndo || activity.ObjectID() != "https://remote.example/users/alice#follows/1" {
+		t.Fatalf("activitypub: wrong activity got:%v", activity)
+	}
+
+	follow, err := activity.ObjectActivity()
+	if err != nil {
+		t.Fatalf("activitypub: error parsing object activity %s", err)
+	}
+	if follow.Type != TypeFollow || follow.ObjectID() != "https://golangnews.com/actor" {
+		t.Fatalf("activitypub: wrong object activity got:%v", follow)
+	}
+
+	// Objects given by id are not activities
+	_, err = follow.ObjectActivity()
+	if err == nil {
+		t.Fatalf("activitypub: expected error for object id")
+	}
+
+	// Activities must have an actor
+	_, err = ParseActivity([]byte(`{"id":"https://remote.example/1","type":"Follow"}`))
+	if err == nil {
+		t.Fatalf("activitypub: expected error for activity without actor")
+	}
+}
+
+func TestWebFinger(t *testing.T) {
+	wf := NewWebFinger("acct:news@golangnews.com", "https://golangnews.com/actor", "https://golangnews.com/")
+	data, err := json.Marshal(wf)
+	if err != nil {
+		t.Fatalf("activitypub: error encoding webfinger %s", err)
+	}
+
+	expected := `{"subject":"acct:news@golangnews.com","aliases":["https://golangnews.com/actor"],"links":[{"rel":"self","type":"application/activity+json","href":"https://golangnews.com/actor"},{"rel":"http://webfinger.net/rel/profile-page","type":"text/html","href":"https://golangnews.com/"}]}`
+	if string(data) != expected {
+		t.Fatalf("activitypub: wrong webfinger expected:%s got:%s", expected, data)
+	}
+
+	if !Accepts(LDContentType) || !Accepts("application/activity+json, */*") || Accepts("text/html") {
+		t.Fatalf("activitypub: wrong accepts")
+	}
+}
@@ -0,0 +1,253 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kennygrant/gohackernews/src/lib/linkmeta"
+)
+
+// maxResponseBytes limits the size of documents we read from remote servers.
+const maxResponseBytes = 1 << 20
+
+// timeout is the time allowed for requests to remote servers.
+const timeout = 10 * time.Second
+
+// maxRedirects is the maximum number of redirects followed.
+const maxRedirects = 3
+
+// ErrURL is returned for urls which may not be contacted, because they are not public https urls.
+var ErrURL = errors.New("activitypub: url not allowed")
+
+// StatusError is returned when a remote server responds to a request with an error status.
+type StatusError struct {
+	URL  string
+	Code int
+}
+
+// Error returns a description of the error.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("activitypub: %s responded %d", e.URL, e.Code)
+}
+
+// Temporary returns true if the request may succeed if tried again later.
+func (e *StatusError) Temporary() bool {
+	return e.Code >= 500 || e.Code == http.StatusTooManyRequests || e.Code == http.StatusRequestTimeout
+}
+
+// Client signs the requests it makes to remote servers with the key of our actor.
+type Client struct {
+	// KeyID is the id of the public key in our actor document, e.g. https://golangnews.com/actor#main-key
+	KeyID string
+
+	// Key is the private key requests are signed with
+	Key *rsa.PrivateKey
+
+	// UserAgent identifies the site in requests
+	UserAgent string
+
+	// HTTP is the client used for requests, by default it refuses to dial private addresses
+	HTTP *http.Client
+
+	// AllowURL reports whether a url may be requested or stored as an inbox, if nil PublicURL is used
+	AllowURL func(string) bool
+}
+
+// Default is the client used by the app, set up from config on startup.
+var Default = New("", nil)
+
+// New returns a new client which signs requests with this key.
+func New(keyID string, key *rsa.PrivateKey) *Client {
+	return &Client{
+		KeyID:     keyID,
+		Key:       key,
+		UserAgent: "gohackernews",
+		HTTP:      publicClient(),
+	}
+}
+
+// publicClient returns an http client which contacts only public addresses, checked when they are dialled,
+// as the urls requested are chosen by remote servers.
+func publicClient() *http.Client {
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           linkmeta.Dialer(timeout, linkmeta.PublicAddr).DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("activitypub: stopped after %d redirects", maxRedirects)
+			}
+			if !PublicURL(req.URL.String()) {
+				return ErrURL
+			}
+			return nil
+		},
+	}
+}
+
+// PublicURL returns true if u is an https url for a host which is not local or a private address.
+// Names are checked again when they are resolved, as they may resolve to private addresses.
+func PublicURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil {
+		return false
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return linkmeta.PublicAddr(ip)
+	}
+	return true
+}
+
+// Allowed returns true if this url may be requested, or stored as the inbox of a follower.
+func (c *Client) Allowed(u string) bool {
+	if c.AllowURL != nil {
+		return c.AllowURL(u)
+	}
+	return PublicURL(u)
+}
+
+// Enabled returns true if the client has a key to sign requests with.
+func (c *Client) Enabled() bool {
+	return c.KeyID != "" && c.Key != nil
+}
+
+// Deliver posts the activity to this inbox, signed.
+func (c *Client) Deliver(inbox string, activity interface{}) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	return c.Post(inbox, body)
+}
+
+// Post posts the activity encoded in body to this inbox, signed.
+func (c *Client) Post(inbox string, body []byte) error {
+	r, err := c.newRequest(http.MethodPost, inbox, body)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", ContentType)
+
+	resp, err := c.HTTP.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{URL: inbox, Code: resp.StatusCode}
+	}
+	return nil
+}
+
+// Get fetches the document at this url, signed for servers which require it, and decodes it into v.
+func (c *Client) Get(u string, v interface{}) error {
+	r, err := c.newRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Accept", ContentType+", "+LDContentType)
+
+	resp, err := c.HTTP.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{URL: u, Code: resp.StatusCode}
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(v)
+}
+
+// FetchActor fetches the actor document at this id.
+func (c *Client) FetchActor(id string) (*Actor, error) {
+	actor := &Actor{}
+	err := c.Get(id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if actor.ID != id || actor.Inbox == "" {
+		return nil, fmt.Errorf("activitypub: invalid actor at %s", id)
+	}
+	return actor, nil
+}
+
+// VerifyRequest checks the signature of a request received in an inbox, using the key of the actor who signed it,
+// which is fetched from their server. It returns the actor who signed the request.
+func (c *Client) VerifyRequest(r *http.Request, body []byte) (*Actor, error) {
+	s, err := ParseSignature(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key id is the id of the actor with a fragment naming the key
+	keyURL, err := url.Parse(s.KeyID)
+	if err != nil {
+		return nil, ErrSignature
+	}
+	keyURL.Fragment = ""
+
+	actor, err := c.FetchActor(keyURL.String())
+	if err != nil {
+		return nil, err
+	}
+	if actor.PublicKey.ID != s.KeyID || actor.PublicKey.Owner != actor.ID {
+		return nil, ErrSignature
+	}
+
+	key, err := ParsePublicKey([]byte(actor.PublicKey.PublicKeyPem))
+	if err != nil {
+		return nil, err
+	}
+
+	err = Verify(r, body, key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return actor, nil
+}
+
+// newRequest returns a request for this url, signed if the client has a key.
+// Only urls which are Allowed are requested.
+func (c *Client) newRequest(method, u string, body []byte) (*http.Request, error) {
+	if !c.Allowed(u) {
+		return nil, fmt.Errorf("%w :%s", ErrURL, u)
+	}
+
+	r, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("User-Agent", c.UserAgent)
+
+	if c.Enabled() {
+		err = Sign(r, c.KeyID, c.Key, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
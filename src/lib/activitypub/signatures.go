@@ -0,0 +1,224 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// This file contains HTTP signatures (draft-cavage-http-signatures) using rsa-sha256,
+// which servers such as Mastodon require on every activity delivered to an inbox.
+
+// Tolerance is the maximum difference between the Date of a signed request and now, to limit replays.
+const Tolerance = time.Hour
+
+// signedHeaders are the headers we sign, and the minimum we accept on a signed POST.
+var signedHeaders = []string{"(request-target)", "host", "date", "digest"}
+
+// ErrSignature is returned for requests without a valid signature.
+var ErrSignature = errors.New("activitypub: invalid http signature")
+
+// Digest returns the Digest header for this body.
+func Digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Sign sets the Date and Digest headers of this request and signs them with key along with
+// the target and host, setting the Signature header. keyID is the id of the public key in the actor document.
+func Sign(r *http.Request, keyID string, key *rsa.PrivateKey, body []byte) error {
+	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	r.Header.Set("Digest", Digest(body))
+
+	hash := sha256.Sum256([]byte(signingString(r, signedHeaders)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(signedHeaders, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// Signature holds the params of a Signature header.
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Signature []byte
+}
+
+// ParseSignature returns the params of the Signature header of this request.
+func ParseSignature(r *http.Request) (*Signature, error) {
+	header := r.Header.Get("Signature")
+	if header == "" {
+		return nil, ErrSignature
+	}
+
+	s := &Signature{Headers: []string{"date"}}
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		v := strings.Trim(kv[1], `"`)
+		switch kv[0] {
+		case "keyId":
+			s.KeyID = v
+		case "algorithm":
+			s.Algorithm = v
+		case "headers":
+			s.Headers = strings.Fields(strings.ToLower(v))
+		case "signature":
+			sig, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, ErrSignature
+			}
+			s.Signature = sig
+		}
+	}
+
+	if s.KeyID == "" || len(s.Signature) == 0 {
+		return nil, ErrSignature
+	}
+	switch s.Algorithm {
+	case "", "rsa-sha256", "hs2019":
+	default:
+		return nil, fmt.Errorf("activitypub: unsupported signature algorithm %s", s.Algorithm)
+	}
+	return s, nil
+}
+
+// Verify checks the request was signed with key no more than Tolerance from now,
+// and that the signature covers the request target, host, date and digest of this body.
+func Verify(r *http.Request, body []byte, key *rsa.PublicKey, now time.Time) error {
+	s, err := ParseSignature(r)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range signedHeaders {
+		if h == "digest" && r.Method == http.MethodGet {
+			continue
+		}
+		if !contains(s.Headers, h) {
+			return ErrSignature
+		}
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return ErrSignature
+	}
+	age := now.Sub(date)
+	if age > Tolerance || age < -Tolerance {
+		return ErrSignature
+	}
+
+	if contains(s.Headers, "digest") && r.Header.Get("Digest") != Digest(body) {
+		return ErrSignature
+	}
+
+	hash := sha256.Sum256([]byte(signingString(r, s.Headers)))
+	if rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], s.Signature) != nil {
+		return ErrSignature
+	}
+	return nil
+}
+
+// signingString returns the string signed for these headers of the request.
+func signingString(r *http.Request, headers []string) string {
+	var b bytes.Buffer
+	for i, h := range headers {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch h {
+		case "(request-target)":
+			fmt.Fprintf(&b, "%s: %s %s", h, strings.ToLower(r.Method), r.URL.RequestURI())
+		case "host":
+			host := r.Host
+			if host == "" {
+				host = r.URL.Host
+			}
+			fmt.Fprintf(&b, "%s: %s", h, host)
+		default:
+			fmt.Fprintf(&b, "%s: %s", h, strings.Join(r.Header.Values(h), ", "))
+		}
+	}
+	return b.String()
+}
+
+// contains returns true if list contains s.
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePrivateKey returns the RSA private key in this PEM data, in PKCS #1 or PKCS #8 form.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("activitypub: no pem data for private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("activitypub: private key is not an rsa key")
+	}
+	return rsaKey, nil
+}
+
+// ParsePublicKey returns the RSA public key in this PEM data, as published in actor documents.
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("activitypub: no pem data for public key")
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("activitypub: public key is not an rsa key")
+	}
+	return rsaKey, nil
+}
+
+// PublicKeyPEM returns the PEM encoding of this public key, for the actor document.
+func PublicKeyPEM(key *rsa.PublicKey) (string, error) {
+	data, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: data})), nil
+}
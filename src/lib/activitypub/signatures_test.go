@@ -0,0 +1,98 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testKey is used to sign requests in tests.
+var testKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+// testRequest returns a request to the inbox signed with key, as a server would receive it.
+func testRequest(t *testing.T, key *rsa.PrivateKey, body []byte) *http.Request {
+	r, err := http.NewRequest("POST", "https://golangnews.com/actor/inbox", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("activitypub: error building request %s", err)
+	}
+	err = Sign(r, "https://remote.example/users/alice#main-key", key, body)
+	if err != nil {
+		t.Fatalf("activitypub: error signing request %s", err)
+	}
+
+	received := httptest.NewRequest("POST", "/actor/inbox", bytes.NewReader(body))
+	received.Host = "golangnews.com"
+	received.Header = r.Header
+	return received
+}
+
+func TestSignatures(t *testing.T) {
+	body := []byte(`{"type":"Follow"}`)
+
+	r := testRequest(t, testKey, body)
+	err := Verify(r, body, &testKey.PublicKey, time.Now())
+	if err != nil {
+		t.Fatalf("activitypub: error verifying signature %s", err)
+	}
+
+	s, err := ParseSignature(r)
+	if err != nil || s.KeyID != "https://remote.example/users/alice#main-key" || s.Algorithm != "rsa-sha256" || len(s.Headers) != 4 {
+		t.Fatalf("activitypub: wrong signature params got:%v %v", s, err)
+	}
+
+	// A changed body fails the digest
+	err = Verify(r, []byte(`{"type":"Undo"}`), &testKey.PublicKey, time.Now())
+	if err != ErrSignature {
+		t.Fatalf("activitypub: verified changed body")
+	}
+
+	// Another host or path fails the signature
+	r = testRequest(t, testKey, body)
+	r.Host = "other.example"
+	if Verify(r, body, &testKey.PublicKey, time.Now()) != ErrSignature {
+		t.Fatalf("activitypub: verified request for another host")
+	}
+
+	// Old signatures are rejected
+	r = testRequest(t, testKey, body)
+	if Verify(r, body, &testKey.PublicKey, time.Now().Add(2*Tolerance)) != ErrSignature {
+		t.Fatalf("activitypub: verified old signature")
+	}
+
+	// Another key fails the signature
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("activitypub: error generating key %s", err)
+	}
+	r = testRequest(t, otherKey, body)
+	if Verify(r, body, &testKey.PublicKey, time.Now()) != ErrSignature {
+		t.Fatalf("activitypub: verified signature with wrong key")
+	}
+
+	// Unsigned requests are rejected
+	r.Header.Del("Signature")
+	if Verify(r, body, &testKey.PublicKey, time.Now()) != ErrSignature {
+		t.Fatalf("activitypub: verified unsigned request")
+	}
+}
+
+func TestKeys(t *testing.T) {
+	data, err := PublicKeyPEM(&testKey.PublicKey)
+	if err != nil {
+		t.Fatalf("activitypub: error encoding public key %s", err)
+	}
+
+	key, err := ParsePublicKey([]byte(data))
+	if err != nil || !key.Equal(&testKey.PublicKey) {
+		t.Fatalf("activitypub: error parsing public key %s", err)
+	}
+
+	_, err = ParsePrivateKey([]byte(data))
+	if err == nil {
+		t.Fatalf("activitypub: parsed public key as private key")
+	}
+}
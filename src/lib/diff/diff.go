@@ -0,0 +1,147 @@
+// Package diff computes word level differences between two versions of a text,
+// it is used to show the revision history of stories and comments.
+package diff
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"unicode"
+)
+
+// Op is the type of change in a diff.
+type Op int
+
+// Operations in a diff
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// MaxWords is the largest number of words compared word by word,
+// longer texts are shown as a single deletion and insertion.
+const MaxWords = 4000
+
+// Change is a run of text which is equal in both versions, or inserted or deleted.
+type Change struct {
+	Op   Op
+	Text string
+}
+
+// Words returns the changes required to turn a into b, comparing words.
+// Whitespace is kept with the preceding word, so that joining the text
+// of the Equal and Delete changes gives a, and Equal and Insert gives b.
+func Words(a, b string) []Change {
+	x, y := split(a), split(b)
+
+	// Strip the common prefix and suffix, which is usually most of the text
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var changes []Change
+	changes = appendChange(changes, Equal, x[:prefix]...)
+	changes = append(changes, lcs(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	changes = appendChange(changes, Equal, x[len(x)-suffix:]...)
+	return changes
+}
+
+// lcs returns the changes between x and y using their longest common subsequence.
+func lcs(x, y []string) []Change {
+	var changes []Change
+
+	if len(x) == 0 || len(y) == 0 || len(x)+len(y) > MaxWords {
+		changes = appendChange(changes, Delete, x...)
+		return appendChange(changes, Insert, y...)
+	}
+
+	// table[i][j] is the length of the lcs of x[i:] and y[j:]
+	table := make([][]int, len(x)+1)
+	for i := range table {
+		table[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			changes = appendChange(changes, Equal, x[i])
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			changes = appendChange(changes, Delete, x[i])
+			i++
+		default:
+			changes = appendChange(changes, Insert, y[j])
+			j++
+		}
+	}
+	changes = appendChange(changes, Delete, x[i:]...)
+	return appendChange(changes, Insert, y[j:]...)
+}
+
+// appendChange adds the words to changes, joining them to the last change if it has the same op.
+func appendChange(changes []Change, op Op, words ...string) []Change {
+	for _, w := range words {
+		if len(changes) > 0 && changes[len(changes)-1].Op == op {
+			changes[len(changes)-1].Text += w
+			continue
+		}
+		changes = append(changes, Change{Op: op, Text: w})
+	}
+	return changes
+}
+
+// split splits s into words, each followed by any whitespace after it.
+func split(s string) []string {
+	var words []string
+	start := 0
+	space := false
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
+		} else if space {
+			words = append(words, s[start:i])
+			start = i
+			space = false
+		}
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+// HTML returns the word diff of a and b as escaped html,
+// with deletions in del tags and insertions in ins tags.
+func HTML(a, b string) template.HTML {
+	var buf bytes.Buffer
+	for _, c := range Words(a, b) {
+		switch c.Op {
+		case Insert:
+			buf.WriteString("<ins>" + html.EscapeString(c.Text) + "</ins>")
+		case Delete:
+			buf.WriteString("<del>" + html.EscapeString(c.Text) + "</del>")
+		default:
+			buf.WriteString(html.EscapeString(c.Text))
+		}
+	}
+	return template.HTML(buf.String())
+}
@@ -0,0 +1,74 @@
+// Tests for the diff package
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+var wordTests = []struct {
+	a, b string
+	out  []Change
+}{
+	{
+		a:   "the quick fox",
+		b:   "the quick fox",
+		out: []Change{{Equal, "the quick fox"}},
+	},
+	{
+		a:   "the quick fox",
+		b:   "the slow fox",
+		out: []Change{{Equal, "the "}, {Delete, "quick "}, {Insert, "slow "}, {Equal, "fox"}},
+	},
+	{
+		a:   "",
+		b:   "new text",
+		out: []Change{{Insert, "new text"}},
+	},
+	{
+		a:   "one two three four",
+		b:   "one three five four",
+		out: []Change{{Equal, "one "}, {Delete, "two "}, {Equal, "three "}, {Insert, "five "}, {Equal, "four"}},
+	},
+}
+
+// TestWords tests word diffs between two strings
+func TestWords(t *testing.T) {
+	for _, test := range wordTests {
+		got := Words(test.a, test.b)
+		if len(got) != len(test.out) {
+			t.Fatalf("diff: wrong changes for %q %q got:%v", test.a, test.b, got)
+		}
+		for i := range got {
+			if got[i] != test.out[i] {
+				t.Fatalf("diff: wrong change for %q %q wanted:%v got:%v", test.a, test.b, test.out[i], got[i])
+			}
+		}
+	}
+}
+
+// TestRebuild tests the changes rebuild both versions of the text
+func TestRebuild(t *testing.T) {
+	a := "Go is an open source programming language\nthat makes it easy to build simple software."
+	b := "Go is an open source language that makes it  easy to build simple, reliable and efficient software.\n"
+	var oldText, newText string
+	for _, c := range Words(a, b) {
+		if c.Op != Insert {
+			oldText += c.Text
+		}
+		if c.Op != Delete {
+			newText += c.Text
+		}
+	}
+	if oldText != a || newText != b {
+		t.Fatalf("diff: changes do not rebuild text\n\told:%q\n\tnew:%q", oldText, newText)
+	}
+}
+
+// TestHTML tests html output is escaped
+func TestHTML(t *testing.T) {
+	got := string(HTML("a <b>", "a <i>"))
+	if strings.Contains(got, "<b>") || !strings.Contains(got, "<del>&lt;b&gt;</del>") || !strings.Contains(got, "<ins>&lt;i&gt;</ins>") {
+		t.Fatalf("diff: html not escaped got:%s", got)
+	}
+}
@@ -0,0 +1,193 @@
+// Package feed renders lists of items as RSS 2.0, Atom 1.0 or JSON Feed 1.1,
+// choosing the format from the extension of the request path or the Accept header,
+// and answering conditional requests with 304 Not Modified.
+package feed
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Formats of feed, named by the extensions which select them.
+const (
+	RSS  = ".xml"
+	Atom = ".atom"
+	JSON = ".json"
+)
+
+// contentTypes are the media types of each format, in the order preferred.
+var contentTypes = []struct {
+	format    string
+	mediaType string
+}{
+	{Atom, "application/atom+xml"},
+	{JSON, "application/feed+json"},
+	{RSS, "application/rss+xml"},
+}
+
+// Feed is a list of items, with urls relative to Root.
+type Feed struct {
+	// Root is the absolute url of the site, e.g. https://golangnews.com
+	Root string
+
+	// Title and Description describe the feed
+	Title       string
+	Description string
+
+	// Path is the path of the html page this feed describes, e.g. /stories
+	Path string
+
+	// FeedPath is the path of this feed, e.g. /stories.atom?q=go, set by Render from the request
+	FeedPath string
+
+	// Updated is the time at which the items were last changed
+	Updated time.Time
+
+	Items []*Item
+}
+
+// Item is a single entry in a feed. Paths without a scheme are made absolute from the Root of the feed.
+type Item struct {
+	// ID is the permanent path of the item on this site, also used as its unique id
+	ID string
+
+	Title string
+
+	// URL is the path of the page about the item, and ExternalURL any link it is about
+	URL         string
+	ExternalURL string
+
+	// Text is the plain text summary of the item, HTML its content if any
+	Text string
+	HTML string
+
+	AuthorName string
+	AuthorPath string
+
+	// CommentsPath is the path of the discussion of the item, if any
+	CommentsPath string
+
+	Tags []string
+
+	Published time.Time
+	Updated   time.Time
+}
+
+// Format returns the feed format requested, chosen by the extension of the request path,
+// or failing that by the Accept header, or an empty string if no feed is requested.
+func Format(r *http.Request) string {
+	switch path.Ext(r.URL.Path) {
+	case RSS:
+		return RSS
+	case Atom:
+		return Atom
+	case JSON:
+		return JSON
+	}
+
+	accept := r.Header.Get("Accept")
+	for _, t := range contentTypes {
+		if strings.Contains(accept, t.mediaType) {
+			return t.format
+		}
+	}
+	return ""
+}
+
+// Negotiate returns the feed format requested as Format does, and marks the response as varying
+// by the Accept header, whether a feed or the html page at the same url is sent, so that shared
+// caches do not serve one in place of the other.
+func Negotiate(w http.ResponseWriter, r *http.Request) string {
+	w.Header().Set("Vary", "Accept")
+	return Format(r)
+}
+
+// Path returns the path of a feed in this format for the request given, keeping the query but not the page.
+func Path(r *http.Request, format string) string {
+	p := strings.TrimSuffix(r.URL.Path, path.Ext(r.URL.Path))
+	if p == "" || p == "/" {
+		p = "/index"
+	}
+
+	q := r.URL.Query()
+	q.Del("page")
+	if len(q) > 0 {
+		return fmt.Sprintf("%s%s?%s", p, format, q.Encode())
+	}
+	return p + format
+}
+
+// ContentType returns the content type of this format.
+func ContentType(format string) string {
+	for _, t := range contentTypes {
+		if t.format == format {
+			return t.mediaType + "; charset=utf-8"
+		}
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// URL returns the absolute url for this path, which is returned unchanged if already absolute.
+func (f *Feed) URL(p string) string {
+	if p == "" || strings.Contains(p, "://") {
+		return p
+	}
+	return strings.TrimSuffix(f.Root, "/") + p
+}
+
+// ETag returns an entity tag for this feed in this format, which changes when the items do.
+func (f *Feed) ETag(format string) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s%s%s%d", format, f.FeedPath, f.Title, f.Updated.Unix())
+	for _, item := range f.Items {
+		fmt.Fprintf(h, "%s%d", item.ID, item.Updated.Unix())
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum64())
+}
+
+// NotModified returns true if the request is conditional on this feed in this format,
+// and the version the client has is current.
+func (f *Feed) NotModified(r *http.Request, format string) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		etag := f.ETag(format)
+		for _, m := range strings.Split(match, ",") {
+			m = strings.TrimPrefix(strings.TrimSpace(m), "W/")
+			if m == etag || m == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !f.Updated.Truncate(time.Second).After(since)
+}
+
+// Render writes the feed in the format given, or 304 Not Modified if the client has the current version.
+func Render(w http.ResponseWriter, r *http.Request, f *Feed, format string) error {
+	f.FeedPath = Path(r, format)
+	w.Header().Set("ETag", f.ETag(format))
+	w.Header().Set("Last-Modified", f.Updated.UTC().Format(http.TimeFormat))
+
+	if f.NotModified(r, format) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", ContentType(format))
+	switch format {
+	case Atom:
+		return f.WriteAtom(w)
+	case JSON:
+		return f.WriteJSON(w)
+	default:
+		return f.WriteRSS(w)
+	}
+}
@@ -0,0 +1,134 @@
+// Tests for the feed package
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFeed returns a feed with a single item.
+func testFeed() *Feed {
+	updated := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	return &Feed{
+		Root:        "https://example.com/",
+		Title:       "Stories",
+		Description: "Latest stories",
+		Path:        "/stories",
+		Updated:     updated,
+		Items: []*Item{
+			{
+				ID:           "/stories/1-go",
+				Title:        "Go & more",
+				URL:          "/stories/1-go",
+				ExternalURL:  "https://go.dev/",
+				Text:         "A summary",
+				AuthorName:   "alice",
+				AuthorPath:   "/users/1",
+				CommentsPath: "/stories/1-go",
+				Tags:         []string{"go"},
+				Published:    updated.Add(-time.Hour),
+				Updated:      updated,
+			},
+		},
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		path   string
+		accept string
+		format string
+	}{
+		{"/stories.xml", "", RSS},
+		{"/stories.atom", "", Atom},
+		{"/stories.json", "application/atom+xml", JSON},
+		{"/stories", "application/atom+xml", Atom},
+		{"/stories", "application/feed+json, */*", JSON},
+		{"/stories", "application/rss+xml", RSS},
+		{"/stories", "text/html,application/xhtml+xml,*/*", ""},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		r.Header.Set("Accept", tt.accept)
+		if got := Format(r); got != tt.format {
+			t.Fatalf("feed: wrong format for %s %s expected:%q got:%q", tt.path, tt.accept, tt.format, got)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/?page=2&q=go", nil)
+	if got := Path(r, Atom); got != "/index.atom?q=go" {
+		t.Fatalf("feed: wrong path got:%s", got)
+	}
+}
+
+// TestNegotiate tests responses vary by Accept whether or not a feed is requested
+func TestNegotiate(t *testing.T) {
+	for accept, format := range map[string]string{"text/html": "", "application/atom+xml": Atom} {
+		r := httptest.NewRequest("GET", "/stories", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		if got := Negotiate(w, r); got != format || w.Header().Get("Vary") != "Accept" {
+			t.Fatalf("feed: wrong negotiation for %s got:%q vary:%q", accept, got, w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, format := range []string{RSS, Atom, JSON} {
+		r := httptest.NewRequest("GET", "/stories"+format, nil)
+		w := httptest.NewRecorder()
+		err := Render(w, r, testFeed(), format)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("feed: error rendering %s %v", format, err)
+		}
+
+		body := w.Body.String()
+		if format == JSON {
+			var v map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &v)
+		} else {
+			var v struct{}
+			err = xml.Unmarshal(w.Body.Bytes(), &v)
+		}
+		if err != nil {
+			t.Fatalf("feed: invalid %s %s", format, err)
+		}
+
+		// URLs are absolute
+		if !strings.Contains(body, "https://example.com/stories/1-go") || !strings.Contains(body, "https://example.com/stories"+format) {
+			t.Fatalf("feed: missing absolute urls in %s got:%s", format, body)
+		}
+
+		// Conditional requests with the etag or a later time are not modified
+		etag := w.Header().Get("ETag")
+		r = httptest.NewRequest("GET", "/stories"+format, nil)
+		r.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		Render(w, r, testFeed(), format)
+		if w.Code != http.StatusNotModified || w.Body.Len() > 0 {
+			t.Fatalf("feed: expected not modified for etag %s got:%d", etag, w.Code)
+		}
+
+		r = httptest.NewRequest("GET", "/stories"+format, nil)
+		r.Header.Set("If-Modified-Since", testFeed().Updated.Add(-time.Second).Format(http.TimeFormat))
+		w = httptest.NewRecorder()
+		Render(w, r, testFeed(), format)
+		if w.Code != http.StatusOK {
+			t.Fatalf("feed: expected modified got:%d", w.Code)
+		}
+	}
+
+	// The etag changes when items change
+	f := testFeed()
+	etag := f.ETag(Atom)
+	f.Items[0].Updated = f.Items[0].Updated.Add(time.Minute)
+	if f.ETag(Atom) == etag || f.ETag(RSS) == f.ETag(Atom) {
+		t.Fatalf("feed: etag unchanged")
+	}
+}
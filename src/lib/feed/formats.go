@@ -0,0 +1,238 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// This file contains the encodings of feeds as RSS 2.0, Atom 1.0 and JSON Feed 1.1.
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Self          atomLink  `xml:"atom:link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Comments    string   `xml:"comments,omitempty"`
+	Categories  []string `xml:"category"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// WriteRSS writes the feed as RSS 2.0.
+func (f *Feed) WriteRSS(w io.Writer) error {
+	feed := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.URL(f.Path),
+			Self:          atomLink{Rel: "self", Type: "application/rss+xml", Href: f.URL(f.FeedPath)},
+			Description:   f.Description,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, item := range f.Items {
+		link := item.ExternalURL
+		if link == "" {
+			link = item.URL
+		}
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        f.URL(link),
+			Description: item.Text,
+			Creator:     item.AuthorName,
+			Comments:    f.URL(item.CommentsPath),
+			Categories:  item.Tags,
+			GUID:        rssGUID{IsPermaLink: true, Value: f.URL(item.ID)},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	return writeXML(w, feed)
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Links      []atomLink     `xml:"link"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+// WriteAtom writes the feed as Atom 1.0.
+func (f *Feed) WriteAtom(w io.Writer) error {
+	feed := atomFeed{
+		ID:       f.URL(f.FeedPath),
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.URL(f.FeedPath)},
+			{Rel: "alternate", Type: "text/html", Href: f.URL(f.Path)},
+		},
+		Author: atomAuthor{Name: f.Title, URI: f.URL("/")},
+	}
+
+	for _, item := range f.Items {
+		entry := atomEntry{
+			ID:        f.URL(item.ID),
+			Title:     item.Title,
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: f.URL(item.URL)}},
+		}
+		if item.ExternalURL != "" {
+			entry.Links = append(entry.Links, atomLink{Rel: "related", Href: f.URL(item.ExternalURL)})
+		}
+		if item.AuthorName != "" {
+			entry.Author = &atomAuthor{Name: item.AuthorName, URI: f.URL(item.AuthorPath)}
+		}
+		if item.Text != "" {
+			entry.Summary = &atomText{Type: "text", Value: item.Text}
+		}
+		if item.HTML != "" {
+			entry.Content = &atomText{Type: "html", Value: item.HTML}
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return writeXML(w, feed)
+}
+
+// writeXML writes v as an xml document.
+func writeXML(w io.Writer, v interface{}) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	return e.Encode(v)
+}
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageURL string     `json:"home_page_url"`
+	FeedURL     string     `json:"feed_url"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url"`
+	ExternalURL   string       `json:"external_url,omitempty"`
+	Title         string       `json:"title"`
+	ContentText   string       `json:"content_text,omitempty"`
+	ContentHTML   string       `json:"content_html,omitempty"`
+	DatePublished time.Time    `json:"date_published"`
+	DateModified  time.Time    `json:"date_modified"`
+	Authors       []jsonAuthor `json:"authors,omitempty"`
+	Tags          []string     `json:"tags,omitempty"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// WriteJSON writes the feed as JSON Feed 1.1.
+func (f *Feed) WriteJSON(w io.Writer) error {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.URL(f.Path),
+		FeedURL:     f.URL(f.FeedPath),
+		Description: f.Description,
+		Items:       []jsonItem{},
+	}
+
+	for _, item := range f.Items {
+		j := jsonItem{
+			ID:            f.URL(item.ID),
+			URL:           f.URL(item.URL),
+			ExternalURL:   f.URL(item.ExternalURL),
+			Title:         item.Title,
+			ContentText:   item.Text,
+			ContentHTML:   item.HTML,
+			DatePublished: item.Published.UTC(),
+			DateModified:  item.Updated.UTC(),
+			Tags:          item.Tags,
+		}
+		// Items must have some content
+		if j.ContentText == "" && j.ContentHTML == "" {
+			j.ContentText = item.Title
+		}
+		if item.AuthorName != "" {
+			j.Authors = []jsonAuthor{{Name: item.AuthorName, URL: f.URL(item.AuthorPath)}}
+		}
+		feed.Items = append(feed.Items, j)
+	}
+
+	return json.NewEncoder(w).Encode(feed)
+}
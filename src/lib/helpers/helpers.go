@@ -31,6 +31,21 @@ func Markup(s string) template.HTML {
 	return helpers.Sanitize(s)
 }
 
+// Duration returns a readable description of a duration, such as 3 hours.
+func Duration(d time.Duration) string {
+	n, unit := int64(d/time.Minute), "minute"
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		n, unit = int64(d/(24*time.Hour)), "day"
+	case d >= time.Hour && d%time.Hour == 0:
+		n, unit = int64(d/time.Hour), "hour"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
 // TimeAgo returns a string for a time in format x seconds ago
 func TimeAgo(d time.Time) string {
 
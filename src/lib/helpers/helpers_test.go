@@ -3,6 +3,7 @@ package helpers
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fragmenta/server/config"
 )
@@ -37,3 +38,22 @@ func TestMarkup(t *testing.T) {
 	}
 
 }
+
+// TestDuration tests durations are described in the largest whole unit
+func TestDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		time.Minute:        "1 minute",
+		90 * time.Minute:   "90 minutes",
+		time.Hour:          "1 hour",
+		3 * time.Hour:      "3 hours",
+		48 * time.Hour:     "2 days",
+		0:                  "0 minutes",
+		25 * time.Hour:     "25 hours",
+		24 * 7 * time.Hour: "7 days",
+	}
+	for d, want := range tests {
+		if got := Duration(d); got != want {
+			t.Fatalf("helpers: Duration wrong for %v wanted:%s got:%s", d, want, got)
+		}
+	}
+}
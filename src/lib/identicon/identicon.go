@@ -0,0 +1,53 @@
+// Package identicon generates simple symmetric avatars as svg,
+// they are deterministic so the same id always produces the same image.
+package identicon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// Grid is the number of cells along each side of the icon.
+const Grid = 5
+
+// SVG returns an svg image of the given size in pixels for this id.
+func SVG(id int64, size int) string {
+	sum := hash(id)
+
+	// The first bytes choose the colour, the rest the cells
+	hue := int(binary.BigEndian.Uint16(sum[0:2])) % 360
+	colour := fmt.Sprintf("hsl(%d,55%%,50%%)", hue)
+	cell := float64(size) / Grid
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, size, size, size, size)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#f0f0f0"/>`, size, size)
+	for y := 0; y < Grid; y++ {
+		for x := 0; x < Grid; x++ {
+			if Filled(sum, x, y) {
+				fmt.Fprintf(&b, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`, float64(x)*cell, float64(y)*cell, cell, cell, colour)
+			}
+		}
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// Filled returns true if the cell at x,y is filled for this hash,
+// the right half of the grid mirrors the left.
+func Filled(sum [sha256.Size]byte, x, y int) bool {
+	if x >= (Grid+1)/2 {
+		x = Grid - 1 - x
+	}
+	i := 2 + y*((Grid+1)/2) + x
+	return sum[i]%2 == 0
+}
+
+// hash returns the hash used to draw the icon for this id.
+func hash(id int64) [sha256.Size]byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(id))
+	return sha256.Sum256(buf)
+}
@@ -0,0 +1,30 @@
+// Tests for the identicon package
+package identicon
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSVG tests icons are deterministic, distinct and symmetric
+func TestSVG(t *testing.T) {
+	a := SVG(1, 80)
+	if a != SVG(1, 80) {
+		t.Fatalf("identicon: icon for the same id differs")
+	}
+	if a == SVG(2, 80) {
+		t.Fatalf("identicon: icons for different ids match")
+	}
+	if !strings.HasPrefix(a, "<svg") || !strings.HasSuffix(a, "</svg>") {
+		t.Fatalf("identicon: invalid svg got:%s", a)
+	}
+
+	sum := hash(123)
+	for y := 0; y < Grid; y++ {
+		for x := 0; x < Grid; x++ {
+			if Filled(sum, x, y) != Filled(sum, Grid-1-x, y) {
+				t.Fatalf("identicon: icon not symmetric at %d,%d", x, y)
+			}
+		}
+	}
+}
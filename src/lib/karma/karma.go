@@ -0,0 +1,256 @@
+// Package karma defines the points policy for users - the privileges
+// points unlock, and the rewards and costs of voting and flagging.
+package karma
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Privilege represents an action which users unlock by gaining points.
+type Privilege int
+
+// Privileges which may be unlocked by points.
+const (
+	Submit Privilege = iota
+	Comment
+	Upvote
+	Downvote
+	Style
+	Flag
+	Invite
+)
+
+// Privileges returns all privileges in the order they are usually unlocked.
+func Privileges() []Privilege {
+	return []Privilege{Comment, Submit, Upvote, Downvote, Style, Flag, Invite}
+}
+
+// String returns a display name for the privilege.
+func (p Privilege) String() string {
+	switch p {
+	case Submit:
+		return "Submit stories"
+	case Comment:
+		return "Comment"
+	case Upvote:
+		return "Upvote"
+	case Downvote:
+		return "Downvote"
+	case Style:
+		return "Style text"
+	case Flag:
+		return "Flag"
+	case Invite:
+		return "Invite users"
+	}
+	return ""
+}
+
+// Policy defines the thresholds, rewards and costs used for user points.
+type Policy struct {
+	// Thresholds holds the points a user must have more than to use a privilege
+	Thresholds map[Privilege]int64
+
+	// InitialPoints is given to new users on sign up
+	InitialPoints int64
+
+	// UpvoteReward is added to an item and its author on upvote
+	UpvoteReward int64
+	// DownvotePenalty is removed from an item and its author on downvote
+	DownvotePenalty int64
+	// FlagPenalty is removed from an item and its author on flag
+	FlagPenalty int64
+
+	// DownvoteCost is removed from the user who downvotes
+	DownvoteCost int64
+	// FlagCost is removed from the user who flags
+	FlagCost int64
+
+	// DailyVotes is the maximum votes a user may cast in 24 hours (0 for no limit)
+	DailyVotes int64
+	// DailyFlags is the maximum flags a user may make in 24 hours (0 for no limit)
+	DailyFlags int64
+	// DailyStories is the maximum stories a user may submit in 24 hours (0 for no limit)
+	DailyStories int64
+
+	// SupporterDailyStories replaces DailyStories for users with a supporter membership,
+	// where DailyStories is set and is lower
+	SupporterDailyStories int64
+
+	// Probation is the period after sign up during which users may not downvote or flag
+	Probation time.Duration
+}
+
+// Current is the policy in use, it should be replaced on startup
+// by one loaded from config.
+var Current = Default()
+
+// Default returns the default policy.
+func Default() *Policy {
+	return &Policy{
+		Thresholds: map[Privilege]int64{
+			Submit:   2,
+			Comment:  0,
+			Upvote:   2,
+			Downvote: 20,
+			Style:    30,
+			Flag:     50,
+			Invite:   100,
+		},
+		InitialPoints:   1,
+		UpvoteReward:    1,
+		DownvotePenalty: 1,
+		FlagPenalty:     5,
+		DownvoteCost:    1,
+		FlagCost:        2,
+		DailyVotes:      100,
+		DailyFlags:      10,
+		DailyStories:    0,
+		Probation:       3 * 24 * time.Hour,
+
+		SupporterDailyStories: 10,
+	}
+}
+
+// configKeys maps config keys to thresholds for privileges.
+var configKeys = map[Privilege]string{
+	Submit:   "karma_submit",
+	Comment:  "karma_comment",
+	Upvote:   "karma_upvote",
+	Downvote: "karma_downvote",
+	Style:    "karma_style",
+	Flag:     "karma_flag",
+	Invite:   "karma_invite",
+}
+
+// Load returns a policy using values from the get function (usually config.Get),
+// keys which are missing use the default value.
+func Load(get func(string) string) (*Policy, error) {
+	p := Default()
+
+	var err error
+	for privilege, key := range configKeys {
+		v := p.Thresholds[privilege]
+		p.Thresholds[privilege], err = loadInt(get, key, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	values := map[string]*int64{
+		"karma_initial_points":   &p.InitialPoints,
+		"karma_upvote_reward":    &p.UpvoteReward,
+		"karma_downvote_penalty": &p.DownvotePenalty,
+		"karma_flag_penalty":     &p.FlagPenalty,
+		"karma_downvote_cost":    &p.DownvoteCost,
+		"karma_flag_cost":        &p.FlagCost,
+		"karma_daily_votes":      &p.DailyVotes,
+		"karma_daily_flags":      &p.DailyFlags,
+		"karma_daily_stories":    &p.DailyStories,
+
+		"karma_supporter_daily_stories": &p.SupporterDailyStories,
+	}
+	for key, v := range values {
+		*v, err = loadInt(get, key, *v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	days, err := loadInt(get, "karma_probation_days", int64(p.Probation/(24*time.Hour)))
+	if err != nil {
+		return nil, err
+	}
+	p.Probation = time.Duration(days) * 24 * time.Hour
+
+	return p, nil
+}
+
+// loadInt returns the int value for key, or the default value d if not set.
+func loadInt(get func(string) string, key string, d int64) (int64, error) {
+	s := get(key)
+	if s == "" {
+		return d, nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("karma: invalid value for %s:%s", key, s)
+	}
+	return i, nil
+}
+
+// Threshold returns the points required (exclusive) for this privilege.
+func (p *Policy) Threshold(privilege Privilege) int64 {
+	return p.Thresholds[privilege]
+}
+
+// Allows returns true if a user with these points, who signed up at created,
+// may use this privilege. Downvotes and flags are not allowed during probation.
+func (p *Policy) Allows(privilege Privilege, points int64, created time.Time) bool {
+	if points <= p.Threshold(privilege) {
+		return false
+	}
+
+	if privilege == Downvote || privilege == Flag {
+		return !p.OnProbation(created)
+	}
+
+	return true
+}
+
+// OnProbation returns true if an account created at this time is still on probation.
+func (p *Policy) OnProbation(created time.Time) bool {
+	return time.Since(created) < p.Probation
+}
+
+// VoteAllowed returns true if a user who has cast count votes today may vote again.
+func (p *Policy) VoteAllowed(count int64) bool {
+	return p.DailyVotes == 0 || count < p.DailyVotes
+}
+
+// FlagAllowed returns true if a user who has flagged count items today may flag again.
+func (p *Policy) FlagAllowed(count int64) bool {
+	return p.DailyFlags == 0 || count < p.DailyFlags
+}
+
+// StoryAllowed returns true if a user who has submitted count stories today may submit another,
+// supporters have a higher allowance.
+func (p *Policy) StoryAllowed(count int64, supporter bool) bool {
+	limit := p.StoryLimit(supporter)
+	return limit == 0 || count < limit
+}
+
+// StoryLimit returns the maximum stories a user may submit in 24 hours (0 for no limit),
+// the supporter allowance is used only where it is more generous.
+func (p *Policy) StoryLimit(supporter bool) int64 {
+	if !supporter || p.DailyStories == 0 {
+		return p.DailyStories
+	}
+	if p.SupporterDailyStories == 0 || p.SupporterDailyStories > p.DailyStories {
+		return p.SupporterDailyStories
+	}
+	return p.DailyStories
+}
+
+// Level describes a privilege and whether it has been unlocked, for display.
+type Level struct {
+	Name      string
+	Threshold int64
+	Unlocked  bool
+}
+
+// Levels returns the privileges available to a user with these points,
+// who signed up at created, in the order they are usually unlocked.
+func (p *Policy) Levels(points int64, created time.Time) []Level {
+	var levels []Level
+	for _, privilege := range Privileges() {
+		levels = append(levels, Level{
+			Name:      privilege.String(),
+			Threshold: p.Threshold(privilege) + 1,
+			Unlocked:  p.Allows(privilege, points, created),
+		})
+	}
+	return levels
+}
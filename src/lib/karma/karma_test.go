@@ -0,0 +1,114 @@
+// Tests for the karma package
+package karma
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAllows tests thresholds and probation for privileges.
+func TestAllows(t *testing.T) {
+	p := Default()
+	old := time.Now().Add(-365 * 24 * time.Hour)
+
+	if p.Allows(Submit, 2, old) {
+		t.Fatalf("karma: submit allowed at threshold")
+	}
+	if !p.Allows(Submit, 3, old) {
+		t.Fatalf("karma: submit not allowed above threshold")
+	}
+	if !p.Allows(Comment, 1, old) {
+		t.Fatalf("karma: comment not allowed with 1 point")
+	}
+
+	// New accounts may not flag or downvote, whatever their points
+	if p.Allows(Flag, 100, time.Now()) || p.Allows(Downvote, 100, time.Now()) {
+		t.Fatalf("karma: flag allowed during probation")
+	}
+	if !p.Allows(Flag, 100, old) {
+		t.Fatalf("karma: flag not allowed after probation")
+	}
+	if !p.Allows(Upvote, 100, time.Now()) {
+		t.Fatalf("karma: upvote not allowed during probation")
+	}
+}
+
+// TestAllowances tests daily vote and flag limits.
+func TestAllowances(t *testing.T) {
+	p := Default()
+	p.DailyVotes = 2
+
+	if !p.VoteAllowed(1) || p.VoteAllowed(2) {
+		t.Fatalf("karma: vote allowance incorrect")
+	}
+
+	p.DailyFlags = 0
+	if !p.FlagAllowed(1000) {
+		t.Fatalf("karma: flag allowance should be unlimited")
+	}
+
+	// Stories are not limited unless a limit is configured
+	if !p.StoryAllowed(1000, false) || !p.StoryAllowed(1000, true) {
+		t.Fatalf("karma: story allowance should be unlimited by default")
+	}
+
+	// Supporters may submit more stories, but never fewer
+	p.DailyStories = 5
+	p.SupporterDailyStories = 10
+	if p.StoryAllowed(5, false) || !p.StoryAllowed(5, true) || p.StoryAllowed(10, true) {
+		t.Fatalf("karma: story allowance incorrect")
+	}
+	p.SupporterDailyStories = 2
+	if p.StoryLimit(true) != 5 {
+		t.Fatalf("karma: supporter allowance lower than default")
+	}
+	p.DailyStories = 0
+	if !p.StoryAllowed(1000, true) {
+		t.Fatalf("karma: story allowance should be unlimited")
+	}
+}
+
+// TestLoad tests loading a policy from config values.
+func TestLoad(t *testing.T) {
+	config := map[string]string{
+		"karma_flag":           "10",
+		"karma_flag_cost":      "3",
+		"karma_probation_days": "0",
+	}
+	get := func(k string) string { return config[k] }
+
+	p, err := Load(get)
+	if err != nil {
+		t.Fatalf("karma: error loading policy %s", err)
+	}
+	if p.Threshold(Flag) != 10 || p.FlagCost != 3 || p.Probation != 0 {
+		t.Fatalf("karma: policy not loaded from config %v", p)
+	}
+
+	// Values not in config should use defaults
+	if p.Threshold(Downvote) != Default().Threshold(Downvote) {
+		t.Fatalf("karma: default not used for missing value")
+	}
+
+	config["karma_submit"] = "ten"
+	_, err = Load(get)
+	if err == nil {
+		t.Fatalf("karma: invalid value accepted")
+	}
+}
+
+// TestLevels tests the privileges shown to users.
+func TestLevels(t *testing.T) {
+	levels := Default().Levels(25, time.Now().Add(-365*24*time.Hour))
+	if len(levels) != len(Privileges()) {
+		t.Fatalf("karma: wrong number of levels")
+	}
+	for _, l := range levels {
+		if l.Name == Flag.String() && l.Unlocked {
+			t.Fatalf("karma: flag unlocked at 25 points")
+		}
+		if l.Name == Downvote.String() && !l.Unlocked {
+			t.Fatalf("karma: downvote locked at 25 points")
+		}
+	}
+}
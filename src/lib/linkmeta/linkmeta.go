@@ -0,0 +1,310 @@
+// Package linkmeta fetches a page and extracts the title, summary and
+// canonical link from its head, for use when submitting links.
+package linkmeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Meta holds the metadata extracted from a page.
+type Meta struct {
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Summary   string `json:"summary"`
+	Canonical string `json:"canonical"`
+	Image     string `json:"image"`
+	SiteName  string `json:"site_name"`
+}
+
+// ErrAddress is returned when a url resolves to an address we refuse to fetch.
+var ErrAddress = errors.New("linkmeta: address not allowed")
+
+// Fetcher retrieves pages with limits on time, size and the addresses contacted.
+type Fetcher struct {
+	// Timeout is the time allowed for the entire request including redirects
+	Timeout time.Duration
+	// MaxBytes is the maximum number of bytes of the body read
+	MaxBytes int64
+	// MaxRedirects is the maximum number of redirects followed
+	MaxRedirects int
+	// UserAgent is sent with each request
+	UserAgent string
+	// AllowAddr reports whether an ip may be contacted, if nil PublicAddr is used
+	AllowAddr func(net.IP) bool
+}
+
+// Default is the fetcher used by Fetch.
+var Default = &Fetcher{
+	Timeout:      5 * time.Second,
+	MaxBytes:     512 * 1024,
+	MaxRedirects: 5,
+	UserAgent:    "GoHackerNews-LinkMeta/1.0",
+}
+
+// Fetch retrieves the metadata for rawURL using the default fetcher.
+func Fetch(rawURL string) (*Meta, error) {
+	return Default.Fetch(rawURL)
+}
+
+// privateNets are ranges which should never be contacted from the server.
+var privateNets = parseCIDRs(
+	"0.0.0.0/8",       // this network
+	"10.0.0.0/8",      // private
+	"100.64.0.0/10",   // carrier grade nat
+	"127.0.0.0/8",     // loopback
+	"169.254.0.0/16",  // link local
+	"172.16.0.0/12",   // private
+	"192.0.0.0/24",    // ietf protocol assignments
+	"192.0.2.0/24",    // documentation
+	"192.168.0.0/16",  // private
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"224.0.0.0/4",     // multicast
+	"240.0.0.0/4",     // reserved and broadcast
+	"::/128",          // unspecified
+	"::1/128",         // loopback
+	"64:ff9b::/96",    // nat64
+	"100::/64",        // discard
+	"2001:db8::/32",   // documentation
+	"fc00::/7",        // unique local
+	"fe80::/10",       // link local
+	"ff00::/8",        // multicast
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// PublicAddr returns true if ip is a public unicast address.
+func PublicAddr(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fetch retrieves rawURL and extracts metadata from the html returned.
+func (f *Fetcher) Fetch(rawURL string) (*Meta, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("linkmeta: invalid url %q", rawURL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", f.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("linkmeta: status %d fetching %s", resp.StatusCode, u)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("linkmeta: unsupported content type %s", mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.MaxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(resp.Request.URL, string(body)), nil
+}
+
+// Dialer returns a dialer which checks every address dialled is allowed, after names are resolved,
+// so that redirects and dns rebinding cannot reach private addresses. If allow is nil PublicAddr is used.
+func Dialer(timeout time.Duration, allow func(net.IP) bool) *net.Dialer {
+	if allow == nil {
+		allow = PublicAddr
+	}
+
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !allow(ip) {
+				return ErrAddress
+			}
+			return nil
+		},
+	}
+}
+
+// client returns an http client which checks every address dialled,
+// so that redirects and dns rebinding cannot reach private addresses.
+func (f *Fetcher) client() *http.Client {
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           Dialer(f.Timeout, f.AllowAddr).DialContext,
+		TLSHandshakeTimeout:   f.Timeout,
+		ResponseHeaderTimeout: f.Timeout,
+		MaxIdleConns:          1,
+		DisableKeepAlives:     true,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > f.MaxRedirects {
+				return fmt.Errorf("linkmeta: stopped after %d redirects", f.MaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("linkmeta: invalid redirect to %s", req.URL)
+			}
+			return nil
+		},
+	}
+}
+
+var (
+	headEnd   = regexp.MustCompile(`(?i)<body[\s>]|</head\s*>`)
+	comments  = regexp.MustCompile(`(?s)<!--.*?-->`)
+	titleTag  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title\s*>`)
+	metaTag   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	linkTag   = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	attribute = regexp.MustCompile(`(?is)([a-z][a-z0-9_:.-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	spaces    = regexp.MustCompile(`\s+`)
+)
+
+// Parse extracts metadata from the head of the html page found at base.
+// OpenGraph values are preferred, then Twitter cards, then the title and description.
+func Parse(base *url.URL, page string) *Meta {
+	if loc := headEnd.FindStringIndex(page); loc != nil {
+		page = page[:loc[0]]
+	}
+	page = comments.ReplaceAllString(page, "")
+
+	values := make(map[string]string)
+	for _, tag := range metaTag.FindAllString(page, -1) {
+		attrs := attributes(tag)
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		if key != "" && values[key] == "" {
+			values[key] = clean(attrs["content"])
+		}
+	}
+
+	canonical := ""
+	for _, tag := range linkTag.FindAllString(page, -1) {
+		attrs := attributes(tag)
+		if hasToken(attrs["rel"], "canonical") && attrs["href"] != "" {
+			canonical = attrs["href"]
+			break
+		}
+	}
+
+	title := ""
+	if m := titleTag.FindStringSubmatch(page); m != nil {
+		title = clean(m[1])
+	}
+
+	meta := &Meta{
+		URL:      base.String(),
+		Title:    first(values["og:title"], values["twitter:title"], title),
+		Summary:  first(values["og:description"], values["twitter:description"], values["description"]),
+		Image:    resolve(base, first(values["og:image"], values["twitter:image"], values["twitter:image:src"])),
+		SiteName: first(values["og:site_name"], values["application-name"]),
+	}
+	meta.Canonical = resolve(base, first(canonical, values["og:url"]))
+
+	return meta
+}
+
+// attributes returns the attributes of a tag keyed by lower case name.
+func attributes(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attribute.FindAllStringSubmatch(tag, -1) {
+		name := strings.ToLower(m[1])
+		if _, ok := attrs[name]; !ok {
+			attrs[name] = html.UnescapeString(m[2] + m[3] + m[4])
+		}
+	}
+	return attrs
+}
+
+// hasToken returns true if the space separated list contains token.
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// clean unescapes entities and collapses whitespace in text.
+func clean(s string) string {
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(spaces.ReplaceAllString(s, " "))
+}
+
+// first returns the first non-empty string.
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// resolve returns ref as an absolute http(s) url relative to base, or "" if invalid.
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
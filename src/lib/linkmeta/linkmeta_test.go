@@ -0,0 +1,182 @@
+// Tests for the linkmeta package
+package linkmeta
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFetcher returns a fetcher which is allowed to contact the local test servers.
+func testFetcher() *Fetcher {
+	return &Fetcher{
+		Timeout:      time.Second,
+		MaxBytes:     64 * 1024,
+		MaxRedirects: 2,
+		UserAgent:    "test",
+		AllowAddr:    func(ip net.IP) bool { return ip.IsLoopback() },
+	}
+}
+
+func serve(contentType, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		fmt.Fprint(w, body)
+	}))
+}
+
+const openGraphPage = `<!DOCTYPE html>
+<html><head>
+<title>Plain title | Site</title>
+<!-- <meta property="og:title" content="Commented out"> -->
+<meta name="description" content="Plain description">
+<meta property="og:title" content="Graph &amp; title">
+<meta property='og:description' content='Graph
+   description'>
+<meta name="twitter:title" content="Twitter title">
+<meta property="og:image" content="/images/card.png">
+<meta property="og:site_name" content="Example">
+<link rel="stylesheet" href="/app.css">
+<link rel="canonical" href="/posts/1">
+</head>
+<body><title>Not this</title></body></html>`
+
+func TestFetchOpenGraph(t *testing.T) {
+	server := serve("text/html; charset=utf-8", openGraphPage)
+	defer server.Close()
+
+	meta, err := testFetcher().Fetch(server.URL + "/posts/1?utm_source=x")
+	if err != nil {
+		t.Fatalf("linkmeta: error fetching page %s", err)
+	}
+	if meta.Title != "Graph & title" {
+		t.Errorf("linkmeta: wrong title got:%q", meta.Title)
+	}
+	if meta.Summary != "Graph description" {
+		t.Errorf("linkmeta: wrong summary got:%q", meta.Summary)
+	}
+	if meta.Canonical != server.URL+"/posts/1" {
+		t.Errorf("linkmeta: wrong canonical got:%q", meta.Canonical)
+	}
+	if meta.Image != server.URL+"/images/card.png" {
+		t.Errorf("linkmeta: wrong image got:%q", meta.Image)
+	}
+	if meta.SiteName != "Example" {
+		t.Errorf("linkmeta: wrong site name got:%q", meta.SiteName)
+	}
+}
+
+func TestFetchFallbacks(t *testing.T) {
+	page := `<html><head><TITLE> Plain
+	title </TITLE><meta content="Card summary" name="twitter:description"></head></html>`
+	server := serve("text/html", page)
+	defer server.Close()
+
+	meta, err := testFetcher().Fetch(server.URL)
+	if err != nil {
+		t.Fatalf("linkmeta: error fetching page %s", err)
+	}
+	if meta.Title != "Plain title" || meta.Summary != "Card summary" || meta.Canonical != "" {
+		t.Errorf("linkmeta: wrong fallbacks got:%+v", meta)
+	}
+}
+
+func TestFetchRedirect(t *testing.T) {
+	target := serve("text/html", `<title>Target</title>`)
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL+"/final", http.StatusFound))
+	defer redirect.Close()
+
+	meta, err := testFetcher().Fetch(redirect.URL)
+	if err != nil {
+		t.Fatalf("linkmeta: error following redirect %s", err)
+	}
+	if meta.Title != "Target" || meta.URL != target.URL+"/final" {
+		t.Errorf("linkmeta: wrong redirect result got:%+v", meta)
+	}
+}
+
+func TestFetchLimits(t *testing.T) {
+	// Pages beyond MaxBytes are truncated rather than read in full
+	long := serve("text/html", "<title>Long</title>"+strings.Repeat("x", 1<<20))
+	defer long.Close()
+	meta, err := testFetcher().Fetch(long.URL)
+	if err != nil || meta.Title != "Long" {
+		t.Errorf("linkmeta: failed to read truncated page got:%v %v", meta, err)
+	}
+
+	// Non html content is rejected
+	pdf := serve("application/pdf", "%PDF")
+	defer pdf.Close()
+	if _, err := testFetcher().Fetch(pdf.URL); err == nil {
+		t.Errorf("linkmeta: fetched non html content")
+	}
+
+	// Slow servers time out
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	f := testFetcher()
+	f.Timeout = 50 * time.Millisecond
+	if _, err := f.Fetch(slow.URL); err == nil {
+		t.Errorf("linkmeta: slow server did not time out")
+	}
+
+	// Redirect loops stop
+	var loop *httptest.Server
+	loop = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, loop.URL, http.StatusFound)
+	}))
+	defer loop.Close()
+	if _, err := testFetcher().Fetch(loop.URL); err == nil {
+		t.Errorf("linkmeta: followed redirect loop")
+	}
+
+	// Invalid schemes are rejected before fetching
+	for _, u := range []string{"file:///etc/passwd", "ftp://example.com", "/relative", ""} {
+		if _, err := testFetcher().Fetch(u); err == nil {
+			t.Errorf("linkmeta: fetched invalid url %s", u)
+		}
+	}
+}
+
+func TestFetchPrivateAddress(t *testing.T) {
+	server := serve("text/html", `<title>Private</title>`)
+	defer server.Close()
+
+	// The default fetcher refuses loopback addresses
+	f := testFetcher()
+	f.AllowAddr = nil
+	if _, err := f.Fetch(server.URL); err == nil {
+		t.Errorf("linkmeta: fetched loopback address")
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	addrs := map[string]bool{
+		"8.8.8.8":          true,
+		"151.101.1.69":     true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.5.4":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"::ffff:127.0.0.1": false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+	}
+	for addr, public := range addrs {
+		if PublicAddr(net.ParseIP(addr)) != public {
+			t.Errorf("linkmeta: wrong result for %s want:%v", addr, public)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package password defines the password policy for users - the strength
+// required of new passwords, and the cost used to hash them.
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxLength is the longest password bcrypt will hash, longer passwords are truncated.
+const MaxLength = 72
+
+// common holds passwords which are always rejected, the blocklist file adds to these.
+var common = []string{
+	"123456", "1234567", "12345678", "123456789", "1234567890", "12345678910",
+	"password", "password1", "password12", "password123", "passw0rd", "p@ssw0rd",
+	"qwerty", "qwerty123", "qwertyuiop", "1q2w3e4r", "1q2w3e4r5t", "1qaz2wsx",
+	"abc123", "abcd1234", "iloveyou", "admin", "admin123", "administrator",
+	"welcome", "welcome1", "letmein", "monkey", "dragon", "football", "baseball",
+	"sunshine", "princess", "master", "shadow", "superman", "trustno1", "starwars",
+	"000000", "111111", "121212", "123123", "654321", "666666", "696969", "777777",
+	"987654321", "11111111", "00000000", "88888888", "asdfghjkl", "zxcvbnm",
+	"changeme", "secret", "login", "hello123", "whatever", "freedom", "access",
+}
+
+// Policy defines the strength required of passwords, and the bcrypt cost used to hash them.
+type Policy struct {
+	// MinLength is the minimum number of characters in a password
+	MinLength int
+
+	// Cost is the bcrypt cost used for new hashes, older hashes
+	// with a lower cost are rehashed when the user next logs in
+	Cost int
+
+	// blocked holds the lower case passwords which are rejected,
+	// and blockedHashes the upper case hex sha1 hashes of passwords which are rejected
+	blocked       map[string]bool
+	blockedHashes map[string]bool
+}
+
+// Current is the policy in use, it should be replaced on startup
+// by one loaded from config.
+var Current = Default()
+
+// Default returns the default policy.
+func Default() *Policy {
+	p := &Policy{
+		MinLength:     8,
+		Cost:          12,
+		blocked:       make(map[string]bool),
+		blockedHashes: make(map[string]bool),
+	}
+	for _, s := range common {
+		p.blocked[s] = true
+	}
+	return p
+}
+
+// Load returns a policy using values from the get function (usually config.Get),
+// keys which are missing use the default value. If password_blocklist is set
+// it is read as a file of blocked passwords.
+func Load(get func(string) string) (*Policy, error) {
+	p := Default()
+
+	var err error
+	p.MinLength, err = loadInt(get, "password_min_length", p.MinLength)
+	if err != nil {
+		return nil, err
+	}
+	if p.MinLength < 1 || p.MinLength > MaxLength {
+		return nil, fmt.Errorf("password: invalid value for password_min_length:%d", p.MinLength)
+	}
+
+	p.Cost, err = loadInt(get, "password_cost", p.Cost)
+	if err != nil {
+		return nil, err
+	}
+	if p.Cost < bcrypt.MinCost || p.Cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("password: invalid value for password_cost:%d", p.Cost)
+	}
+
+	path := get("password_blocklist")
+	if path != "" {
+		err = p.LoadBlocklist(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// loadInt returns the int value for key, or the default value d if not set.
+func loadInt(get func(string) string, key string, d int) (int, error) {
+	s := get(key)
+	if s == "" {
+		return d, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("password: invalid value for %s:%s", key, s)
+	}
+	return i, nil
+}
+
+// LoadBlocklist adds the passwords in the file at path to those rejected.
+// The file has one entry per line, either a password or the hex sha1 hash
+// of a password as used in breached password lists. Anything after a colon
+// (such as a breach count) is ignored for hashes.
+func (p *Policy) LoadBlocklist(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if h := strings.SplitN(line, ":", 2)[0]; isSHA1(h) {
+			p.blockedHashes[strings.ToUpper(h)] = true
+			continue
+		}
+		p.blocked[strings.ToLower(line)] = true
+	}
+
+	return scanner.Err()
+}
+
+// isSHA1 returns true if s looks like a hex encoded sha1 hash.
+func isSHA1(s string) bool {
+	if len(s) != sha1.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Blocked returns true if this password is on the list of common or breached passwords.
+func (p *Policy) Blocked(pass string) bool {
+	if p.blocked[strings.ToLower(pass)] {
+		return true
+	}
+	if len(p.blockedHashes) == 0 {
+		return false
+	}
+	sum := sha1.Sum([]byte(pass))
+	return p.blockedHashes[strings.ToUpper(hex.EncodeToString(sum[:]))]
+}
+
+// Check returns an error suitable for display to the user if this password is too weak.
+// Passwords may not contain any of the personal strings given (such as the user name).
+func (p *Policy) Check(pass string, personal ...string) error {
+	if len([]rune(pass)) < p.MinLength {
+		return fmt.Errorf("Sorry, passwords must be at least %d characters long", p.MinLength)
+	}
+	if len(pass) > MaxLength {
+		return fmt.Errorf("Sorry, passwords must be at most %d characters long", MaxLength)
+	}
+	if p.Blocked(pass) {
+		return fmt.Errorf("Sorry, that password is too common, please choose another")
+	}
+	lower := strings.ToLower(pass)
+	for _, s := range personal {
+		if len(s) > 2 && strings.Contains(lower, strings.ToLower(s)) {
+			return fmt.Errorf("Sorry, passwords may not contain your name or email")
+		}
+	}
+	return nil
+}
+
+// Hash returns the bcrypt hash of this password using the policy cost.
+func (p *Policy) Hash(pass string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pass), p.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// NeedsRehash returns true if this hash was made with a lower cost than the policy.
+func (p *Policy) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false
+	}
+	return cost < p.Cost
+}
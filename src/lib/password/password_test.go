@@ -0,0 +1,97 @@
+// Tests for the password package
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestCheck tests the strength rules for passwords
+func TestCheck(t *testing.T) {
+	p := Default()
+
+	weak := []string{"short", "password123", "Password123", "kenny-secure-pass"}
+	for _, pass := range weak {
+		if p.Check(pass, "kenny", "kenny@example.com") == nil {
+			t.Fatalf("password: weak password accepted:%s", pass)
+		}
+	}
+
+	if err := p.Check("correct horse battery", "kenny"); err != nil {
+		t.Fatalf("password: strong password rejected:%s", err)
+	}
+}
+
+// TestBlocklist tests loading plain and hashed passwords from a file
+func TestBlocklist(t *testing.T) {
+	f, err := ioutil.TempFile("", "blocklist")
+	if err != nil {
+		t.Fatalf("password: error creating file:%s", err)
+	}
+	defer os.Remove(f.Name())
+
+	// sha1 of "hunter2hunter2" with a breach count
+	f.WriteString("Tr0ub4dor&3\nFC8C5EB194806E31A213F073131E73B0012A0FB5:42\n")
+	f.Close()
+
+	p, err := Load(func(key string) string {
+		switch key {
+		case "password_min_length":
+			return "10"
+		case "password_blocklist":
+			return f.Name()
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("password: error loading policy:%s", err)
+	}
+
+	if p.MinLength != 10 {
+		t.Fatalf("password: min length not loaded got:%d", p.MinLength)
+	}
+	if !p.Blocked("tr0ub4dor&3") {
+		t.Fatalf("password: plain blocklist entry not blocked")
+	}
+	if !p.Blocked("hunter2hunter2") {
+		t.Fatalf("password: hashed blocklist entry not blocked")
+	}
+	if p.Blocked("correct horse battery") {
+		t.Fatalf("password: unlisted password blocked")
+	}
+
+	_, err = Load(func(key string) string {
+		if key == "password_cost" {
+			return "2"
+		}
+		return ""
+	})
+	if err == nil {
+		t.Fatalf("password: invalid cost accepted")
+	}
+}
+
+// TestHash tests hashes verify, and older costs are rehashed
+func TestHash(t *testing.T) {
+	p := Default()
+	p.Cost = bcrypt.MinCost
+
+	hash, err := p.Hash("correct horse battery")
+	if err != nil {
+		t.Fatalf("password: error hashing:%s", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte("correct horse battery")) != nil {
+		t.Fatalf("password: hash does not match")
+	}
+	if p.NeedsRehash(hash) {
+		t.Fatalf("password: current hash needs rehash")
+	}
+
+	p.Cost = bcrypt.MinCost + 1
+	if !p.NeedsRehash(hash) {
+		t.Fatalf("password: old hash does not need rehash")
+	}
+}
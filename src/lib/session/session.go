@@ -1,8 +1,10 @@
 package session
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/fragmenta/auth"
 	"github.com/fragmenta/mux"
@@ -11,6 +13,9 @@ import (
 	"github.com/kennygrant/gohackernews/src/users"
 )
 
+// LoginKey is the session key for the time the user logged in, as a unix timestamp.
+const LoginKey = "login_at"
+
 // CurrentUser returns the saved user (or an empty anon user)
 // for the current session cookie
 func CurrentUser(w http.ResponseWriter, r *http.Request) *users.User {
@@ -49,11 +54,37 @@ func CurrentUser(w http.ResponseWriter, r *http.Request) *users.User {
 			log.Info(log.V{"msg": "session error user not found", "user_id": id, "error": err, "status": http.StatusNotFound})
 			return user
 		}
+
+		// Suspended users are treated as anonymous, so they cannot write
+		if user.Suspended() {
+			return &users.User{}
+		}
+
+		// Sessions started before the user revoked their sessions are no longer valid
+		if !user.SessionsAt.IsZero() {
+			loginAt, _ := strconv.ParseInt(session.Get(LoginKey), 10, 64)
+			if loginAt < user.SessionsAt.Unix() {
+				log.Info(log.V{"msg": "session error revoked", "user_id": id, "status": http.StatusUnauthorized})
+				return &users.User{}
+			}
+		}
 	}
 
 	return user
 }
 
+// Login saves the user id and login time in the session cookie for this request.
+func Login(w http.ResponseWriter, r *http.Request, user *users.User) error {
+	session, err := auth.Session(w, r)
+	if err != nil {
+		return err
+	}
+
+	session.Set(auth.SessionUserKey, fmt.Sprintf("%d", user.ID))
+	session.Set(LoginKey, fmt.Sprintf("%d", time.Now().Unix()))
+	return session.Save(w)
+}
+
 // clearSession clears the request session cookie entirely.
 // If an error is encountered in processing params, the session is cleared.
 func clearSession(w http.ResponseWriter, r *http.Request) error {
@@ -0,0 +1,276 @@
+// Package stripe is a small client for the parts of the Stripe API we use:
+// creating Checkout sessions, cancelling subscriptions, and verifying the webhook events which follow them.
+package stripe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIURL is the base url of the Stripe API.
+const APIURL = "https://api.stripe.com"
+
+// Tolerance is the maximum age of a webhook event signature, to limit replays.
+const Tolerance = 5 * time.Minute
+
+// Checkout modes
+const (
+	ModePayment      = "payment"
+	ModeSubscription = "subscription"
+)
+
+// ErrSignature is returned for webhook payloads without a valid signature.
+var ErrSignature = errors.New("stripe: invalid webhook signature")
+
+// Client calls the Stripe API with a secret key, and verifies webhooks with a webhook secret.
+type Client struct {
+	// Key is the secret api key (sk_...)
+	Key string
+
+	// WebhookSecret is the signing secret of the webhook endpoint (whsec_...)
+	WebhookSecret string
+
+	// BaseURL is the url requests are sent to, which tests may point at a local stand-in
+	BaseURL string
+
+	// HTTP is the client used for requests
+	HTTP *http.Client
+}
+
+// Default is the client used by the app, set up from config on startup.
+var Default = New("", "")
+
+// New returns a new client for the Stripe API.
+func New(key, webhookSecret string) *Client {
+	return &Client{
+		Key:           key,
+		WebhookSecret: webhookSecret,
+		BaseURL:       APIURL,
+		HTTP:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled returns true if the client has the keys required to take payments.
+func (c *Client) Enabled() bool {
+	return c.Key != "" && c.WebhookSecret != ""
+}
+
+// CheckoutParams are the params used to create a Checkout session for a single price.
+type CheckoutParams struct {
+	Mode       string
+	Price      string
+	Quantity   int
+	SuccessURL string
+	CancelURL  string
+
+	// ClientReferenceID identifies the purchase in our ledger
+	ClientReferenceID string
+	CustomerEmail     string
+
+	// Metadata is set on the session, and in subscription mode on the subscription too,
+	// so that later subscription and invoice events may be matched to the purchase
+	Metadata map[string]string
+}
+
+// Session is a Checkout session, as returned by the API and in webhook events.
+type Session struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	Mode              string            `json:"mode"`
+	Status            string            `json:"status"`
+	PaymentStatus     string            `json:"payment_status"`
+	AmountTotal       int64             `json:"amount_total"`
+	Currency          string            `json:"currency"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	Customer          string            `json:"customer"`
+	PaymentIntent     string            `json:"payment_intent"`
+	Subscription      string            `json:"subscription"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// Paid returns true if the payment for this session has been received.
+func (s *Session) Paid() bool {
+	return s.PaymentStatus == "paid" || s.PaymentStatus == "no_payment_required"
+}
+
+// CreateCheckoutSession creates a Checkout session, the customer should be redirected to its URL.
+func (c *Client) CreateCheckoutSession(p CheckoutParams) (*Session, error) {
+	if p.Mode == "" {
+		p.Mode = ModePayment
+	}
+	if p.Quantity < 1 {
+		p.Quantity = 1
+	}
+
+	form := url.Values{}
+	form.Set("mode", p.Mode)
+	form.Set("line_items[0][price]", p.Price)
+	form.Set("line_items[0][quantity]", strconv.Itoa(p.Quantity))
+	form.Set("success_url", p.SuccessURL)
+	form.Set("cancel_url", p.CancelURL)
+	if p.ClientReferenceID != "" {
+		form.Set("client_reference_id", p.ClientReferenceID)
+	}
+	if p.CustomerEmail != "" {
+		form.Set("customer_email", p.CustomerEmail)
+	}
+	for k, v := range p.Metadata {
+		form.Set(fmt.Sprintf("metadata[%s]", k), v)
+		if p.Mode == ModeSubscription {
+			form.Set(fmt.Sprintf("subscription_data[metadata][%s]", k), v)
+		}
+	}
+
+	session := &Session{}
+	err := c.request(http.MethodPost, "/v1/checkout/sessions", form, session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// request sends the form to the api path given with this method, and decodes the response into v.
+func (c *Client) request(method, path string, form url.Values, v interface{}) error {
+	if c.Key == "" {
+		return errors.New("stripe: no api key set")
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Key)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &e)
+		return fmt.Errorf("stripe: %s %d %s %s", path, resp.StatusCode, e.Error.Type, e.Error.Message)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// Event is a webhook event, Data.Object holds the object concerned, such as a Session.
+type Event struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Created  int64  `json:"created"`
+	Livemode bool   `json:"livemode"`
+	Data     struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Session returns the Checkout session this event concerns.
+func (e *Event) Session() (*Session, error) {
+	session := &Session{}
+	err := json.Unmarshal(e.Data.Object, session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ParseEvent verifies the Stripe-Signature header for this payload, and returns the event it contains.
+func (c *Client) ParseEvent(payload []byte, header string) (*Event, error) {
+	if c.WebhookSecret == "" {
+		return nil, errors.New("stripe: no webhook secret set")
+	}
+
+	err := Verify(payload, header, c.WebhookSecret, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	event := &Event{}
+	err = json.Unmarshal(payload, event)
+	if err != nil {
+		return nil, err
+	}
+	if event.ID == "" || event.Type == "" {
+		return nil, errors.New("stripe: invalid webhook event")
+	}
+	return event, nil
+}
+
+// Verify checks the header contains a v1 signature of this payload made with secret
+// no more than Tolerance before now.
+func Verify(payload []byte, header, secret string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+
+	t, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrSignature
+	}
+
+	age := now.Sub(time.Unix(t, 0))
+	if age > Tolerance || age < -Tolerance {
+		return ErrSignature
+	}
+
+	expected := sign(payload, secret, timestamp)
+	for _, s := range signatures {
+		sig, err := hex.DecodeString(s)
+		if err == nil && hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+
+	return ErrSignature
+}
+
+// SignatureHeader returns a Stripe-Signature header for this payload signed at t,
+// as Stripe would send it. It is used to replay events in tests.
+func SignatureHeader(payload []byte, secret string, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(sign(payload, secret, timestamp)))
+}
+
+// sign returns the hmac of the timestamp and payload using secret.
+func sign(payload []byte, secret, timestamp string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
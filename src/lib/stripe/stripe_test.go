@@ -0,0 +1,126 @@
+package stripe
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+var testEvent = []byte(`{"id":"evt_1","type":"checkout.session.completed","created":1700000000,"data":{"object":{"id":"cs_test_1","mode":"payment","payment_status":"paid","amount_total":5000,"currency":"usd","client_reference_id":"7","payment_intent":"pi_1","subscription":null,"metadata":{"product":"jobs"}}}}`)
+
+// TestVerify tests webhook signatures are checked against payload, secret and time.
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	header := SignatureHeader(testEvent, testSecret, now)
+	parts := strings.Split(header, ",")
+
+	if err := Verify(testEvent, header, testSecret, now); err != nil {
+		t.Fatalf("stripe: valid signature rejected %s", err)
+	}
+
+	// Stripe sends several signatures while secrets are rolled, any may match
+	rolled := parts[0] + ",v1=deadbeef," + parts[1] + ",v0=ignored"
+	if err := Verify(testEvent, rolled, testSecret, now); err != nil {
+		t.Fatalf("stripe: valid signature with others rejected %s", err)
+	}
+
+	tampered := []byte(strings.Replace(string(testEvent), "5000", "1", 1))
+	invalid := []struct {
+		payload []byte
+		header  string
+		secret  string
+		now     time.Time
+	}{
+		{tampered, header, testSecret, now},
+		{testEvent, header, "whsec_other", now},
+		{testEvent, header, testSecret, now.Add(Tolerance + time.Minute)},
+		{testEvent, SignatureHeader(testEvent, testSecret, now.Add(time.Hour)), testSecret, now},
+		{testEvent, "", testSecret, now},
+		{testEvent, "t=abc,v1=00", testSecret, now},
+		{testEvent, parts[0], testSecret, now},
+	}
+	for i, c := range invalid {
+		if Verify(c.payload, c.header, c.secret, c.now) != ErrSignature {
+			t.Errorf("stripe: invalid signature %d accepted", i)
+		}
+	}
+}
+
+// TestParseEvent tests events are parsed only when signed with the webhook secret.
+func TestParseEvent(t *testing.T) {
+	c := New("sk_test", testSecret)
+
+	event, err := c.ParseEvent(testEvent, SignatureHeader(testEvent, testSecret, time.Now()))
+	if err != nil {
+		t.Fatalf("stripe: error parsing event %s", err)
+	}
+	if event.ID != "evt_1" || event.Type != "checkout.session.completed" {
+		t.Fatalf("stripe: wrong event parsed %v", event)
+	}
+
+	session, err := event.Session()
+	if err != nil {
+		t.Fatalf("stripe: error parsing session %s", err)
+	}
+	if session.ID != "cs_test_1" || !session.Paid() || session.AmountTotal != 5000 || session.ClientReferenceID != "7" || session.Metadata["product"] != "jobs" {
+		t.Fatalf("stripe: wrong session parsed %v", session)
+	}
+
+	_, err = c.ParseEvent(testEvent, SignatureHeader(testEvent, "whsec_other", time.Now()))
+	if err == nil {
+		t.Fatalf("stripe: event with wrong signature parsed")
+	}
+
+	_, err = New("sk_test", "").ParseEvent(testEvent, SignatureHeader(testEvent, "", time.Now()))
+	if err == nil {
+		t.Fatalf("stripe: event parsed without webhook secret")
+	}
+}
+
+// TestCreateCheckoutSession tests sessions are created with a local stand-in for the api.
+func TestCreateCheckoutSession(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.URL.Path != "/v1/checkout/sessions" || r.Header.Get("Authorization") != "Bearer sk_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"Invalid API Key provided"}}`))
+			return
+		}
+		if r.Form.Get("line_items[0][price]") != "price_1" || r.Form.Get("line_items[0][quantity]") != "1" ||
+			r.Form.Get("mode") != ModePayment || r.Form.Get("client_reference_id") != "7" || r.Form.Get("metadata[product]") != "jobs" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"Missing params"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"cs_test_1","url":"https://checkout.stripe.com/c/pay/cs_test_1","mode":"payment","payment_status":"unpaid"}`))
+	}))
+	defer api.Close()
+
+	params := CheckoutParams{
+		Price:             "price_1",
+		SuccessURL:        "https://example.com/stripe/thanks",
+		CancelURL:         "https://example.com/stripe/cancel",
+		ClientReferenceID: "7",
+		Metadata:          map[string]string{"product": "jobs"},
+	}
+
+	c := New("sk_test", testSecret)
+	c.BaseURL = api.URL
+	session, err := c.CreateCheckoutSession(params)
+	if err != nil {
+		t.Fatalf("stripe: error creating session %s", err)
+	}
+	if session.ID != "cs_test_1" || session.URL == "" || session.Paid() {
+		t.Fatalf("stripe: wrong session created %v", session)
+	}
+
+	c.Key = "sk_wrong"
+	_, err = c.CreateCheckoutSession(params)
+	if err == nil || !strings.Contains(err.Error(), "Invalid API Key") {
+		t.Fatalf("stripe: expected api error got:%v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package stripe
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Subscription statuses which Stripe considers paid up, or awaiting payment after a failure.
+const (
+	SubscriptionActive   = "active"
+	SubscriptionTrialing = "trialing"
+	SubscriptionPastDue  = "past_due"
+	SubscriptionUnpaid   = "unpaid"
+	SubscriptionCanceled = "canceled"
+)
+
+// Subscription is a recurring payment created by a Checkout session in subscription mode.
+type Subscription struct {
+	ID                string            `json:"id"`
+	Customer          string            `json:"customer"`
+	Status            string            `json:"status"`
+	CurrentPeriodEnd  int64             `json:"current_period_end"`
+	CancelAtPeriodEnd bool              `json:"cancel_at_period_end"`
+	Metadata          map[string]string `json:"metadata"`
+
+	// Items holds the prices subscribed to, recent api versions set the period on these instead
+	Items struct {
+		Data []struct {
+			CurrentPeriodEnd int64 `json:"current_period_end"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// PeriodEnd returns the end of the period paid for, when the subscription renews or ends.
+func (s *Subscription) PeriodEnd() time.Time {
+	end := s.CurrentPeriodEnd
+	if end == 0 && len(s.Items.Data) > 0 {
+		end = s.Items.Data[0].CurrentPeriodEnd
+	}
+	if end == 0 {
+		return time.Time{}
+	}
+	return time.Unix(end, 0).UTC()
+}
+
+// Invoice is a bill for one period of a subscription.
+type Invoice struct {
+	ID            string `json:"id"`
+	Customer      string `json:"customer"`
+	Subscription  string `json:"subscription"`
+	Status        string `json:"status"`
+	BillingReason string `json:"billing_reason"`
+	AmountPaid    int64  `json:"amount_paid"`
+	Currency      string `json:"currency"`
+
+	// SubscriptionDetails holds the metadata of the subscription when the invoice was made
+	SubscriptionDetails struct {
+		Metadata map[string]string `json:"metadata"`
+	} `json:"subscription_details"`
+
+	// Parent is used instead of the fields above by recent api versions
+	Parent struct {
+		SubscriptionDetails struct {
+			Subscription string            `json:"subscription"`
+			Metadata     map[string]string `json:"metadata"`
+		} `json:"subscription_details"`
+	} `json:"parent"`
+}
+
+// SubscriptionID returns the id of the subscription this invoice bills.
+func (i *Invoice) SubscriptionID() string {
+	if i.Subscription != "" {
+		return i.Subscription
+	}
+	return i.Parent.SubscriptionDetails.Subscription
+}
+
+// Metadata returns the metadata of the subscription this invoice bills.
+func (i *Invoice) Metadata() map[string]string {
+	if len(i.SubscriptionDetails.Metadata) > 0 {
+		return i.SubscriptionDetails.Metadata
+	}
+	return i.Parent.SubscriptionDetails.Metadata
+}
+
+// Subscription returns the subscription this event concerns.
+func (e *Event) Subscription() (*Subscription, error) {
+	subscription := &Subscription{}
+	err := json.Unmarshal(e.Data.Object, subscription)
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Invoice returns the invoice this event concerns.
+func (e *Event) Invoice() (*Invoice, error) {
+	invoice := &Invoice{}
+	err := json.Unmarshal(e.Data.Object, invoice)
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// CreatedAt returns the time the event was created, which is used to ignore events received out of order.
+func (e *Event) CreatedAt() time.Time {
+	return time.Unix(e.Created, 0).UTC()
+}
+
+// CancelSubscription cancels the subscription with this id, at the end of the period
+// paid for if atPeriodEnd is true, or otherwise immediately. The change is confirmed by webhook events.
+func (c *Client) CancelSubscription(id string, atPeriodEnd bool) (*Subscription, error) {
+	subscription := &Subscription{}
+	path := "/v1/subscriptions/" + url.PathEscape(id)
+
+	var err error
+	if atPeriodEnd {
+		form := url.Values{}
+		form.Set("cancel_at_period_end", "true")
+		err = c.request(http.MethodPost, path, form, subscription)
+	} else {
+		err = c.request(http.MethodDelete, path, url.Values{}, subscription)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
@@ -0,0 +1,75 @@
+package stripe
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSubscriptionEvents tests subscriptions and invoices are read from events of either api version.
+func TestSubscriptionEvents(t *testing.T) {
+	event := &Event{ID: "evt_1", Type: "customer.subscription.updated", Created: 1700000000}
+	event.Data.Object = []byte(`{"id":"sub_1","customer":"cus_1","status":"active","items":{"data":[{"current_period_end":1702592000}]},"metadata":{"item_id":"3"}}`)
+
+	subscription, err := event.Subscription()
+	if err != nil {
+		t.Fatalf("stripe: error reading subscription %s", err)
+	}
+	if subscription.ID != "sub_1" || subscription.Metadata["item_id"] != "3" || !subscription.PeriodEnd().Equal(time.Unix(1702592000, 0)) {
+		t.Fatalf("stripe: wrong subscription read %v", subscription)
+	}
+	if !event.CreatedAt().Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("stripe: wrong event time %s", event.CreatedAt())
+	}
+
+	invoices := []string{
+		`{"id":"in_1","subscription":"sub_1","subscription_details":{"metadata":{"item_id":"3"}}}`,
+		`{"id":"in_1","parent":{"subscription_details":{"subscription":"sub_1","metadata":{"item_id":"3"}}}}`,
+	}
+	for _, object := range invoices {
+		event.Data.Object = []byte(object)
+		invoice, err := event.Invoice()
+		if err != nil {
+			t.Fatalf("stripe: error reading invoice %s", err)
+		}
+		if invoice.SubscriptionID() != "sub_1" || invoice.Metadata()["item_id"] != "3" {
+			t.Fatalf("stripe: wrong invoice read %s", object)
+		}
+	}
+}
+
+// TestCancelSubscription tests subscriptions are cancelled now or at the end of the period.
+func TestCancelSubscription(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/subscriptions/sub_1":
+			w.Write([]byte(`{"id":"sub_1","status":"canceled"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/subscriptions/sub_1" && r.Form.Get("cancel_at_period_end") == "true":
+			w.Write([]byte(`{"id":"sub_1","status":"active","cancel_at_period_end":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"No such subscription"}}`))
+		}
+	}))
+	defer api.Close()
+
+	c := New("sk_test", testSecret)
+	c.BaseURL = api.URL
+
+	subscription, err := c.CancelSubscription("sub_1", true)
+	if err != nil || !subscription.CancelAtPeriodEnd || subscription.Status != SubscriptionActive {
+		t.Fatalf("stripe: error cancelling at period end %v %v", subscription, err)
+	}
+
+	subscription, err = c.CancelSubscription("sub_1", false)
+	if err != nil || subscription.Status != SubscriptionCanceled {
+		t.Fatalf("stripe: error cancelling now %v %v", subscription, err)
+	}
+
+	_, err = c.CancelSubscription("sub_2", false)
+	if err == nil {
+		t.Fatalf("stripe: cancelled missing subscription")
+	}
+}
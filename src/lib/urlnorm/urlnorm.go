@@ -0,0 +1,198 @@
+// Package urlnorm converts urls to a canonical form, so that links to the same
+// page submitted in different forms can be found as duplicates.
+package urlnorm
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// clickParams are click ids added to links by ad and email platforms, they are removed
+// from every url, as they identify the visitor rather than the page.
+var clickParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+}
+
+// clickPrefixes are prefixes of query params removed from every url.
+var clickPrefixes = []string{"utm_"}
+
+// trackingParams are query params which usually do not change the page shown, but
+// sometimes do, so they are removed only from the canonical url used to find duplicates.
+var trackingParams = map[string]bool{
+	"ref":       true,
+	"ref_src":   true,
+	"ref_url":   true,
+	"referrer":  true,
+	"source":    true,
+	"share":     true,
+	"si":        true,
+	"feature":   true,
+	"hmsr":      true,
+	"spm":       true,
+	"cmpid":     true,
+	"trk":       true,
+	"s_cid":     true,
+	"ncid":      true,
+	"sr_share":  true,
+	"campaign":  true,
+	"partner":   true,
+	"recruiter": true,
+}
+
+// trackingPrefixes are prefixes of query params removed from the canonical url.
+var trackingPrefixes = []string{"pk_", "mtm_", "hsa_", "__s"}
+
+// hostPrefixes are removed from the host, as sites serve the same pages without them.
+var hostPrefixes = []string{"www.", "m.", "mobile.", "amp."}
+
+// Clean returns the url with surrounding space, click ids, utm params and mobile
+// hosts removed, it is suitable for display and for following the link.
+func Clean(raw string) (string, error) {
+	u, err := parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	u.RawQuery = cleanQuery(u.Query(), false).Encode()
+	if u.Host == "m.youtube.com" {
+		u.Host = "www.youtube.com"
+	}
+
+	return u.String(), nil
+}
+
+// Canonical returns the canonical form of the url, used to find duplicates.
+// Scheme, www and mobile hosts, default ports, fragments, trailing slashes,
+// tracking params and the order of query params are all ignored, and
+// short links for well known sites are expanded.
+func Canonical(raw string) (string, error) {
+	u, err := parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	// Fragments rarely identify a different page, unless they are used for routing
+	if !strings.HasPrefix(u.Fragment, "!") && !strings.HasPrefix(u.Fragment, "/") {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	host := u.Hostname()
+	for _, p := range hostPrefixes {
+		host = strings.TrimPrefix(host, p)
+	}
+	// Mobile wikipedia uses en.m.wikipedia.org
+	host = strings.Replace(host, ".m.wikipedia.org", ".wikipedia.org", 1)
+
+	port := u.Port()
+	if port != "" && port != "80" && port != "443" {
+		host = host + ":" + port
+	}
+
+	u.Scheme = "https"
+	u.Host = host
+	u.User = nil
+
+	// Clean the path of duplicate slashes, dot segments and trailing slashes
+	if u.Path != "" {
+		p := path.Clean(u.Path)
+		if p == "/" || p == "." {
+			p = ""
+		}
+		u.Path = p
+		u.RawPath = ""
+	}
+
+	q := cleanQuery(u.Query(), true)
+	expandShortLinks(u, q)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// parse parses an absolute http or https url, lower casing the scheme and host.
+func parse(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("urlnorm: url must be http or https :%s", raw)
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("urlnorm: url has no host :%s", raw)
+	}
+
+	return u, nil
+}
+
+// cleanQuery removes click ids and utm params from the query values,
+// and if all is true, the other params used for tracking.
+func cleanQuery(q url.Values, all bool) url.Values {
+	for k := range q {
+		key := strings.ToLower(k)
+		if clickParams[key] || hasPrefix(key, clickPrefixes) {
+			q.Del(k)
+			continue
+		}
+		if all && (trackingParams[key] || hasPrefix(key, trackingPrefixes)) {
+			q.Del(k)
+		}
+	}
+	return q
+}
+
+// hasPrefix returns true if the key starts with one of these prefixes.
+func hasPrefix(key string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandShortLinks rewrites short and alternative links for the same video
+// to the standard youtube watch url, keeping only the video id.
+func expandShortLinks(u *url.URL, q url.Values) {
+	var id string
+	switch u.Host {
+	case "youtu.be":
+		id = strings.TrimPrefix(u.Path, "/")
+	case "youtube.com", "youtube-nocookie.com":
+		switch {
+		case u.Path == "/watch":
+			id = q.Get("v")
+		case strings.HasPrefix(u.Path, "/embed/"), strings.HasPrefix(u.Path, "/shorts/"), strings.HasPrefix(u.Path, "/live/"), strings.HasPrefix(u.Path, "/v/"):
+			id = path.Base(u.Path)
+		}
+	default:
+		return
+	}
+
+	if id == "" || strings.Contains(id, "/") {
+		return
+	}
+
+	u.Host = "youtube.com"
+	u.Path = "/watch"
+	for k := range q {
+		delete(q, k)
+	}
+	q.Set("v", id)
+}
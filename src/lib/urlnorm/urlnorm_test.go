@@ -0,0 +1,99 @@
+// Tests for the urlnorm package
+package urlnorm
+
+import (
+	"testing"
+)
+
+var canonicalTests = []struct {
+	in  string
+	out string
+}{
+	// Scheme, host case, www and default ports
+	{"http://example.com/a", "https://example.com/a"},
+	{"HTTPS://WWW.Example.COM/a", "https://example.com/a"},
+	{"https://example.com:443/a", "https://example.com/a"},
+	{"http://example.com:80/a", "https://example.com/a"},
+	{"http://example.com:8080/a", "https://example.com:8080/a"},
+	{"  https://example.com/a  ", "https://example.com/a"},
+
+	// Paths keep their case, but lose trailing and duplicate slashes
+	{"https://example.com/", "https://example.com"},
+	{"https://example.com", "https://example.com"},
+	{"https://example.com/Blog/Post/", "https://example.com/Blog/Post"},
+	{"https://example.com//blog/./post", "https://example.com/blog/post"},
+
+	// Fragments are dropped unless used for routing
+	{"https://medium.com/post-123#abcd", "https://medium.com/post-123"},
+	{"https://example.com/app#!/page", "https://example.com/app#!/page"},
+
+	// Tracking params are removed, and others sorted
+	{"https://example.com/a?utm_source=x&utm_medium=y", "https://example.com/a"},
+	{"https://example.com/a?fbclid=123", "https://example.com/a"},
+	{"https://example.com/a?ref=hn&id=2", "https://example.com/a?id=2"},
+	{"https://example.com/a?b=2&a=1", "https://example.com/a?a=1&b=2"},
+	{"https://example.com/a?a=1&b=2&UTM_Campaign=z", "https://example.com/a?a=1&b=2"},
+
+	// Mobile hosts
+	{"https://m.example.com/a", "https://example.com/a"},
+	{"https://en.m.wikipedia.org/wiki/Go", "https://en.wikipedia.org/wiki/Go"},
+
+	// YouTube links for the same video
+	{"https://youtu.be/sZx3oZt7LVg", "https://youtube.com/watch?v=sZx3oZt7LVg"},
+	{"https://youtu.be/sZx3oZt7LVg?si=abc&t=10", "https://youtube.com/watch?v=sZx3oZt7LVg"},
+	{"https://m.youtube.com/watch?v=sZx3oZt7LVg", "https://youtube.com/watch?v=sZx3oZt7LVg"},
+	{"https://www.youtube.com/watch?feature=share&v=sZx3oZt7LVg", "https://youtube.com/watch?v=sZx3oZt7LVg"},
+	{"https://www.youtube.com/embed/sZx3oZt7LVg", "https://youtube.com/watch?v=sZx3oZt7LVg"},
+	{"https://www.youtube.com/shorts/sZx3oZt7LVg", "https://youtube.com/watch?v=sZx3oZt7LVg"},
+	{"https://www.youtube.com/channel/abc", "https://youtube.com/channel/abc"},
+}
+
+// TestCanonical tests urls are converted to their canonical form
+func TestCanonical(t *testing.T) {
+	for _, test := range canonicalTests {
+		got, err := Canonical(test.in)
+		if err != nil {
+			t.Fatalf("urlnorm: Canonical failed for %s :%s", test.in, err)
+		}
+		if got != test.out {
+			t.Errorf("urlnorm: Canonical wrong for %s\n\twanted:%s\n\tgot:%s", test.in, test.out, got)
+		}
+	}
+}
+
+// TestCanonicalInvalid tests urls which are not http are rejected
+func TestCanonicalInvalid(t *testing.T) {
+	for _, in := range []string{"", "example.com/a", "ftp://example.com", "javascript:alert(1)", "https://", "https:///path"} {
+		_, err := Canonical(in)
+		if err == nil {
+			t.Errorf("urlnorm: Canonical accepted invalid url %s", in)
+		}
+	}
+}
+
+var cleanTests = []struct {
+	in  string
+	out string
+}{
+	{"https://example.com/a/?utm_source=x", "https://example.com/a/"},
+	{"https://example.com/a?id=2&fbclid=3#section", "https://example.com/a?id=2#section"},
+	{"https://m.youtube.com/watch?v=sZx3oZt7LVg", "https://www.youtube.com/watch?v=sZx3oZt7LVg"},
+	{"HTTP://Example.com/Path", "http://example.com/Path"},
+
+	// Params which may change the page are kept in the link, though not in the canonical url
+	{"https://example.com/a?ref=v2&source=docs&utm_medium=y", "https://example.com/a?ref=v2&source=docs"},
+	{"https://example.com/jobs?partner=acme&campaign=go&si=1", "https://example.com/jobs?campaign=go&partner=acme&si=1"},
+}
+
+// TestClean tests click ids and utm params are removed from urls for display
+func TestClean(t *testing.T) {
+	for _, test := range cleanTests {
+		got, err := Clean(test.in)
+		if err != nil {
+			t.Fatalf("urlnorm: Clean failed for %s :%s", test.in, err)
+		}
+		if got != test.out {
+			t.Errorf("urlnorm: Clean wrong for %s\n\twanted:%s\n\tgot:%s", test.in, test.out, got)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package membershipactions
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+	"github.com/kennygrant/gohackernews/src/memberships"
+	"github.com/kennygrant/gohackernews/src/payments"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// api is a local stand-in for the Stripe api, which cancels the subscription sub_test at the end of the period.
+var api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if r.URL.Path != "/v1/subscriptions/sub_test" || r.Form.Get("cancel_at_period_end") != "true" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"Invalid request"}}`))
+		return
+	}
+	w.Write([]byte(`{"id":"sub_test","status":"active","cancel_at_period_end":true}`))
+}))
+
+// TestSetup performs setup for integration tests
+// using the test database, real views, mock authorisation and a stand-in for stripe
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(3)
+	if err != nil {
+		fmt.Printf("memberships: Setup db failed %s", err)
+	}
+
+	// Set up mock auth, readers may become supporters and cancel their own memberships as in app
+	resource.SetupAuthorisation()
+	can.Authorise(10, can.CreateResource, memberships.TableName)
+	can.AuthoriseOwner(10, can.UpdateResource, memberships.TableName)
+
+	// Load templates for rendering
+	resource.SetupView(3)
+
+	router := mux.New()
+	mux.SetDefault(router)
+
+	router.Add("/memberships", nil)
+	router.Add("/memberships/create", nil).Post()
+	router.Add("/memberships/{id:\\d+}/cancel", nil).Post()
+
+	// Point stripe at the local stand-in, and register only the supporter tier as a product
+	stripe.Default = stripe.New("sk_test", "whsec_test")
+	stripe.Default.BaseURL = api.URL
+	payments.Register(&payments.Product{
+		Name:        users.TierName(users.TierSupporter),
+		Description: "supporter membership",
+		Price:       "price_test_supporter",
+		Mode:        stripe.ModeSubscription,
+		Find: func(id int64) (payments.Item, error) {
+			return memberships.Find(id)
+		},
+	})
+
+	// Delete all memberships to ensure we get consistent results
+	query.ExecSQL("delete from memberships;")
+	query.ExecSQL("ALTER SEQUENCE memberships_id_seq RESTART WITH 1;")
+
+	// Delete all users to ensure we get consistent results
+	_, err = query.ExecSQL("delete from users;")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	// Insert a test admin user, a supporter and another reader
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(1,'example@example.com','admin',100,100,100,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(2,'example2@example.com','supporter',100,100,10,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(3,'example3@example.com','reader',100,100,10,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	query.ExecSQL("ALTER SEQUENCE users_id_seq RESTART WITH 4;")
+}
+
+// testCreate posts a request to become a supporter of this tier as this user.
+func testCreate(t *testing.T, id int, tier int64) (*httptest.ResponseRecorder, error) {
+	form := url.Values{}
+	form.Add("tier", fmt.Sprintf("%d", tier))
+	form.Add("status", "100")
+	body := strings.NewReader(form.Encode())
+
+	r := httptest.NewRequest("POST", "/memberships/create", body)
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	err := resource.AddUserSessionCookie(w, r, id)
+	if err != nil {
+		t.Fatalf("membershipactions: error setting session %s", err)
+	}
+
+	return w, HandleCreate(w, r)
+}
+
+// Test POST /memberships/create
+func TestCreateMemberships(t *testing.T) {
+
+	// Tiers without a price may not be bought
+	_, err := testCreate(t, 2, users.TierPatron)
+	if err == nil {
+		t.Fatalf("membershipactions: membership created for tier without price")
+	}
+
+	w, err := testCreate(t, 2, users.TierSupporter)
+	if err != nil {
+		t.Fatalf("membershipactions: error handling HandleCreate %s", err)
+	}
+
+	// Test we are sent on to pay for the membership
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/stripe/pay?product=supporter&item_id=1" {
+		t.Fatalf("membershipactions: unexpected response for HandleCreate got:%d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	// Check the membership awaits payment by the supporter, whatever the params said
+	m, err := memberships.Find(1)
+	if err != nil || !m.Payable() || m.UserID != 2 || m.Tier != users.TierSupporter {
+		t.Fatalf("membershipactions: error with created membership values: %v %v", m, err)
+	}
+
+	// Once paid, the supporter may not start a second membership
+	err = m.Fulfil()
+	if err != nil {
+		t.Fatalf("membershipactions: error fulfilling membership %s", err)
+	}
+	_, err = testCreate(t, 2, users.TierSupporter)
+	if err == nil {
+		t.Fatalf("membershipactions: second membership created for supporter")
+	}
+}
+
+// Test POST /memberships/1/cancel
+func TestCancelMemberships(t *testing.T) {
+
+	_, err := query.ExecSQL("update memberships set subscription_id='sub_test' where id=1;")
+	if err != nil {
+		t.Fatalf("membershipactions: error setting subscription %s", err)
+	}
+
+	// Only the supporter may cancel their membership
+	for _, id := range []int{3, 2} {
+		r := httptest.NewRequest("POST", "/memberships/1/cancel", nil)
+		w := httptest.NewRecorder()
+
+		err = resource.AddUserSessionCookie(w, r, id)
+		if err != nil {
+			t.Fatalf("membershipactions: error setting session %s", err)
+		}
+
+		err = HandleCancel(w, r)
+		if id == 3 {
+			if err == nil {
+				t.Fatalf("membershipactions: user allowed to cancel membership of another user")
+			}
+			continue
+		}
+
+		if err != nil || w.Code != http.StatusFound {
+			t.Fatalf("membershipactions: error handling HandleCancel %s", err)
+		}
+	}
+}
+
+// Test GET /memberships
+func TestIndexMemberships(t *testing.T) {
+
+	// Admins see all memberships, readers only their own
+	for id, count := range map[int]int{1: 1, 2: 1, 3: 0} {
+		r := httptest.NewRequest("GET", "/memberships", nil)
+		w := httptest.NewRecorder()
+
+		err := resource.AddUserSessionCookie(w, r, id)
+		if err != nil {
+			t.Fatalf("membershipactions: error setting session %s", err)
+		}
+
+		err = HandleIndex(w, r)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("membershipactions: error handling HandleIndex %s", err)
+		}
+
+		pattern := "<td>Supporter</td>"
+		if strings.Count(w.Body.String(), pattern) != count {
+			t.Fatalf("membershipactions: unexpected response for HandleIndex user:%d expected:%d of %s got:%s", id, count, pattern, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package membershipactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+	"github.com/kennygrant/gohackernews/src/memberships"
+)
+
+// HandleCancel responds to POST /memberships/{id}/cancel by cancelling the subscription
+// at the end of the period paid for. The membership is updated by the webhook events which follow.
+func HandleCancel(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the membership
+	membership, err := memberships.Find(params.GetInt(memberships.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - users may cancel only their own memberships
+	currentUser := session.CurrentUser(w, r)
+	err = can.Update(membership, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	if !membership.Cancellable() {
+		return server.NotAuthorizedError(nil, "Sorry", "This membership is not active, so cannot be cancelled.")
+	}
+
+	_, err = stripe.Default.CancelSubscription(membership.SubscriptionID, true)
+	if err != nil {
+		return server.InternalError(err, "Payment Error", "Sorry, we were unable to cancel your membership, please try again later.")
+	}
+
+	// Show the membership as ending now, the webhook event which follows records the same
+	err = membership.Update(map[string]string{"cancelling": "true"})
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	log.Info(log.V{"msg": "Cancelled membership", "membership_id": membership.ID, "user_id": currentUser.ID})
+
+	return server.Redirect(w, r, "/memberships")
+}
@@ -0,0 +1,78 @@
+package membershipactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/memberships"
+	"github.com/kennygrant/gohackernews/src/payments"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// HandleCreate responds to POST /memberships/create by starting a membership of a tier,
+// and sending the user on to pay for it.
+func HandleCreate(w http.ResponseWriter, r *http.Request) error {
+
+	membership := memberships.New()
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise
+	currentUser := session.CurrentUser(w, r)
+	err = can.Create(membership, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Check the tier may be bought
+	tier := params.GetInt("tier")
+	if users.TierName(tier) == "" || !payments.Enabled(users.TierName(tier)) {
+		return server.NotFoundError(nil, "Not Available", "Sorry, that membership is not available at present.")
+	}
+
+	// Users may change tier by cancelling their membership, but may not hold two at once
+	count, err := memberships.Supporting().Where("user_id=?", currentUser.ID).Count()
+	if err != nil {
+		return server.InternalError(err)
+	}
+	if count > 0 {
+		return server.NotAuthorizedError(nil, "Already a Supporter", "Thank you, you already have a membership. To change tier, please cancel it first.")
+	}
+
+	membershipParams := map[string]string{
+		"status":  fmt.Sprintf("%d", memberships.Pending),
+		"user_id": fmt.Sprintf("%d", currentUser.ID),
+		"tier":    fmt.Sprintf("%d", tier),
+	}
+	ID, err := membership.Create(membershipParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log creation
+	log.Info(log.V{"msg": "Created membership", "membership_id": ID, "user_id": currentUser.ID, "tier": tier})
+
+	// Redirect to payment for the new membership
+	membership, err = memberships.Find(ID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	return server.Redirect(w, r, membership.PayURL())
+}
@@ -0,0 +1,77 @@
+package membershipactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/karma"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/memberships"
+	"github.com/kennygrant/gohackernews/src/payments"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// listLimit is the number of memberships shown per page
+const listLimit = 50
+
+// tier describes a tier of membership for display.
+type tier struct {
+	Tier    int64
+	Name    string
+	Enabled bool
+}
+
+// HandleIndex responds to GET /memberships by describing the tiers of membership, and
+// showing users their own memberships. Admins see all memberships.
+func HandleIndex(w http.ResponseWriter, r *http.Request) error {
+
+	// No Authorisation - anyone may read about memberships
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	var tiers []tier
+	for _, t := range users.Tiers() {
+		tiers = append(tiers, tier{Tier: t, Name: users.TierDisplay(t), Enabled: payments.Enabled(users.TierName(t))})
+	}
+
+	// Show only their own memberships to users, and none to anon users
+	currentUser := session.CurrentUser(w, r)
+	q := memberships.Query().Limit(listLimit)
+	if !currentUser.Admin() {
+		q.Where("user_id=?", currentUser.ID)
+	}
+
+	// Set the offset in pages if we have one
+	page := params.GetInt("page")
+	if page > 0 {
+		q.Offset(listLimit * int(page))
+	}
+
+	var results []*memberships.Membership
+	if !currentUser.Anon() {
+		results, err = memberships.FindAll(q)
+		if err != nil {
+			return server.InternalError(err)
+		}
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.Template("memberships/views/index.html.got")
+	view.AddKey("tiers", tiers)
+	view.AddKey("memberships", results)
+	view.AddKey("page", page)
+	view.AddKey("more", len(results) == listLimit)
+	view.AddKey("storyLimit", karma.Current.StoryLimit(false))
+	view.AddKey("supporterStoryLimit", karma.Current.StoryLimit(true))
+	view.AddKey("meta_title", "Support Golang News")
+	view.AddKey("currentUser", currentUser)
+	return view.Render()
+}
@@ -0,0 +1,151 @@
+package memberships
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+)
+
+// This file contains the handling of webhook events for the subscriptions which renew memberships.
+// The first payment is taken at checkout, and fulfilled like other purchases by the payments package.
+
+// Subscription and invoice events handled by Process.
+const (
+	EventCreated       = "customer.subscription.created"
+	EventUpdated       = "customer.subscription.updated"
+	EventCancelled     = "customer.subscription.deleted"
+	EventRenewed       = "invoice.paid"
+	EventPaymentFailed = "invoice.payment_failed"
+)
+
+// ErrUnknownMembership is returned for events about subscriptions which are not for a membership.
+var ErrUnknownMembership = errors.New("memberships: event for unknown membership")
+
+// Process applies a verified webhook event to the membership it concerns, and sets the supporter
+// tier of the user. Events may be sent more than once or out of order, so events older than the
+// last applied are ignored, cancelled memberships never change, and events of other types are ignored.
+func Process(event *stripe.Event) error {
+
+	var membership *Membership
+	var applied bool
+	var err error
+
+	switch event.Type {
+	case EventCreated, EventUpdated, EventCancelled:
+		var subscription *stripe.Subscription
+		subscription, err = event.Subscription()
+		if err != nil {
+			return err
+		}
+		membership, err = findMembership(subscription.Metadata, subscription.ID)
+		if err != nil {
+			return err
+		}
+		applied, err = membership.applySubscription(event, subscription)
+
+	case EventRenewed, EventPaymentFailed:
+		var invoice *stripe.Invoice
+		invoice, err = event.Invoice()
+		if err != nil {
+			return err
+		}
+		membership, err = findMembership(invoice.Metadata(), invoice.SubscriptionID())
+		if err != nil {
+			return err
+		}
+		applied, err = membership.applyInvoice(event, invoice)
+
+	default:
+		return nil
+	}
+
+	if err != nil || !applied {
+		return err
+	}
+
+	return SyncUser(membership.UserID)
+}
+
+// applySubscription records the status and period of the subscription for this membership,
+// it returns false if the event was not applied.
+func (m *Membership) applySubscription(event *stripe.Event, subscription *stripe.Subscription) (bool, error) {
+
+	status := Pending
+	switch subscription.Status {
+	case stripe.SubscriptionActive, stripe.SubscriptionTrialing:
+		status = Active
+	case stripe.SubscriptionPastDue:
+		status = PastDue
+	case stripe.SubscriptionUnpaid:
+		status = Lapsed
+	case stripe.SubscriptionCanceled:
+		status = Cancelled
+	}
+	if event.Type == EventCancelled {
+		status = Cancelled
+	}
+
+	// Subscriptions awaiting their first payment at checkout are left pending
+	if status == Pending {
+		return false, nil
+	}
+
+	sql := `update memberships set status=$1, subscription_id=$2, customer_id=$3, renews_at=$4, cancelling=$5, event_at=$6, updated_at=$7
+	where id=$8 and status<>$9 and (event_at IS NULL OR event_at <= $6)`
+	return m.transition(sql, status, subscription.ID, subscription.Customer, query.TimeString(subscription.PeriodEnd()), subscription.CancelAtPeriodEnd,
+		query.TimeString(event.CreatedAt()), m.now(), m.ID, Cancelled)
+}
+
+// applyInvoice records a payment or failed payment for a period of this membership,
+// it returns false if the event was not applied.
+func (m *Membership) applyInvoice(event *stripe.Event, invoice *stripe.Invoice) (bool, error) {
+
+	// A renewal makes a lapsed membership active again, a failure leaves a membership supporting while payment is retried
+	status, from := int64(Active), int64(Lapsed)
+	if event.Type == EventPaymentFailed {
+		status, from = PastDue, PastDue
+	}
+
+	sql := `update memberships set status=$1, customer_id=$2, event_at=$3, updated_at=$4
+	where id=$5 and status>=$6 and (event_at IS NULL OR event_at <= $3)`
+	return m.transition(sql, status, invoice.Customer, query.TimeString(event.CreatedAt()), m.now(), m.ID, from)
+}
+
+// findMembership returns the membership for a subscription, from the metadata set at checkout,
+// or from the subscription id recorded by earlier events.
+func findMembership(metadata map[string]string, subscriptionID string) (*Membership, error) {
+
+	id, err := strconv.ParseInt(metadata["item_id"], 10, 64)
+	if err == nil {
+		m, err := Find(id)
+		if err == nil && m.ProductName() == metadata["product"] && fmt.Sprintf("%d", m.UserID) == metadata["user_id"] {
+			return m, nil
+		}
+	}
+
+	if subscriptionID != "" {
+		m, err := FindFirst("subscription_id=?", subscriptionID)
+		if err == nil {
+			return m, nil
+		}
+	}
+
+	return nil, ErrUnknownMembership
+}
+
+// transition executes an update of status, and returns true if it changed this membership.
+func (m *Membership) transition(sql string, args ...interface{}) (bool, error) {
+	result, err := query.Exec(sql, args...)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
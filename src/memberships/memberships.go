@@ -0,0 +1,175 @@
+// Package memberships represents recurring supporter memberships,
+// paid for with Stripe subscriptions
+package memberships
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// Status values valid in the status field of memberships. Memberships are paid for at checkout,
+// then follow the status of their subscription as webhook events are received.
+const (
+	Pending   = 0
+	Cancelled = 10
+	Lapsed    = 20
+	PastDue   = 50
+	Active    = 100
+)
+
+// Membership is a supporter membership of a tier, renewed each period by a Stripe subscription.
+type Membership struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	Status int64
+
+	// The supporter, and the tier they pay for
+	UserID int64
+	Tier   int64
+
+	// Stripe ids for the subscription and the customer paying for it
+	SubscriptionID string
+	CustomerID     string
+
+	// RenewsAt is the end of the period paid for, Cancelling is set if the membership ends then
+	RenewsAt   time.Time
+	Cancelling bool
+
+	// EventAt is the time of the last webhook event applied, older events are ignored
+	EventAt time.Time
+}
+
+// PayURL returns the url of the page used to pay for this membership.
+func (m *Membership) PayURL() string {
+	return fmt.Sprintf("/stripe/pay?product=%s&item_id=%d", m.ProductName(), m.ID)
+}
+
+// CancelURL returns the url used to cancel this membership.
+func (m *Membership) CancelURL() string {
+	return fmt.Sprintf("/memberships/%d/cancel", m.ID)
+}
+
+// ProductName returns the name of the product bought for this membership, which is the name of its tier.
+func (m *Membership) ProductName() string {
+	return users.TierName(m.Tier)
+}
+
+// TierDisplay returns the tier of this membership for display.
+func (m *Membership) TierDisplay() string {
+	return users.TierDisplay(m.Tier)
+}
+
+// StatusDisplay returns a description of the status of this membership.
+func (m *Membership) StatusDisplay() string {
+	switch m.Status {
+	case Pending:
+		return "Awaiting payment"
+	case Cancelled:
+		return "Cancelled"
+	case Lapsed:
+		return "Lapsed"
+	case PastDue:
+		return "Payment failed"
+	case Active:
+		if m.Cancelling {
+			return "Ending"
+		}
+		return "Active"
+	}
+	return ""
+}
+
+// OwnedBy returns true if this user id pays for this membership.
+func (m *Membership) OwnedBy(uid int64) bool {
+	return uid == m.UserID
+}
+
+// Payable returns true if the membership may be paid for now, memberships are paid
+// for only once at checkout and are then renewed by their subscription.
+func (m *Membership) Payable() bool {
+	return m.Status == Pending
+}
+
+// Supporting returns true if this membership gives the user a supporter badge. Memberships
+// are still supporting while Stripe retries a failed payment.
+func (m *Membership) Supporting() bool {
+	return m.Status >= PastDue
+}
+
+// Cancellable returns true if the subscription of this membership may be cancelled.
+func (m *Membership) Cancellable() bool {
+	return m.Supporting() && m.SubscriptionID != "" && !m.Cancelling
+}
+
+// Fulfil activates the membership once the first payment is received at checkout,
+// it may be called again without effect.
+func (m *Membership) Fulfil() error {
+	_, err := query.Exec("update memberships set status=$1, updated_at=$2 where id=$3 and status=$4", Active, m.now(), m.ID, Pending)
+	if err != nil {
+		return err
+	}
+	return SyncUser(m.UserID)
+}
+
+// Cancel marks the membership cancelled, it is used when the subscription is cancelled
+// as the user is deleted, after which webhook events cannot be applied to the user.
+func (m *Membership) Cancel() error {
+	_, err := query.Exec("update memberships set status=$1, cancelling=false, updated_at=$2 where id=$3", Cancelled, m.now(), m.ID)
+	if err != nil {
+		return err
+	}
+	return SyncUser(m.UserID)
+}
+
+// CancelUser cancels the subscriptions of all this user's memberships immediately,
+// it is called before the user is deleted.
+func CancelUser(userID int64) error {
+	results, err := FindAll(Supporting().Where("user_id=?", userID))
+	if err != nil {
+		return err
+	}
+
+	for _, m := range results {
+		if m.SubscriptionID != "" {
+			_, err = stripe.Default.CancelSubscription(m.SubscriptionID, false)
+			if err != nil {
+				return err
+			}
+		}
+		err = m.Cancel()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SyncUser sets the supporter tier of this user to the highest tier of their supporting memberships.
+func SyncUser(userID int64) error {
+	results, err := FindAll(Supporting().Where("user_id=?", userID))
+	if err != nil {
+		return err
+	}
+
+	var tier int64
+	for _, m := range results {
+		if m.Tier > tier {
+			tier = m.Tier
+		}
+	}
+
+	return users.SetSupporter(userID, tier)
+}
+
+// now returns the current time for updates.
+func (m *Membership) now() string {
+	return query.TimeString(time.Now().UTC())
+}
@@ -0,0 +1,141 @@
+// Tests for the memberships package
+package memberships
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+var userID int64
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("memberships: Setup db failed %s", err)
+	}
+
+	query.ExecSQL("delete from memberships;")
+	query.ExecSQL("delete from users where email='supporter_test@example.com';")
+
+	userID, err = users.New().Create(map[string]string{"name": "supporter_test", "email": "supporter_test@example.com", "points": "1"})
+	if err != nil {
+		t.Fatalf("memberships: error creating user %s", err)
+	}
+}
+
+// testEvent returns an event of this type for the object given, created at the time given.
+func testEvent(t *testing.T, id string, eventType string, created time.Time, object interface{}) *stripe.Event {
+	data, err := json.Marshal(object)
+	if err != nil {
+		t.Fatalf("memberships: error building event %s", err)
+	}
+	event := &stripe.Event{ID: id, Type: eventType, Created: created.Unix()}
+	event.Data.Object = data
+	return event
+}
+
+// testSupporter returns the user's supporter tier
+func testSupporter(t *testing.T) int64 {
+	user, err := users.Find(userID)
+	if err != nil {
+		t.Fatalf("memberships: error finding user %s", err)
+	}
+	if user.Supporter != (user.SupporterTier > 0) {
+		t.Fatalf("memberships: supporter flag does not match tier %d", user.SupporterTier)
+	}
+	return user.SupporterTier
+}
+
+// Test memberships are activated at checkout, and follow their subscription
+func TestProcess(t *testing.T) {
+
+	params := map[string]string{
+		"user_id": fmt.Sprintf("%d", userID),
+		"tier":    fmt.Sprintf("%d", users.TierPatron),
+	}
+	id, err := New().Create(params)
+	if err != nil {
+		t.Fatalf("memberships: Create failed :%s", err)
+	}
+
+	m, err := Find(id)
+	if err != nil || !m.Payable() || m.ProductName() != "patron" {
+		t.Fatalf("memberships: Create membership not payable %v", err)
+	}
+
+	// Paid memberships are fulfilled at checkout
+	err = m.Fulfil()
+	if err != nil || testSupporter(t) != users.TierPatron {
+		t.Fatalf("memberships: Fulfil failed %v", err)
+	}
+
+	now := time.Now().UTC()
+	subscription := map[string]interface{}{
+		"id":                 "sub_test",
+		"customer":           "cus_test",
+		"status":             "active",
+		"current_period_end": now.AddDate(0, 1, 0).Unix(),
+		"metadata": map[string]string{
+			"item_id": fmt.Sprintf("%d", id),
+			"user_id": fmt.Sprintf("%d", userID),
+			"product": "patron",
+		},
+	}
+	err = Process(testEvent(t, "evt_1", EventCreated, now, subscription))
+	if err != nil {
+		t.Fatalf("memberships: Process created failed :%s", err)
+	}
+	m, err = Find(id)
+	if err != nil || m.SubscriptionID != "sub_test" || m.Status != Active || !m.Cancellable() {
+		t.Fatalf("memberships: subscription not recorded %v", err)
+	}
+
+	// Failed payments leave the badge while payment is retried, and renewals restore the membership
+	invoice := map[string]interface{}{"id": "in_test", "subscription": "sub_test"}
+	err = Process(testEvent(t, "evt_2", EventPaymentFailed, now.Add(time.Minute), invoice))
+	m, _ = Find(id)
+	if err != nil || m.Status != PastDue || testSupporter(t) != users.TierPatron {
+		t.Fatalf("memberships: payment failure not recorded %v", err)
+	}
+	err = Process(testEvent(t, "evt_3", EventRenewed, now.Add(2*time.Minute), invoice))
+	m, _ = Find(id)
+	if err != nil || m.Status != Active {
+		t.Fatalf("memberships: renewal not recorded %v", err)
+	}
+
+	// Events older than the last applied are ignored
+	err = Process(testEvent(t, "evt_2", EventPaymentFailed, now.Add(time.Minute), invoice))
+	m, _ = Find(id)
+	if err != nil || m.Status != Active {
+		t.Fatalf("memberships: old event applied %v", err)
+	}
+
+	// Cancelled memberships remove the badge, and are never changed again
+	subscription["status"] = "canceled"
+	err = Process(testEvent(t, "evt_4", EventCancelled, now.Add(3*time.Minute), subscription))
+	if err != nil || testSupporter(t) != users.TierNone {
+		t.Fatalf("memberships: cancellation not recorded %v", err)
+	}
+	err = Process(testEvent(t, "evt_5", EventRenewed, now.Add(4*time.Minute), invoice))
+	m, _ = Find(id)
+	if err != nil || m.Status != Cancelled {
+		t.Fatalf("memberships: cancelled membership changed %v", err)
+	}
+
+	// Events for other subscriptions, or of other types, are not applied
+	invoice["subscription"] = "sub_other"
+	if Process(testEvent(t, "evt_6", EventRenewed, now, invoice)) != ErrUnknownMembership {
+		t.Fatalf("memberships: event for other subscription applied")
+	}
+	if Process(testEvent(t, "evt_7", "invoice.created", now, invoice)) != nil {
+		t.Fatalf("memberships: event of other type not ignored")
+	}
+}
@@ -0,0 +1,101 @@
+package memberships
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "memberships"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "created_at desc, id desc"
+)
+
+// NewWithColumns creates a new membership instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Membership {
+
+	membership := New()
+	membership.ID = resource.ValidateInt(cols["id"])
+	membership.CreatedAt = resource.ValidateTime(cols["created_at"])
+	membership.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	membership.Status = resource.ValidateInt(cols["status"])
+	membership.UserID = resource.ValidateInt(cols["user_id"])
+	membership.Tier = resource.ValidateInt(cols["tier"])
+	membership.SubscriptionID = resource.ValidateString(cols["subscription_id"])
+	membership.CustomerID = resource.ValidateString(cols["customer_id"])
+	membership.RenewsAt = resource.ValidateTime(cols["renews_at"])
+	membership.Cancelling = resource.ValidateBoolean(cols["cancelling"])
+	membership.EventAt = resource.ValidateTime(cols["event_at"])
+
+	return membership
+}
+
+// New creates and initialises a new membership instance.
+func New() *Membership {
+	membership := &Membership{}
+	membership.CreatedAt = time.Now()
+	membership.UpdatedAt = time.Now()
+	membership.TableName = TableName
+	membership.KeyName = KeyName
+	membership.Status = Pending
+	return membership
+}
+
+// FindFirst fetches a single membership record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Membership, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single membership record from the database by id.
+func Find(id int64) (*Membership, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all membership records matching this query from the database.
+func FindAll(q *query.Query) ([]*Membership, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of memberships constructed from the results
+	var memberships []*Membership
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		memberships = append(memberships, p)
+	}
+
+	return memberships, nil
+}
+
+// Query returns a new query for memberships with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for memberships with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
+
+// Supporting returns a query for memberships which give their user a supporter badge.
+func Supporting() *query.Query {
+	return Where("status >= ?", PastDue)
+}
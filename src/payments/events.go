@@ -0,0 +1,84 @@
+package payments
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+)
+
+// This file contains the handling of webhook events, which is the only way payments are fulfilled.
+
+// Checkout session events handled by Process.
+const (
+	EventCompleted      = "checkout.session.completed"
+	EventAsyncSucceeded = "checkout.session.async_payment_succeeded"
+	EventAsyncFailed    = "checkout.session.async_payment_failed"
+	EventExpired        = "checkout.session.expired"
+)
+
+// ErrUnknownPayment is returned for events about sessions which are not in the ledger.
+var ErrUnknownPayment = errors.New("payments: event for unknown payment")
+
+// Process applies a verified webhook event to the ledger, fulfilling payments which are paid.
+// Events may be sent more than once or out of order, so each change is made at most once,
+// and events of other types are ignored.
+func Process(event *stripe.Event) error {
+
+	switch event.Type {
+	case EventCompleted, EventAsyncSucceeded, EventAsyncFailed, EventExpired:
+	default:
+		return nil
+	}
+
+	session, err := event.Session()
+	if err != nil {
+		return err
+	}
+
+	payment, err := findSessionPayment(session)
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case EventAsyncFailed:
+		_, err = payment.MarkUnpaid(Failed, event.ID)
+		return err
+	case EventExpired:
+		_, err = payment.MarkUnpaid(Expired, event.ID)
+		return err
+	}
+
+	// Completed sessions for delayed payment methods are paid later, with EventAsyncSucceeded
+	if !session.Paid() {
+		return nil
+	}
+
+	_, err = payment.MarkPaid(session, event.ID)
+	if err != nil {
+		return err
+	}
+
+	// Fetch the payment again, as it may have been fulfilled by an earlier event
+	payment, err = Find(payment.ID)
+	if err != nil {
+		return err
+	}
+
+	return payment.Fulfil()
+}
+
+// findSessionPayment returns the payment in the ledger for this session, which must
+// match both the client reference and the session id recorded at checkout.
+func findSessionPayment(session *stripe.Session) (*Payment, error) {
+	id, err := strconv.ParseInt(session.ClientReferenceID, 10, 64)
+	if err != nil {
+		return nil, ErrUnknownPayment
+	}
+	payment, err := Find(id)
+	if err != nil || payment.SessionID != session.ID {
+		return nil, ErrUnknownPayment
+	}
+	return payment, nil
+}
@@ -0,0 +1,140 @@
+// Package payments represents the ledger of payments taken through Stripe Checkout
+package payments
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+)
+
+// Status values valid in the status field of payments.
+const (
+	Pending   = 0
+	Failed    = 10
+	Expired   = 20
+	Paid      = 50
+	Fulfilled = 100
+)
+
+// Payment records a Checkout session created for a user to buy an item, and what became of it.
+type Payment struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	Status int64
+
+	// The user who is paying
+	UserID int64
+
+	// Product is the name of the product bought, ItemID the item it was bought for (such as a job)
+	Product string
+	ItemID  int64
+
+	// Amount is in the smallest unit of Currency (e.g. cents), as charged by Stripe
+	Amount   int64
+	Currency string
+
+	// Stripe ids for the Checkout session, its payment, and the last webhook event applied
+	SessionID     string
+	PaymentIntent string
+	EventID       string
+
+	FulfilledAt time.Time
+}
+
+// StatusDisplay returns a description of the status of this payment.
+func (p *Payment) StatusDisplay() string {
+	switch p.Status {
+	case Pending:
+		return "Pending"
+	case Failed:
+		return "Failed"
+	case Expired:
+		return "Expired"
+	case Paid:
+		return "Paid"
+	case Fulfilled:
+		return "Fulfilled"
+	}
+	return ""
+}
+
+// AmountDisplay returns the amount paid for display, e.g. USD 50.00
+func (p *Payment) AmountDisplay() string {
+	if p.Currency == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s %d.%02d", strings.ToUpper(p.Currency), p.Amount/100, p.Amount%100)
+}
+
+// Complete returns true if this payment has been received and fulfilled.
+func (p *Payment) Complete() bool {
+	return p.Status == Fulfilled
+}
+
+// OwnedBy returns true if this user id made this payment.
+func (p *Payment) OwnedBy(uid int64) bool {
+	return uid == p.UserID
+}
+
+// MarkPaid records that the Checkout session for this payment has been paid,
+// it returns false if the payment was already marked paid by an earlier event.
+func (p *Payment) MarkPaid(session *stripe.Session, eventID string) (bool, error) {
+	now := query.TimeString(time.Now().UTC())
+	sql := "update payments set status=$1, amount=$2, currency=$3, payment_intent=$4, event_id=$5, updated_at=$6 where id=$7 and status<$1"
+	return p.transition(sql, Paid, session.AmountTotal, session.Currency, session.PaymentIntent, eventID, now, p.ID)
+}
+
+// MarkUnpaid records that the Checkout session for this payment failed or expired,
+// payments which have been paid are never marked unpaid.
+func (p *Payment) MarkUnpaid(status int64, eventID string) (bool, error) {
+	now := query.TimeString(time.Now().UTC())
+	sql := "update payments set status=$1, event_id=$2, updated_at=$3 where id=$4 and status<$5"
+	return p.transition(sql, status, eventID, now, p.ID, Paid)
+}
+
+// Fulfil gives the user the item they paid for. The payment is claimed before
+// fulfilment so that it is fulfilled at most once, even if events are repeated.
+func (p *Payment) Fulfil() error {
+
+	product, err := FindProduct(p.Product)
+	if err != nil {
+		return err
+	}
+
+	now := query.TimeString(time.Now().UTC())
+	claimed, err := p.transition("update payments set status=$1, fulfilled_at=$2, updated_at=$2 where id=$3 and status=$4", Fulfilled, now, p.ID, Paid)
+	if err != nil || !claimed {
+		return err
+	}
+
+	item, err := product.Find(p.ItemID)
+	if err == nil {
+		err = item.Fulfil()
+	}
+	if err != nil {
+		// Return the payment to paid, so that fulfilment is tried again when the event is retried
+		p.transition("update payments set status=$1, fulfilled_at=NULL where id=$2", Paid, p.ID)
+		return fmt.Errorf("payments: error fulfilling payment %d :%s", p.ID, err)
+	}
+
+	return nil
+}
+
+// transition executes an update of status, and returns true if it changed this payment.
+func (p *Payment) transition(sql string, args ...interface{}) (bool, error) {
+	result, err := query.Exec(sql, args...)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
@@ -0,0 +1,187 @@
+// Tests for the payments package
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+)
+
+// testItem counts the times it has been fulfilled.
+type testItem struct {
+	fulfilled int
+	err       error
+}
+
+// OwnedBy returns true for user 1
+func (i *testItem) OwnedBy(uid int64) bool {
+	return uid == 1
+}
+
+// Payable returns true
+func (i *testItem) Payable() bool {
+	return true
+}
+
+// Fulfil counts fulfilments, or fails with err
+func (i *testItem) Fulfil() error {
+	if i.err != nil {
+		return i.err
+	}
+	i.fulfilled++
+	return nil
+}
+
+var item = &testItem{}
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("payments: Setup db failed %s", err)
+	}
+
+	query.ExecSQL("delete from payments;")
+
+	Register(&Product{
+		Name:  "test",
+		Price: "price_test",
+		Find: func(id int64) (Item, error) {
+			return item, nil
+		},
+	})
+}
+
+// testEvent returns an event of this type for the session given.
+func testEvent(t *testing.T, id string, eventType string, session *stripe.Session) *stripe.Event {
+	object, err := json.Marshal(session)
+	if err != nil {
+		t.Fatalf("payments: error building event %s", err)
+	}
+	event := &stripe.Event{ID: id, Type: eventType}
+	event.Data.Object = object
+	return event
+}
+
+// Test Create method
+func TestCreatePayments(t *testing.T) {
+	params := map[string]string{
+		"user_id":    "1",
+		"product":    "test",
+		"item_id":    "1",
+		"session_id": "cs_test_payments",
+	}
+
+	id, err := New().Create(params)
+	if err != nil {
+		t.Fatalf("payments: Create payment failed :%s", err)
+	}
+
+	payment, err := FindSession("cs_test_payments")
+	if err != nil || payment.ID != id {
+		t.Fatalf("payments: Create payment find failed %v", err)
+	}
+
+	if payment.Status != Pending || payment.Complete() {
+		t.Fatalf("payments: Create payment not pending %d", payment.Status)
+	}
+
+	_, err = FindSession("")
+	if err == nil {
+		t.Fatalf("payments: found payment without session")
+	}
+}
+
+// Test events are applied to the ledger at most once
+func TestProcess(t *testing.T) {
+
+	payment, err := FindSession("cs_test_payments")
+	if err != nil {
+		t.Fatalf("payments: Process no payment found :%s", err)
+	}
+
+	session := &stripe.Session{
+		ID:                "cs_test_payments",
+		PaymentStatus:     "unpaid",
+		AmountTotal:       2500,
+		Currency:          "eur",
+		ClientReferenceID: fmt.Sprintf("%d", payment.ID),
+	}
+
+	// Sessions completed without payment are not fulfilled
+	err = Process(testEvent(t, "evt_1", EventCompleted, session))
+	if err != nil || item.fulfilled != 0 {
+		t.Fatalf("payments: unpaid session fulfilled %v", err)
+	}
+
+	// Items which fail to be fulfilled are left paid, to be fulfilled when the event is retried
+	session.PaymentStatus = "paid"
+	item.err = errors.New("unavailable")
+	err = Process(testEvent(t, "evt_2", EventAsyncSucceeded, session))
+	if err == nil {
+		t.Fatalf("payments: failed fulfilment not reported")
+	}
+	payment, err = Find(payment.ID)
+	if err != nil || payment.Status != Paid || payment.AmountDisplay() != "EUR 25.00" {
+		t.Fatalf("payments: payment not left paid %v %v", payment, err)
+	}
+
+	// Repeated events fulfil items once
+	item.err = nil
+	for i := 0; i < 2; i++ {
+		err = Process(testEvent(t, "evt_2", EventAsyncSucceeded, session))
+		if err != nil {
+			t.Fatalf("payments: Process failed :%s", err)
+		}
+	}
+	if item.fulfilled != 1 {
+		t.Fatalf("payments: item fulfilled %d times", item.fulfilled)
+	}
+
+	// Late events for the session do not change paid payments
+	err = Process(testEvent(t, "evt_3", EventExpired, session))
+	if err != nil {
+		t.Fatalf("payments: Process failed :%s", err)
+	}
+	payment, err = Find(payment.ID)
+	if err != nil || !payment.Complete() || payment.EventID != "evt_2" {
+		t.Fatalf("payments: payment changed by late event %v %v", payment, err)
+	}
+
+	// Events for other sessions, or of other types, are not applied
+	session.ID = "cs_test_other"
+	if Process(testEvent(t, "evt_4", EventCompleted, session)) != ErrUnknownPayment {
+		t.Fatalf("payments: event for other session applied")
+	}
+	if Process(testEvent(t, "evt_5", "invoice.paid", session)) != nil {
+		t.Fatalf("payments: event of other type not ignored")
+	}
+}
+
+// Test products are registered and found by name
+func TestProducts(t *testing.T) {
+	p, err := FindProduct("test")
+	if err != nil || p.Mode != stripe.ModePayment {
+		t.Fatalf("payments: product not registered %v", err)
+	}
+
+	_, err = FindProduct("missing")
+	if err == nil {
+		t.Fatalf("payments: found missing product")
+	}
+
+	// Products may not be bought until stripe is set up
+	stripe.Default = stripe.New("", "")
+	if Enabled("test") {
+		t.Fatalf("payments: product enabled without stripe")
+	}
+	stripe.Default = stripe.New("sk_test", "whsec_test")
+	if !Enabled("test") || Enabled("missing") {
+		t.Fatalf("payments: product not enabled with stripe")
+	}
+}
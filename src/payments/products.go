@@ -0,0 +1,76 @@
+package payments
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+)
+
+// Item is something which may be bought, such as a job listing.
+type Item interface {
+	// OwnedBy returns true if this user may pay for the item
+	OwnedBy(uid int64) bool
+
+	// Payable returns true if the item may be paid for now
+	Payable() bool
+
+	// Fulfil gives the buyer what they paid for, it is called only once payment is received
+	Fulfil() error
+}
+
+// Quantifier is implemented by items bought in several units, such as days of sponsorship.
+type Quantifier interface {
+	Quantity() int
+}
+
+// Product is a kind of item which may be bought with a Stripe price.
+type Product struct {
+	// Name identifies the product in the ledger and in urls, e.g. jobs
+	Name        string
+	Description string
+
+	// Price is the Stripe price id (price_...) set in config, the product may not be bought without one
+	Price string
+	Mode  string
+
+	// Find returns the item with this id
+	Find func(id int64) (Item, error)
+}
+
+// Enabled returns true if this product may be bought.
+func (p *Product) Enabled() bool {
+	return p.Price != "" && p.Find != nil && stripe.Default.Enabled()
+}
+
+var (
+	mu       sync.RWMutex
+	products = make(map[string]*Product)
+)
+
+// Register adds this product to those which may be bought, replacing any of the same name.
+func Register(p *Product) {
+	mu.Lock()
+	defer mu.Unlock()
+	if p.Mode == "" {
+		p.Mode = stripe.ModePayment
+	}
+	products[p.Name] = p
+}
+
+// FindProduct returns the product with this name.
+func FindProduct(name string) (*Product, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := products[name]
+	if !ok {
+		return nil, fmt.Errorf("payments: no product named %s", name)
+	}
+	return p, nil
+}
+
+// Enabled returns true if the product with this name may be bought.
+func Enabled(name string) bool {
+	p, err := FindProduct(name)
+	return err == nil && p.Enabled()
+}
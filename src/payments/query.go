@@ -0,0 +1,107 @@
+package payments
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "payments"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "created_at desc, id desc"
+)
+
+// NewWithColumns creates a new payment instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Payment {
+
+	payment := New()
+	payment.ID = resource.ValidateInt(cols["id"])
+	payment.CreatedAt = resource.ValidateTime(cols["created_at"])
+	payment.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	payment.Status = resource.ValidateInt(cols["status"])
+	payment.UserID = resource.ValidateInt(cols["user_id"])
+	payment.Product = resource.ValidateString(cols["product"])
+	payment.ItemID = resource.ValidateInt(cols["item_id"])
+	payment.Amount = resource.ValidateInt(cols["amount"])
+	payment.Currency = resource.ValidateString(cols["currency"])
+	payment.SessionID = resource.ValidateString(cols["session_id"])
+	payment.PaymentIntent = resource.ValidateString(cols["payment_intent"])
+	payment.EventID = resource.ValidateString(cols["event_id"])
+	payment.FulfilledAt = resource.ValidateTime(cols["fulfilled_at"])
+
+	return payment
+}
+
+// New creates and initialises a new payment instance.
+func New() *Payment {
+	payment := &Payment{}
+	payment.CreatedAt = time.Now()
+	payment.UpdatedAt = time.Now()
+	payment.TableName = TableName
+	payment.KeyName = KeyName
+	payment.Status = Pending
+	return payment
+}
+
+// FindFirst fetches a single payment record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Payment, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single payment record from the database by id.
+func Find(id int64) (*Payment, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindSession fetches the payment for this Checkout session id.
+func FindSession(sessionID string) (*Payment, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("payments: no session id")
+	}
+	return FindFirst("session_id=?", sessionID)
+}
+
+// FindAll fetches all payment records matching this query from the database.
+func FindAll(q *query.Query) ([]*Payment, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of payments constructed from the results
+	var payments []*Payment
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		payments = append(payments, p)
+	}
+
+	return payments, nil
+}
+
+// Query returns a new query for payments with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for payments with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
@@ -0,0 +1,89 @@
+package revisions
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "revisions"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "created_at asc, id asc"
+)
+
+// NewWithColumns creates a new revision instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Revision {
+
+	revision := New()
+	revision.ID = resource.ValidateInt(cols["id"])
+	revision.CreatedAt = resource.ValidateTime(cols["created_at"])
+	revision.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	revision.StoryID = resource.ValidateInt(cols["story_id"])
+	revision.CommentID = resource.ValidateInt(cols["comment_id"])
+	revision.UserID = resource.ValidateInt(cols["user_id"])
+	revision.UserName = resource.ValidateString(cols["user_name"])
+	revision.Name = resource.ValidateString(cols["name"])
+	revision.Summary = resource.ValidateString(cols["summary"])
+	revision.URL = resource.ValidateString(cols["url"])
+	revision.Text = resource.ValidateString(cols["text"])
+
+	return revision
+}
+
+// New creates and initialises a new revision instance.
+func New() *Revision {
+	revision := &Revision{}
+	revision.CreatedAt = time.Now()
+	revision.UpdatedAt = time.Now()
+	revision.TableName = TableName
+	revision.KeyName = KeyName
+	return revision
+}
+
+// Find fetches a single revision record from the database by id.
+func Find(id int64) (*Revision, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all revision records matching this query from the database.
+func FindAll(q *query.Query) ([]*Revision, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of revisions constructed from the results
+	var revisions []*Revision
+	for _, cols := range results {
+		revisions = append(revisions, NewWithColumns(cols))
+	}
+
+	return revisions, nil
+}
+
+// Query returns a new query for revisions with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// ForStory returns a query for the revisions of a story, oldest first.
+func ForStory(id int64) *query.Query {
+	return Query().Where("story_id=?", id)
+}
+
+// ForComment returns a query for the revisions of a comment, oldest first.
+func ForComment(id int64) *query.Query {
+	return Query().Where("comment_id=?", id)
+}
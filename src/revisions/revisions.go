@@ -0,0 +1,165 @@
+// Package revisions represents the previous versions of stories and comments
+package revisions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// Revision holds the values of a story or comment before it was changed.
+type Revision struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	// StoryID is set for story revisions, and CommentID for comment revisions
+	StoryID   int64
+	CommentID int64
+
+	// UserID and UserName record the user who changed the story or comment
+	UserID   int64
+	UserName string
+
+	// The previous values of the story or comment
+	Name    string
+	Summary string
+	URL     string
+	Text    string
+}
+
+// Change is a revision along with the version which replaced it.
+type Change struct {
+	Revision *Revision
+	Next     *Revision
+}
+
+// storyFields and commentFields are the fields recorded in revisions.
+var (
+	storyFields   = []string{"name", "summary", "url"}
+	commentFields = []string{"text"}
+)
+
+// FromStory returns the current version of the story as a revision, it is not saved.
+func FromStory(s *stories.Story) *Revision {
+	r := New()
+	r.CreatedAt = s.CreatedAt
+	if s.Edited() {
+		r.CreatedAt = s.EditedAt
+	}
+	r.StoryID = s.ID
+	r.UserID = s.UserID
+	r.UserName = s.UserName
+	r.Name = s.Name
+	r.Summary = s.Summary
+	r.URL = s.URL
+	return r
+}
+
+// FromComment returns the current version of the comment as a revision, it is not saved.
+func FromComment(c *comments.Comment) *Revision {
+	r := New()
+	r.CreatedAt = c.CreatedAt
+	if c.Edited() {
+		r.CreatedAt = c.EditedAt
+	}
+	r.CommentID = c.ID
+	r.UserID = c.UserID
+	r.UserName = c.UserName
+	r.Text = c.Text
+	return r
+}
+
+// RecordStory saves the current version of the story as a revision if the params
+// would change it, and marks the story as edited in params.
+func RecordStory(s *stories.Story, params map[string]string, user *users.User) error {
+	current := FromStory(s)
+	if !changed(current.values(storyFields), params) {
+		return nil
+	}
+
+	revisionParams := current.values(storyFields)
+	revisionParams["story_id"] = fmt.Sprintf("%d", s.ID)
+	return record(revisionParams, params, user)
+}
+
+// RecordComment saves the current version of the comment as a revision if the params
+// would change it, and marks the comment as edited in params.
+func RecordComment(c *comments.Comment, params map[string]string, user *users.User) error {
+	current := FromComment(c)
+	if !changed(current.values(commentFields), params) {
+		return nil
+	}
+
+	revisionParams := current.values(commentFields)
+	revisionParams["comment_id"] = fmt.Sprintf("%d", c.ID)
+	return record(revisionParams, params, user)
+}
+
+// record inserts a revision with the params given, and sets edited_at in the update params.
+func record(revisionParams map[string]string, updateParams map[string]string, user *users.User) error {
+	revisionParams["user_id"] = fmt.Sprintf("%d", user.ID)
+	revisionParams["user_name"] = user.Name
+
+	_, err := New().Create(revisionParams)
+	if err != nil {
+		return err
+	}
+
+	updateParams["edited_at"] = query.TimeString(time.Now().UTC())
+	return nil
+}
+
+// changed returns true if any of the params differ from the current values.
+func changed(current map[string]string, params map[string]string) bool {
+	for k, v := range current {
+		if p, ok := params[k]; ok && p != v {
+			return true
+		}
+	}
+	return false
+}
+
+// values returns the revision values for the fields given.
+func (r *Revision) values(fields []string) map[string]string {
+	all := map[string]string{
+		"name":    r.Name,
+		"summary": r.Summary,
+		"url":     r.URL,
+		"text":    r.Text,
+	}
+	values := make(map[string]string)
+	for _, f := range fields {
+		values[f] = all[f]
+	}
+	return values
+}
+
+// StoryParams returns the params required to restore a story to this revision.
+func (r *Revision) StoryParams() map[string]string {
+	return r.values(storyFields)
+}
+
+// CommentParams returns the params required to restore a comment to this revision.
+func (r *Revision) CommentParams() map[string]string {
+	return r.values(commentFields)
+}
+
+// Changes pairs each revision (oldest first) with the version which replaced it,
+// ending with the current version. The changes are returned newest first.
+func Changes(revisions []*Revision, current *Revision) []*Change {
+	var changes []*Change
+	for i := len(revisions) - 1; i >= 0; i-- {
+		next := current
+		if i+1 < len(revisions) {
+			next = revisions[i+1]
+		}
+		changes = append(changes, &Change{Revision: revisions[i], Next: next})
+	}
+	return changes
+}
@@ -0,0 +1,90 @@
+// Tests for the revisions package
+package revisions
+
+import (
+	"testing"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("revisions: Setup db failed %s", err)
+	}
+}
+
+// Test recording a story revision on update
+func TestRecordStory(t *testing.T) {
+
+	id, err := stories.New().Create(map[string]string{"name": "first", "summary": "the first summary", "status": "100"})
+	if err != nil {
+		t.Fatalf("revisions: Create story failed :%s", err)
+	}
+	story, err := stories.Find(id)
+	if err != nil {
+		t.Fatalf("revisions: Create story find failed")
+	}
+	user := users.MockAdmin()
+
+	// Params which change nothing record nothing
+	params := map[string]string{"name": "first"}
+	err = RecordStory(story, params, user)
+	if err != nil || params["edited_at"] != "" {
+		t.Fatalf("revisions: RecordStory recorded unchanged story :%v", err)
+	}
+
+	params = map[string]string{"name": "second"}
+	err = RecordStory(story, params, user)
+	if err != nil {
+		t.Fatalf("revisions: RecordStory failed :%s", err)
+	}
+	err = story.Update(params)
+	if err != nil {
+		t.Fatalf("revisions: Update story failed :%s", err)
+	}
+
+	story, _ = stories.Find(id)
+	if !story.Edited() {
+		t.Fatalf("revisions: story not marked as edited")
+	}
+
+	results, err := FindAll(ForStory(id))
+	if err != nil || len(results) != 1 {
+		t.Fatalf("revisions: wrong revisions for story got:%d :%v", len(results), err)
+	}
+	if results[0].Name != "first" || results[0].Summary != "the first summary" {
+		t.Fatalf("revisions: revision has wrong values got:%s", results[0].Name)
+	}
+
+	// Restoring the revision gives back the original name
+	if results[0].StoryParams()["name"] != "first" {
+		t.Fatalf("revisions: StoryParams wrong got:%v", results[0].StoryParams())
+	}
+
+	changes := Changes(results, FromStory(story))
+	if len(changes) != 1 || changes[0].Next.Name != "second" {
+		t.Fatalf("revisions: Changes wrong got:%v", changes)
+	}
+
+	for _, r := range results {
+		r.Destroy()
+	}
+	story.Destroy()
+}
+
+// Test changes are paired with the version which replaced them, newest first
+func TestChanges(t *testing.T) {
+	a, b, current := New(), New(), New()
+	a.Text, b.Text, current.Text = "a", "b", "c"
+
+	changes := Changes([]*Revision{a, b}, current)
+	if len(changes) != 2 {
+		t.Fatalf("revisions: Changes wrong length got:%d", len(changes))
+	}
+	if changes[0].Revision != b || changes[0].Next != current || changes[1].Revision != a || changes[1].Next != b {
+		t.Fatalf("revisions: Changes wrong order")
+	}
+}
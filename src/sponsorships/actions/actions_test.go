@@ -0,0 +1,277 @@
+package sponsorshipactions
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+	"github.com/kennygrant/gohackernews/src/payments"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+)
+
+// TestSetup performs setup for integration tests
+// using the test database, real views, and mock authorisation
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(3)
+	if err != nil {
+		fmt.Printf("sponsorships: Setup db failed %s", err)
+	}
+
+	// Set up mock auth, readers may sponsor and see only their own sponsorships as in app
+	resource.SetupAuthorisation()
+	can.Authorise(10, can.CreateResource, sponsorships.TableName)
+	can.AuthoriseOwner(10, can.ShowResource, sponsorships.TableName)
+
+	// Load templates for rendering
+	resource.SetupView(3)
+
+	router := mux.New()
+	mux.SetDefault(router)
+
+	router.Add("/sponsorships", nil)
+	router.Add("/sponsorships/create", nil)
+	router.Add("/sponsorships/create", nil).Post()
+	router.Add("/sponsorships/{id:\\d+}/approve", nil).Post()
+	router.Add("/sponsorships/{id:\\d+}/reject", nil).Post()
+	router.Add("/sponsorships/{id:\\d+}/click", nil)
+	router.Add("/sponsorships/{id:\\d+}", nil)
+
+	// Sponsorships may be bought only once stripe is set up with a price
+	stripe.Default = stripe.New("sk_test", "whsec_test")
+	payments.Register(&payments.Product{
+		Name:        sponsorships.TableName,
+		Description: "sponsored placement",
+		Price:       "price_test_sponsorships",
+		Find: func(id int64) (payments.Item, error) {
+			return sponsorships.Find(id)
+		},
+	})
+
+	// Delete all sponsorships and stories to ensure we get consistent results
+	query.ExecSQL("delete from sponsorships;")
+	query.ExecSQL("ALTER SEQUENCE sponsorships_id_seq RESTART WITH 1;")
+	query.ExecSQL("delete from stories;")
+
+	// Delete all users to ensure we get consistent results
+	_, err = query.ExecSQL("delete from users;")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	// Insert a test admin user, a sponsor and another reader
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(1,'example@example.com','admin',100,100,100,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(2,'example2@example.com','sponsor',100,100,10,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(3,'example3@example.com','reader',100,100,10,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	query.ExecSQL("ALTER SEQUENCE users_id_seq RESTART WITH 4;")
+
+	// Insert a published story by the sponsor
+	_, err = query.ExecSQL("INSERT INTO stories (id,created_at,status,name,url,user_id,user_name,points) VALUES(1,now(),100,'Sponsored story','https://example.com/go',2,'sponsor',1);")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+}
+
+// Test GET /sponsorships/create?story_id=1
+func TestShowCreateSponsorships(t *testing.T) {
+
+	for _, id := range []int{2, 3} {
+		r := httptest.NewRequest("GET", "/sponsorships/create", nil)
+		w := httptest.NewRecorder()
+
+		err := resource.AddUserSessionCookie(w, r, id)
+		if err != nil {
+			t.Fatalf("sponsorshipactions: error setting session %s", err)
+		}
+		r.URL.RawQuery += "&story_id=1"
+
+		err = HandleCreateShow(w, r)
+
+		// Only the author may sponsor their story
+		if id == 3 {
+			if err == nil {
+				t.Fatalf("sponsorshipactions: user allowed to sponsor story of another user")
+			}
+			continue
+		}
+
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("sponsorshipactions: error handling HandleCreateShow %s", err)
+		}
+
+		pattern := "sponsorships-form"
+		if !strings.Contains(w.Body.String(), pattern) {
+			t.Fatalf("sponsorshipactions: unexpected response for HandleCreateShow expected:%s got:%s", pattern, w.Body.String())
+		}
+	}
+}
+
+// Test POST /sponsorships/create
+func TestCreateSponsorships(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("story_id", "1")
+	form.Add("days", "3")
+	form.Add("starts_at", time.Now().UTC().Format("2006-01-02"))
+	form.Add("status", "100")
+	form.Add("user_id", "3")
+	body := strings.NewReader(form.Encode())
+
+	r := httptest.NewRequest("POST", "/sponsorships/create", body)
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	err := resource.AddUserSessionCookie(w, r, 2)
+	if err != nil {
+		t.Fatalf("sponsorshipactions: error setting session %s", err)
+	}
+
+	err = HandleCreate(w, r)
+	if err != nil {
+		t.Fatalf("sponsorshipactions: error handling HandleCreate %s", err)
+	}
+
+	// Test we are sent on to pay for the sponsorship
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/stripe/pay?product=sponsorships&item_id=1" {
+		t.Fatalf("sponsorshipactions: unexpected response for HandleCreate got:%d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	// Check the sponsorship awaits payment by the sponsor, whatever the params said
+	s, err := sponsorships.Find(1)
+	if err != nil || !s.Payable() || s.UserID != 2 || s.Days != 3 || s.Name != "Sponsored story" {
+		t.Fatalf("sponsorshipactions: error with created sponsorship values: %v %v", s, err)
+	}
+}
+
+// Test POST /sponsorships/1/approve
+func TestApproveSponsorships(t *testing.T) {
+
+	s, err := sponsorships.Find(1)
+	if err != nil {
+		t.Fatalf("sponsorshipactions: error finding sponsorship %s", err)
+	}
+	err = s.Fulfil()
+	if err != nil {
+		t.Fatalf("sponsorshipactions: error fulfilling sponsorship %s", err)
+	}
+
+	// Only admins may approve sponsorships
+	for _, id := range []int{2, 1} {
+		r := httptest.NewRequest("POST", "/sponsorships/1/approve", nil)
+		w := httptest.NewRecorder()
+
+		err = resource.AddUserSessionCookie(w, r, id)
+		if err != nil {
+			t.Fatalf("sponsorshipactions: error setting session %s", err)
+		}
+
+		err = HandleApprove(w, r)
+		if id == 2 {
+			if err == nil {
+				t.Fatalf("sponsorshipactions: sponsor allowed to approve sponsorship")
+			}
+			continue
+		}
+
+		if err != nil || w.Code != http.StatusFound {
+			t.Fatalf("sponsorshipactions: error handling HandleApprove %s", err)
+		}
+	}
+
+	// The sponsorship starts today, so is live once approved
+	s, err = sponsorships.Find(1)
+	if err != nil || s.Status != sponsorships.Live {
+		t.Fatalf("sponsorshipactions: approved sponsorship not live %v", err)
+	}
+}
+
+// Test GET /sponsorships/1/click
+func TestClickSponsorships(t *testing.T) {
+
+	r := httptest.NewRequest("GET", "/sponsorships/1/click", nil)
+	w := httptest.NewRecorder()
+
+	err := HandleClick(w, r)
+	if err != nil || w.Code != http.StatusFound || w.Header().Get("Location") != "https://example.com/go" {
+		t.Fatalf("sponsorshipactions: error handling HandleClick %s %s", err, w.Header().Get("Location"))
+	}
+
+	s, err := sponsorships.Find(1)
+	if err != nil || s.Clicks != 1 {
+		t.Fatalf("sponsorshipactions: click not counted %v", err)
+	}
+}
+
+// Test GET /sponsorships/1
+func TestShowSponsorships(t *testing.T) {
+
+	for _, id := range []int{3, 2} {
+		r := httptest.NewRequest("GET", "/sponsorships/1", nil)
+		w := httptest.NewRecorder()
+
+		err := resource.AddUserSessionCookie(w, r, id)
+		if err != nil {
+			t.Fatalf("sponsorshipactions: error setting session %s", err)
+		}
+
+		err = HandleShow(w, r)
+
+		// Only the sponsor may see the report
+		if id == 3 {
+			if err == nil {
+				t.Fatalf("sponsorshipactions: user allowed to see report of another user")
+			}
+			continue
+		}
+
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("sponsorshipactions: error handling HandleShow %s", err)
+		}
+
+		pattern := "<td>1</td>"
+		if !strings.Contains(w.Body.String(), pattern) {
+			t.Fatalf("sponsorshipactions: unexpected response for HandleShow expected:%s got:%s", pattern, w.Body.String())
+		}
+	}
+}
+
+// Test GET /sponsorships
+func TestIndexSponsorships(t *testing.T) {
+
+	// Admins see all sponsorships, readers only their own
+	for id, count := range map[int]int{1: 1, 2: 1, 3: 0} {
+		r := httptest.NewRequest("GET", "/sponsorships", nil)
+		w := httptest.NewRecorder()
+
+		err := resource.AddUserSessionCookie(w, r, id)
+		if err != nil {
+			t.Fatalf("sponsorshipactions: error setting session %s", err)
+		}
+
+		err = HandleIndex(w, r)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("sponsorshipactions: error handling HandleIndex %s", err)
+		}
+
+		if strings.Count(w.Body.String(), "Sponsored story") != count {
+			t.Fatalf("sponsorshipactions: user %d saw wrong sponsorships got:%s", id, w.Body.String())
+		}
+	}
+}
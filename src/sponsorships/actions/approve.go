@@ -0,0 +1,102 @@
+package sponsorshipactions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+)
+
+// HandleApprove responds to POST /sponsorships/{id}/approve by scheduling the sponsorship,
+// with the name and url of the story or job as they are now.
+func HandleApprove(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the sponsorship
+	sponsorship, err := sponsorships.Find(params.GetInt(sponsorships.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may review sponsorships
+	currentUser := session.CurrentUser(w, r)
+	err = can.Update(sponsorship, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	name, url, err := findSponsorable(sponsorship, 0)
+	if err != nil {
+		return err
+	}
+
+	err = sponsorship.Approve(name, url)
+	if err != nil {
+		return server.NotAuthorizedError(err, "Sorry", "Only paid sponsorships awaiting approval may be approved.")
+	}
+
+	// Make the sponsorship live now if it has started, rather than waiting for the schedule
+	_, _, err = sponsorships.Schedule(time.Now())
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	log.Info(log.V{"msg": "Approved sponsorship", "sponsorship_id": sponsorship.ID, "user_id": currentUser.ID})
+
+	return server.Redirect(w, r, "/sponsorships")
+}
+
+// HandleReject responds to POST /sponsorships/{id}/reject by removing the sponsorship from the schedule.
+func HandleReject(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the sponsorship
+	sponsorship, err := sponsorships.Find(params.GetInt(sponsorships.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may review sponsorships
+	currentUser := session.CurrentUser(w, r)
+	err = can.Update(sponsorship, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	err = sponsorship.Reject()
+	if err != nil {
+		return server.NotAuthorizedError(err, "Sorry", "Only sponsorships which have not started may be rejected.")
+	}
+
+	log.Info(log.V{"msg": "Rejected sponsorship", "sponsorship_id": sponsorship.ID, "user_id": currentUser.ID})
+
+	return server.Redirect(w, r, "/sponsorships")
+}
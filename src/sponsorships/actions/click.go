@@ -0,0 +1,43 @@
+package sponsorshipactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+)
+
+// HandleClick responds to GET /sponsorships/{id}/click by counting a click
+// on a live sponsorship and redirecting to the story or job.
+func HandleClick(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the sponsorship
+	sponsorship, err := sponsorships.Find(params.GetInt(sponsorships.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// No Authorisation - anyone may follow the link, but only clicks while live are counted
+	if sponsorship.Status == sponsorships.Live {
+		err = sponsorship.Click()
+		if err != nil {
+			log.Error(log.V{"msg": "sponsorship click failed", "sponsorship_id": sponsorship.ID, "error": err})
+		}
+	}
+
+	url := sponsorship.URL
+	if url == "" {
+		url = sponsorship.ItemURL()
+	}
+
+	return server.Redirect(w, r, url)
+}
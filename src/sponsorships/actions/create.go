@@ -0,0 +1,166 @@
+package sponsorshipactions
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+	"github.com/kennygrant/gohackernews/src/payments"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+	"github.com/kennygrant/gohackernews/src/stories"
+)
+
+// HandleCreateShow serves the form to sponsor a story or job via GET /sponsorships/create?story_id=1
+func HandleCreateShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	sponsorship := sponsorships.New()
+	sponsorship.StoryID = params.GetInt("story_id")
+	sponsorship.JobID = params.GetInt("job_id")
+
+	// Authorise
+	currentUser := session.CurrentUser(w, r)
+	err = can.Create(sponsorship, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Find the story or job, which must belong to the sponsor
+	name, _, err := findSponsorable(sponsorship, currentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.Template("sponsorships/views/create.html.got")
+	view.AddKey("sponsorship", sponsorship)
+	view.AddKey("name", name)
+	view.AddKey("maxDays", sponsorships.MaxDays)
+	view.AddKey("today", time.Now().UTC().Format("2006-01-02"))
+	view.AddKey("currentUser", currentUser)
+	return view.Render()
+}
+
+// HandleCreate handles the POST of the create form for sponsorships,
+// and sends the sponsor on to pay for the placement.
+func HandleCreate(w http.ResponseWriter, r *http.Request) error {
+
+	sponsorship := sponsorships.New()
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise
+	currentUser := session.CurrentUser(w, r)
+	err = can.Create(sponsorship, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Validate the params, removing any we don't accept
+	sponsorshipParams := sponsorship.ValidateParams(params.Map(), sponsorships.AllowedParams())
+	err = sponsorships.Validate(sponsorshipParams, time.Now())
+	if err != nil {
+		return server.NotAuthorizedError(err, "Invalid Sponsorship", err.Error())
+	}
+
+	// Find the story or job, which must belong to the sponsor
+	sponsorship.StoryID = params.GetInt("story_id")
+	sponsorship.JobID = params.GetInt("job_id")
+	if sponsorship.JobID > 0 {
+		sponsorship.StoryID = 0
+	}
+	name, url, err := findSponsorable(sponsorship, currentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	// Set a few params to known good values
+	sponsorshipParams["status"] = fmt.Sprintf("%d", sponsorships.Unpaid)
+	sponsorshipParams["user_id"] = fmt.Sprintf("%d", currentUser.ID)
+	sponsorshipParams["story_id"] = fmt.Sprintf("%d", sponsorship.StoryID)
+	sponsorshipParams["job_id"] = fmt.Sprintf("%d", sponsorship.JobID)
+	sponsorshipParams["name"] = name
+	sponsorshipParams["url"] = url
+
+	ID, err := sponsorship.Create(sponsorshipParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log creation
+	log.Info(log.V{"msg": "Created sponsorship", "sponsorship_id": ID, "user_id": currentUser.ID})
+
+	// Redirect to payment for the new sponsorship
+	sponsorship, err = sponsorships.Find(ID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	return server.Redirect(w, r, sponsorship.PayURL())
+}
+
+// findSponsorable returns the name and url to show for the story or job of this sponsorship,
+// if sponsorships may be bought and it is published and belongs to the user given.
+// If the user id is 0 ownership is not checked.
+func findSponsorable(sponsorship *sponsorships.Sponsorship, userID int64) (string, string, error) {
+
+	if !payments.Enabled(sponsorships.TableName) {
+		return "", "", server.NotFoundError(nil, "Not Available", "Sorry, sponsorships are not available at present.")
+	}
+
+	var name, url string
+	var owned bool
+	switch {
+	case sponsorship.JobID > 0:
+		job, err := jobs.Find(sponsorship.JobID)
+		if err != nil || job.Status < status.Published || job.Expired() {
+			return "", "", server.NotFoundError(err, "Not Found", "Sorry, only jobs which are listed may be sponsored.")
+		}
+		name = fmt.Sprintf("%s at %s", job.Name, job.Company)
+		url = job.CanonicalURL()
+		owned = job.OwnedBy(userID)
+
+	case sponsorship.StoryID > 0:
+		story, err := stories.Find(sponsorship.StoryID)
+		if err != nil || story.Status < status.Published {
+			return "", "", server.NotFoundError(err, "Not Found", "Sorry, only published stories may be sponsored.")
+		}
+		name = story.NameDisplay()
+		url = story.PrimaryURL()
+		owned = story.OwnedBy(userID)
+
+	default:
+		return "", "", server.NotFoundError(nil)
+	}
+
+	if userID > 0 && !owned {
+		return "", "", server.NotAuthorizedError(nil, "Sorry", "You may only sponsor your own stories and jobs.")
+	}
+
+	return name, url, nil
+}
@@ -0,0 +1,61 @@
+package sponsorshipactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+)
+
+// listLimit is the number of sponsorships shown per page
+const listLimit = 50
+
+// HandleIndex responds to GET /sponsorships by showing admins all sponsorships
+// for review, and sponsors their own sponsorships.
+func HandleIndex(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise - anon users have no sponsorships
+	currentUser := session.CurrentUser(w, r)
+	if currentUser.Anon() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Show those awaiting review first to admins, and only their own to sponsors
+	q := sponsorships.Query().Limit(listLimit)
+	if currentUser.Admin() {
+		q.Order(fmt.Sprintf("status=%d desc, created_at desc", sponsorships.Pending))
+	} else {
+		q.Where("user_id=?", currentUser.ID)
+	}
+
+	// Set the offset in pages if we have one
+	page := params.GetInt("page")
+	if page > 0 {
+		q.Offset(listLimit * int(page))
+	}
+
+	results, err := sponsorships.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.Template("sponsorships/views/index.html.got")
+	view.AddKey("sponsorships", results)
+	view.AddKey("page", page)
+	view.AddKey("more", len(results) == listLimit)
+	view.AddKey("currentUser", currentUser)
+	return view.Render()
+}
@@ -0,0 +1,22 @@
+package sponsorshipactions
+
+import (
+	"time"
+
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+)
+
+// ScheduleSponsorships makes approved sponsorships live as they start, and finishes
+// them as they end. It is called regularly by the app, as the schedule is enforced only here.
+func ScheduleSponsorships() {
+	started, finished, err := sponsorships.Schedule(time.Now())
+	if err != nil {
+		log.Error(log.V{"msg": "sponsorship schedule failed", "error": err})
+		return
+	}
+	if started > 0 || finished > 0 {
+		log.Info(log.V{"msg": "sponsorship schedule", "started": started, "finished": finished})
+	}
+}
@@ -0,0 +1,44 @@
+package sponsorshipactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+)
+
+// HandleShow responds to GET /sponsorships/{id} with the sponsor's report,
+// showing the schedule, impressions and clicks for the sponsorship.
+func HandleShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the sponsorship
+	sponsorship, err := sponsorships.Find(params.GetInt(sponsorships.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise - only the sponsor and admins may see the report
+	currentUser := session.CurrentUser(w, r)
+	err = can.Show(sponsorship, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.Template("sponsorships/views/show.html.got")
+	view.AddKey("sponsorship", sponsorship)
+	view.AddKey("currentUser", currentUser)
+	return view.Render()
+}
@@ -0,0 +1,114 @@
+package sponsorships
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "sponsorships"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "created_at desc, id desc"
+)
+
+// AllowedParams returns the cols editable by sponsors
+func AllowedParams() []string {
+	return []string{"story_id", "job_id", "days", "starts_at"}
+}
+
+// NewWithColumns creates a new sponsorship instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Sponsorship {
+
+	sponsorship := New()
+	sponsorship.ID = resource.ValidateInt(cols["id"])
+	sponsorship.CreatedAt = resource.ValidateTime(cols["created_at"])
+	sponsorship.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	sponsorship.Status = resource.ValidateInt(cols["status"])
+	sponsorship.UserID = resource.ValidateInt(cols["user_id"])
+	sponsorship.StoryID = resource.ValidateInt(cols["story_id"])
+	sponsorship.JobID = resource.ValidateInt(cols["job_id"])
+	sponsorship.Name = resource.ValidateString(cols["name"])
+	sponsorship.URL = resource.ValidateString(cols["url"])
+	sponsorship.Days = resource.ValidateInt(cols["days"])
+	sponsorship.StartsAt = resource.ValidateTime(cols["starts_at"])
+	sponsorship.EndsAt = resource.ValidateTime(cols["ends_at"])
+	sponsorship.Impressions = resource.ValidateInt(cols["impressions"])
+	sponsorship.Clicks = resource.ValidateInt(cols["clicks"])
+
+	return sponsorship
+}
+
+// New creates and initialises a new sponsorship instance.
+func New() *Sponsorship {
+	sponsorship := &Sponsorship{}
+	sponsorship.CreatedAt = time.Now()
+	sponsorship.UpdatedAt = time.Now()
+	sponsorship.TableName = TableName
+	sponsorship.KeyName = KeyName
+	sponsorship.Status = Unpaid
+	return sponsorship
+}
+
+// FindFirst fetches a single sponsorship record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Sponsorship, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single sponsorship record from the database by id.
+func Find(id int64) (*Sponsorship, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all sponsorship records matching this query from the database.
+func FindAll(q *query.Query) ([]*Sponsorship, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of sponsorships constructed from the results
+	var sponsorships []*Sponsorship
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		sponsorships = append(sponsorships, p)
+	}
+
+	return sponsorships, nil
+}
+
+// Query returns a new query for sponsorships with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for sponsorships with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
+
+// Pinned returns a query for sponsorships which are live, in the order they started.
+func Pinned() *query.Query {
+	return query.New(TableName, KeyName).Where("status=?", Live).Order("starts_at asc, id asc")
+}
+
+// PinnedJobs returns a query for sponsored jobs which are live, for the job board.
+func PinnedJobs() *query.Query {
+	return Pinned().Where("job_id > 0")
+}
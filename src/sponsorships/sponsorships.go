@@ -0,0 +1,252 @@
+// Package sponsorships represents paid placements of stories and jobs,
+// pinned to a marked slot on the home page and the job board
+package sponsorships
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+// MaxDays is the longest placement which may be bought at once.
+const MaxDays = 30
+
+// Slots is the number of live sponsorships shown at once in the marked slot on a page.
+const Slots = 2
+
+// Status values valid in the status field of sponsorships. Sponsorships are
+// paid for, approved by an admin, then made live and finished by Schedule.
+const (
+	Unpaid   = 0
+	Pending  = 10
+	Rejected = 20
+	Approved = 50
+	Live     = 100
+	Finished = 110
+)
+
+// Sponsorship is a placement of a story or job bought for a number of days.
+type Sponsorship struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	Status int64
+
+	// The sponsor who paid for the placement
+	UserID int64
+
+	// The story or job placed, only one is set
+	StoryID int64
+	JobID   int64
+
+	// Name and URL are the title and link shown in the slot, copied from the
+	// story or job when it is approved so that they cannot change afterwards
+	Name string
+	URL  string
+
+	// The placement is live from StartsAt until EndsAt
+	Days     int64
+	StartsAt time.Time
+	EndsAt   time.Time
+
+	// Impressions and Clicks are counted for the sponsor's report
+	Impressions int64
+	Clicks      int64
+}
+
+// ClickURL returns the url used to follow the sponsored link, so that clicks are counted.
+func (s *Sponsorship) ClickURL() string {
+	return fmt.Sprintf("/sponsorships/%d/click", s.ID)
+}
+
+// ApproveURL returns the url used by admins to approve this sponsorship.
+func (s *Sponsorship) ApproveURL() string {
+	return fmt.Sprintf("/sponsorships/%d/approve", s.ID)
+}
+
+// RejectURL returns the url used by admins to reject this sponsorship.
+func (s *Sponsorship) RejectURL() string {
+	return fmt.Sprintf("/sponsorships/%d/reject", s.ID)
+}
+
+// PayURL returns the url of the page used to pay for this sponsorship.
+func (s *Sponsorship) PayURL() string {
+	return fmt.Sprintf("/stripe/pay?product=%s&item_id=%d", TableName, s.ID)
+}
+
+// ItemURL returns the url of the story or job on this site.
+func (s *Sponsorship) ItemURL() string {
+	if s.JobID > 0 {
+		return fmt.Sprintf("/jobs/%d", s.JobID)
+	}
+	return fmt.Sprintf("/stories/%d", s.StoryID)
+}
+
+// KindDisplay returns the kind of item sponsored.
+func (s *Sponsorship) KindDisplay() string {
+	if s.JobID > 0 {
+		return "Job"
+	}
+	return "Story"
+}
+
+// StatusDisplay returns a description of the status of this sponsorship.
+func (s *Sponsorship) StatusDisplay() string {
+	switch s.Status {
+	case Unpaid:
+		return "Awaiting payment"
+	case Pending:
+		return "Awaiting approval"
+	case Rejected:
+		return "Rejected"
+	case Approved:
+		return "Scheduled"
+	case Live:
+		return "Live"
+	case Finished:
+		return "Finished"
+	}
+	return ""
+}
+
+// ClickRate returns the percentage of impressions which led to a click.
+func (s *Sponsorship) ClickRate() string {
+	if s.Impressions == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(s.Clicks)*100/float64(s.Impressions))
+}
+
+// OwnedBy returns true if this user id paid for this sponsorship.
+func (s *Sponsorship) OwnedBy(uid int64) bool {
+	return uid == s.UserID
+}
+
+// AwaitingApproval returns true if the sponsorship has been paid for and may be approved now.
+func (s *Sponsorship) AwaitingApproval() bool {
+	return s.Status == Pending
+}
+
+// Reviewable returns true if an admin may approve or reject this sponsorship now.
+func (s *Sponsorship) Reviewable() bool {
+	return s.Status == Pending || s.Status == Approved
+}
+
+// Payable returns true if the sponsorship may be paid for now.
+func (s *Sponsorship) Payable() bool {
+	return s.Status == Unpaid
+}
+
+// Quantity returns the number of days bought, which is the quantity charged at checkout.
+func (s *Sponsorship) Quantity() int {
+	return int(s.Days)
+}
+
+// Fulfil marks the sponsorship paid, it then awaits approval by an admin.
+func (s *Sponsorship) Fulfil() error {
+	_, err := query.Exec("update sponsorships set status=$1, updated_at=$2 where id=$3 and status=$4", Pending, s.now(), s.ID, Unpaid)
+	return err
+}
+
+// Approve schedules the sponsorship to go live with this name and url.
+func (s *Sponsorship) Approve(name, url string) error {
+	return s.transition("update sponsorships set status=$1, name=$2, url=$3, updated_at=$4 where id=$5 and status=$6", Approved, name, url, s.now(), s.ID, Pending)
+}
+
+// Reject removes the sponsorship from the schedule, any refund is made by hand.
+func (s *Sponsorship) Reject() error {
+	return s.transition("update sponsorships set status=$1, updated_at=$2 where id=$3 and status in ($4,$5)", Rejected, s.now(), s.ID, Pending, Approved)
+}
+
+// Click counts a click on this sponsorship.
+func (s *Sponsorship) Click() error {
+	_, err := query.Exec("update sponsorships set clicks=clicks+1 where id=$1", s.ID)
+	return err
+}
+
+// CountImpressions counts one impression for each of these sponsorships.
+func CountImpressions(list []*Sponsorship) error {
+	if len(list) == 0 {
+		return nil
+	}
+	var ids []string
+	for _, s := range list {
+		ids = append(ids, strconv.FormatInt(s.ID, 10))
+	}
+	_, err := query.Exec(fmt.Sprintf("update sponsorships set impressions=impressions+1 where id in (%s)", strings.Join(ids, ",")))
+	return err
+}
+
+// Schedule makes approved sponsorships live once they start, and finishes those which have ended.
+// It returns the number of sponsorships started and finished.
+func Schedule(now time.Time) (int64, int64, error) {
+	t := query.TimeString(now.UTC())
+
+	result, err := query.Exec("update sponsorships set status=$1, updated_at=$2 where status in ($3,$4) and ends_at <= $2", Finished, t, Approved, Live)
+	if err != nil {
+		return 0, 0, err
+	}
+	finished, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	result, err = query.Exec("update sponsorships set status=$1, updated_at=$2 where status=$3 and starts_at <= $2 and ends_at > $2", Live, t, Approved)
+	if err != nil {
+		return 0, finished, err
+	}
+	started, err := result.RowsAffected()
+	return started, finished, err
+}
+
+// Validate checks the params for a new sponsorship, setting ends_at from starts_at and days.
+func Validate(params map[string]string, now time.Time) error {
+
+	days, err := strconv.ParseInt(params["days"], 10, 64)
+	if err != nil || days < 1 || days > MaxDays {
+		return fmt.Errorf("Sorry, sponsorships must be between 1 and %d days", MaxDays)
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	start := today
+	if params["starts_at"] != "" {
+		start, err = time.Parse("2006-01-02", params["starts_at"])
+		if err != nil {
+			return fmt.Errorf("Sorry, the start date must be a date like %s", today.Format("2006-01-02"))
+		}
+	}
+	if start.Before(today) || start.After(today.AddDate(0, 3, 0)) {
+		return fmt.Errorf("Sorry, sponsorships must start within the next three months")
+	}
+
+	params["days"] = fmt.Sprintf("%d", days)
+	params["starts_at"] = query.TimeString(start)
+	params["ends_at"] = query.TimeString(start.AddDate(0, 0, int(days)))
+	return nil
+}
+
+// now returns the current time for updates.
+func (s *Sponsorship) now() string {
+	return query.TimeString(time.Now().UTC())
+}
+
+// transition executes an update of status, returning an error if it did not apply to this sponsorship.
+func (s *Sponsorship) transition(sql string, args ...interface{}) error {
+	result, err := query.Exec(sql, args...)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("sponsorships: sponsorship %d is %s", s.ID, s.StatusDisplay())
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Tests for the sponsorships package
+package sponsorships
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("sponsorships: Setup db failed %s", err)
+	}
+
+	query.ExecSQL("delete from sponsorships;")
+}
+
+// Test the days and dates of new sponsorships are validated
+func TestValidate(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 0, 0, 0, time.UTC)
+
+	params := map[string]string{"days": "7", "starts_at": "2026-10-20"}
+	err := Validate(params, now)
+	if err != nil {
+		t.Fatalf("sponsorships: Validate failed :%s", err)
+	}
+	if params["starts_at"] != "2026-10-20 00:00:00" || params["ends_at"] != "2026-10-27 00:00:00" {
+		t.Fatalf("sponsorships: Validate dates wrong %s %s", params["starts_at"], params["ends_at"])
+	}
+
+	// Without a start date sponsorships start today
+	params = map[string]string{"days": "1"}
+	err = Validate(params, now)
+	if err != nil || params["starts_at"] != "2026-10-19 00:00:00" {
+		t.Fatalf("sponsorships: Validate default start wrong %s %v", params["starts_at"], err)
+	}
+
+	invalid := []map[string]string{
+		{"days": "0"},
+		{"days": "31"},
+		{"days": "seven"},
+		{"days": "7", "starts_at": "2026-10-18"},
+		{"days": "7", "starts_at": "2027-02-01"},
+		{"days": "7", "starts_at": "tomorrow"},
+	}
+	for _, params := range invalid {
+		if Validate(params, now) == nil {
+			t.Fatalf("sponsorships: Validate accepted %v", params)
+		}
+	}
+}
+
+// Test sponsorships move through payment, approval and the schedule
+func TestSchedule(t *testing.T) {
+	now := time.Now().UTC()
+
+	params := map[string]string{
+		"user_id":   "1",
+		"story_id":  "1",
+		"days":      "2",
+		"starts_at": query.TimeString(now.Add(-time.Hour)),
+		"ends_at":   query.TimeString(now.Add(47 * time.Hour)),
+	}
+	id, err := New().Create(params)
+	if err != nil {
+		t.Fatalf("sponsorships: Create failed :%s", err)
+	}
+
+	s, err := Find(id)
+	if err != nil || !s.Payable() || s.Quantity() != 2 {
+		t.Fatalf("sponsorships: Create sponsorship not payable %v", err)
+	}
+
+	// Unpaid sponsorships may not be approved
+	if s.Approve("foo", "https://example.com") == nil {
+		t.Fatalf("sponsorships: unpaid sponsorship approved")
+	}
+
+	// Fulfilment may be repeated
+	for i := 0; i < 2; i++ {
+		err = s.Fulfil()
+		if err != nil {
+			t.Fatalf("sponsorships: Fulfil failed :%s", err)
+		}
+	}
+
+	err = s.Approve("foo", "https://example.com")
+	if err != nil {
+		t.Fatalf("sponsorships: Approve failed :%s", err)
+	}
+
+	// Approved sponsorships which have started are made live
+	started, finished, err := Schedule(now)
+	if err != nil || started != 1 || finished != 0 {
+		t.Fatalf("sponsorships: Schedule start failed %d %d %v", started, finished, err)
+	}
+
+	results, err := FindAll(Pinned())
+	if err != nil || len(results) != 1 || results[0].Name != "foo" {
+		t.Fatalf("sponsorships: live sponsorship not pinned %v", err)
+	}
+	jobResults, err := FindAll(PinnedJobs())
+	if err != nil || len(jobResults) != 0 {
+		t.Fatalf("sponsorships: story pinned on the job board %v", err)
+	}
+
+	// Impressions and clicks are counted for the report
+	err = CountImpressions(results)
+	if err != nil {
+		t.Fatalf("sponsorships: CountImpressions failed :%s", err)
+	}
+	err = s.Click()
+	if err != nil {
+		t.Fatalf("sponsorships: Click failed :%s", err)
+	}
+	s, err = Find(id)
+	if err != nil || s.Impressions != 1 || s.Clicks != 1 || s.ClickRate() != "100.0%" {
+		t.Fatalf("sponsorships: report counts wrong %v", s)
+	}
+
+	// Live sponsorships may not be rejected, and finish when they end
+	if s.Reject() == nil {
+		t.Fatalf("sponsorships: live sponsorship rejected")
+	}
+	started, finished, err = Schedule(now.Add(48 * time.Hour))
+	if err != nil || started != 0 || finished != 1 {
+		t.Fatalf("sponsorships: Schedule finish failed %d %d %v", started, finished, err)
+	}
+	s, err = Find(id)
+	if err != nil || s.Status != Finished {
+		t.Fatalf("sponsorships: sponsorship not finished %v", err)
+	}
+}
@@ -38,7 +38,8 @@ func TestSetup(t *testing.T) {
 
 	// FIXME - Need to write routes out here again, but without pkg prefix
 	// Any neat way to do this instead? We'd need a separate routes package under app...
-	router.Add("/stories", nil)
+	router.Add("/stories{format:(.xml|.atom|.json)?}", nil)
+	router.Add("/stories/upvoted{format:(.xml|.atom|.json)?}", nil)
 	router.Add("/stories/create", nil)
 	router.Add("/stories/create", nil).Post()
 	router.Add("/stories/login", nil)
@@ -172,6 +173,118 @@ func TestListStories(t *testing.T) {
 
 }
 
+// Test GET /stories.xml, /stories.atom, /stories.json and feeds chosen by Accept header
+func TestListStoriesFeeds(t *testing.T) {
+
+	tests := []struct {
+		path    string
+		accept  string
+		pattern string
+	}{
+		{"/stories.xml", "", `<rss version="2.0"`},
+		{"/stories.atom", "", `<feed xmlns="http://www.w3.org/2005/Atom">`},
+		{"/stories.json", "", `"version":"https://jsonfeed.org/version/1.1"`},
+		{"/stories", "application/atom+xml", `<feed xmlns="http://www.w3.org/2005/Atom">`},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+
+		err := HandleIndex(w, r)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("storyactions: error handling HandleIndex for %s %s", tt.path, err)
+		}
+
+		body := w.Body.String()
+		if !strings.Contains(body, tt.pattern) || !strings.Contains(body, names[0]) {
+			t.Fatalf("storyactions: unexpected response for %s expected:%s got:%s", tt.path, tt.pattern, body)
+		}
+
+		// Requests with the current etag are not modified
+		etag := w.Header().Get("ETag")
+		r = httptest.NewRequest("GET", tt.path, nil)
+		r.Header.Set("Accept", tt.accept)
+		r.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+
+		err = HandleIndex(w, r)
+		if err != nil || w.Code != http.StatusNotModified {
+			t.Fatalf("storyactions: unexpected response for conditional %s expected:%d got:%d", tt.path, http.StatusNotModified, w.Code)
+		}
+	}
+}
+
+// Test feeds of stories filtered by tag, domain and user, and the private upvoted feed
+func TestListStoriesFiltered(t *testing.T) {
+
+	_, err := query.ExecSQL("INSERT INTO stories (id,name,url,points,user_id,user_name,created_at,updated_at) VALUES(100,'Tagged story #gofeeds','https://www.example.org/post',5,2,'test',NOW(),NOW());")
+	if err != nil {
+		t.Fatalf("storyactions: error inserting story %s", err)
+	}
+
+	tests := []struct {
+		path    string
+		pattern string
+		found   bool
+	}{
+		{"/stories.atom?tag=gofeeds", "<title>Stories tagged #gofeeds", true},
+		{"/stories.atom?tag=gofeed", "<title>Stories tagged #gofeed", false},
+		{"/stories.atom?domain=example.org", "https://www.example.org/post", true},
+		{"/stories.xml?domain=www.example.org", "<title>Stories from example.org", true},
+		{"/stories.atom?domain=example.com", "<title>Stories from example.com", false},
+		{"/stories.json?u=2", "Stories by test", true},
+		{"/stories.atom?u=1&tag=gofeeds", "<title>Stories by admin", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		err := HandleIndex(w, r)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("storyactions: error handling HandleIndex for %s %s", tt.path, err)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, tt.pattern) || strings.Contains(body, "Tagged story") != tt.found {
+			t.Fatalf("storyactions: unexpected response for %s expected:%s got:%s", tt.path, tt.pattern, body)
+		}
+	}
+
+	// The upvoted feed identifies the user by their token, without a session
+	token := strings.Repeat("a", 64)
+	_, err = query.ExecSQL("UPDATE users SET feed_token='" + token + "' WHERE id=1;")
+	if err != nil {
+		t.Fatalf("storyactions: error setting token %s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO votes (created_at,story_id,user_id,points) VALUES(NOW(),100,1,1);")
+	if err != nil {
+		t.Fatalf("storyactions: error inserting vote %s", err)
+	}
+
+	r := httptest.NewRequest("GET", "/stories/upvoted.atom?token="+token, nil)
+	w := httptest.NewRecorder()
+	err = HandleListUpvoted(w, r)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Tagged story") {
+		t.Fatalf("storyactions: unexpected response for private upvoted feed %v got:%s", err, w.Body.String())
+	}
+
+	// Without a token or session there are no upvoted stories, and invalid tokens are refused
+	r = httptest.NewRequest("GET", "/stories/upvoted.atom", nil)
+	w = httptest.NewRecorder()
+	err = HandleListUpvoted(w, r)
+	if err != nil || strings.Contains(w.Body.String(), "Tagged story") {
+		t.Fatalf("storyactions: unexpected response for anonymous upvoted feed %v got:%s", err, w.Body.String())
+	}
+
+	r = httptest.NewRequest("GET", "/stories/upvoted.atom?token="+strings.Repeat("b", 64), nil)
+	w = httptest.NewRecorder()
+	err = HandleListUpvoted(w, r)
+	if err == nil {
+		t.Fatalf("storyactions: unexpected success for upvoted feed with invalid token")
+	}
+}
+
 // Test of GET /stories/1
 func TestShowStories(t *testing.T) {
 
@@ -200,6 +313,39 @@ func TestShowStories(t *testing.T) {
 	}
 }
 
+// Test GET /stories/1?sort=old orders comments
+func TestShowStoriesSorted(t *testing.T) {
+
+	query.ExecSQL("delete from comments where id in (901,902);")
+	_, err := query.ExecSQL("INSERT INTO comments (id,created_at,status,story_id,parent_id,dotted_ids,points,rank,text,user_id,user_name) VALUES(901,now() - interval '1 hour',100,1,0,'901',1,1,'older comment',1,'admin'),(902,now(),100,1,0,'902',1,1,'newer comment',1,'admin');")
+	if err != nil {
+		t.Fatalf("storyactions: error inserting comments %s", err)
+	}
+
+	for sort, first := range map[string]string{"old": "901", "new": "902"} {
+		r := httptest.NewRequest("GET", "/stories/1", nil)
+		w := httptest.NewRecorder()
+
+		err = resource.AddUserSessionCookie(w, r, 1)
+		if err != nil {
+			t.Fatalf("storyactions: error setting session %s", err)
+		}
+		r.URL.RawQuery += "&sort=" + sort
+
+		err = HandleShow(w, r)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("storyactions: error handling HandleShow %s", err)
+		}
+
+		body := w.Body.String()
+		if strings.Index(body, `id="comment`+first+`"`) > strings.Index(body, `id="comment901"`) || !strings.Contains(body, `class="selected">`+sort) {
+			t.Fatalf("storyactions: comments not sorted by %s got:%s", sort, body)
+		}
+	}
+
+	query.ExecSQL("delete from comments where id in (901,902);")
+}
+
 // Test GET /stories/123/update
 func TestShowUpdateStories(t *testing.T) {
 
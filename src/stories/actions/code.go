@@ -2,13 +2,13 @@ package storyactions
 
 import (
 	"net/http"
-	"strings"
 
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/server/config"
 	"github.com/fragmenta/view"
 
+	"github.com/kennygrant/gohackernews/src/lib/feed"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/stories"
 )
@@ -26,9 +26,14 @@ func HandleListCode(w http.ResponseWriter, r *http.Request) error {
 	// Build a query
 	q := stories.Query().Where("points > -6").Order("rank desc, points desc, id desc").Limit(listLimit)
 
+	// Hide stories by shadow banned users from everyone else
+	currentUser := session.CurrentUser(w, r)
+	currentUser.WhereVisible(q)
+
 	// Restrict to stories with have a url starting with github.com or bitbucket.org
 	// other code repos can be added later
-	q.Where("url ILIKE 'https://github.com%'").OrWhere("url ILIKE 'https://bitbucket.org%'")
+	// in parentheses so that the visibility and points conditions above still apply
+	q.Where("(url ILIKE ? OR url ILIKE ?)", "https://github.com%", "https://bitbucket.org%")
 
 	// Set the offset in pages if we have one
 	page := int(params.GetInt("page"))
@@ -42,6 +47,11 @@ func HandleListCode(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
+	// Serve a feed of the stories if requested
+	if format := feed.Negotiate(w, r); format != "" {
+		return renderStoriesFeed(w, r, format, "Go Code", config.Get("meta_desc"), results)
+	}
+
 	// Render the template
 	view := view.NewRenderer(w, r)
 	view.AddKey("page", page)
@@ -50,15 +60,9 @@ func HandleListCode(w http.ResponseWriter, r *http.Request) error {
 	view.AddKey("meta_title", "Go Code")
 	view.AddKey("meta_desc", config.Get("meta_desc"))
 	view.AddKey("meta_keywords", config.Get("meta_keywords"))
-	view.AddKey("meta_rss", storiesXMLPath(w, r))
+	addFeedKeys(view, r)
 	view.Template("stories/views/index.html.got")
-	view.AddKey("currentUser", session.CurrentUser(w, r))
-
-	// If xml requested, serve with that template
-	if strings.HasSuffix(r.URL.Path, ".xml") {
-		view.Layout("")
-		view.Template("stories/views/index.xml.got")
-	}
+	view.AddKey("currentUser", currentUser)
 
 	return view.Render()
 
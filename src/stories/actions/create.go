@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/fragmenta/auth/can"
 	"github.com/fragmenta/mux"
@@ -11,8 +12,10 @@ import (
 	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
+	"github.com/kennygrant/gohackernews/src/lib/karma"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
 )
 
 // HandleCreateShow serves the create form via GET for stories.
@@ -38,6 +41,14 @@ func HandleCreateShow(w http.ResponseWriter, r *http.Request) error {
 	story.Name = params.Get("n")
 	story.Summary = params.Get("s")
 
+	// Select the kind if the name starts with the prefix of a kind
+	prefill := map[string]string{"name": story.Name}
+	kind, err := stories.CleanKindParams(prefill)
+	if err == nil && kind != nil {
+		story.Name = prefill["name"]
+		story.Kind = kind.ID
+	}
+
 	// Render the template
 	view := view.NewRenderer(w, r)
 	view.AddKey("story", story)
@@ -69,7 +80,14 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 
 	// Check permissions - if not logged in and above points, redirect to error
 	if !currentUser.CanSubmit() {
-		return server.NotAuthorizedError(nil, "Sorry", "You need to be registered and have more than 2 points to submit stories.")
+		msg := fmt.Sprintf("You need to be registered and have more than %d points to submit stories.", karma.Current.Threshold(karma.Submit))
+		return server.NotAuthorizedError(nil, "Sorry", msg)
+	}
+
+	// Limit the stories users may submit each day, supporters have a higher allowance
+	if !currentUser.CanSubmitToday() {
+		msg := fmt.Sprintf("Sorry, you may submit up to %d stories a day, please try again tomorrow.", currentUser.StoryLimit())
+		return server.NotAuthorizedError(nil, "Daily Limit Reached", msg)
 	}
 
 	// Get the params
@@ -99,60 +117,46 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(nil, "URL too long", "The URL of your story is too long, the maximum is 666.")
 	}
 
-	// Strip trailing slashes on url before comparisons
-	if strings.HasSuffix(url, "/") {
-		url = strings.Trim(url, "/")
-	}
-
-	// Strip ?utm_source etc - remove all after ?utm_source
-	if strings.Contains(url, "?utm_") {
-		url = strings.Split(url, "?utm_")[0]
+	// Clean params according to role
+	accepted := stories.AllowedParams()
+	if currentUser.Admin() {
+		accepted = stories.AllowedParamsAdmin()
 	}
+	storyParams := story.ValidateParams(params.Map(), accepted)
 
-	// Strip url fragments (For example trailing # on medium urls)
-	// for now only strip on medium urls
-	if strings.Contains(url, "#") && strings.Contains(url, "medium.com") {
-		url = strings.Split(url, "#")[0]
+	// Set the kind from the form or a title prefix, and check this user may post it
+	kind, err := stories.CleanKindParams(storyParams)
+	if err != nil {
+		return server.NotAuthorizedError(nil, "Invalid Kind", "Sorry, that kind of story is not recognised.")
 	}
-
-	// Rewrite mobile youtube links
-	if strings.HasPrefix(url, "https://m.youtube.com") {
-		url = strings.Replace(url, "https://m.youtube.com", "https://www.youtube.com", 1)
+	if kind != nil && !kind.Allows(currentUser.Points) && !currentUser.Admin() {
+		msg := fmt.Sprintf("You need more than %d points to submit stories of this kind.", kind.SubmitPoints)
+		return server.NotAuthorizedError(nil, "Sorry", msg)
 	}
 
-	params.Set("url", []string{url})
-
-	// Check that no story with this url already exists
-	q := stories.Where("url=?", url)
-	duplicates, err := stories.FindAll(q)
+	// Remove tracking params from the url, and set the canonical url used to find duplicates
+	err = stories.CleanURLParams(storyParams)
 	if err != nil {
-		return server.InternalError(err)
-	}
-
-	// If we have a duplicate story, with the same non-null url, upvote or reject
-	if len(duplicates) > 0 && url != "" {
-		story = duplicates[0]
-
-		// Add a point to dupe if not already voted
-		if !storyHasUserVote(story, currentUser) {
-			addStoryVote(story, currentUser, ip, 1)
+		return server.NotAuthorizedError(nil, "Invalid URL", "Sorry, the URL of your story is not valid.")
+	}
+
+	// If this link has already been submitted, show the story rather than posting it again,
+	// stories this user may not see are not shown, but still may not be posted again
+	if storyParams["canonical_url"] != "" {
+		duplicate, err := stories.FindFirst("canonical_url=?", storyParams["canonical_url"])
+		if err == nil {
+			if authoriseShow(duplicate, currentUser) != nil {
+				return server.NotAuthorizedError(nil, "Already Submitted", "Sorry, that URL has already been submitted.")
+			}
+			return renderDuplicate(w, r, duplicate, currentUser)
 		}
-
-		// Redirect to the story
-		return server.Redirect(w, r, story.ShowURL())
 	}
 
-	// Clean params according to role
-	accepted := stories.AllowedParams()
-	if currentUser.Admin() {
-		accepted = stories.AllowedParamsAdmin()
-	}
-	storyParams := story.ValidateParams(params.Map(), accepted)
-
 	// Set a few params to known good values
 	storyParams["points"] = "1"
 	storyParams["user_id"] = fmt.Sprintf("%d", currentUser.ID)
 	storyParams["user_name"] = currentUser.Name
+	storyParams["user_supporter"] = fmt.Sprintf("%d", currentUser.SupporterTier)
 
 	ID, err := story.Create(storyParams)
 	if err != nil {
@@ -182,3 +186,27 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 
 	return server.Redirect(w, r, story.IndexURL())
 }
+
+// renderDuplicate shows the story which was already submitted with the same link.
+func renderDuplicate(w http.ResponseWriter, r *http.Request, story *stories.Story, currentUser *users.User) error {
+	view := view.NewRenderer(w, r)
+	view.AddKey("story", story)
+	view.AddKey("days", int(time.Since(story.CreatedAt).Hours()/24))
+	view.AddKey("meta_title", "Already submitted")
+	view.AddKey("currentUser", currentUser)
+	view.Template("stories/views/duplicate.html.got")
+	return view.Render()
+}
+
+// CanonicaliseStories sets the canonical url on stories posted before canonical urls
+// were stored, so that they are found as duplicates.
+func CanonicaliseStories() {
+	count, err := stories.BackfillCanonicalURLs(1000)
+	if err != nil {
+		log.Error(log.V{"msg": "canonical url backfill failed", "error": err})
+		return
+	}
+	if count > 0 {
+		log.Info(log.V{"msg": "canonical urls set", "count": count})
+	}
+}
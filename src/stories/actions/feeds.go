@@ -0,0 +1,61 @@
+package storyactions
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fragmenta/server/config"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/feed"
+	"github.com/kennygrant/gohackernews/src/stories"
+)
+
+// renderStoriesFeed writes these stories as a feed in the format given, for the listing at the request path.
+func renderStoriesFeed(w http.ResponseWriter, r *http.Request, format, title, description string, results []*stories.Story) error {
+	f := &feed.Feed{
+		Root:        config.Get("root_url"),
+		Title:       title,
+		Description: description,
+		Path:        strings.TrimSuffix(r.URL.Path, format),
+		Updated:     storiesModTime(results),
+	}
+	for _, story := range results {
+		f.Items = append(f.Items, storyFeedItem(story))
+	}
+	return feed.Render(w, r, f, format)
+}
+
+// storyFeedItem returns the feed item for a story, which links to the story url if it has one.
+func storyFeedItem(story *stories.Story) *feed.Item {
+	item := &feed.Item{
+		ID:           story.ShowURL(),
+		Title:        story.Name,
+		URL:          story.CanonicalURL(),
+		Text:         fmt.Sprintf("%d points posted by %s", story.Points, story.UserName),
+		AuthorName:   story.UserName,
+		AuthorPath:   fmt.Sprintf("/users/%d", story.UserID),
+		CommentsPath: story.CanonicalURL(),
+		Tags:         story.Tags(),
+		Published:    story.CreatedAt,
+		Updated:      story.UpdatedAt,
+	}
+	if story.KindLabel() != "" {
+		item.Title = story.KindLabel() + " " + story.Name
+	}
+	if story.Summary != "" {
+		item.Text = story.Summary + " " + item.Text
+	}
+	if story.DestinationURL() != story.CanonicalURL() {
+		item.ExternalURL = story.DestinationURL()
+	}
+	return item
+}
+
+// addFeedKeys adds the paths of the feeds for this listing to the view, for links in the page head.
+func addFeedKeys(view *view.Renderer, r *http.Request) {
+	view.AddKey("meta_rss", feed.Path(r, feed.RSS))
+	view.AddKey("meta_atom", feed.Path(r, feed.Atom))
+	view.AddKey("meta_json", feed.Path(r, feed.JSON))
+}
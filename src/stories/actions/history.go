@@ -0,0 +1,108 @@
+package storyactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/revisions"
+	"github.com/kennygrant/gohackernews/src/stories"
+)
+
+// HandleHistory responds to GET /stories/{id}/history
+// by showing the changes made to a story since it was posted.
+func HandleHistory(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the story
+	story, err := stories.Find(params.GetInt(stories.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise access - as for showing the story
+	currentUser := session.CurrentUser(w, r)
+	err = authoriseShow(story, currentUser)
+	if err != nil {
+		return err
+	}
+
+	results, err := revisions.FindAll(revisions.ForStory(story.ID))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("story", story)
+	view.AddKey("changes", revisions.Changes(results, revisions.FromStory(story)))
+	view.AddKey("meta_title", fmt.Sprintf("History of %s", story.Name))
+	view.AddKey("currentUser", currentUser)
+	view.Template("stories/views/history.html.got")
+	return view.Render()
+}
+
+// HandleRestore responds to POST /stories/{id}/revisions/{revision_id}/restore
+// by restoring the story to the revision given, the version replaced is kept as a revision.
+func HandleRestore(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the story
+	story, err := stories.Find(params.GetInt(stories.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Find the revision, which must belong to this story
+	revision, err := revisions.Find(params.GetInt("revision_id"))
+	if err != nil || revision.StoryID != story.ID {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may restore revisions
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	storyParams := revision.StoryParams()
+	err = stories.CleanURLParams(storyParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+	err = revisions.RecordStory(story, storyParams, currentUser)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	err = story.Update(storyParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "story revision restored", "story_id": story.ID, "revision_id": revision.ID, "admin_id": currentUser.ID})
+
+	return server.Redirect(w, r, story.ShowURL())
+}
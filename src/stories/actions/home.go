@@ -3,15 +3,18 @@ package storyactions
 import (
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/server/config"
+	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/feed"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/lib/stats"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
 	"github.com/kennygrant/gohackernews/src/stories"
 )
 
@@ -24,6 +27,10 @@ func HandleHome(w http.ResponseWriter, r *http.Request) error {
 	// Build a query
 	q := stories.Query().Limit(listLimit)
 
+	// Hide stories by shadow banned users from everyone else
+	currentUser := session.CurrentUser(w, r)
+	currentUser.WhereVisible(q)
+
 	// Select only above 0 points,  Order by rank, then points, then name
 	q.Where("points > 0").Order("rank desc, points desc, id desc")
 
@@ -45,24 +52,48 @@ func HandleHome(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
+	// Serve a feed of the stories if requested
+	if format := feed.Negotiate(w, r); format != "" {
+		return renderStoriesFeed(w, r, format, config.Get("meta_title"), config.Get("meta_desc"), results)
+	}
+
+	// Fetch the newest jobs to link to the job board from the first page
+	var latestJobs []*jobs.Job
+	if page == 0 {
+		latestJobs, err = jobs.FindAll(jobs.Listed().Limit(3))
+		if err != nil {
+			return server.InternalError(err)
+		}
+	}
+
+	// Fetch live sponsorships for the marked slot on the first page, counting an impression for each
+	var sponsored []*sponsorships.Sponsorship
+	if page == 0 {
+		sponsored, err = sponsorships.FindAll(sponsorships.Pinned().Limit(sponsorships.Slots))
+		if err != nil {
+			return server.InternalError(err)
+		}
+		err = sponsorships.CountImpressions(sponsored)
+		if err != nil {
+			log.Error(log.V{"msg": "sponsorship impressions failed", "error": err})
+		}
+	}
+
 	// Render the template
 	view := view.NewRenderer(w, r)
 	view.AddKey("page", page)
 	view.AddKey("stories", results)
+	view.AddKey("jobs", latestJobs)
+	view.AddKey("sponsored", sponsored)
 	view.Template("stories/views/index.html.got")
 	view.AddKey("pubdate", storiesModTime(results))
 	view.AddKey("meta_title", fmt.Sprintf("%s - %s", config.Get("meta_title"), config.Get("meta_desc")))
 	view.AddKey("meta_desc", config.Get("meta_desc"))
 	view.AddKey("meta_keywords", config.Get("meta_keywords"))
 	view.AddKey("meta_foot", config.Get("meta_desc"))
-	view.AddKey("meta_rss", storiesXMLPath(w, r))
+	addFeedKeys(view, r)
 	view.AddKey("userCount", stats.UserCount())
-	view.AddKey("currentUser", session.CurrentUser(w, r))
-	// For rss feeds use xml templates
-	if strings.HasSuffix(r.URL.Path, ".xml") {
-		view.Layout("")
-		view.Template("stories/views/index.xml.got")
-	}
+	view.AddKey("currentUser", currentUser)
 
 	return view.Render()
 
@@ -3,17 +3,21 @@ package storyactions
 import (
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/server/config"
 	"github.com/fragmenta/view"
 
+	"github.com/kennygrant/gohackernews/src/lib/feed"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/lib/stats"
 	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
 )
 
 const listLimit = 50
@@ -34,11 +38,23 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 	// Build a query
 	q := stories.Query().Limit(listLimit)
 
+	// Hide stories by shadow banned users from everyone else
+	currentUser := session.CurrentUser(w, r)
+	currentUser.WhereVisible(q)
+
 	// Order by date by default
 	q.Where("points > -6").Order("created_at desc")
 
 	// Filter if necessary - this assumes name and summary cols
 	filter := params.Get("q")
+
+	// Links filtering on the title prefixes once used for kinds go to the listing for that kind
+	for _, k := range stories.Kinds {
+		if k.Prefix != "" && strings.EqualFold(filter, k.Prefix) {
+			return server.Redirect(w, r, k.Path)
+		}
+	}
+
 	if len(filter) > 0 {
 
 		// Replace special characters with escaped sequence
@@ -48,12 +64,18 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 		wildcard := "%" + filter + "%"
 
 		// Perform a wildcard search for name or url
-		q.Where("stories.name ILIKE ? OR stories.url ILIKE ?", wildcard, wildcard)
+		q.Where("(stories.name ILIKE ? OR stories.url ILIKE ?)", wildcard, wildcard)
 
 		// If filtering, order by rank, not by date
 		q.Order("rank desc, points desc, id desc")
 	}
 
+	// Filter on tag, domain or user, so that feeds may follow them
+	title, err := filterStories(q, params.Get("tag"), params.Get("domain"), params.GetInt("u"), currentUser)
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
 	// Set the offset in pages if we have one
 	page := params.GetInt("page")
 	if page > 0 {
@@ -66,10 +88,9 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
-	windowTitle := config.Get("meta_title")
-	switch filter {
-	case "Video:":
-		windowTitle = "Golang Videos"
+	// Serve a feed of the stories if requested
+	if format := feed.Negotiate(w, r); format != "" {
+		return renderStoriesFeed(w, r, format, title, config.Get("meta_desc"), results)
 	}
 
 	// Render the template
@@ -77,43 +98,63 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 	view.AddKey("page", page)
 	view.AddKey("stories", results)
 	view.AddKey("pubdate", storiesModTime(results))
-	view.AddKey("meta_title", windowTitle)
+	view.AddKey("meta_title", title)
 	view.AddKey("meta_desc", config.Get("meta_desc"))
 	view.AddKey("meta_keywords", config.Get("meta_keywords"))
-	view.AddKey("meta_rss", storiesXMLPath(w, r))
-	view.AddKey("currentUser", session.CurrentUser(w, r))
-
-	if strings.HasSuffix(r.URL.Path, ".xml") {
-		view.Layout("")
-		view.Template("stories/views/index.xml.got")
-	}
+	addFeedKeys(view, r)
+	view.AddKey("currentUser", currentUser)
 
 	return view.Render()
 
 }
 
-// storiesModTime returns the mod time of the first story, or current time if no stories
-func storiesModTime(availableStories []*stories.Story) time.Time {
-	if len(availableStories) == 0 {
-		return time.Now()
+// filterStories restricts the query to stories with this tag, from this domain, or by this user,
+// and returns the title of the list. The user must be visible to the current user.
+func filterStories(q *query.Query, tag, domain string, userID int64, currentUser *users.User) (string, error) {
+	title := config.Get("meta_title")
+
+	// Tags are words prefixed with # at the end of the name, as in Story.Tags
+	tag = strings.TrimPrefix(tag, "#")
+	if len(tag) > 0 {
+		q.Where("(stories.name || ' ') ILIKE ?", "% #"+escapeLike(tag)+" %")
+		title = fmt.Sprintf("Stories tagged #%s - %s", tag, title)
+	}
+
+	// Domains match as in Story.Domain, ignoring the scheme and www
+	domain = strings.TrimPrefix(strings.ToLower(domain), "www.")
+	if len(domain) > 0 {
+		q.Where("stories.url ~* ?", `^[a-z]+://(www\.)?`+regexp.QuoteMeta(domain)+`(/|:|\?|#|$)`)
+		title = fmt.Sprintf("Stories from %s - %s", domain, title)
+	}
+
+	if userID > 0 {
+		user, err := users.Find(userID)
+		if err != nil || !currentUser.CanSee(user) {
+			return "", fmt.Errorf("storyactions: user %d not found", userID)
+		}
+		q.Where("stories.user_id=?", userID)
+		title = fmt.Sprintf("Stories by %s - %s", user.Name, title)
 	}
-	story := availableStories[0]
 
-	return story.UpdatedAt
+	return title, nil
 }
 
-// storiesXMLPath returns the xml path for a given request to a stories link
-func storiesXMLPath(w http.ResponseWriter, r *http.Request) string {
+// escapeLike escapes the special characters in s for use in an ILIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "_", "\\_", "%", "\\%").Replace(s)
+}
 
-	p := strings.Replace(r.URL.Path, ".xml", "", 1)
-	if p == "/" {
-		p = "/index"
+// storiesModTime returns the latest mod time of the stories, or current time if no stories
+func storiesModTime(availableStories []*stories.Story) time.Time {
+	if len(availableStories) == 0 {
+		return time.Now()
 	}
 
-	q := r.URL.RawQuery
-	if len(q) > 0 {
-		q = "?" + q
+	var modTime time.Time
+	for _, story := range availableStories {
+		if story.UpdatedAt.After(modTime) {
+			modTime = story.UpdatedAt
+		}
 	}
-
-	return fmt.Sprintf("%s.xml%s", p, q)
+	return modTime
 }
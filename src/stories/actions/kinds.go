@@ -0,0 +1,107 @@
+package storyactions
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/config"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/feed"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/lib/stats"
+	"github.com/kennygrant/gohackernews/src/stories"
+)
+
+// HandleListShow responds to GET /show
+func HandleListShow(w http.ResponseWriter, r *http.Request) error {
+	return handleListKind(w, r, stories.KindFor(stories.KindShow))
+}
+
+// HandleListAsk responds to GET /ask
+func HandleListAsk(w http.ResponseWriter, r *http.Request) error {
+	return handleListKind(w, r, stories.KindFor(stories.KindAsk))
+}
+
+// HandleListVideos responds to GET /videos
+func HandleListVideos(w http.ResponseWriter, r *http.Request) error {
+	return handleListKind(w, r, stories.KindFor(stories.KindVideo))
+}
+
+// HandleListEvents responds to GET /events
+func HandleListEvents(w http.ResponseWriter, r *http.Request) error {
+	return handleListKind(w, r, stories.KindFor(stories.KindEvent))
+}
+
+// handleListKind displays a list of stories of one kind ordered by rank,
+// or as a feed if one is requested
+func handleListKind(w http.ResponseWriter, r *http.Request, kind *stories.Kind) error {
+
+	// No Authorisation - anyone can view stories
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	stats.RegisterHit(r)
+
+	// Build a query
+	q := stories.Query().Limit(listLimit)
+
+	// Hide stories by shadow banned users from everyone else
+	currentUser := session.CurrentUser(w, r)
+	currentUser.WhereVisible(q)
+
+	// Filter on kind, and order by rank
+	q.Where("kind=?", kind.ID).Where("points > 0").Order("rank desc, points desc, id desc")
+
+	// Filter if necessary - this assumes name and summary cols
+	filter := params.Get("q")
+	if len(filter) > 0 {
+
+		// Replace special characters with escaped sequence
+		filter = strings.Replace(filter, "_", "\\_", -1)
+		filter = strings.Replace(filter, "%", "\\%", -1)
+
+		wildcard := "%" + filter + "%"
+
+		// Perform a wildcard search for name or url
+		q.Where("(stories.name ILIKE ? OR stories.url ILIKE ?)", wildcard, wildcard)
+	}
+
+	// Set the offset in pages if we have one
+	page := params.GetInt("page")
+	if page > 0 {
+		q.Offset(listLimit * int(page))
+	}
+
+	// Fetch the stories
+	results, err := stories.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Serve a feed of the stories if requested
+	if format := feed.Negotiate(w, r); format != "" {
+		return renderStoriesFeed(w, r, format, kind.Title, kind.Description, results)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.Template("stories/views/index.html.got")
+	view.AddKey("page", page)
+	view.AddKey("stories", results)
+	view.AddKey("kind", kind)
+	view.AddKey("pubdate", storiesModTime(results))
+	view.AddKey("meta_title", kind.Title)
+	view.AddKey("meta_desc", kind.Description)
+	view.AddKey("meta_keywords", strings.ToLower(kind.Name)+" "+config.Get("meta_keywords"))
+	addFeedKeys(view, r)
+	view.AddKey("currentUser", currentUser)
+
+	return view.Render()
+}
@@ -0,0 +1,44 @@
+package storyactions
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/lib/linkmeta"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/stories"
+)
+
+// HandleMetadata fetches the title and summary of the page at the url param,
+// and returns them as json for use in the submit form.
+func HandleMetadata(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Authorise - only users who may submit stories can fetch pages
+	currentUser := session.CurrentUser(w, r)
+	err = can.Create(stories.New(), currentUser)
+	if err != nil || !currentUser.CanSubmit() {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Fetch the page metadata, failures are not shown to users
+	// as the form can still be filled in by hand
+	meta, err := linkmeta.Fetch(params.Get("url"))
+	if err != nil {
+		log.Info(log.V{"msg": "error fetching link metadata", "url": params.Get("url"), "error": err})
+		return server.NotFoundError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(meta)
+}
@@ -0,0 +1,195 @@
+package storyactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// HandleFlagged responds to GET /stories/flagged
+// by showing moderators the stories with unresolved flags.
+func HandleFlagged(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise - only moderators may see flagged stories
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.CanModerate() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	results, err := stories.FindAll(stories.Flagged().Limit(listLimit))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("stories", results)
+	view.AddKey("meta_title", "Flagged stories")
+	view.AddKey("currentUser", currentUser)
+	view.Template("stories/views/flagged.html.got")
+	return view.Render()
+}
+
+// HandleResolveFlags responds to POST /stories/{id}/flags/resolve
+// by upholding the flags (suspending the story) or dismissing them (restoring the points they removed).
+func HandleResolveFlags(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the story
+	story, err := stories.Find(params.GetInt(stories.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only moderators may resolve flags
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.CanModerate() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	action := params.Get("action")
+	switch action {
+	case "uphold":
+		err = story.Update(map[string]string{"status": fmt.Sprintf("%d", status.Suspended)})
+		if err != nil {
+			return server.InternalError(err)
+		}
+	case "dismiss":
+		// Give back the points taken from the story and its author by the flags
+		points, err := story.FlagPoints()
+		if err != nil {
+			return server.InternalError(err)
+		}
+		if points < 0 {
+			err = story.Update(map[string]string{"points": fmt.Sprintf("%d", story.Points-points)})
+			if err != nil {
+				return server.InternalError(err)
+			}
+			err = users.AddPoints(story.UserID, -points, users.ReasonReversal, story.ID, 0)
+			if err != nil {
+				return server.InternalError(err)
+			}
+		}
+	default:
+		return server.NotAuthorizedError(nil, "Resolve Failed", "Sorry, flags must be upheld or dismissed")
+	}
+
+	err = story.ClearFlags()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	err = updateStoriesRank()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "story flags resolved", "story_id": story.ID, "action": action, "moderator_id": currentUser.ID})
+
+	return server.Redirect(w, r, "/stories/flagged")
+}
+
+// HandleLock responds to POST /stories/{id}/lock
+// by locking or unlocking the comment thread on the story.
+func HandleLock(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the story
+	story, err := stories.Find(params.GetInt(stories.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only moderators may lock threads
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.CanModerate() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	err = story.SetLocked(!story.Locked)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "story lock set", "story_id": story.ID, "locked": story.Locked, "moderator_id": currentUser.ID})
+
+	return server.Redirect(w, r, story.ShowURL())
+}
+
+// HandleMerge responds to POST /stories/{id}/merge
+// by merging the story (a duplicate) into the story given by the into param.
+func HandleMerge(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the story
+	story, err := stories.Find(params.GetInt(stories.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Find the story to merge into
+	target, err := stories.Find(params.GetInt("into"))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only moderators may merge stories
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.CanModerate() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	err = story.MergeInto(target)
+	if err != nil {
+		return server.InternalError(err, "Merge Failed", "Sorry, these stories could not be merged")
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "story merged", "story_id": story.ID, "into_id": target.ID, "moderator_id": currentUser.ID})
+
+	return server.Redirect(w, r, target.ShowURL())
+}
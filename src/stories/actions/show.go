@@ -13,7 +13,10 @@ import (
 	"github.com/kennygrant/gohackernews/src/comments"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/lib/status"
+	"github.com/kennygrant/gohackernews/src/payments"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
 	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
 )
 
 // HandleShow displays a single story.
@@ -31,21 +34,32 @@ func HandleShow(w http.ResponseWriter, r *http.Request) error {
 		return server.NotFoundError(err)
 	}
 
-	// Authorise access - for now all stories are visible, later might control on draft/published
-	if story.Status < status.None { // status.Published
-		err = can.Show(story, session.CurrentUser(w, r))
-		if err != nil {
-			return server.NotAuthorizedError(err)
-		}
+	// Duplicates which have been merged redirect to the story they were merged into
+	if story.Merged() {
+		return server.Redirect(w, r, fmt.Sprintf("/stories/%d", story.MergedInto))
+	}
+
+	// Authorise access
+	currentUser := session.CurrentUser(w, r)
+	err = authoriseShow(story, currentUser)
+	if err != nil {
+		return err
 	}
 
-	// Find the comments for this story, excluding those under 0
+	// Find the comments for this story, excluding those under 0, sorted as requested
+	sort := comments.ValidSort(params.Get("sort"))
 	q := comments.Where("story_id=?", story.ID).Where("points > 0").Order(comments.Order)
-	comments, err := comments.FindAll(q)
+	currentUser.WhereVisible(q)
+	results, err := comments.FindTree(q, sort)
 	if err != nil {
 		return server.InternalError(err)
 	}
 
+	// Show a page of threads at a time, long threads are continued on request
+	page := int(params.GetInt("page"))
+	results, more := comments.Page(results, page, comments.CommentsPerPage)
+	comments.Trim(results, comments.RepliesPerComment, comments.ThreadDepth)
+
 	meta := story.Summary
 	if meta == "" {
 		meta = fmt.Sprintf("%s - %s", config.Get("meta_title"), config.Get("meta_desc"))
@@ -56,13 +70,46 @@ func HandleShow(w http.ResponseWriter, r *http.Request) error {
 
 	// Render the template
 	view := view.NewRenderer(w, r)
-	view.CacheKey(story.CacheKey())
+	view.CacheKey(fmt.Sprintf("%s%s%d", story.CacheKey(), sort, page))
 	view.AddKey("story", story)
 	view.AddKey("meta_title", metaTitle)
 	view.AddKey("meta_desc", meta)
 	view.AddKey("meta_foot", config.Get("meta_desc"))
 	view.AddKey("meta_keywords", fmt.Sprintf("%s %s", story.Name, config.Get("meta_keywords")))
-	view.AddKey("comments", comments)
-	view.AddKey("currentUser", session.CurrentUser(w, r))
+	view.AddKey("comments", results)
+	view.AddKey("sort", sort)
+	view.AddKey("sorts", comments.Sorts())
+	view.AddKey("page", page)
+	view.AddKey("meta_rss", fmt.Sprintf("/comments.xml?story=%d", story.ID))
+	view.AddKey("meta_atom", fmt.Sprintf("/comments.atom?story=%d", story.ID))
+	view.AddKey("meta_json", fmt.Sprintf("/comments.json?story=%d", story.ID))
+	view.AddKey("more", more)
+	view.AddKey("sponsor", payments.Enabled(sponsorships.TableName))
+	view.AddKey("currentUser", currentUser)
 	return view.Render()
 }
+
+// authoriseShow returns an error if this user may not see this story.
+func authoriseShow(story *stories.Story, currentUser *users.User) error {
+
+	// Authorise access - for now all stories are visible, later might control on draft/published
+	if story.Status < status.None { // status.Published
+		err := can.Show(story, currentUser)
+		if err != nil {
+			return server.NotAuthorizedError(err)
+		}
+	}
+
+	// Suspended stories are seen only by moderators
+	if story.Status == status.Suspended && !currentUser.CanModerate() {
+		return server.NotFoundError(nil)
+	}
+
+	// Stories by shadow banned users are not found by anyone else
+	author, err := users.Find(story.UserID)
+	if err == nil && !currentUser.CanSee(author) {
+		return server.NotFoundError(nil)
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/fragmenta/view"
 
 	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
 )
 
 // HandleSiteMap renders a site map of top stories
@@ -18,6 +19,9 @@ func HandleSiteMap(w http.ResponseWriter, r *http.Request) error {
 	// Select only above 0 points,  Order by points, then id
 	q.Where("points > 0").Order("points desc, id desc")
 
+	// Leave out stories by shadow banned users
+	users.WherePublic(q)
+
 	// Fetch the stories
 	results, err := stories.FindAll(q)
 	if err != nil {
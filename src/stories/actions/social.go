@@ -11,6 +11,7 @@ import (
 
 	"github.com/kennygrant/gohackernews/src/lib/twitter"
 	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
 )
 
 // TweetTopStory tweets the top story
@@ -26,6 +27,9 @@ func TweetTopStory() {
 	// Don't fetch stories that have already been tweeted
 	q.Where("tweeted_at IS NULL")
 
+	// Don't tweet stories by shadow banned users
+	users.WherePublic(q)
+
 	// Fetch the stories
 	results, err := stories.FindAll(q)
 	if err != nil {
@@ -36,7 +40,7 @@ func TweetTopStory() {
 	// If no results, fall back to older stories which have been tweeted ordered by last tweeted (oldest first)
 	/*
 		if len(results) == 0 {
-			q = stories.Where("points > 10").Where("name not ilike '%release%'").Where("name not like 'Event:%'").Order("tweeted_at asc").Limit(1)
+			q = stories.Where("points > 10").Where("name not ilike '%release%'").Where("kind <> ?", stories.KindEvent).Order("tweeted_at asc").Limit(1)
 			results, err = stories.FindAll(q)
 			if err != nil {
 				log.Log(log.Values{"message": "stories: error getting top story tweet", "error": err})
@@ -1,6 +1,7 @@
 package storyactions
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/fragmenta/auth/can"
@@ -8,7 +9,9 @@ import (
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/view"
 
+	"github.com/kennygrant/gohackernews/src/lib/helpers"
 	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/revisions"
 	"github.com/kennygrant/gohackernews/src/stories"
 )
 
@@ -34,6 +37,13 @@ func HandleUpdateShow(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err)
 	}
 
+	// Authors may only edit within the edit window, admins are exempt, as are moderators
+	// who may only change the status of stories by others
+	moderating := currentUser.Editor() && story.UserID != currentUser.ID
+	if !currentUser.Admin() && !moderating && !story.Editable() {
+		return editWindowClosed()
+	}
+
 	// Render the template
 	view := view.NewRenderer(w, r)
 	view.AddKey("story", story)
@@ -69,13 +79,53 @@ func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err)
 	}
 
-	// Clean params according to role
+	// Authors may only edit within the edit window, admins are exempt, as are moderators
+	// who may only change the status of stories by others
+	moderating := currentUser.Editor() && story.UserID != currentUser.ID
+	if !currentUser.Admin() && !moderating && !story.Editable() {
+		return editWindowClosed()
+	}
+
+	// Clean params according to role, moderators may only change the status of stories by others
 	accepted := stories.AllowedParams()
 	if currentUser.Admin() {
 		accepted = stories.AllowedParamsAdmin()
+	} else if moderating {
+		accepted = stories.AllowedParamsModerator()
 	}
 	storyParams := story.ValidateParams(params.Map(), accepted)
 
+	// Check the kind, authors may only change it to kinds they can post
+	kind, err := stories.CleanKindParams(storyParams)
+	if err != nil {
+		return server.NotAuthorizedError(nil, "Invalid Kind", "Sorry, that kind of story is not recognised.")
+	}
+	if kind != nil && kind.ID != story.Kind && !kind.Allows(currentUser.Points) && !currentUser.CanModerate() {
+		msg := fmt.Sprintf("You need more than %d points to post stories of this kind.", kind.SubmitPoints)
+		return server.NotAuthorizedError(nil, "Sorry", msg)
+	}
+
+	// Clean the url, and check it does not duplicate another story
+	err = stories.CleanURLParams(storyParams)
+	if err != nil {
+		return server.NotAuthorizedError(nil, "Invalid URL", "Sorry, the URL of your story is not valid.")
+	}
+	if c := storyParams["canonical_url"]; c != "" && c != story.NormalURL {
+		duplicate, err := stories.FindFirst("canonical_url=?", c)
+		if err == nil && duplicate.ID != story.ID {
+			if authoriseShow(duplicate, currentUser) != nil {
+				return server.NotAuthorizedError(nil, "Already Submitted", "Sorry, that URL has already been submitted.")
+			}
+			return server.NotAuthorizedError(nil, "Already Submitted", fmt.Sprintf("Sorry, that URL has already been submitted as %s.", duplicate.Name))
+		}
+	}
+
+	// Keep the previous version if the story text has changed
+	err = revisions.RecordStory(story, storyParams, currentUser)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
 	err = story.Update(storyParams)
 	if err != nil {
 		return server.InternalError(err)
@@ -84,3 +134,9 @@ func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
 	// Redirect to story
 	return server.Redirect(w, r, story.ShowURL())
 }
+
+// editWindowClosed returns the error shown to authors who try to edit a story after the edit window.
+func editWindowClosed() error {
+	msg := fmt.Sprintf("Sorry, stories may only be edited for %s after they are posted.", helpers.Duration(stories.EditWindow))
+	return server.NotAuthorizedError(nil, "Editing Closed", msg)
+}
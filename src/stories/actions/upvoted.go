@@ -1,8 +1,8 @@
 package storyactions
 
 import (
+	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/query"
@@ -10,38 +10,51 @@ import (
 	"github.com/fragmenta/server/config"
 	"github.com/fragmenta/view"
 
+	"github.com/kennygrant/gohackernews/src/lib/feed"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/lib/stats"
 	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
 )
 
-// HandleListUpvoted displays a list of stories the user has upvoted in the past
+// HandleListUpvoted displays a list of stories the user has upvoted in the past,
+// feeds of the list identify the user by the token param as feed readers have no session
 func HandleListUpvoted(w http.ResponseWriter, r *http.Request) error {
 	stats.RegisterHit(r)
 
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user from their session, or from the token in private feed urls
+	user := session.CurrentUser(w, r)
+	format := feed.Negotiate(w, r)
+	if token := params.Get("token"); format != "" && token != "" {
+		user, err = users.FindFeedToken(token)
+		if err != nil {
+			return server.NotAuthorizedError(err, "Invalid Token", "This feed link is not valid, please copy the current link from your profile.")
+		}
+	}
+
 	// Build a query
 	q := stories.Query().Limit(listLimit)
 
 	// Select only above 0 points,  Order by rank, then points, then name
 	q.Where("points > 0").Order("rank desc, points desc, id desc")
 
-	// Select only stories which the user has upvoted
-	user := session.CurrentUser(w, r)
-	if !user.Anon() {
-		// Can we use a join instead?
-		v := query.New("votes", "story_id").Select("select story_id as id from votes").Where("user_id=? AND story_id IS NOT NULL AND points > 0", user.ID)
+	// Hide stories by shadow banned users from everyone else
+	user.WhereVisible(q)
 
-		storyIDs := v.ResultIDs()
-		if len(storyIDs) > 0 {
-			q.WhereIn("id", storyIDs)
-		}
-	}
-
-	// Fetch the  params
-	params, err := mux.Params(r)
-	if err != nil {
-		return server.InternalError(err)
+	// Select only stories which the user has upvoted, anonymous users have none
+	// Can we use a join instead?
+	v := query.New("votes", "story_id").Select("select story_id as id from votes").Where("user_id=? AND story_id IS NOT NULL AND points > 0", user.ID)
+	storyIDs := v.ResultIDs()
+	if user.Anon() || len(storyIDs) == 0 {
+		storyIDs = []int64{0}
 	}
+	q.WhereIn("id", storyIDs)
 
 	// Set the offset in pages if we have one
 	page := int(params.GetInt("page"))
@@ -55,6 +68,11 @@ func HandleListUpvoted(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
+	// Serve a feed of the stories if requested
+	if format != "" {
+		return renderStoriesFeed(w, r, format, fmt.Sprintf("Stories upvoted by %s", user.Name), config.Get("meta_desc"), results)
+	}
+
 	// Render the template
 	view := view.NewRenderer(w, r)
 
@@ -64,14 +82,14 @@ func HandleListUpvoted(w http.ResponseWriter, r *http.Request) error {
 	view.AddKey("meta_title", "Stories you have upvoted")
 	view.AddKey("meta_desc", config.Get("meta_desc"))
 	view.AddKey("meta_keywords", config.Get("meta_keywords"))
-	view.AddKey("meta_rss", storiesXMLPath(w, r))
-	view.Template("stories/views/index.html.got")
-	view.AddKey("currentUser", session.CurrentUser(w, r))
-
-	if strings.HasSuffix(r.URL.Path, ".xml") {
-		view.Layout("")
-		view.Template("stories/views/index.xml.got")
+	addFeedKeys(view, r)
+	if user.FeedToken != "" {
+		view.AddKey("meta_rss", user.FeedURL(feed.Path(r, feed.RSS)))
+		view.AddKey("meta_atom", user.FeedURL(feed.Path(r, feed.Atom)))
+		view.AddKey("meta_json", user.FeedURL(feed.Path(r, feed.JSON)))
 	}
+	view.Template("stories/views/index.html.got")
+	view.AddKey("currentUser", user)
 
 	return view.Render()
 
@@ -10,6 +10,7 @@ import (
 	"github.com/fragmenta/query"
 	"github.com/fragmenta/server"
 
+	"github.com/kennygrant/gohackernews/src/lib/karma"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/stories"
 	"github.com/kennygrant/gohackernews/src/users"
@@ -48,21 +49,25 @@ func HandleFlag(w http.ResponseWriter, r *http.Request) error {
 	if !user.CanFlag() {
 		return server.NotAuthorizedError(err, "Flag Failed", "Sorry, you can't flag yet")
 	}
+	if !user.Admin() && !user.CanFlagToday() {
+		return server.NotAuthorizedError(err, "Flag Failed", "Sorry, you have used all your flags for today")
+	}
+
 	// Flags are more expensive than downvotes
-	err = adjustUserPoints(user, -2)
+	err = users.AddPoints(user.ID, -karma.Current.FlagCost, users.ReasonFlagCost, story.ID, 0)
 	if err != nil {
 		return server.InternalError(err, "Failed to adjust points")
 	}
 
 	// Record the flag separately
-	delta := int64(-5)
+	delta := -karma.Current.FlagPenalty
 	err = recordStoryFlag(story, user, ip, delta)
 	if err != nil {
 		return server.InternalError(err, "Failed to add vote")
 	}
 
 	// Downvote the story massively - note this adds a vote record already
-	err = addStoryVote(story, user, ip, delta)
+	err = addStoryVote(story, user, ip, delta, users.ReasonStoryFlagged)
 	if err != nil {
 		return server.InternalError(err, "Failed to add vote")
 	}
@@ -110,14 +115,17 @@ func HandleDownvote(w http.ResponseWriter, r *http.Request) error {
 	if !user.CanDownvote() {
 		return server.NotAuthorizedError(err, "Vote Failed", "Sorry, you can't downvote yet")
 	}
+	if !user.Admin() && !user.CanVoteToday() {
+		return server.NotAuthorizedError(err, "Vote Failed", "Sorry, you have used all your votes for today")
+	}
 
-	err = adjustUserPoints(user, -1)
+	err = users.AddPoints(user.ID, -karma.Current.DownvoteCost, users.ReasonDownvoteCost, story.ID, 0)
 	if err != nil {
-		return err
+		return server.InternalError(err, "Vote Failed", "Sorry could not adjust user points")
 	}
 
 	// Adjust points on story and add to the vote table
-	err = addStoryVote(story, user, ip, -1)
+	err = addStoryVote(story, user, ip, -karma.Current.DownvotePenalty, users.ReasonStoryDownvoted)
 	if err != nil {
 		return err
 	}
@@ -162,9 +170,12 @@ func HandleUpvote(w http.ResponseWriter, r *http.Request) error {
 	if !user.CanUpvote() {
 		return server.NotAuthorizedError(err, "Vote Failed", "Sorry, you can't upvote yet")
 	}
+	if !user.Admin() && !user.CanVoteToday() {
+		return server.NotAuthorizedError(err, "Vote Failed", "Sorry, you have used all your votes for today")
+	}
 
 	// Adjust points on story and add to the vote table
-	err = addStoryVote(story, user, ip, +1)
+	err = addStoryVote(story, user, ip, karma.Current.UpvoteReward, users.ReasonStoryUpvoted)
 	if err != nil {
 		return err
 	}
@@ -173,7 +184,8 @@ func HandleUpvote(w http.ResponseWriter, r *http.Request) error {
 }
 
 // addStoryVote adjusts the story points, and adds a vote record for this user
-func addStoryVote(story *stories.Story, user *users.User, ip string, delta int64) error {
+// the author of the story gains delta points for the reason given
+func addStoryVote(story *stories.Story, user *users.User, ip string, delta int64, reason string) error {
 
 	if story.Points < -5 && delta < 0 {
 		return server.InternalError(nil, "Vote Failed", "Story is already hidden")
@@ -186,28 +198,12 @@ func addStoryVote(story *stories.Story, user *users.User, ip string, delta int64
 	}
 
 	// Update the *story* posting user points by delta
-	storyUser, err := users.Find(story.UserID)
-	if err != nil {
-		return err
-	}
-	err = adjustUserPoints(storyUser, delta)
-	if err != nil {
-		return err
-	}
-
-	return recordStoryVote(story, user, ip, delta)
-}
-
-// removeUserPoints removes these points from this user
-func adjustUserPoints(user *users.User, delta int64) error {
-
-	// Update the user points
-	err := user.Update(map[string]string{"points": fmt.Sprintf("%d", user.Points+delta)})
+	err = users.AddPoints(story.UserID, delta, reason, story.ID, 0)
 	if err != nil {
 		return server.InternalError(err, "Vote Failed", "Sorry could not adjust user points")
 	}
 
-	return nil
+	return recordStoryVote(story, user, ip, delta)
 }
 
 // recordStoryVote adds a vote record for this user
@@ -278,11 +274,13 @@ func getUserIP(r *http.Request) string {
 }
 
 // updateStoriesRank updates the rank of all stories with a rank based on their point score / time elapsed (as represented by id)
-//  to the power of gravity
-//    update stories set rank = points / POWER((select count(*) from stories) - id + 1,1.8);
+// to the power of gravity, which is set for each kind of story, e.g.
+//
+//	update stories set rank = points / POWER((select count(*) from stories) - id + 1,1.8);
+//
 // Similar to HN ranking scheme
 func updateStoriesRank() error {
-	sql := "update stories set rank = 100 * points / POWER((select max(id) from stories) - id + 1,1.2)"
+	sql := "update stories set rank = 100 * points / POWER((select max(id) from stories) - id + 1," + stories.GravitySQL() + ")"
 	_, err := query.Exec(sql)
 	return err
 }
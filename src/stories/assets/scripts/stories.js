@@ -7,54 +7,43 @@ DOM.Ready(function() {
 
 
 // SetSubmitStoryName sets the story name from a URL
-//  attempt to extract a last param from URL, and set name to a munged version of that
+//  fill in a name from the url locally, then ask the server for the page title and summary
 function SetSubmitStoryName() {
 
   DOM.On('.active_url_field', 'change', function(e) {
     var name = DOM.First('.name_field')
-    var summary = DOM.First('.summary_field')
+    var summary = DOM.First('#story-content-textarea')
+    var editable = DOM.First('#story-content-editable')
     var original_name = name.value
     var original_url = this.value
     
-    // First locally fill in the name field 
-    if (original_name== "") {
-      
-      // For github urls, try to fetch some more info 
-      if (original_url.startsWith('https://github.com/')) {
-      
-        var url = original_url.replace('https://github.com/','https://api.github.com/repos/')
-         DOM.Get(url,function(request){
-           var data = JSON.parse(request.response);
-          
-           // if we got a reponse, try using it. 
-           name.value =  data.name + " - " + data.description
-           summary.value = data.description + " by " + data.owner.login
-           
-           // later use 
-           // created_at -> original_published_at
-           // updated_at -> original_updated_at
-           // data.owner.name -> original_author
-           
-         },function(){
-           console.log("failed to fetch github data")
-         });
-      
-        return false;
-      } 
-      
-      // We could also attempt to fetch the html page, and grab metadata from it 
-      // author, pubdate, metadesc etc
-      // would this be better done in a background way after story submission?
-      
-      
-      // Else just use name from local url if we can
-      name.value = urlToSentenceCase(original_url);
-    
-  
+    // Leave names the user has already typed alone
+    if (original_name != "" || original_url == "") {
+      return
     }
-  
     
+    // First locally fill in the name field from the url
+    var local_name = urlToSentenceCase(original_url)
+    name.value = local_name
     
+    // Then fetch the page metadata, and use it if the user has not edited the fields 
+    var url = '/stories/metadata?url=' + encodeURIComponent(original_url)
+    DOM.Get(url, function(request) {
+      var data = JSON.parse(request.response);
+      
+      if (data.title && name.value == local_name) {
+        name.value = data.title
+      }
+      
+      if (data.summary && summary !== undefined && summary.value == "") {
+        summary.value = data.summary
+        if (editable !== undefined) {
+          editable.textContent = data.summary
+        }
+      }
+    }, function() {
+      console.log("failed to fetch link metadata")
+    });
   });
 
 }
@@ -0,0 +1,136 @@
+package stories
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/view/helpers"
+)
+
+// Kind values valid in the kind field of stories.
+const (
+	KindLink  = 0
+	KindShow  = 1
+	KindAsk   = 2
+	KindVideo = 3
+	KindJob   = 4
+	KindEvent = 5
+)
+
+// Kind defines the display, ranking and privilege rules for one kind of story.
+type Kind struct {
+	ID   int64
+	Name string
+
+	// Prefix was used at the start of titles to mark this kind before kinds were stored
+	Prefix string
+
+	// Path is the listing page for this kind, Title and Description are used on it
+	Path        string
+	Title       string
+	Description string
+
+	// Gravity is the exponent used to decay rank with age, higher values fall faster
+	Gravity float64
+
+	// SubmitPoints is the points a user must have more than to submit this kind,
+	// in addition to the usual threshold for submitting stories
+	SubmitPoints int64
+
+	// Discussion kinds link to the story page in lists rather than to the url
+	Discussion bool
+}
+
+// Kinds holds the rules for each kind of story, in the order shown on the submit form.
+var Kinds = []*Kind{
+	{ID: KindLink, Name: "Link", Gravity: 1.2},
+	{ID: KindShow, Name: "Show", Prefix: "Show:", Path: "/show", Title: "Show - Go projects shared by their authors", Description: "Projects shared by Go programmers", Gravity: 1.2, Discussion: true},
+	{ID: KindAsk, Name: "Ask", Prefix: "Ask:", Path: "/ask", Title: "Ask - Questions for Go programmers", Description: "Questions for Go programmers", Gravity: 1.2, Discussion: true},
+	{ID: KindVideo, Name: "Video", Prefix: "Video:", Path: "/videos", Title: "Golang Videos", Description: "Videos about the Go programming language", Gravity: 1.0, Discussion: true},
+	{ID: KindJob, Name: "Job", Prefix: "Hiring:", Path: "/jobs/stories", Title: "Hiring - Go jobs shared as stories", Description: "Jobs for Go hackers", Gravity: 0.8, SubmitPoints: 10},
+	{ID: KindEvent, Name: "Event", Prefix: "Event:", Path: "/events", Title: "Go Events - Conferences and meetups for Go programmers", Description: "Events for Go programmers", Gravity: 0.8, SubmitPoints: 10},
+}
+
+// KindFor returns the kind with this id, or the link kind if none is found.
+func KindFor(id int64) *Kind {
+	for _, k := range Kinds {
+		if k.ID == id {
+			return k
+		}
+	}
+	return Kinds[0]
+}
+
+// KindOptions returns an array of kinds for a kind select.
+func KindOptions() []helpers.Option {
+	var options []helpers.Option
+	for _, k := range Kinds {
+		options = append(options, helpers.Option{Id: k.ID, Name: k.Name})
+	}
+	return options
+}
+
+// Allows returns true if a user with these points may submit this kind of story.
+func (k *Kind) Allows(points int64) bool {
+	return points > k.SubmitPoints
+}
+
+// CleanKindParams checks the kind param is valid, and where the name starts with the
+// prefix of a kind (for example from the bookmarklet), it sets the kind and removes the prefix.
+// It returns the kind set in params, or nil if the kind is unchanged.
+func CleanKindParams(params map[string]string) (*Kind, error) {
+	kind := int64(KindLink)
+	v, given := params["kind"]
+	if given && v != "" {
+		var err error
+		kind, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || KindFor(kind).ID != kind {
+			return nil, fmt.Errorf("stories: invalid kind %s", v)
+		}
+	}
+
+	if name, ok := params["name"]; ok {
+		for _, k := range Kinds {
+			if k.Prefix == "" || !strings.HasPrefix(strings.ToLower(name), strings.ToLower(k.Prefix)) {
+				continue
+			}
+			params["name"] = strings.TrimSpace(name[len(k.Prefix):])
+			if kind == KindLink {
+				kind = k.ID
+			}
+			given = true
+			break
+		}
+	}
+
+	if !given {
+		return nil, nil
+	}
+	params["kind"] = fmt.Sprintf("%d", kind)
+	return KindFor(kind), nil
+}
+
+// GravitySQL returns an sql expression giving the gravity for the kind of each story.
+func GravitySQL() string {
+	sql := "CASE kind"
+	for _, k := range Kinds {
+		sql += fmt.Sprintf(" WHEN %d THEN %g", k.ID, k.Gravity)
+	}
+	return sql + fmt.Sprintf(" ELSE %g END", Kinds[0].Gravity)
+}
+
+// KindInfo returns the rules for the kind of this story.
+func (s *Story) KindInfo() *Kind {
+	return KindFor(s.Kind)
+}
+
+// KindOptions returns an array of kinds for a kind select for this story.
+func (s *Story) KindOptions() []helpers.Option {
+	return KindOptions()
+}
+
+// KindLabel returns the label shown before the name of this story, or "" for links.
+func (s *Story) KindLabel() string {
+	return s.KindInfo().Prefix
+}
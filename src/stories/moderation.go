@@ -0,0 +1,81 @@
+package stories
+
+import (
+	"fmt"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+)
+
+// This file contains functions used by moderators to lock, merge and resolve flags on stories.
+
+// Flagged returns a query for stories which have unresolved flags.
+func Flagged() *query.Query {
+	return Query().Where("id IN (select story_id from flags where story_id IS NOT NULL AND comment_id IS NULL)").Order("created_at desc, id desc")
+}
+
+// Merged returns true if this story has been merged into another.
+func (s *Story) Merged() bool {
+	return s.MergedInto > 0
+}
+
+// SetLocked locks or unlocks the comment thread on this story.
+func (s *Story) SetLocked(locked bool) error {
+	err := s.Update(map[string]string{"locked": fmt.Sprintf("%t", locked)})
+	if err != nil {
+		return err
+	}
+	s.Locked = locked
+	return nil
+}
+
+// MergeInto moves the comments on this duplicate story to the target story,
+// and suspends this story, recording the story it was merged into.
+func (s *Story) MergeInto(target *Story) error {
+	if target.ID == s.ID || target.Merged() {
+		return fmt.Errorf("stories: cannot merge story %d into story %d", s.ID, target.ID)
+	}
+
+	_, err := query.Exec("update comments set story_id=$1, story_name=$2 where story_id=$3", target.ID, target.Name, s.ID)
+	if err != nil {
+		return err
+	}
+
+	sql := "update stories set comment_count=(select count(*) from comments where story_id=$1 and points > 0 and deleted_at IS NULL) where id=$1"
+	_, err = query.Exec(sql, target.ID)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		"status":        fmt.Sprintf("%d", status.Suspended),
+		"merged_into":   fmt.Sprintf("%d", target.ID),
+		"comment_count": "0",
+	}
+	err = s.Update(params)
+	if err != nil {
+		return err
+	}
+
+	s.Status = status.Suspended
+	s.MergedInto = target.ID
+	s.CommentCount = 0
+	return nil
+}
+
+// FlagPoints returns the total points removed from this story by flags which have not been resolved.
+func (s *Story) FlagPoints() (int64, error) {
+	results, err := query.New("flags", "story_id").Select("select coalesce(sum(points),0) as points from flags").Where("story_id=? AND comment_id IS NULL", s.ID).Results()
+	if err != nil || len(results) == 0 {
+		return 0, err
+	}
+	return resource.ValidateInt(results[0]["points"]), nil
+}
+
+// ClearFlags removes the flags on this story once they have been resolved.
+func (s *Story) ClearFlags() error {
+	_, err := query.Exec("delete from flags where story_id=$1 and comment_id IS NULL", s.ID)
+	return err
+}
@@ -20,12 +20,18 @@ const (
 
 // AllowedParams returns the cols editable by everyone
 func AllowedParams() []string {
-	return []string{"name", "summary", "url"}
+	return []string{"name", "kind", "summary", "url"}
 }
 
 // AllowedParamsAdmin returns the cols editable by admins
 func AllowedParamsAdmin() []string {
-	return []string{"status", "comment_count", "name", "points", "rank", "summary", "url", "user_id", "user_name"}
+	return []string{"status", "comment_count", "name", "kind", "points", "rank", "summary", "url", "user_id", "user_name"}
+}
+
+// AllowedParamsModerator returns the cols editable by moderators on stories by others,
+// who may suspend stories but not change their content, points or ownership
+func AllowedParamsModerator() []string {
+	return []string{"status"}
 }
 
 // NewWithColumns creates a new story instance and fills it with data from the database cols provided.
@@ -38,12 +44,18 @@ func NewWithColumns(cols map[string]interface{}) *Story {
 	story.Status = resource.ValidateInt(cols["status"])
 	story.CommentCount = resource.ValidateInt(cols["comment_count"])
 	story.Name = resource.ValidateString(cols["name"])
+	story.Kind = resource.ValidateInt(cols["kind"])
 	story.Points = resource.ValidateInt(cols["points"])
 	story.Rank = resource.ValidateInt(cols["rank"])
 	story.Summary = resource.ValidateString(cols["summary"])
 	story.URL = resource.ValidateString(cols["url"])
+	story.NormalURL = resource.ValidateString(cols["canonical_url"])
 	story.UserID = resource.ValidateInt(cols["user_id"])
 	story.UserName = resource.ValidateString(cols["user_name"])
+	story.UserSupporter = resource.ValidateInt(cols["user_supporter"])
+	story.Locked = resource.ValidateBoolean(cols["locked"])
+	story.MergedInto = resource.ValidateInt(cols["merged_into"])
+	story.EditedAt = resource.ValidateTime(cols["edited_at"])
 
 	return story
 }
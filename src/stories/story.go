@@ -12,6 +12,10 @@ import (
 	"github.com/kennygrant/gohackernews/src/lib/status"
 )
 
+// EditWindow is the time after posting during which authors may edit their stories,
+// it is replaced on startup by the value of story_edit_minutes in config.
+var EditWindow = time.Hour
+
 // Story handles saving and retreiving stories from the database
 type Story struct {
 	// resource.Base defines behaviour and fields shared between all resources
@@ -21,6 +25,7 @@ type Story struct {
 	status.ResourceStatus
 
 	Name         string
+	Kind         int64
 	Summary      string
 	URL          string
 	UserID       int64
@@ -28,8 +33,23 @@ type Story struct {
 	Rank         int64
 	CommentCount int64
 
+	// NormalURL is the canonical form of URL (stored as canonical_url), used to find duplicates
+	NormalURL string
+
 	// UserName denormalises the user name - pull from users join
 	UserName string
+
+	// UserSupporter denormalises the supporter tier of the user, for the badge next to their name
+	UserSupporter int64
+
+	// Locked stories accept no new comments
+	Locked bool
+
+	// MergedInto is the story this duplicate was merged into (if any)
+	MergedInto int64
+
+	// EditedAt is the time the name, summary or url were last changed
+	EditedAt time.Time
 }
 
 // Domain returns the domain of the story URL
@@ -46,11 +66,6 @@ func (s *Story) Domain() string {
 	return "GN"
 }
 
-// ShowAsk returns true if this is a Show: or Ask: story
-func (s *Story) ShowAsk() bool {
-	return strings.HasPrefix(s.Name, "Show:") || strings.HasPrefix(s.Name, "Ask:")
-}
-
 // DestinationURL returns the URL of the story
 // if no url is set, it uses the CanonicalURL
 func (s *Story) DestinationURL() string {
@@ -67,11 +82,11 @@ func (s *Story) DestinationURL() string {
 }
 
 // PrimaryURL returns the URL to use for this story in lists
-// Videos and Show Ask stories link to the story
+// Videos and discussion kinds such as Show and Ask link to the story
 // for other links for now it is the destination
 func (s *Story) PrimaryURL() string {
-	// If video or show or empty, return story url
-	if s.YouTube() || s.ShowAsk() || s.URL == "" {
+	// If video or discussion or empty, return story url
+	if s.YouTube() || s.KindInfo().Discussion || s.URL == "" {
 		return s.CanonicalURL()
 	}
 
@@ -155,10 +170,15 @@ func (s *Story) Tags() []string {
 	return tags
 }
 
-// Editable returns true if this story is editable.
-// Stories are editable if less than 1 hours old
+// Editable returns true if this story is editable by its author.
+// Stories are editable if they were posted within the EditWindow
 func (s *Story) Editable() bool {
-	return time.Now().Sub(s.CreatedAt) < time.Hour*1
+	return time.Now().Sub(s.CreatedAt) < EditWindow
+}
+
+// Edited returns true if this story has been changed since it was posted.
+func (s *Story) Edited() bool {
+	return !s.EditedAt.IsZero()
 }
 
 // OwnedBy returns true if this user id owns this story.
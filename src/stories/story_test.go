@@ -2,9 +2,12 @@
 package stories
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/status"
 )
 
 var testName = "foo"
@@ -132,6 +135,121 @@ func TestDestroyStories(t *testing.T) {
 
 }
 
+// Test moderation of stories
+func TestModerateStories(t *testing.T) {
+
+	id, err := New().Create(map[string]string{"name": "original", "status": "100"})
+	if err != nil {
+		t.Fatalf("stories: Create story failed :%s", err)
+	}
+	duplicateID, err := New().Create(map[string]string{"name": "duplicate", "status": "100"})
+	if err != nil {
+		t.Fatalf("stories: Create story failed :%s", err)
+	}
+	original, _ := Find(id)
+	duplicate, _ := Find(duplicateID)
+
+	err = original.SetLocked(true)
+	if err != nil {
+		t.Fatalf("stories: SetLocked failed :%s", err)
+	}
+	original, _ = Find(id)
+	if !original.Locked {
+		t.Fatalf("stories: SetLocked did not lock story")
+	}
+
+	err = duplicate.MergeInto(duplicate)
+	if err == nil {
+		t.Fatalf("stories: MergeInto merged story into itself")
+	}
+
+	err = duplicate.MergeInto(original)
+	if err != nil {
+		t.Fatalf("stories: MergeInto failed :%s", err)
+	}
+	duplicate, _ = Find(duplicateID)
+	if !duplicate.Merged() || duplicate.MergedInto != id || duplicate.Status != status.Suspended {
+		t.Fatalf("stories: MergeInto did not merge story")
+	}
+
+	duplicate.Destroy()
+	original.Destroy()
+}
+
+// Test stories are editable only within the edit window
+func TestEditableStories(t *testing.T) {
+	story := New()
+	if !story.Editable() {
+		t.Fatalf("stories: new story not editable")
+	}
+	story.CreatedAt = time.Now().Add(-EditWindow - time.Minute)
+	if story.Editable() {
+		t.Fatalf("stories: story editable after edit window")
+	}
+}
+
+// Test urls are cleaned and given a canonical form on create and update
+func TestCleanURLParams(t *testing.T) {
+	params := map[string]string{"url": "http://www.example.com/post/?utm_source=feed&id=1"}
+	err := CleanURLParams(params)
+	if err != nil {
+		t.Fatalf("stories: CleanURLParams failed :%s", err)
+	}
+	if params["url"] != "http://www.example.com/post/?id=1" || params["canonical_url"] != "https://example.com/post?id=1" {
+		t.Fatalf("stories: CleanURLParams wrong got:%v", params)
+	}
+
+	// Stories without a url have no canonical url
+	params = map[string]string{"url": ""}
+	err = CleanURLParams(params)
+	if err != nil || params["canonical_url"] != "" {
+		t.Fatalf("stories: CleanURLParams wrong for empty url got:%v", params)
+	}
+
+	params = map[string]string{"url": "javascript:alert(1)"}
+	err = CleanURLParams(params)
+	if err == nil {
+		t.Fatalf("stories: CleanURLParams accepted invalid url")
+	}
+}
+
+// Test kinds are set from the form or from title prefixes
+func TestCleanKindParams(t *testing.T) {
+	params := map[string]string{"name": "Show: A new router", "kind": "0"}
+	kind, err := CleanKindParams(params)
+	if err != nil || kind.ID != KindShow || params["name"] != "A new router" || params["kind"] != "1" {
+		t.Fatalf("stories: CleanKindParams wrong for prefix got:%v", params)
+	}
+
+	// A kind chosen on the form is kept, but the prefix is still removed
+	params = map[string]string{"name": "hiring: Go developer", "kind": "5"}
+	kind, err = CleanKindParams(params)
+	if err != nil || kind.ID != KindEvent || params["name"] != "Go developer" {
+		t.Fatalf("stories: CleanKindParams wrong for chosen kind got:%v", params)
+	}
+
+	// Updates without a kind or prefix leave the kind alone
+	params = map[string]string{"name": "A plain link"}
+	kind, err = CleanKindParams(params)
+	if err != nil || kind != nil || params["kind"] != "" {
+		t.Fatalf("stories: CleanKindParams set kind got:%v", params)
+	}
+
+	params = map[string]string{"name": "Unknown", "kind": "99"}
+	_, err = CleanKindParams(params)
+	if err == nil {
+		t.Fatalf("stories: CleanKindParams accepted invalid kind")
+	}
+
+	// Kinds carry their own privilege and ranking rules
+	if KindFor(KindJob).Allows(KindFor(KindJob).SubmitPoints) || !KindFor(KindLink).Allows(1) {
+		t.Fatalf("stories: wrong kind privileges")
+	}
+	if !strings.Contains(GravitySQL(), "WHEN 4 THEN 0.8") {
+		t.Fatalf("stories: wrong gravity sql got:%s", GravitySQL())
+	}
+}
+
 // TestAllowedParams should always return some params
 func TestAllowedParams(t *testing.T) {
 	if len(AllowedParams()) == 0 {
@@ -0,0 +1,63 @@
+package stories
+
+import (
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/urlnorm"
+)
+
+// This file contains functions for finding stories by the canonical form of their url.
+
+// CleanURLParams cleans the url param (if any) and sets canonical_url from it,
+// it returns an error if the url is invalid. Stories without a url have an empty canonical_url.
+func CleanURLParams(params map[string]string) error {
+	raw, ok := params["url"]
+	if !ok {
+		return nil
+	}
+	if raw == "" {
+		params["canonical_url"] = ""
+		return nil
+	}
+
+	clean, err := urlnorm.Clean(raw)
+	if err != nil {
+		return err
+	}
+	canonical, err := urlnorm.Canonical(clean)
+	if err != nil {
+		return err
+	}
+
+	params["url"] = clean
+	params["canonical_url"] = canonical
+	return nil
+}
+
+// BackfillCanonicalURLs sets canonical_url on stories which do not have one yet.
+// Where older stories are duplicates only the first keeps the canonical url,
+// the others are given an empty one. It returns the number of stories updated.
+func BackfillCanonicalURLs(limit int) (int, error) {
+	results, err := query.New(TableName, KeyName).Where("canonical_url IS NULL").Order("id asc").Limit(limit).Results()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cols := range results {
+		story := NewWithColumns(cols)
+
+		canonical, err := urlnorm.Canonical(story.URL)
+		if err != nil {
+			canonical = ""
+		} else if _, err = FindFirst("canonical_url=?", canonical); err == nil {
+			canonical = ""
+		}
+
+		_, err = query.Exec("update stories set canonical_url=$1 where id=$2", canonical, story.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(results), nil
+}
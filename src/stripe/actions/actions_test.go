@@ -0,0 +1,288 @@
+package stripeactions
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+	"github.com/kennygrant/gohackernews/src/payments"
+)
+
+// testWebhookSecret is used to sign the webhook fixtures in testdata.
+const testWebhookSecret = "whsec_test"
+
+// api is a local stand-in for the Stripe api, which creates the checkout session cs_test_1.
+var api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if r.URL.Path != "/v1/checkout/sessions" || r.Form.Get("line_items[0][price]") != "price_test_jobs" || r.Form.Get("client_reference_id") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"Invalid request"}}`))
+		return
+	}
+	w.Write([]byte(`{"id":"cs_test_1","object":"checkout.session","url":"https://checkout.stripe.com/c/pay/cs_test_1","mode":"payment","payment_status":"unpaid"}`))
+}))
+
+// TestSetup performs setup for integration tests
+// using the test database, real views, mock authorisation and a stand-in for stripe
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(3)
+	if err != nil {
+		fmt.Printf("stripe: Setup db failed %s", err)
+	}
+
+	// Set up mock auth
+	resource.SetupAuthorisation()
+
+	// Load templates for rendering
+	resource.SetupView(3)
+
+	router := mux.New()
+	mux.SetDefault(router)
+
+	router.Add("/stripe/pay", nil)
+	router.Add("/stripe/thanks", nil)
+	router.Add("/stripe/checkout", nil).Post()
+	router.Add("/stripe/webhook", nil).Post()
+	router.Add("/stripe/payments", nil)
+
+	// Point stripe at the local stand-in, and register jobs as a product
+	stripe.Default = stripe.New("sk_test", testWebhookSecret)
+	stripe.Default.BaseURL = api.URL
+	payments.Register(&payments.Product{
+		Name:        jobs.TableName,
+		Description: "job listing",
+		Price:       "price_test_jobs",
+		Find: func(id int64) (payments.Item, error) {
+			return jobs.Find(id)
+		},
+	})
+
+	// Delete all payments and jobs to ensure we get consistent results
+	query.ExecSQL("delete from payments;")
+	query.ExecSQL("ALTER SEQUENCE payments_id_seq RESTART WITH 1;")
+	query.ExecSQL("delete from jobs;")
+	query.ExecSQL("ALTER SEQUENCE jobs_id_seq RESTART WITH 1;")
+
+	// Delete all users to ensure we get consistent results
+	_, err = query.ExecSQL("delete from users;")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	// Insert a test admin user, and an employer
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(1,'example@example.com','admin',100,100,100,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	_, err = query.ExecSQL("INSERT INTO users (id,email,name,points,status,role,password_hash) VALUES(2,'example2@example.com','employer',100,100,10,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+	query.ExecSQL("ALTER SEQUENCE users_id_seq RESTART WITH 3;")
+
+	// Insert a job awaiting payment by the employer
+	_, err = query.ExecSQL("INSERT INTO jobs (id,created_at,status,name,company,user_id,user_name,expires_at) VALUES(1,now(),1,'Go developer','Example',2,'employer',now());")
+	if err != nil {
+		t.Fatalf("error setting up:%s", err)
+	}
+}
+
+// Test GET /stripe/pay for a job
+func TestShowPay(t *testing.T) {
+
+	for _, id := range []int{1, 2} {
+		r := httptest.NewRequest("GET", "/stripe/pay", nil)
+		w := httptest.NewRecorder()
+
+		err := resource.AddUserSessionCookie(w, r, id)
+		if err != nil {
+			t.Fatalf("stripeactions: error setting session %s", err)
+		}
+		r.URL.RawQuery += "&product=jobs&item_id=1"
+
+		err = HandleShowPay(w, r)
+
+		// Only the employer may pay for their job
+		if id == 1 {
+			if err == nil {
+				t.Fatalf("stripeactions: user allowed to pay for job of another user")
+			}
+			continue
+		}
+
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("stripeactions: error handling HandleShowPay %s", err)
+		}
+
+		pattern := `action="/stripe/checkout"`
+		if !strings.Contains(w.Body.String(), pattern) {
+			t.Fatalf("stripeactions: unexpected response for HandleShowPay expected:%s got:%s", pattern, w.Body.String())
+		}
+	}
+}
+
+// Test POST /stripe/checkout records a pending payment and redirects to checkout
+func TestCheckout(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("product", jobs.TableName)
+	form.Add("item_id", "1")
+	body := strings.NewReader(form.Encode())
+
+	r := httptest.NewRequest("POST", "/stripe/checkout", body)
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	err := resource.AddUserSessionCookie(w, r, 2)
+	if err != nil {
+		t.Fatalf("stripeactions: error setting session %s", err)
+	}
+
+	err = HandleCheckout(w, r)
+	if err != nil {
+		t.Fatalf("stripeactions: error handling HandleCheckout %s", err)
+	}
+
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://checkout.stripe.com/c/pay/cs_test_1" {
+		t.Fatalf("stripeactions: unexpected redirect for HandleCheckout got:%d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	payment, err := payments.Find(1)
+	if err != nil || payment.SessionID != "cs_test_1" || payment.Status != payments.Pending || payment.UserID != 2 || payment.ItemID != 1 {
+		t.Fatalf("stripeactions: wrong payment recorded %v %v", payment, err)
+	}
+
+	// Checkout alone must not list the job
+	job, err := jobs.Find(1)
+	if err != nil || !job.AwaitingPayment() {
+		t.Fatalf("stripeactions: job listed before payment %v", err)
+	}
+}
+
+// replay posts the webhook fixture in testdata to HandleWebhook, signed with secret.
+func replay(t *testing.T, name string, secret string) (*httptest.ResponseRecorder, error) {
+	payload, err := ioutil.ReadFile(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatalf("stripeactions: error reading fixture %s", err)
+	}
+
+	r := httptest.NewRequest("POST", "/stripe/webhook", bytes.NewReader(payload))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Stripe-Signature", stripe.SignatureHeader(payload, secret, time.Now()))
+	w := httptest.NewRecorder()
+
+	return w, HandleWebhook(w, r)
+}
+
+// Test POST /stripe/webhook fulfils payments once, and only for signed events
+func TestWebhook(t *testing.T) {
+
+	// Events with an invalid signature are rejected
+	_, err := replay(t, "checkout.session.completed", "whsec_other")
+	if err == nil {
+		t.Fatalf("stripeactions: webhook accepted with invalid signature")
+	}
+	job, err := jobs.Find(1)
+	if err != nil || !job.AwaitingPayment() {
+		t.Fatalf("stripeactions: job listed after invalid webhook %v", err)
+	}
+
+	// A completed session fulfils the payment, listing the job
+	w, err := replay(t, "checkout.session.completed", testWebhookSecret)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("stripeactions: error handling HandleWebhook %s", err)
+	}
+
+	payment, err := payments.Find(1)
+	if err != nil || !payment.Complete() || payment.Amount != 5000 || payment.PaymentIntent != "pi_test_1" || payment.EventID != "evt_test_completed" {
+		t.Fatalf("stripeactions: payment not fulfilled %v %v", payment, err)
+	}
+	job, err = jobs.Find(1)
+	if err != nil || job.AwaitingPayment() || job.Expired() {
+		t.Fatalf("stripeactions: job not listed after payment %v", err)
+	}
+	expires := job.ExpiresAt
+
+	// Repeated and late events change nothing
+	for _, name := range []string{"checkout.session.completed", "checkout.session.expired"} {
+		w, err = replay(t, name, testWebhookSecret)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("stripeactions: error handling HandleWebhook %s %s", name, err)
+		}
+	}
+	payment, err = payments.Find(1)
+	if err != nil || !payment.Complete() {
+		t.Fatalf("stripeactions: payment changed by repeated events %v %v", payment, err)
+	}
+	job, err = jobs.Find(1)
+	if err != nil || !job.ExpiresAt.Equal(expires) {
+		t.Fatalf("stripeactions: job fulfilled twice %v", err)
+	}
+
+	// Events for sessions which are not ours are acknowledged and ignored
+	w, err = replay(t, "checkout.session.unknown", testWebhookSecret)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("stripeactions: error handling HandleWebhook for unknown session %s", err)
+	}
+}
+
+// Test GET /stripe/thanks shows the payment status to the payer
+func TestShowPayThanks(t *testing.T) {
+
+	r := httptest.NewRequest("GET", "/stripe/thanks", nil)
+	w := httptest.NewRecorder()
+
+	err := resource.AddUserSessionCookie(w, r, 2)
+	if err != nil {
+		t.Fatalf("stripeactions: error setting session %s", err)
+	}
+	r.URL.RawQuery += "&session_id=cs_test_1"
+
+	err = HandleShowPayThanks(w, r)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("stripeactions: error handling HandleShowPayThanks %s", err)
+	}
+
+	pattern := "USD 50.00 has been received"
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("stripeactions: unexpected response for HandleShowPayThanks expected:%s got:%s", pattern, w.Body.String())
+	}
+}
+
+// Test GET /stripe/payments is shown only to admins
+func TestIndex(t *testing.T) {
+
+	for _, id := range []int{2, 1} {
+		r := httptest.NewRequest("GET", "/stripe/payments", nil)
+		w := httptest.NewRecorder()
+
+		err := resource.AddUserSessionCookie(w, r, id)
+		if err != nil {
+			t.Fatalf("stripeactions: error setting session %s", err)
+		}
+
+		err = HandleIndex(w, r)
+		if id == 2 {
+			if err == nil {
+				t.Fatalf("stripeactions: payments shown to employer")
+			}
+			continue
+		}
+
+		if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "cs_test_1") {
+			t.Fatalf("stripeactions: error handling HandleIndex %s", err)
+		}
+	}
+}
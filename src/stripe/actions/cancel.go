@@ -3,19 +3,20 @@ package stripeactions
 import (
 	"net/http"
 
-	"github.com/fragmenta/mux/log"
+	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
 	"github.com/kennygrant/gohackernews/src/lib/session"
 )
 
-// HandleShowPayCancel displays a page for canceleld payments
+// HandleShowPayCancel displays a page for cancelled payments, the pending payment
+// is left in the ledger until Stripe tells us the session has expired.
 func HandleShowPayCancel(w http.ResponseWriter, r *http.Request) error {
 
 	// Find logged in user (if any)
 	currentUser := session.CurrentUser(w, r)
 
-	log.Printf("pay: page thanks shown for user:%d", currentUser.ID)
+	log.Info(log.V{"msg": "pay: page cancel shown", "user_id": currentUser.ID})
 
 	// Render the template
 	view := view.NewRenderer(w, r)
@@ -0,0 +1,90 @@
+package stripeactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/config"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+	"github.com/kennygrant/gohackernews/src/payments"
+)
+
+// HandleCheckout responds to POST /stripe/checkout by recording a pending payment
+// for the current user and item, and redirecting to a Stripe Checkout session for it.
+// Nothing is fulfilled here, payments are fulfilled only by HandleWebhook.
+func HandleCheckout(w http.ResponseWriter, r *http.Request) error {
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Authorise - users may pay only for their own items
+	currentUser := session.CurrentUser(w, r)
+	product, item, err := findPurchase(params.Get("product"), params.GetInt("item_id"), currentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	// Record the payment in the ledger before checkout, so the webhook can find it
+	paymentParams := map[string]string{
+		"status":  fmt.Sprintf("%d", payments.Pending),
+		"user_id": fmt.Sprintf("%d", currentUser.ID),
+		"product": product.Name,
+		"item_id": fmt.Sprintf("%d", params.GetInt("item_id")),
+	}
+	ID, err := payments.New().Create(paymentParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	root := config.Get("root_url")
+	checkout := stripe.CheckoutParams{
+		Mode:              product.Mode,
+		Price:             product.Price,
+		SuccessURL:        root + "/stripe/thanks?session_id={CHECKOUT_SESSION_ID}",
+		CancelURL:         root + "/stripe/cancel",
+		ClientReferenceID: fmt.Sprintf("%d", ID),
+		CustomerEmail:     currentUser.Email,
+		Metadata: map[string]string{
+			"user_id": fmt.Sprintf("%d", currentUser.ID),
+			"product": product.Name,
+			"item_id": paymentParams["item_id"],
+		},
+	}
+
+	// Some items are bought in several units, such as days of sponsorship
+	if q, ok := item.(payments.Quantifier); ok {
+		checkout.Quantity = q.Quantity()
+	}
+
+	checkoutSession, err := stripe.Default.CreateCheckoutSession(checkout)
+	if err != nil {
+		return server.InternalError(err, "Payment Error", "Sorry, we were unable to start checkout, please try again later.")
+	}
+
+	payment, err := payments.Find(ID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+	err = payment.Update(map[string]string{"session_id": checkoutSession.ID})
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	log.Info(log.V{"msg": "pay: checkout started", "payment_id": ID, "user_id": currentUser.ID, "product": product.Name})
+
+	return server.Redirect(w, r, checkoutSession.URL)
+}
@@ -0,0 +1,52 @@
+package stripeactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/payments"
+)
+
+// paymentsLimit is the number of payments shown per page
+const paymentsLimit = 50
+
+// HandleIndex responds to GET /stripe/payments by showing the payments ledger to admins.
+func HandleIndex(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise - only admins may see payments
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Set the offset in pages if we have one
+	q := payments.Query().Limit(paymentsLimit)
+	page := params.GetInt("page")
+	if page > 0 {
+		q.Offset(paymentsLimit * int(page))
+	}
+
+	results, err := payments.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.Template("stripe/views/index.html.got")
+	view.AddKey("payments", results)
+	view.AddKey("page", page)
+	view.AddKey("more", len(results) == paymentsLimit)
+	view.AddKey("currentUser", currentUser)
+	return view.Render()
+}
@@ -3,22 +3,70 @@ package stripeactions
 import (
 	"net/http"
 
-	"github.com/fragmenta/mux/log"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
 	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+	"github.com/kennygrant/gohackernews/src/payments"
 )
 
-// HandleShowPay displays a payment page.
+// HandleShowPay displays a payment page for an item, such as a job,
+// with a button which starts checkout. Responds to GET /stripe/pay?product=jobs&item_id=1
 func HandleShowPay(w http.ResponseWriter, r *http.Request) error {
 
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
 	// Find logged in user (if any)
 	currentUser := session.CurrentUser(w, r)
 
-	log.Printf("pay: page shown for user:%d", currentUser.ID)
+	// Without a product, explain how to advertise
+	var product *payments.Product
+	if params.Get("product") != "" {
+		product, _, err = findPurchase(params.Get("product"), params.GetInt("item_id"), currentUser.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Info(log.V{"msg": "pay: page shown", "user_id": currentUser.ID, "product": params.Get("product"), "item_id": params.GetInt("item_id")})
 
 	// Render the template
 	view := view.NewRenderer(w, r)
+	view.AddKey("product", product)
+	view.AddKey("subscription", product != nil && product.Mode == stripe.ModeSubscription)
+	view.AddKey("itemID", params.GetInt("item_id"))
 	view.AddKey("currentUser", currentUser)
 	return view.Render()
 }
+
+// findPurchase returns the product with this name if it may be bought,
+// and the item with this id if it exists and belongs to the user.
+func findPurchase(name string, itemID int64, userID int64) (*payments.Product, payments.Item, error) {
+
+	product, err := payments.FindProduct(name)
+	if err != nil || !product.Enabled() {
+		return nil, nil, server.NotFoundError(err)
+	}
+
+	item, err := product.Find(itemID)
+	if err != nil {
+		return nil, nil, server.NotFoundError(err)
+	}
+
+	if userID == 0 || !item.OwnedBy(userID) {
+		return nil, nil, server.NotAuthorizedError(nil, "Sorry", "You may only pay for your own listings.")
+	}
+
+	if !item.Payable() {
+		return nil, nil, server.NotAuthorizedError(nil, "Sorry", "This listing does not need to be paid for now.")
+	}
+
+	return product, item, nil
+}
@@ -3,22 +3,39 @@ package stripeactions
 import (
 	"net/http"
 
-	"github.com/fragmenta/mux/log"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
 	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/payments"
 )
 
-// HandleShowPayThanks displays a payment page thanks.
+// HandleShowPayThanks displays a payment page thanks, with the status of the payment
+// in the ledger. Payment is confirmed only by the webhook, which may arrive after the customer.
 func HandleShowPayThanks(w http.ResponseWriter, r *http.Request) error {
 
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
 	// Find logged in user (if any)
 	currentUser := session.CurrentUser(w, r)
 
-	log.Printf("pay: page thanks shown for user:%d", currentUser.ID)
+	// Find the payment for this session, if it belongs to the user
+	payment, err := payments.FindSession(params.Get("session_id"))
+	if err != nil || !payment.OwnedBy(currentUser.ID) {
+		payment = nil
+	}
+
+	log.Info(log.V{"msg": "pay: page thanks shown", "user_id": currentUser.ID})
 
 	// Render the template
 	view := view.NewRenderer(w, r)
+	view.AddKey("payment", payment)
 	view.AddKey("currentUser", currentUser)
 	return view.Render()
 }
@@ -0,0 +1,52 @@
+package stripeactions
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/lib/stripe"
+	"github.com/kennygrant/gohackernews/src/memberships"
+	"github.com/kennygrant/gohackernews/src/payments"
+)
+
+// maxWebhookBytes limits the size of webhook payloads we read.
+const maxWebhookBytes = 64 * 1024
+
+// HandleWebhook responds to POST /stripe/webhook from Stripe. Events are accepted only
+// with a valid signature, and are applied to the payments ledger, which fulfils paid purchases,
+// and to the memberships renewed by subscriptions.
+// There is no authenticity token or session, the signature authenticates the request.
+func HandleWebhook(w http.ResponseWriter, r *http.Request) error {
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	event, err := stripe.Default.ParseEvent(payload, r.Header.Get("Stripe-Signature"))
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	err = payments.Process(event)
+	if err == nil {
+		err = memberships.Process(event)
+	}
+	switch err {
+	case nil:
+		log.Info(log.V{"msg": "pay: webhook processed", "event_id": event.ID, "type": event.Type})
+	case payments.ErrUnknownPayment, memberships.ErrUnknownMembership:
+		// Other integrations may share the account, retrying will not help
+		log.Info(log.V{"msg": "pay: webhook ignored", "event_id": event.ID, "type": event.Type, "error": err})
+	default:
+		// Failure is reported so that Stripe sends the event again later
+		log.Error(log.V{"msg": "pay: webhook failed", "event_id": event.ID, "type": event.Type, "error": err})
+		return server.InternalError(err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fragmenta/auth"
 	"github.com/fragmenta/mux"
@@ -48,8 +50,15 @@ func TestSetup(t *testing.T) {
 	router.Add("/users/logout", nil).Post()
 	router.Add("/users/{id:\\d+}/update", nil)
 	router.Add("/users/{id:\\d+}/update", nil).Post()
+	router.Add("/users/{id:\\d+}/password", nil)
+	router.Add("/users/{id:\\d+}/password", nil).Post()
 	router.Add("/users/{id:\\d+}/destroy", nil).Post()
-	router.Add("/users/{id:\\d+}", nil)
+	router.Add("/users/{id:\\d+}/export", nil)
+	router.Add("/users/{id:\\d+}/export", nil).Post()
+	router.Add("/users/{id:\\d+}/export/download", nil)
+	router.Add("/users/{id:\\d+}/feeds/token", nil).Post()
+	router.Add("/users/{id:\\d+}/avatar.svg", nil)
+	router.Add("/users/{id:\\d+}{format:(.json)?}", nil)
 
 	// Delete all users to ensure we get consistent results?
 	_, err = query.ExecSQL("delete from users;")
@@ -197,6 +206,108 @@ func TestShowUsers(t *testing.T) {
 	}
 }
 
+// Test GET /users/123.json
+func TestShowUsersJSON(t *testing.T) {
+
+	// Setup request and recorder
+	r := httptest.NewRequest("GET", "/users/1.json", nil)
+	w := httptest.NewRecorder()
+
+	// Run the handler
+	err := HandleShow(w, r)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("useractions: error handling HandleShow json %s", err)
+	}
+
+	// Test the body for the user name and avatar
+	pattern := fmt.Sprintf(`"name":"%s"`, names[0])
+	if !strings.Contains(w.Body.String(), pattern) || !strings.Contains(w.Body.String(), "/users/1/avatar.svg") {
+		t.Fatalf("useractions: unexpected response for HandleShow json expected:%s got:%s", pattern, w.Body.String())
+	}
+}
+
+// Test GET /users/123/avatar.svg
+func TestAvatarUsers(t *testing.T) {
+
+	// Setup request and recorder
+	r := httptest.NewRequest("GET", "/users/1/avatar.svg", nil)
+	w := httptest.NewRecorder()
+
+	// Run the handler
+	err := HandleAvatar(w, r)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("useractions: error handling HandleAvatar %s", err)
+	}
+
+	if !strings.HasPrefix(w.Body.String(), "<svg") {
+		t.Fatalf("useractions: unexpected response for HandleAvatar got:%s", w.Body.String())
+	}
+}
+
+// Test building an export for user 1 and downloading it
+func TestExportUsers(t *testing.T) {
+
+	user, err := users.Find(1)
+	if err != nil {
+		t.Fatalf("useractions: error finding user %s", err)
+	}
+
+	// Build the export synchronously, HandleExport does this in the background
+	err = buildExport(user)
+	if err != nil {
+		t.Fatalf("useractions: error building export %s", err)
+	}
+	defer os.RemoveAll(exportDir())
+
+	// Fetch the user again to get the token
+	user, err = users.Find(1)
+	if err != nil || !exportValid(user) {
+		t.Fatalf("useractions: export token not set on user %s", err)
+	}
+
+	// Setup request and recorder
+	r := httptest.NewRequest("GET", exportURL(user), nil)
+	w := httptest.NewRecorder()
+
+	// Set up user session cookie for admin user above
+	err = resource.AddUserSessionCookie(w, r, 1)
+	if err != nil {
+		t.Fatalf("useractions: error setting session %s", err)
+	}
+
+	// AddUserSessionCookie overwrites the query, so set the token again
+	r.URL.RawQuery = r.URL.RawQuery + "&token=" + user.ExportToken
+
+	// Run the handler
+	err = HandleExportDownload(w, r)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("useractions: error handling HandleExportDownload %s %d", err, w.Code)
+	}
+
+	// Test the body is a zip archive
+	if w.Header().Get("Content-Type") != "application/zip" || !strings.HasPrefix(w.Body.String(), "PK") {
+		t.Fatalf("useractions: unexpected response for HandleExportDownload %s", w.Header())
+	}
+
+	// Test an invalid token is rejected
+	r = httptest.NewRequest("GET", "/users/1/export/download", nil)
+	w = httptest.NewRecorder()
+	err = resource.AddUserSessionCookie(w, r, 1)
+	if err != nil {
+		t.Fatalf("useractions: error setting session %s", err)
+	}
+	r.URL.RawQuery = r.URL.RawQuery + "&token=deadfishdeadfish"
+	err = HandleExportDownload(w, r)
+	if err == nil {
+		t.Fatalf("useractions: unexpected success for HandleExportDownload with invalid token")
+	}
+
+}
+
 // Test GET /users/123/update
 func TestShowUpdateUsers(t *testing.T) {
 
@@ -226,6 +337,52 @@ func TestShowUpdateUsers(t *testing.T) {
 
 }
 
+// Test POST /users/1/feeds/token
+func TestFeedTokenUsers(t *testing.T) {
+
+	r := httptest.NewRequest("POST", "/users/1/feeds/token", nil)
+	w := httptest.NewRecorder()
+
+	err := resource.AddUserSessionCookie(w, r, 1)
+	if err != nil {
+		t.Fatalf("useractions: error setting session %s", err)
+	}
+
+	err = HandleFeedToken(w, r)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("useractions: error handling HandleFeedToken %s", err)
+	}
+
+	user, err := users.Find(1)
+	if err != nil || len(user.FeedToken) < users.MinFeedToken {
+		t.Fatalf("useractions: feed token not set on user %v", err)
+	}
+
+	found, err := users.FindFeedToken(user.FeedToken)
+	if err != nil || found.ID != user.ID {
+		t.Fatalf("useractions: user not found by feed token %v", err)
+	}
+
+	_, err = users.FindFeedToken("deadfish")
+	if err == nil {
+		t.Fatalf("useractions: unexpected user found for invalid feed token")
+	}
+
+	// The tokens of suspended users are refused
+	err = user.Suspend(time.Time{}, "spam")
+	if err != nil {
+		t.Fatalf("useractions: error suspending user %s", err)
+	}
+	_, err = users.FindFeedToken(user.FeedToken)
+	if err == nil {
+		t.Fatalf("useractions: unexpected user found for feed token of suspended user")
+	}
+	err = user.Unsuspend()
+	if err != nil {
+		t.Fatalf("useractions: error unsuspending user %s", err)
+	}
+}
+
 // Test POST /users/123/update
 func TestUpdateUsers(t *testing.T) {
 
@@ -347,6 +504,65 @@ func TestShowLogin(t *testing.T) {
 
 }
 
+// Test POST /users/1/password
+func TestPasswordChange(t *testing.T) {
+
+	// A wrong current password should be rejected
+	form := url.Values{}
+	form.Add("current_password", "wrong")
+	form.Add("password", "correct horse battery")
+	form.Add("password_confirm", "correct horse battery")
+	r := httptest.NewRequest("POST", "/users/1/password", strings.NewReader(form.Encode()))
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	err := resource.AddUserSessionCookie(w, r, 1)
+	if err != nil {
+		t.Fatalf("useractions: error setting session %s", err)
+	}
+
+	err = HandlePasswordChange(w, r)
+	if err != nil || !strings.Contains(w.Header().Get("Location"), "error=failed_password") {
+		t.Fatalf("useractions: HandlePasswordChange accepted wrong password %s", err)
+	}
+
+	// The correct current password should change it, and revoke other sessions
+	form.Set("current_password", "Hunter2")
+	r = httptest.NewRequest("POST", "/users/1/password", strings.NewReader(form.Encode()))
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+
+	err = resource.AddUserSessionCookie(w, r, 1)
+	if err != nil {
+		t.Fatalf("useractions: error setting session %s", err)
+	}
+
+	err = HandlePasswordChange(w, r)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("useractions: error handling HandlePasswordChange %s", err)
+	}
+
+	user, err := users.Find(1)
+	if err != nil {
+		t.Fatalf("useractions: error finding user %s", err)
+	}
+	if auth.CheckPassword("correct horse battery", user.PasswordHash) != nil {
+		t.Fatalf("useractions: password not changed")
+	}
+	if user.SessionsAt.IsZero() {
+		t.Fatalf("useractions: sessions not revoked")
+	}
+	if user.FeedToken != "" {
+		t.Fatalf("useractions: feed token not revoked")
+	}
+
+	// Restore the original password for the tests below
+	_, err = query.ExecSQL("update users set sessions_at=NULL, password_hash='$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6' where id=1;")
+	if err != nil {
+		t.Fatalf("useractions: error restoring user %s", err)
+	}
+}
+
 // Test POST /users/login
 func TestLogin(t *testing.T) {
 
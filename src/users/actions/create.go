@@ -4,13 +4,14 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/fragmenta/auth"
 	"github.com/fragmenta/auth/can"
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
+	"github.com/kennygrant/gohackernews/src/lib/karma"
+	"github.com/kennygrant/gohackernews/src/lib/password"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/lib/status"
 	"github.com/kennygrant/gohackernews/src/users"
@@ -37,9 +38,19 @@ func HandleCreateShow(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
+	// Check the invite if we have one, invites are required if registration is invite only
+	token := params.Get("invite")
+	if token != "" || inviteOnly() {
+		_, err = users.FindInvite(token)
+		if err != nil {
+			return server.NotAuthorizedError(err, "Invite Required", "Sorry, registration is by invitation only, or your invite has expired")
+		}
+	}
+
 	// Render the template
 	view := view.NewRenderer(w, r)
 	view.AddKey("user", user)
+	view.AddKey("invite", token)
 	view.AddKey("hideSubmit", true)
 	view.AddKey("error", params.Get("error"))
 	return view.Render()
@@ -72,6 +83,16 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
+	// Check the invite if we have one, invites are required if registration is invite only
+	var invite *users.Invite
+	token := params.Get("invite")
+	if token != "" || inviteOnly() {
+		invite, err = users.FindInvite(token)
+		if err != nil {
+			return server.NotAuthorizedError(err, "Invite Required", "Sorry, registration is by invitation only, or your invite has expired")
+		}
+	}
+
 	// Check a user doesn't exist with this name or email already
 	name := params.Get("name")
 	email := params.Get("email")
@@ -82,9 +103,15 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err, "Name too short", "Sorry, names must be at least 2 characters long")
 	}
 
-	// Password must be at least 6 characters
-	if len(pass) < 6 {
-		return server.InternalError(err, "Password too short", "Sorry, passwords must be at least 6 characters long")
+	// Name must not be the placeholder for deleted accounts
+	if users.ReservedName(name) {
+		return server.InternalError(err, "Name not allowed", "Sorry, that name is reserved")
+	}
+
+	// Password must meet the password policy
+	err = password.Current.Check(pass, name, email)
+	if err != nil {
+		return server.InternalError(err, "Password too weak", err.Error())
 	}
 
 	// Name is not optional so always check duplicates
@@ -93,7 +120,7 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 	if len(duplicates) > 0 {
-		return server.Redirect(w, r, "/users/create?error=duplicate_name")
+		return server.Redirect(w, r, createURL("duplicate_name", token))
 	}
 
 	// Email is optional, so allow blank email and don't check duplicates if so
@@ -103,30 +130,54 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 			return server.InternalError(err)
 		}
 		if len(duplicates) > 0 {
-			return server.Redirect(w, r, "/users/create?error=duplicate_email")
+			return server.Redirect(w, r, createURL("duplicate_email", token))
 		}
 	}
 
+	// Validate the params, removing any we don't accept
+	userParams := user.ValidateParams(params.Map(), users.AllowedParams())
+
 	// Set the password hash from the password
-	hash, err := auth.HashPassword(pass)
+	userParams["password_hash"], err = password.Current.Hash(pass)
 	if err != nil {
 		return server.InternalError(err)
 	}
-	params.SetString("password_hash", hash)
-
-	// Validate the params, removing any we don't accept
-	userParams := user.ValidateParams(params.Map(), users.AllowedParams())
 
 	// Set some defaults for the new user
 	userParams["status"] = fmt.Sprintf("%d", status.Published)
 	userParams["role"] = fmt.Sprintf("%d", users.Reader)
-	userParams["points"] = "1"
+	userParams["points"] = "0"
+
+	// Claim the invite before creating the user, so that an invite may not be used twice at once
+	if invite != nil {
+		err = invite.Claim()
+		if err != nil {
+			return server.NotAuthorizedError(err, "Invite Required", "Sorry, registration is by invitation only, or your invite has expired")
+		}
+	}
 
 	id, err := user.Create(userParams)
 	if err != nil {
+		if invite != nil {
+			invite.Release()
+		}
 		return server.InternalError(err)
 	}
 
+	// Record the initial points in the ledger
+	err = users.AddPoints(id, karma.Current.InitialPoints, users.ReasonSignup, 0, 0)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Mark the invite used and record the inviter on the new user
+	if invite != nil {
+		err = invite.Accept(id)
+		if err != nil {
+			return server.InternalError(err)
+		}
+	}
+
 	// Redirect to the new user
 	user, err = users.Find(id)
 	if err != nil {
@@ -134,17 +185,21 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	// Log in automatically as the new user they have just created
-	session, err := auth.Session(w, r)
+	err = session.Login(w, r, user)
 	if err != nil {
 		log.Info(log.V{"msg": "login failed", "email": user.Email, "user_id": user.ID, "status": http.StatusInternalServerError})
 	}
 
-	// Success, log it and set the cookie with user id
-	session.Set(auth.SessionUserKey, fmt.Sprintf("%d", user.ID))
-	session.Save(w)
-
 	// Log action
 	log.Info(log.V{"msg": "login success", "user_email": user.Email, "user_id": user.ID})
 
 	return server.Redirect(w, r, "/")
 }
+
+// createURL returns the url of the create form showing this error, keeping the invite token (if any).
+func createURL(e string, token string) string {
+	if token != "" {
+		return fmt.Sprintf("/users/create?error=%s&invite=%s", e, token)
+	}
+	return fmt.Sprintf("/users/create?error=%s", e)
+}
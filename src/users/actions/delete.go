@@ -0,0 +1,221 @@
+package useractions
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fragmenta/auth"
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/memberships"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+const (
+	// DeletionGracePeriod is the time users have to cancel a deletion request
+	DeletionGracePeriod = 14 * 24 * time.Hour
+)
+
+// HandleDeleteShow responds to GET /users/{id}/delete
+// by showing a form asking the user to confirm their password.
+func HandleDeleteShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise - only the user may ask to delete their account
+	currentUser := session.CurrentUser(w, r)
+	if currentUser.ID != user.ID {
+		return server.NotAuthorizedError(nil)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	switch params.Get("error") {
+	case "failed_password":
+		view.AddKey("warning", "Sorry, the password was incorrect, please try again.")
+	}
+	view.AddKey("user", user)
+	view.AddKey("currentUser", currentUser)
+	view.AddKey("gracePeriod", int(DeletionGracePeriod.Hours()/24))
+	view.Template("users/views/delete.html.got")
+	return view.Render()
+}
+
+// HandleDelete responds to POST /users/{id}/delete
+// by scheduling deletion of the account after the grace period.
+func HandleDelete(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only the user may ask to delete their account
+	if session.CurrentUser(w, r).ID != user.ID {
+		return server.NotAuthorizedError(nil)
+	}
+
+	// Check password against the stored password
+	err = auth.CheckPassword(params.Get("password"), user.PasswordHash)
+	if err != nil {
+		log.Info(log.V{"msg": "delete failed", "error": err, "user_id": user.ID, "status": http.StatusUnauthorized})
+		return server.Redirect(w, r, fmt.Sprintf("/users/%d/delete?error=failed_password", user.ID))
+	}
+
+	deleteAt := time.Now().UTC().Add(DeletionGracePeriod)
+	err = user.Update(map[string]string{"delete_at": query.TimeString(deleteAt)})
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "delete requested", "user_email": user.Email, "user_id": user.ID, "delete_at": deleteAt})
+
+	return server.Redirect(w, r, user.ShowURL())
+}
+
+// HandleDeleteCancel responds to POST /users/{id}/delete/cancel
+// by cancelling a pending deletion.
+func HandleDeleteCancel(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update user (the user or an admin may cancel)
+	err = can.Update(user, session.CurrentUser(w, r))
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	_, err = query.Exec("update users set delete_at=NULL where id=$1", user.ID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "delete cancelled", "user_email": user.Email, "user_id": user.ID})
+
+	return server.Redirect(w, r, user.ShowURL())
+}
+
+// HandlePurge responds to POST /users/{id}/purge by deleting the user
+// and all their content, this is intended for spam accounts.
+func HandlePurge(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may purge users
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	// Admins may not purge themselves, or the placeholder which holds the content of deleted accounts
+	if user.ID == currentUser.ID || user.Anon() && users.ReservedName(user.Name) {
+		return server.NotAuthorizedError(nil, "Purge Failed", "Sorry, this account may not be purged.")
+	}
+
+	// Stop any subscriptions before the user is removed
+	err = memberships.CancelUser(user.ID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	err = user.Purge()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "user purged", "user_email": user.Email, "user_id": user.ID, "admin_id": currentUser.ID})
+
+	// Redirect to users root
+	return server.Redirect(w, r, user.IndexURL())
+}
+
+// DeleteScheduledUsers deletes the accounts of users whose grace period has passed,
+// it is called at intervals by the app.
+func DeleteScheduledUsers() {
+
+	q := users.Where("delete_at IS NOT NULL AND delete_at < ?", query.TimeString(time.Now().UTC()))
+	results, err := users.FindAll(q)
+	if err != nil {
+		log.Error(log.V{"msg": "scheduled delete failed", "error": err})
+		return
+	}
+
+	for _, user := range results {
+		err = memberships.CancelUser(user.ID)
+		if err != nil {
+			log.Error(log.V{"msg": "scheduled delete failed", "user_id": user.ID, "error": err})
+			continue
+		}
+		err = user.Anonymise()
+		if err != nil {
+			log.Error(log.V{"msg": "scheduled delete failed", "user_id": user.ID, "error": err})
+			continue
+		}
+		log.Info(log.V{"msg": "user deleted", "user_id": user.ID})
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"github.com/fragmenta/server"
 
 	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/memberships"
 	"github.com/kennygrant/gohackernews/src/users"
 )
 
-// HandleDestroy responds to /users/n/destroy by deleting the user.
+// HandleDestroy responds to /users/n/destroy by deleting the user immediately.
+// Their content is kept but reassigned to the deleted placeholder user.
 func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
 
 	// Fetch the  params
@@ -38,8 +40,17 @@ func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err)
 	}
 
-	// Destroy the user
-	user.Destroy()
+	// Stop any subscriptions before the user is removed
+	err = memberships.CancelUser(user.ID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Delete the user, reassigning their content to the deleted placeholder
+	err = user.Anonymise()
+	if err != nil {
+		return server.InternalError(err)
+	}
 
 	// Redirect to users root
 	return server.Redirect(w, r, user.IndexURL())
@@ -0,0 +1,507 @@
+package useractions
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fragmenta/auth"
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/config"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/jobs"
+	"github.com/kennygrant/gohackernews/src/lib/mail"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/memberships"
+	"github.com/kennygrant/gohackernews/src/payments"
+	"github.com/kennygrant/gohackernews/src/revisions"
+	"github.com/kennygrant/gohackernews/src/sponsorships"
+	"github.com/kennygrant/gohackernews/src/stories"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+const (
+	// ExportLifetime is the maximum time an export download link is valid for
+	ExportLifetime = 48 * time.Hour
+
+	// ExportInterval is the minimum time between export requests for one user
+	ExportInterval = time.Hour
+)
+
+// exportData holds all the data we store about a user, for export.
+type exportData struct {
+	GeneratedAt  time.Time                `json:"generated_at"`
+	Profile      exportProfile            `json:"profile"`
+	Stories      []exportStory            `json:"stories"`
+	Comments     []exportComment          `json:"comments"`
+	Votes        []map[string]interface{} `json:"votes"`
+	Flags        []map[string]interface{} `json:"flags"`
+	Points       []map[string]interface{} `json:"point_events"`
+	Invites      []map[string]interface{} `json:"invites"`
+	Revisions    []map[string]interface{} `json:"revisions"`
+	Jobs         []map[string]interface{} `json:"jobs"`
+	Payments     []map[string]interface{} `json:"payments"`
+	Sponsorships []map[string]interface{} `json:"sponsorships"`
+	Memberships  []map[string]interface{} `json:"memberships"`
+	Sessions     string                   `json:"sessions"`
+	Settings     string                   `json:"notification_settings"`
+}
+
+// exportProfile holds the user record, excluding password hashes and tokens.
+type exportProfile struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Summary   string    `json:"summary"`
+	Title     string    `json:"title"`
+	About     string    `json:"about"`
+	Website   string    `json:"website"`
+	GitHub    string    `json:"github"`
+	Mastodon  string    `json:"mastodon"`
+	Points    int64     `json:"points"`
+	Role      string    `json:"role"`
+	Status    string    `json:"status"`
+}
+
+// exportStory holds the fields of a story submitted by the user.
+type exportStory struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Summary   string    `json:"summary"`
+	Points    int64     `json:"points"`
+	Link      string    `json:"link"`
+}
+
+// exportComment holds the fields of a comment written by the user.
+type exportComment struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	StoryID   int64     `json:"story_id"`
+	StoryName string    `json:"story_name"`
+	ParentID  int64     `json:"parent_id"`
+	Text      string    `json:"text"`
+	Points    int64     `json:"points"`
+	Link      string    `json:"link"`
+}
+
+// HandleExportShow responds to GET /users/{id}/export
+// by showing the status of the user's data export.
+func HandleExportShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise - only the user (or an admin) may export their data
+	currentUser := session.CurrentUser(w, r)
+	err = can.Update(user, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("user", user)
+	view.AddKey("currentUser", currentUser)
+	view.AddKey("ready", exportValid(user))
+	view.AddKey("expires", user.ExportAt.Add(ExportLifetime))
+	view.AddKey("url", exportURL(user))
+	view.AddKey("message", params.Get("message"))
+	view.Template("users/views/export.html.got")
+	return view.Render()
+}
+
+// HandleExport responds to POST /users/{id}/export
+// by starting a background job to build the export archive.
+func HandleExport(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only the user (or an admin) may export their data
+	err = can.Update(user, session.CurrentUser(w, r))
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Exports are expensive, so limit the rate at which users can request them
+	if time.Since(user.ExportAt) < ExportInterval {
+		return server.Redirect(w, r, fmt.Sprintf("/users/%d/export?message=export_recent", user.ID))
+	}
+
+	// Remove the previous export and record the request time, so that repeated requests are refused,
+	// and the previous link is not offered again while the new export is built
+	removeExport(user)
+	err = user.Update(map[string]string{
+		"export_token": "",
+		"export_at":    query.TimeString(time.Now().UTC()),
+	})
+	if err != nil {
+		return server.InternalError(err)
+	}
+	user.ExportToken = ""
+
+	// Build the export in the background, the user is emailed when it is ready
+	go func() {
+		err := buildExport(user)
+		if err != nil {
+			log.Error(log.V{"msg": "export failed", "user_id": user.ID, "error": err})
+		}
+	}()
+
+	log.Info(log.V{"msg": "export requested", "user_email": user.Email, "user_id": user.ID})
+
+	return server.Redirect(w, r, fmt.Sprintf("/users/%d/export?message=export_started", user.ID))
+}
+
+// HandleExportDownload responds to GET /users/{id}/export/download?token=DEADFISH
+// by serving the export archive if the token is valid and has not expired.
+func HandleExportDownload(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise - the user must be logged in as well as holding the token
+	err = can.Update(user, session.CurrentUser(w, r))
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Check the token against the one stored on the user
+	token := params.Get("token")
+	if len(token) < 10 || token != user.ExportToken {
+		return server.NotAuthorizedError(nil, "Invalid Token", "This export link is not valid, please request another export.")
+	}
+
+	// Make sure the export has not expired, if it has remove it
+	if !exportValid(user) {
+		removeExport(user)
+		return server.NotAuthorizedError(nil, "Export expired", "Your export link has expired, please request another export.")
+	}
+
+	p := exportPath(token)
+	if _, err = os.Stat(p); err != nil {
+		return server.NotFoundError(err, "Export not found", "Your export could not be found, please request another export.")
+	}
+
+	log.Info(log.V{"msg": "export downloaded", "user_email": user.Email, "user_id": user.ID})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"export-%d.zip\"", user.ID))
+	http.ServeFile(w, r, p)
+	return nil
+}
+
+// PurgeExports removes export archives which have expired,
+// it is called at intervals by the app.
+func PurgeExports() {
+	files, err := filepath.Glob(filepath.Join(exportDir(), "*.zip"))
+	if err != nil {
+		log.Error(log.V{"msg": "export purge failed", "error": err})
+		return
+	}
+
+	for _, f := range files {
+		s, err := os.Stat(f)
+		if err == nil && time.Since(s.ModTime()) > ExportLifetime {
+			os.Remove(f)
+		}
+	}
+}
+
+// buildExport collects all the data for this user into a zip archive,
+// stores the token on the user and emails them a link to download it.
+func buildExport(user *users.User) error {
+
+	data, err := collectExport(user)
+	if err != nil {
+		return err
+	}
+
+	// Render a readable index of the data for those who don't read json
+	index, err := renderExportIndex(user, data)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(exportDir(), 0700)
+	if err != nil {
+		return err
+	}
+
+	// Write the archive under a random token, which is also used in the link
+	token := auth.BytesToHex(auth.RandomToken(32))
+	err = writeExport(exportPath(token), data, index)
+	if err != nil {
+		return err
+	}
+
+	// Remove any previous export before recording this one
+	removeExport(user)
+
+	err = user.Update(map[string]string{
+		"export_token": token,
+		"export_at":    query.TimeString(time.Now().UTC()),
+	})
+	if err != nil {
+		return err
+	}
+	user.ExportToken = token
+
+	log.Info(log.V{"msg": "export ready", "user_email": user.Email, "user_id": user.ID})
+
+	// Email is optional on users, if there is none they must check the export page
+	if user.Email == "" {
+		return nil
+	}
+
+	emailContext := map[string]interface{}{
+		"url":  config.Get("root_url") + exportURL(user),
+		"name": user.Name,
+	}
+	e := mail.New(user.Email)
+	e.Subject = "Your data export is ready"
+	e.Template = "users/views/mail/export.html.got"
+	return mail.Send(e, emailContext)
+}
+
+// collectExport fetches the user's profile, content, votes and flags.
+func collectExport(user *users.User) (*exportData, error) {
+
+	data := &exportData{
+		GeneratedAt: time.Now().UTC(),
+		Profile: exportProfile{
+			ID:        user.ID,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+			Name:      user.Name,
+			Email:     user.Email,
+			Summary:   user.Summary,
+			Title:     user.Title,
+			About:     user.Text,
+			Website:   user.Website,
+			GitHub:    user.GitHub,
+			Mastodon:  user.Mastodon,
+			Points:    user.Points,
+			Role:      user.RoleDisplay(),
+			Status:    user.StatusDisplay(),
+		},
+		Sessions: "Sessions are stored only in an encrypted cookie in your browser, no session records are kept on the server.",
+		Settings: "No notification settings are stored, email is only used for password resets and exports.",
+	}
+
+	// Get the user stories
+	userStories, err := stories.FindAll(stories.Where("user_id=?", user.ID).Order("created_at asc"))
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range userStories {
+		data.Stories = append(data.Stories, exportStory{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			Name:      s.Name,
+			URL:       s.URL,
+			Summary:   s.Summary,
+			Points:    s.Points,
+			Link:      config.Get("root_url") + s.ShowURL(),
+		})
+	}
+
+	// Get the user comments - we don't use comments.FindAll as it only returns root comments
+	results, err := comments.Where("user_id=?", user.ID).Order("created_at asc").Results()
+	if err != nil {
+		return nil, err
+	}
+	for _, cols := range results {
+		c := comments.NewWithColumns(cols)
+		data.Comments = append(data.Comments, exportComment{
+			ID:        c.ID,
+			CreatedAt: c.CreatedAt,
+			StoryID:   c.StoryID,
+			StoryName: c.StoryName,
+			ParentID:  c.ParentID,
+			Text:      c.Text,
+			Points:    c.Points,
+			Link:      config.Get("root_url") + c.ShowURL(),
+		})
+	}
+
+	// Get the user votes and flags
+	data.Votes, err = query.New("votes", "story_id").Where("user_id=?", user.ID).Order("created_at asc").Results()
+	if err != nil {
+		return nil, err
+	}
+
+	data.Flags, err = query.New("flags", "story_id").Where("user_id=?", user.ID).Order("created_at asc").Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the history of changes to user points
+	data.Points, err = query.New(users.PointEventsTableName, users.KeyName).Where("user_id=?", user.ID).Order("created_at asc, id asc").Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the invites sent by the user
+	data.Invites, err = query.New(users.InvitesTableName, users.KeyName).Select("select created_at, email, used_at from invites").Where("user_id=?", user.ID).Order("created_at asc").Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the previous versions of the user's stories and comments
+	q := revisions.Query().Where("(story_id IN (select id from stories where user_id=?) OR comment_id IN (select id from comments where user_id=?))", user.ID, user.ID)
+	data.Revisions, err = q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the jobs the user has posted
+	data.Jobs, err = jobs.Where("user_id=?", user.ID).Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the payments the user has made
+	data.Payments, err = payments.Where("user_id=?", user.ID).Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the sponsorships the user has bought
+	data.Sponsorships, err = sponsorships.Where("user_id=?", user.ID).Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the memberships the user has paid for
+	data.Memberships, err = memberships.Where("user_id=?", user.ID).Results()
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// renderExportIndex renders the readable html index for the archive.
+func renderExportIndex(user *users.User, data *exportData) (string, error) {
+	view := view.NewWithPath("", nil)
+	view.Layout("users/views/export/layout.html.got")
+	view.Template("users/views/export/index.html.got")
+	view.Context(map[string]interface{}{
+		"user": user,
+		"data": data,
+	})
+	return view.RenderToStringWithLayout()
+}
+
+// writeExport writes the data as json with the html index to a zip archive at path p.
+func writeExport(p string, data *exportData, index string) error {
+
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	archive := zip.NewWriter(f)
+
+	dataJSON, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{
+		"export.json": dataJSON,
+		"index.html":  []byte(index),
+	}
+	for name, content := range files {
+		fw, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// removeExport deletes the current export file for this user (if any).
+func removeExport(user *users.User) {
+	if user.ExportToken == "" {
+		return
+	}
+	os.Remove(exportPath(user.ExportToken))
+}
+
+// exportValid returns true if the user has an export which has not yet expired.
+func exportValid(user *users.User) bool {
+	return user.ExportToken != "" && time.Since(user.ExportAt) < ExportLifetime
+}
+
+// exportURL returns the download url for the user's current export.
+func exportURL(user *users.User) string {
+	return fmt.Sprintf("/users/%d/export/download?token=%s", user.ID, user.ExportToken)
+}
+
+// exportDir returns the directory exports are stored in, which must not be public.
+func exportDir() string {
+	if config.Get("export_path") != "" {
+		return config.Get("export_path")
+	}
+	return filepath.Join("db", "exports")
+}
+
+// exportPath returns the path for the export archive with this token.
+func exportPath(token string) string {
+	return filepath.Join(exportDir(), token+".zip")
+}
@@ -0,0 +1,54 @@
+package useractions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth"
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// HandleFeedToken responds to POST /users/{id}/feeds/token by creating a new token
+// for the private feeds of this user, which replaces any previous token.
+func HandleFeedToken(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update user
+	err = can.Update(user, session.CurrentUser(w, r))
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Replace the token, so that any links shared before stop working
+	err = user.Update(map[string]string{"feed_token": auth.BytesToHex(auth.RandomToken(32))})
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "feed token reset", "user_email": user.Email, "user_id": user.ID})
+
+	return server.Redirect(w, r, user.UpdateURL()+"#feeds")
+}
@@ -0,0 +1,160 @@
+package useractions
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fragmenta/auth"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/config"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/karma"
+	"github.com/kennygrant/gohackernews/src/lib/mail"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// inviteOnly returns true if new users must have an invite to register.
+func inviteOnly() bool {
+	return config.GetBool("invite_only")
+}
+
+// HandleInvitesShow responds to GET /users/invites
+// by showing the invites the current user has sent.
+func HandleInvitesShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise - users must be logged in to invite
+	currentUser := session.CurrentUser(w, r)
+	if currentUser.Anon() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	invites, err := users.FindInvites(currentUser.Invites())
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("invites", invites)
+	view.AddKey("threshold", karma.Current.Threshold(karma.Invite)+1)
+	view.AddKey("days", int(users.InviteLifetime.Hours()/24))
+	view.AddKey("rootURL", config.Get("root_url"))
+	view.AddKey("currentUser", currentUser)
+	view.Template("users/views/invites.html.got")
+	return view.Render()
+}
+
+// HandleInviteCreate responds to POST /users/invites
+// by creating an invite, and emailing it if an email is given.
+func HandleInviteCreate(w http.ResponseWriter, r *http.Request) error {
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - users must have enough points to invite
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.CanInvite() {
+		return server.NotAuthorizedError(nil, "Invite Failed", "Sorry, you can't invite users yet")
+	}
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	token := auth.BytesToHex(auth.RandomToken(32))
+	email := strings.TrimSpace(params.Get("email"))
+
+	err = currentUser.CreateInvite(token, email)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "invite created", "user_id": currentUser.ID, "invite_email": email})
+
+	// Email is optional, without it the user shares the link themselves
+	if email != "" {
+		invite := &users.Invite{Token: token}
+		emailContext := map[string]interface{}{
+			"url":  config.Get("root_url") + invite.URL(),
+			"name": currentUser.Name,
+			"days": int(users.InviteLifetime.Hours() / 24),
+		}
+		e := mail.New(email)
+		e.Subject = "You have been invited to " + config.Get("meta_title")
+		e.Template = "users/views/mail/invite.html.got"
+		err = mail.Send(e, emailContext)
+		if err != nil {
+			return server.InternalError(err, "Invite Failed", "Sorry, the invite email could not be sent")
+		}
+	}
+
+	return server.Redirect(w, r, "/users/invites")
+}
+
+// HandleInviteTree responds to GET /users/invites/tree
+// by showing who invited whom.
+func HandleInviteTree(w http.ResponseWriter, r *http.Request) error {
+
+	// No authorisation on the invite tree, it is public
+
+	tree, err := users.InviteTree()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("tree", tree)
+	view.AddKey("currentUser", session.CurrentUser(w, r))
+	view.Template("users/views/tree.html.got")
+	return view.Render()
+}
+
+// HandleBanInvited responds to POST /users/{id}/ban
+// by suspending the user and every user they invited.
+func HandleBanInvited(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may ban users
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	count, err := user.SuspendInvited()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "user tree banned", "user_id": user.ID, "count": count, "admin_id": currentUser.ID})
+
+	return server.Redirect(w, r, user.ShowURL())
+}
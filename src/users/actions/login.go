@@ -1,7 +1,6 @@
 package useractions
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/fragmenta/auth"
@@ -10,6 +9,7 @@ import (
 	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
+	"github.com/kennygrant/gohackernews/src/lib/password"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/users"
 )
@@ -82,20 +82,58 @@ func HandleLogin(w http.ResponseWriter, r *http.Request) error {
 		return server.Redirect(w, r, "/users/login?error=failed_password")
 	}
 
+	// Lift suspensions which have ended
+	if user.SuspensionExpired() {
+		err = user.Unsuspend()
+		if err != nil {
+			return server.InternalError(err)
+		}
+	}
+
+	// Suspended users may not log in, tell them why
+	if user.Suspended() {
+		log.Info(log.V{"msg": "login failed", "email": email, "user_id": user.ID, "status": http.StatusForbidden})
+		return renderSuspended(w, r, user)
+	}
+
+	// Upgrade the password hash if it was made with an older cost
+	if password.Current.NeedsRehash(user.PasswordHash) {
+		hash, err := password.Current.Hash(params.Get("password"))
+		if err == nil {
+			err = user.Rehash(hash)
+		}
+		if err != nil {
+			log.Error(log.V{"msg": "rehash failed", "user_id": user.ID, "error": err})
+		}
+	}
+
 	// Now save the user details in a secure cookie,
 	// so that we remember the next request
-	session, err := auth.Session(w, r)
+	err = session.Login(w, r, user)
 	if err != nil {
 		log.Info(log.V{"msg": "login failed", "email": email, "user_id": user.ID, "status": http.StatusInternalServerError})
 	}
 
-	// Success, log it and set the cookie with user id
-	session.Set(auth.SessionUserKey, fmt.Sprintf("%d", user.ID))
-	session.Save(w)
-
 	// Log action
 	log.Info(log.V{"msg": "login", "user_email": user.Email, "user_name": user.Name, "user_id": user.ID})
 
 	// Redirect - ideally here we'd redirect to their original request path
 	return server.Redirect(w, r, "/")
 }
+
+// renderSuspended shows the login page with the reason for this user's suspension.
+func renderSuspended(w http.ResponseWriter, r *http.Request, user *users.User) error {
+	msg := "Sorry, this account has been suspended"
+	if !user.SuspendedUntil.IsZero() {
+		msg += " until " + user.SuspendedUntil.UTC().Format("2 Jan 2006 15:04 MST")
+	}
+	if user.SuspendedReason != "" {
+		msg += ": " + user.SuspendedReason
+	}
+
+	view := view.NewRenderer(w, r)
+	view.AddKey("warning", msg+".")
+	view.AddKey("hideSubmit", true)
+	view.Template("users/views/login.html.got")
+	return view.Render()
+}
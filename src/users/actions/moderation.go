@@ -0,0 +1,138 @@
+package useractions
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// HandleSuspend responds to POST /users/{id}/suspend
+// by suspending the user for a number of days (or indefinitely) with a reason.
+func HandleSuspend(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may suspend users, and not themselves
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() || currentUser.ID == user.ID {
+		return server.NotAuthorizedError(nil)
+	}
+
+	// Zero days suspends the user until the suspension is lifted
+	var until time.Time
+	days := params.GetInt("days")
+	if days > 0 {
+		until = time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	}
+	reason := strings.TrimSpace(params.Get("reason"))
+
+	err = user.Suspend(until, reason)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "user suspended", "user_id": user.ID, "days": days, "reason": reason, "admin_id": currentUser.ID})
+
+	return server.Redirect(w, r, user.ShowURL())
+}
+
+// HandleUnsuspend responds to POST /users/{id}/unsuspend by lifting a suspension.
+func HandleUnsuspend(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may lift suspensions
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	err = user.Unsuspend()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "user unsuspended", "user_id": user.ID, "admin_id": currentUser.ID})
+
+	return server.Redirect(w, r, user.ShowURL())
+}
+
+// HandleShadowBan responds to POST /users/{id}/shadowban
+// by turning the shadow ban for the user on or off.
+func HandleShadowBan(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may shadow ban users, and not themselves
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() || currentUser.ID == user.ID {
+		return server.NotAuthorizedError(nil)
+	}
+
+	err = user.SetShadowBanned(!user.ShadowBanned)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "user shadow ban set", "user_id": user.ID, "shadow_banned": user.ShadowBanned, "admin_id": currentUser.ID})
+
+	return server.Redirect(w, r, user.ShowURL())
+}
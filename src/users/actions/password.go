@@ -14,6 +14,7 @@ import (
 	"github.com/fragmenta/view"
 
 	"github.com/kennygrant/gohackernews/src/lib/mail"
+	"github.com/kennygrant/gohackernews/src/lib/password"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/users"
 )
@@ -130,19 +131,145 @@ func HandlePasswordReset(w http.ResponseWriter, r *http.Request) error {
 
 	// Log in the user and store in the session
 	// Now save the user details in a secure cookie, so that we remember the next request
-	// Build the session from the secure cookie, or create a new one
-	session, err := auth.Session(w, r)
+	err = session.Login(w, r, user)
 	if err != nil {
 		return server.NotAuthorizedError(err)
 	}
 
-	// Save login the session cookie
-	session.Set(auth.SessionUserKey, fmt.Sprintf("%d", user.ID))
-	session.Save(w)
-
 	// Log action
 	log.Info(log.V{"msg": "reset password", "user_email": user.Email, "user_id": user.ID})
 
-	// Redirect to the user update page so that they can change their password
-	return server.Redirect(w, r, fmt.Sprintf("/users/%d/update", user.ID))
+	// Redirect to the password page so that they can set a new password
+	return server.Redirect(w, r, fmt.Sprintf("/users/%d/password", user.ID))
+}
+
+// HandlePasswordChangeShow responds to GET /users/{id}/password
+// by showing the form to change the user password.
+func HandlePasswordChangeShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise - only the user may change their password
+	currentUser := session.CurrentUser(w, r)
+	if currentUser.ID != user.ID {
+		return server.NotAuthorizedError(nil)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	switch params.Get("error") {
+	case "failed_password":
+		view.AddKey("warning", "Sorry, your current password was incorrect, please try again.")
+	case "failed_confirm":
+		view.AddKey("warning", "Sorry, the new passwords did not match, please try again.")
+	}
+	view.AddKey("user", user)
+	view.AddKey("currentUser", currentUser)
+	view.AddKey("reset", user.ResetVerified(ResetLifetime))
+	view.AddKey("minLength", password.Current.MinLength)
+	view.Template("users/views/password.html.got")
+	return view.Render()
+}
+
+// HandlePasswordChange responds to POST /users/{id}/password
+// by checking the current password, setting the new one and revoking other sessions.
+func HandlePasswordChange(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only the user may change their password
+	currentUser := session.CurrentUser(w, r)
+	if currentUser.ID != user.ID {
+		return server.NotAuthorizedError(nil)
+	}
+
+	// Check the current password, unless they have just followed a reset link
+	if !user.ResetVerified(ResetLifetime) {
+		err = auth.CheckPassword(params.Get("current_password"), user.PasswordHash)
+		if err != nil {
+			log.Info(log.V{"msg": "password change failed", "user_id": user.ID, "status": http.StatusUnauthorized})
+			return server.Redirect(w, r, fmt.Sprintf("/users/%d/password?error=failed_password", user.ID))
+		}
+	}
+
+	pass := params.Get("password")
+	if pass != params.Get("password_confirm") {
+		return server.Redirect(w, r, fmt.Sprintf("/users/%d/password?error=failed_confirm", user.ID))
+	}
+
+	// Password must meet the password policy
+	err = password.Current.Check(pass, user.Name, user.Email)
+	if err != nil {
+		return server.InternalError(err, "Password too weak", err.Error())
+	}
+
+	hash, err := password.Current.Hash(pass)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Set the password, this revokes all sessions, then log in again on this one
+	err = user.SetPassword(hash)
+	if err != nil {
+		return server.InternalError(err)
+	}
+	err = session.Login(w, r, user)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "password changed", "user_email": user.Email, "user_id": user.ID})
+
+	// Let the user know in case they did not make the change
+	err = sendPasswordChanged(user)
+	if err != nil {
+		log.Error(log.V{"msg": "password changed email failed", "user_id": user.ID, "error": err})
+	}
+
+	return server.Redirect(w, r, user.ShowURL())
+}
+
+// sendPasswordChanged emails the user to tell them their password has changed.
+func sendPasswordChanged(user *users.User) error {
+
+	// Email is optional on users
+	if user.Email == "" {
+		return nil
+	}
+
+	emailContext := map[string]interface{}{
+		"url":  config.Get("root_url") + "/users/password/reset",
+		"name": user.Name,
+	}
+	e := mail.New(user.Email)
+	e.Subject = "Your password has been changed"
+	e.Template = "users/views/mail/password_changed.html.got"
+	return mail.Send(e, emailContext)
 }
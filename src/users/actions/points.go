@@ -0,0 +1,158 @@
+package useractions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// pointsLimit is the number of point events shown per page
+const pointsLimit = 50
+
+// HandlePoints responds to GET /users/{id}/points
+// by showing the history of changes to the user's points.
+func HandlePoints(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise - only the user and admins may see the history
+	currentUser := session.CurrentUser(w, r)
+	if currentUser.ID != user.ID && !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	// Set the offset in pages if we have one
+	q := user.PointEvents().Limit(pointsLimit)
+	page := params.GetInt("page")
+	if page > 0 {
+		q.Offset(pointsLimit * int(page))
+	}
+
+	events, err := users.FindPointEvents(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("user", user)
+	view.AddKey("events", events)
+	view.AddKey("page", page)
+	view.AddKey("more", len(events) == pointsLimit)
+	view.AddKey("currentUser", currentUser)
+	view.Template("users/views/points.html.got")
+	return view.Render()
+}
+
+// HandleReverse responds to POST /users/points/{id}/reverse
+// by recording an event which cancels out a mistaken change.
+func HandleReverse(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the event
+	event, err := users.FindPointEvent(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may reverse points
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	// The ledger is append only, so we add a compensating event, once
+	err = event.Reverse()
+	if err != nil {
+		return server.InternalError(err, "Reverse Failed", "Sorry, this change has already been reversed or is a reversal")
+	}
+
+	// Log action
+	log.Info(log.V{"msg": "points reversed", "event_id": event.ID, "user_id": event.UserID, "delta": event.Delta, "admin_id": currentUser.ID})
+
+	user := users.New()
+	user.ID = event.UserID
+	return server.Redirect(w, r, user.ShowURL()+"/points")
+}
+
+// HandleReconcileShow responds to GET /users/reconcile
+// by listing users whose points differ from their ledger.
+func HandleReconcileShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise - only admins may reconcile points
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	drift, err := users.FindDrift()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("drift", drift)
+	view.AddKey("currentUser", currentUser)
+	view.Template("users/views/reconcile.html.got")
+	return view.Render()
+}
+
+// HandleReconcile responds to POST /users/reconcile
+// by setting the points of every drifted user to the sum of their ledger.
+func HandleReconcile(w http.ResponseWriter, r *http.Request) error {
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise - only admins may reconcile points
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Admin() {
+		return server.NotAuthorizedError(nil)
+	}
+
+	drift, err := users.FindDrift()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	for _, d := range drift {
+		err = d.Repair()
+		if err != nil {
+			return server.InternalError(err)
+		}
+		log.Info(log.V{"msg": "points reconciled", "user_id": d.User.ID, "points": d.User.Points, "ledger": d.Ledger, "admin_id": currentUser.ID})
+	}
+
+	return server.Redirect(w, r, "/users/reconcile")
+}
@@ -0,0 +1,41 @@
+package useractions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/kennygrant/gohackernews/src/lib/karma"
+	"github.com/kennygrant/gohackernews/src/lib/session"
+	"github.com/kennygrant/gohackernews/src/users"
+)
+
+// HandlePrivileges responds to GET /users/{id}/privileges
+// by showing the privileges the user has unlocked with their points.
+func HandlePrivileges(w http.ResponseWriter, r *http.Request) error {
+
+	// No authorisation on privileges, the policy is public
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("user", user)
+	view.AddKey("policy", karma.Current)
+	view.AddKey("probationDays", int64(karma.Current.Probation.Hours()/24))
+	view.AddKey("currentUser", session.CurrentUser(w, r))
+	view.Template("users/views/privileges.html.got")
+	return view.Render()
+}
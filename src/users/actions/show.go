@@ -1,13 +1,20 @@
 package useractions
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/config"
 	"github.com/fragmenta/view"
 
 	"github.com/kennygrant/gohackernews/src/comments"
+	"github.com/kennygrant/gohackernews/src/lib/identicon"
 	"github.com/kennygrant/gohackernews/src/lib/session"
 	"github.com/kennygrant/gohackernews/src/stories"
 	"github.com/kennygrant/gohackernews/src/users"
@@ -30,8 +37,17 @@ func HandleShow(w http.ResponseWriter, r *http.Request) error {
 		return server.NotFoundError(err)
 	}
 
-	// Get the user comments
+	// For json requests serve the public profile
+	if strings.HasSuffix(r.URL.Path, ".json") {
+		return renderUserJSON(w, user)
+	}
+
+	// Find logged in user (if any)
+	currentUser := session.CurrentUser(w, r)
+
+	// Get the user comments, hidden from others if the user is shadow banned
 	q := comments.Where("user_id=?", user.ID).Limit(10).Order("created_at desc")
+	currentUser.WhereVisible(q)
 	userComments, err := comments.FindAll(q)
 	if err != nil {
 		return server.InternalError(err)
@@ -39,13 +55,20 @@ func HandleShow(w http.ResponseWriter, r *http.Request) error {
 
 	// Get the user stories
 	q = stories.Where("user_id=?", user.ID).Limit(50).Order("created_at desc")
+	currentUser.WhereVisible(q)
 	userStories, err := stories.FindAll(q)
 	if err != nil {
 		return server.InternalError(err)
 	}
 
-	// Find logged in user (if any)
-	currentUser := session.CurrentUser(w, r)
+	// Get recent changes to points, which only the user and admins may see
+	var events []*users.PointEvent
+	if currentUser.ID == user.ID || currentUser.Admin() {
+		events, err = users.FindPointEvents(user.PointEvents().Limit(10))
+		if err != nil {
+			return server.InternalError(err)
+		}
+	}
 
 	// Render the template
 	view := view.NewRenderer(w, r)
@@ -53,10 +76,73 @@ func HandleShow(w http.ResponseWriter, r *http.Request) error {
 	view.AddKey("user", user)
 	view.AddKey("stories", userStories)
 	view.AddKey("comments", userComments)
+	view.AddKey("events", events)
+	view.AddKey("meta_rss", fmt.Sprintf("/stories.xml?u=%d", user.ID))
+	view.AddKey("meta_atom", fmt.Sprintf("/stories.atom?u=%d", user.ID))
+	view.AddKey("meta_json", fmt.Sprintf("/stories.json?u=%d", user.ID))
 	view.AddKey("currentUser", currentUser)
 	return view.Render()
 }
 
+// userJSON is the public representation of a user in json.
+type userJSON struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Points    int64     `json:"points"`
+	Title     string    `json:"title"`
+	About     string    `json:"about"`
+	Website   string    `json:"website"`
+	GitHub    string    `json:"github"`
+	Mastodon  string    `json:"mastodon"`
+	Avatar    string    `json:"avatar"`
+	URL       string    `json:"url"`
+}
+
+// renderUserJSON writes the public profile of this user as json.
+func renderUserJSON(w http.ResponseWriter, user *users.User) error {
+	root := config.Get("root_url")
+	data := userJSON{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		Name:      user.Name,
+		Points:    user.Points,
+		Title:     user.Title,
+		About:     user.Text,
+		Website:   user.Website,
+		GitHub:    user.GitHub,
+		Mastodon:  user.Mastodon,
+		Avatar:    root + user.AvatarURL(),
+		URL:       root + user.ShowURL(),
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(data)
+}
+
+// HandleAvatar serves the generated avatar for a user as svg.
+func HandleAvatar(w http.ResponseWriter, r *http.Request) error {
+
+	// No authorisation on avatars
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Avatars never change, so may be cached for a long time
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age=2592000")
+	_, err = io.WriteString(w, identicon.SVG(user.ID, 80))
+	return err
+}
+
 // HandleShowName redirects a GET request of /u/username to the user show page
 func HandleShowName(w http.ResponseWriter, r *http.Request) error {
 
@@ -3,7 +3,6 @@ package useractions
 import (
 	"net/http"
 
-	"github.com/fragmenta/auth"
 	"github.com/fragmenta/auth/can"
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/server"
@@ -62,21 +61,35 @@ func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	// Authorise update user
-	err = can.Update(user, session.CurrentUser(w, r))
+	currentUser := session.CurrentUser(w, r)
+	err = can.Update(user, currentUser)
 	if err != nil {
 		return server.NotAuthorizedError(err)
 	}
 
-	// Set the password hash from the password
-	hash, err := auth.HashPassword(params.Get("password"))
-	if err != nil {
-		return server.InternalError(err)
+	// Validate the params, removing any we don't accept, only admins may set roles and status
+	// passwords are changed separately with HandlePasswordChange
+	accepted := users.AllowedParams()
+	if currentUser.Admin() {
+		accepted = users.AllowedParamsAdmin()
+	}
+	userParams := user.ValidateParams(params.Map(), accepted)
+
+	// Role must be one of those offered
+	if role, ok := userParams["role"]; ok && !users.ValidRole(role) {
+		return server.InternalError(nil, "Invalid Role", "Sorry, that role is not recognised")
 	}
-	// FIXME: For user update we should require the old password too, to match existing
-	params.SetString("password_hash", hash)
 
-	// Validate the params, removing any we don't accept
-	userParams := user.ValidateParams(params.Map(), users.AllowedParams())
+	// Name must not be the placeholder for deleted accounts
+	if users.ReservedName(userParams["name"]) {
+		return server.InternalError(nil, "Name not allowed", "Sorry, that name is reserved")
+	}
+
+	// Check the profile fields, this also normalises handles
+	err = users.ValidateProfile(userParams)
+	if err != nil {
+		return server.InternalError(err, "Invalid Profile", err.Error())
+	}
 
 	err = user.Update(userParams)
 	if err != nil {
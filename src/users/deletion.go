@@ -0,0 +1,292 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+)
+
+// This file contains functions related to deleting user accounts.
+
+// DeletedName is the name of the placeholder user which deleted content is reassigned to.
+const DeletedName = "[deleted]"
+
+// ReservedName returns true if this name may not be used by people, as it is
+// used by the placeholder for deleted accounts.
+func ReservedName(name string) bool {
+	return strings.EqualFold(strings.TrimSpace(name), DeletedName)
+}
+
+// FindDeleted returns the placeholder user for deleted accounts, creating it if required.
+// The placeholder is identified by its role as well as its name, which people cannot take.
+func FindDeleted() (*User, error) {
+	user, err := FindFirst("name=? AND role=?", DeletedName, Anon)
+	if err == nil {
+		return user, nil
+	}
+
+	// The placeholder cannot log in as it has no password and no role
+	params := map[string]string{
+		"name":   DeletedName,
+		"status": fmt.Sprintf("%d", status.Suspended),
+		"role":   fmt.Sprintf("%d", Anon),
+		"points": "0",
+	}
+	id, err := New().Create(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return Find(id)
+}
+
+// DeletePending returns true if this user has asked for their account to be deleted.
+func (u *User) DeletePending() bool {
+	return !u.DeleteAt.IsZero()
+}
+
+// Anonymise deletes this user, reassigning their stories and comments to
+// the deleted placeholder user. Votes and flags are kept for scoring,
+// but no longer reference the user or their ip.
+func (u *User) Anonymise() error {
+
+	deleted, err := FindDeleted()
+	if err != nil {
+		return err
+	}
+
+	// Never delete the placeholder itself
+	if deleted.ID == u.ID {
+		return fmt.Errorf("users: cannot anonymise placeholder user")
+	}
+
+	sql := "update stories set user_id=$1, user_name=$2, user_supporter=0 where user_id=$3"
+	_, err = query.Exec(sql, deleted.ID, deleted.Name, u.ID)
+	if err != nil {
+		return err
+	}
+
+	sql = "update comments set user_id=$1, user_name=$2, user_supporter=0 where user_id=$3"
+	_, err = query.Exec(sql, deleted.ID, deleted.Name, u.ID)
+	if err != nil {
+		return err
+	}
+
+	sql = "update revisions set user_id=$1, user_name=$2 where user_id=$3"
+	_, err = query.Exec(sql, deleted.ID, deleted.Name, u.ID)
+	if err != nil {
+		return err
+	}
+
+	sql = "update jobs set user_id=$1, user_name=$2 where user_id=$3"
+	_, err = query.Exec(sql, deleted.ID, deleted.Name, u.ID)
+	if err != nil {
+		return err
+	}
+
+	// Payments, sponsorships and memberships are kept for accounting, but no longer reference the user
+	_, err = query.Exec("update payments set user_id=$1 where user_id=$2", deleted.ID, u.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = query.Exec("update sponsorships set user_id=$1 where user_id=$2", deleted.ID, u.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = query.Exec("update memberships set user_id=$1 where user_id=$2", deleted.ID, u.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = query.Exec("update votes set user_id=NULL, user_ip=NULL where user_id=$1", u.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = query.Exec("update flags set user_id=NULL, user_ip=NULL where user_id=$1", u.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = query.Exec("delete from point_events where user_id=$1", u.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = query.Exec("delete from invites where user_id=$1", u.ID)
+	if err != nil {
+		return err
+	}
+
+	return u.Destroy()
+}
+
+// Purge deletes this user along with all their stories, votes and flags, and their comments
+// which have no replies. It is intended for spam accounts. Comments with replies are kept as tombstones,
+// and the points the user's votes gave to content and its authors are reversed.
+func (u *User) Purge() error {
+
+	deleted, err := FindDeleted()
+	if err != nil {
+		return err
+	}
+
+	// Never purge the placeholder, which holds the content of every deleted account
+	if deleted.ID == u.ID {
+		return fmt.Errorf("users: cannot purge placeholder user")
+	}
+
+	// Reverse the points this user's votes gave to authors, through the ledger
+	err = u.reverseVotePoints()
+	if err != nil {
+		return err
+	}
+
+	// Reverse the effect of this user's votes on stories and comments
+	sql := `update stories set points = points - (select sum(points) from votes where votes.story_id = stories.id and votes.user_id=$1)
+	where id in (select story_id from votes where user_id=$1)`
+	_, err = query.Exec(sql, u.ID)
+	if err != nil {
+		return err
+	}
+
+	sql = `update comments set points = points - (select sum(points) from votes where votes.comment_id = comments.id and votes.user_id=$1)
+	where id in (select comment_id from votes where user_id=$1)`
+	_, err = query.Exec(sql, u.ID)
+	if err != nil {
+		return err
+	}
+
+	// Find stories this user commented on before we remove their comments
+	storyIDs := query.New("comments", "story_id").Select("select distinct story_id as id from comments").Where("user_id=?", u.ID).ResultIDs()
+
+	// Remove the user's stories with the whole thread of comments on them
+	sql = "delete from %s where comment_id in (select id from comments where story_id in (select id from stories where user_id=$1))"
+	for _, table := range []string{"votes", "flags", "revisions"} {
+		_, err = query.Exec(fmt.Sprintf(sql, table), u.ID)
+		if err != nil {
+			return err
+		}
+	}
+	sql = "delete from %s where story_id in (select id from stories where user_id=$1)"
+	for _, table := range []string{"votes", "flags", "revisions", "comments"} {
+		_, err = query.Exec(fmt.Sprintf(sql, table), u.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the user's comments without replies, removing a comment may leave its parent without replies, so repeat until none are left
+	for {
+		ids := query.New("comments", "id").Select("select id from comments").Where("user_id=?", u.ID).Where("NOT EXISTS (select 1 from comments replies where replies.parent_id=comments.id)").ResultIDs()
+		if len(ids) == 0 {
+			break
+		}
+
+		for _, table := range []string{"votes", "flags", "revisions"} {
+			err = query.New(table, "comment_id").WhereIn("comment_id", ids).Delete()
+			if err != nil {
+				return err
+			}
+		}
+
+		err = query.New("comments", "id").WhereIn("id", ids).Delete()
+		if err != nil {
+			return err
+		}
+	}
+
+	// The comments which remain have replies by others, so keep them as tombstones without their text
+	_, err = query.Exec("delete from revisions where comment_id in (select id from comments where user_id=$1)", u.ID)
+	if err != nil {
+		return err
+	}
+
+	sql = "update comments set user_id=$1, user_name=$2, user_supporter=0, text='', deleted_at=coalesce(deleted_at,$3) where user_id=$4"
+	_, err = query.Exec(sql, deleted.ID, deleted.Name, query.TimeString(time.Now().UTC()), u.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range []string{"votes", "flags", "stories", "point_events", "invites", "revisions", "jobs", "sponsorships"} {
+		_, err = query.Exec(fmt.Sprintf("delete from %s where user_id=$1", table), u.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Payments and memberships are kept for accounting, but no longer reference the user
+	_, err = query.Exec("update payments set user_id=$1 where user_id=$2", deleted.ID, u.ID)
+	if err != nil {
+		return err
+	}
+	_, err = query.Exec("update memberships set user_id=$1 where user_id=$2", deleted.ID, u.ID)
+	if err != nil {
+		return err
+	}
+
+	// Recount comments on the stories which remain
+	for _, id := range storyIDs {
+		sql = "update stories set comment_count = (select count(*) from comments where story_id=$1 and points > 0 and deleted_at IS NULL) where id=$1"
+		_, err = query.Exec(sql, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return u.Destroy()
+}
+
+// reverseVotePoints records events in the ledger which cancel the points this user's votes and flags
+// gave to the authors of stories and comments, so that their points match their ledger.
+func (u *User) reverseVotePoints() error {
+
+	sql := `select coalesce(comments.user_id, stories.user_id) as author_id, coalesce(votes.story_id, comments.story_id) as story_id,
+	votes.comment_id as comment_id, votes.points as points from votes
+	left join stories on stories.id = votes.story_id and votes.comment_id IS NULL
+	left join comments on comments.id = votes.comment_id`
+	results, err := query.New("votes", "story_id").Select(sql).Where("votes.user_id=?", u.ID).Results()
+	if err != nil {
+		return err
+	}
+
+	// Sum the points given to each author for each story or comment
+	type source struct {
+		authorID, storyID, commentID int64
+	}
+	var sources []source
+	points := make(map[source]int64)
+	for _, cols := range results {
+		s := source{
+			authorID:  resource.ValidateInt(cols["author_id"]),
+			storyID:   resource.ValidateInt(cols["story_id"]),
+			commentID: resource.ValidateInt(cols["comment_id"]),
+		}
+		if s.authorID == 0 || s.authorID == u.ID {
+			continue
+		}
+		if _, ok := points[s]; !ok {
+			sources = append(sources, s)
+		}
+		points[s] += resource.ValidateInt(cols["points"])
+	}
+
+	for _, s := range sources {
+		if points[s] == 0 {
+			continue
+		}
+		err = AddPoints(s.authorID, -points[s], ReasonReversal, s.storyID, s.commentID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file contains functions for private feeds, which feed readers fetch without
+// a session, so the user is identified by a token in the feed url instead.
+
+// MinFeedToken is the minimum length of a valid feed token.
+const MinFeedToken = 32
+
+// FindFeedToken returns the user with this feed token, the tokens of suspended users are refused.
+func FindFeedToken(token string) (*User, error) {
+	if len(token) < MinFeedToken {
+		return nil, fmt.Errorf("users: invalid feed token")
+	}
+	user, err := FindFirst("feed_token=?", token)
+	if err != nil {
+		return nil, err
+	}
+	if user.Suspended() {
+		return nil, fmt.Errorf("users: feed token of suspended user %d", user.ID)
+	}
+	return user, nil
+}
+
+// FeedURL returns the private url of the feed at this path for this user, or "" if they have no feed token.
+func (u *User) FeedURL(path string) string {
+	if u.FeedToken == "" {
+		return ""
+	}
+	if strings.Contains(path, "?") {
+		return fmt.Sprintf("%s&token=%s", path, u.FeedToken)
+	}
+	return fmt.Sprintf("%s?token=%s", path, u.FeedToken)
+}
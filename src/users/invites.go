@@ -0,0 +1,267 @@
+package users
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/status"
+)
+
+// This file contains invitations, and the tree of users they create.
+
+// InvitesTableName is the database table for invites.
+const InvitesTableName = "invites"
+
+// InviteLifetime is the time an invite may be used for after it is created.
+const InviteLifetime = 30 * 24 * time.Hour
+
+// Invite is a single use link which allows a new user to register.
+type Invite struct {
+	resource.Base
+
+	// UserID is the user who created the invite
+	UserID int64
+	Token  string
+	Email  string
+
+	// UsedAt and UsedBy record the user who registered with the invite
+	UsedAt time.Time
+	UsedBy int64
+}
+
+// NewInviteWithColumns creates a new invite and fills it with data from the database cols provided.
+func NewInviteWithColumns(cols map[string]interface{}) *Invite {
+	i := &Invite{}
+	i.TableName = InvitesTableName
+	i.KeyName = KeyName
+	i.ID = resource.ValidateInt(cols["id"])
+	i.CreatedAt = resource.ValidateTime(cols["created_at"])
+	i.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	i.UserID = resource.ValidateInt(cols["user_id"])
+	i.Token = resource.ValidateString(cols["token"])
+	i.Email = resource.ValidateString(cols["email"])
+	i.UsedAt = resource.ValidateTime(cols["used_at"])
+	i.UsedBy = resource.ValidateInt(cols["used_by"])
+	return i
+}
+
+// InvitesQuery returns a new query for invites, newest first.
+func InvitesQuery() *query.Query {
+	return query.New(InvitesTableName, KeyName).Order("created_at desc, id desc")
+}
+
+// FindInvites fetches all invites matching this query from the database.
+func FindInvites(q *query.Query) ([]*Invite, error) {
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	var invites []*Invite
+	for _, cols := range results {
+		invites = append(invites, NewInviteWithColumns(cols))
+	}
+
+	return invites, nil
+}
+
+// FindInvite fetches an unused invite by token, it returns an error
+// if the invite does not exist, has been used or has expired.
+func FindInvite(token string) (*Invite, error) {
+	if len(token) < 10 || len(token) > 64 {
+		return nil, fmt.Errorf("users: invalid invite token")
+	}
+
+	result, err := InvitesQuery().Where("token=?", token).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := NewInviteWithColumns(result)
+	if !invite.Valid() {
+		return nil, fmt.Errorf("users: invite %d is no longer valid", invite.ID)
+	}
+
+	return invite, nil
+}
+
+// Invites returns a query for the invites this user has created.
+func (u *User) Invites() *query.Query {
+	return InvitesQuery().Where("user_id=?", u.ID)
+}
+
+// CreateInvite records a new invite from this user with the token given.
+func (u *User) CreateInvite(token string, email string) error {
+	now := query.TimeString(time.Now().UTC())
+	params := map[string]string{
+		"created_at": now,
+		"updated_at": now,
+		"user_id":    fmt.Sprintf("%d", u.ID),
+		"token":      token,
+		"email":      email,
+	}
+	_, err := query.New(InvitesTableName, KeyName).Insert(params)
+	return err
+}
+
+// Used returns true if this invite has been used to register.
+func (i *Invite) Used() bool {
+	return !i.UsedAt.IsZero()
+}
+
+// Valid returns true if this invite may still be used to register.
+func (i *Invite) Valid() bool {
+	return !i.Used() && time.Since(i.CreatedAt) < InviteLifetime
+}
+
+// URL returns the registration url for this invite, relative to the site root.
+func (i *Invite) URL() string {
+	return fmt.Sprintf("/users/create?invite=%s", i.Token)
+}
+
+// Claim marks this invite as used if it is still unused, so that only one registration may use it.
+// It should be called before the user is created, and the claim released if that fails.
+func (i *Invite) Claim() error {
+	now := query.TimeString(time.Now().UTC())
+	result, err := query.Exec("update invites set used_at=$1, updated_at=$1 where id=$2 and used_at IS NULL", now, i.ID)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("users: invite %d has already been used", i.ID)
+	}
+	return nil
+}
+
+// Release marks a claimed invite as unused again, if no user has accepted it.
+func (i *Invite) Release() error {
+	_, err := query.Exec("update invites set used_at=NULL where id=$1 and used_by IS NULL", i.ID)
+	return err
+}
+
+// Accept records the user given as having used this invite, and records the inviter on that user.
+// The invite is claimed if it has not been already, each invite may be accepted once.
+func (i *Invite) Accept(userID int64) error {
+	now := query.TimeString(time.Now().UTC())
+
+	// Only update the invite if it has not been accepted, so that it may be used once
+	result, err := query.Exec("update invites set used_at=coalesce(used_at,$1), used_by=$2, updated_at=$1 where id=$3 and used_by IS NULL", now, userID, i.ID)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("users: invite %d has already been used", i.ID)
+	}
+
+	_, err = query.Exec("update users set invited_by=$1 where id=$2", i.UserID, userID)
+	return err
+}
+
+// InviteNode is a user in the invite tree, with the users they invited.
+type InviteNode struct {
+	User     *User
+	Children []*InviteNode
+}
+
+// InviteTree returns the users who have invited others, with the users they invited below them.
+// Users who registered without an invite and have not invited anyone are omitted.
+func InviteTree() ([]*InviteNode, error) {
+
+	results, err := Query().Order("id asc").Results()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[int64]*InviteNode)
+	var list []*InviteNode
+	for _, cols := range results {
+		node := &InviteNode{User: NewWithColumns(cols)}
+		nodes[node.User.ID] = node
+		list = append(list, node)
+	}
+
+	var roots []*InviteNode
+	for _, node := range list {
+		parent := nodes[node.User.InvitedBy]
+		if parent != nil && parent != node {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	// Keep only roots which have invited others
+	var tree []*InviteNode
+	for _, node := range roots {
+		if len(node.Children) > 0 {
+			tree = append(tree, node)
+		}
+	}
+
+	return tree, nil
+}
+
+// InvitedUserIDs returns the ids of all users invited by this user, directly or indirectly.
+func (u *User) InvitedUserIDs() ([]int64, error) {
+
+	results, err := query.New(TableName, KeyName).Select("select id, invited_by from users").Where("invited_by IS NOT NULL").Results()
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[int64][]int64)
+	for _, cols := range results {
+		parent := resource.ValidateInt(cols["invited_by"])
+		children[parent] = append(children[parent], resource.ValidateInt(cols["id"]))
+	}
+
+	// Walk down the tree, guarding against cycles
+	var ids []int64
+	seen := map[int64]bool{u.ID: true}
+	queue := []int64{u.ID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, child := range children[id] {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			ids = append(ids, child)
+			queue = append(queue, child)
+		}
+	}
+
+	return ids, nil
+}
+
+// SuspendInvited suspends this user and every user they invited, directly or indirectly.
+// It returns the number of users suspended.
+func (u *User) SuspendInvited() (int, error) {
+	ids, err := u.InvitedUserIDs()
+	if err != nil {
+		return 0, err
+	}
+	ids = append([]int64{u.ID}, ids...)
+
+	reason := fmt.Sprintf("Banned along with the users invited by %s", u.Name)
+	for _, id := range ids {
+		_, err = query.Exec("update users set status=$1, suspended_until=NULL, suspended_reason=$2 where id=$3", status.Suspended, reason, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), nil
+}
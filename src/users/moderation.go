@@ -0,0 +1,94 @@
+package users
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/status"
+)
+
+// This file contains functions related to suspending and shadow banning users.
+
+// Suspended returns true if this user is currently suspended,
+// suspensions with no end time last until they are lifted.
+func (u *User) Suspended() bool {
+	if u.Status != status.Suspended {
+		return false
+	}
+	return u.SuspendedUntil.IsZero() || time.Now().Before(u.SuspendedUntil)
+}
+
+// SuspensionExpired returns true if this user was suspended, but the suspension has ended.
+func (u *User) SuspensionExpired() bool {
+	return u.Status == status.Suspended && !u.Suspended()
+}
+
+// Suspend suspends this user until the time given (or indefinitely if zero) for the reason given.
+func (u *User) Suspend(until time.Time, reason string) error {
+	var end interface{}
+	if !until.IsZero() {
+		end = query.TimeString(until.UTC())
+	}
+
+	sql := "update users set status=$1, suspended_until=$2, suspended_reason=$3 where id=$4"
+	_, err := query.Exec(sql, status.Suspended, end, reason, u.ID)
+	if err != nil {
+		return err
+	}
+
+	u.Status = status.Suspended
+	u.SuspendedUntil = until
+	u.SuspendedReason = reason
+	return nil
+}
+
+// Unsuspend lifts any suspension on this user.
+func (u *User) Unsuspend() error {
+	sql := "update users set status=$1, suspended_until=NULL, suspended_reason=NULL where id=$2"
+	_, err := query.Exec(sql, status.Published, u.ID)
+	if err != nil {
+		return err
+	}
+
+	u.Status = status.Published
+	u.SuspendedUntil = time.Time{}
+	u.SuspendedReason = ""
+	return nil
+}
+
+// SetShadowBanned sets whether this user is shadow banned, the stories and comments
+// of shadow banned users are visible only to themselves and admins.
+func (u *User) SetShadowBanned(banned bool) error {
+	_, err := query.Exec("update users set shadow_banned=$1 where id=$2", banned, u.ID)
+	if err != nil {
+		return err
+	}
+	u.ShadowBanned = banned
+	return nil
+}
+
+// WhereVisible restricts a query on stories or comments to those this user may see,
+// content by shadow banned users is shown only to its author and to admins,
+// and suspended content only to moderators and admins.
+func (u *User) WhereVisible(q *query.Query) *query.Query {
+	if u.Admin() {
+		return q
+	}
+	if !u.CanModerate() {
+		q.Where("COALESCE(status,?) <> ?", status.Published, status.Suspended)
+	}
+	return q.Where("(user_id=? OR user_id NOT IN (select id from users where shadow_banned=true))", u.ID)
+}
+
+// WherePublic restricts a query on stories or comments to those visible to everyone,
+// excluding suspended content and content by shadow banned users.
+func WherePublic(q *query.Query) *query.Query {
+	q.Where("COALESCE(status,?) <> ?", status.Published, status.Suspended)
+	return q.Where("user_id NOT IN (select id from users where shadow_banned=true)")
+}
+
+// CanSee returns true if this user may see content posted by the author given.
+func (u *User) CanSee(author *User) bool {
+	return !author.ShadowBanned || u.Admin() || u.ID == author.ID
+}
@@ -0,0 +1,51 @@
+package users
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+)
+
+// This file contains functions related to changing user passwords.
+
+// SetPassword stores a new password hash for this user, clearing any reset token.
+// All existing sessions for the user are revoked, as is the token for their private feeds.
+func (u *User) SetPassword(hash string) error {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	sql := "update users set password_hash=$1, password_reset_token=NULL, password_reset_at=NULL, feed_token=NULL, sessions_at=$2, updated_at=$2 where id=$3"
+	_, err := query.Exec(sql, hash, query.TimeString(now), u.ID)
+	if err != nil {
+		return err
+	}
+
+	u.PasswordHash = hash
+	u.PasswordResetAt = time.Time{}
+	u.FeedToken = ""
+	u.SessionsAt = now
+	return nil
+}
+
+// Rehash replaces the password hash for this user without revoking sessions,
+// it is used to upgrade the hash when the user logs in.
+func (u *User) Rehash(hash string) error {
+	_, err := query.Exec("update users set password_hash=$1 where id=$2", hash, u.ID)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	return nil
+}
+
+// ResetVerified returns true if this user followed a password reset link within lifetime,
+// and so may set a new password without entering their current password.
+func (u *User) ResetVerified(lifetime time.Duration) bool {
+	if u.PasswordResetAt.IsZero() || time.Since(u.PasswordResetAt) > lifetime {
+		return false
+	}
+
+	// The token is removed when the link is followed
+	q := query.New(TableName, KeyName).Where("id=?", u.ID).Where("(password_reset_token IS NULL OR password_reset_token = '')")
+	count, err := q.Count()
+	return err == nil && count == 1
+}
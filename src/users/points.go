@@ -0,0 +1,194 @@
+package users
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/resource"
+)
+
+// This file contains the points ledger, which records every change to user points.
+
+// PointEventsTableName is the database table for the points ledger.
+const PointEventsTableName = "point_events"
+
+// Reasons for changes to user points recorded in the ledger.
+const (
+	ReasonSignup           = "signup"
+	ReasonOpening          = "opening balance"
+	ReasonStoryUpvoted     = "story upvoted"
+	ReasonStoryDownvoted   = "story downvoted"
+	ReasonStoryFlagged     = "story flagged"
+	ReasonCommentUpvoted   = "comment upvoted"
+	ReasonCommentDownvoted = "comment downvoted"
+	ReasonCommentFlagged   = "comment flagged"
+	ReasonDownvoteCost     = "downvote cost"
+	ReasonFlagCost         = "flag cost"
+	ReasonReversal         = "reversal"
+)
+
+// PointEvent records a single change to a user's points.
+type PointEvent struct {
+	resource.Base
+
+	UserID int64
+	Delta  int64
+	Reason string
+
+	// The source resource of the change (if any)
+	StoryID   int64
+	CommentID int64
+
+	// The event this event reverses (if any)
+	ReversedID int64
+}
+
+// NewPointEventWithColumns creates a new point event and fills it with data from the database cols provided.
+func NewPointEventWithColumns(cols map[string]interface{}) *PointEvent {
+	e := &PointEvent{}
+	e.TableName = PointEventsTableName
+	e.KeyName = KeyName
+	e.ID = resource.ValidateInt(cols["id"])
+	e.CreatedAt = resource.ValidateTime(cols["created_at"])
+	e.UpdatedAt = e.CreatedAt
+	e.UserID = resource.ValidateInt(cols["user_id"])
+	e.Delta = resource.ValidateInt(cols["delta"])
+	e.Reason = resource.ValidateString(cols["reason"])
+	e.StoryID = resource.ValidateInt(cols["story_id"])
+	e.CommentID = resource.ValidateInt(cols["comment_id"])
+	e.ReversedID = resource.ValidateInt(cols["reversed_id"])
+	return e
+}
+
+// PointEventsQuery returns a new query for point events, newest first.
+func PointEventsQuery() *query.Query {
+	return query.New(PointEventsTableName, KeyName).Order("created_at desc, id desc")
+}
+
+// FindPointEvent fetches a single point event from the database by id.
+func FindPointEvent(id int64) (*PointEvent, error) {
+	result, err := PointEventsQuery().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewPointEventWithColumns(result), nil
+}
+
+// FindPointEvents fetches all point events matching this query from the database.
+func FindPointEvents(q *query.Query) ([]*PointEvent, error) {
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*PointEvent
+	for _, cols := range results {
+		events = append(events, NewPointEventWithColumns(cols))
+	}
+
+	return events, nil
+}
+
+// PointEvents returns a query for the point events of this user, newest first.
+func (u *User) PointEvents() *query.Query {
+	return PointEventsQuery().Where("user_id=?", u.ID)
+}
+
+// SourceURL returns the url of the story or comment which caused this event (if any).
+func (e *PointEvent) SourceURL() string {
+	if e.CommentID > 0 {
+		return fmt.Sprintf("/comments/%d", e.CommentID)
+	}
+	if e.StoryID > 0 {
+		return fmt.Sprintf("/stories/%d", e.StoryID)
+	}
+	return ""
+}
+
+// AddPoints records a change to a user's points in the ledger, and applies it to their points.
+// storyID and commentID record the source of the change and may be 0.
+func AddPoints(userID int64, delta int64, reason string, storyID int64, commentID int64) error {
+	return addPoints(userID, delta, reason, storyID, commentID, 0)
+}
+
+// addPoints inserts an event in the ledger and applies the delta to the user's points
+// in a single statement, so that the ledger and the points cannot drift apart if one fails.
+// The delta is applied in the db rather than writing a total, so that concurrent votes are not lost.
+func addPoints(userID int64, delta int64, reason string, storyID int64, commentID int64, reversedID int64) error {
+	sql := `WITH event AS (
+INSERT INTO point_events (created_at, user_id, delta, reason, story_id, comment_id, reversed_id)
+VALUES ($1, $2, $3, $4, nullif($5,0), nullif($6,0), nullif($7,0)) RETURNING user_id, delta)
+UPDATE users SET points = coalesce(points,0) + event.delta FROM event WHERE users.id = event.user_id`
+	_, err := query.Exec(sql, query.TimeString(time.Now().UTC()), userID, delta, reason, storyID, commentID, reversedID)
+	return err
+}
+
+// Reversed returns true if an event which cancels out this one has been recorded.
+func (e *PointEvent) Reversed() (bool, error) {
+	count, err := query.New(PointEventsTableName, KeyName).Where("reversed_id=?", e.ID).Count()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Reverse records an event which cancels out this one. Each event may be reversed once,
+// and reversals may not themselves be reversed.
+func (e *PointEvent) Reverse() error {
+	if e.ReversedID > 0 {
+		return fmt.Errorf("users: point event %d is a reversal", e.ID)
+	}
+
+	reversed, err := e.Reversed()
+	if err != nil {
+		return err
+	}
+	if reversed {
+		return fmt.Errorf("users: point event %d has already been reversed", e.ID)
+	}
+
+	// The unique index on reversed_id rejects a concurrent second reversal
+	reason := fmt.Sprintf("%s of #%d %s", ReasonReversal, e.ID, e.Reason)
+	return addPoints(e.UserID, -e.Delta, reason, e.StoryID, e.CommentID, e.ID)
+}
+
+// Drift records a user whose points do not match the sum of their ledger.
+type Drift struct {
+	User   *User
+	Ledger int64
+}
+
+// Difference returns the points the user has which are not accounted for by the ledger.
+func (d *Drift) Difference() int64 {
+	return d.User.Points - d.Ledger
+}
+
+// FindDrift returns all users whose points differ from the sum of their ledger.
+func FindDrift() ([]*Drift, error) {
+	ledger := "coalesce((select sum(delta) from point_events where point_events.user_id = users.id),0)"
+	sql := fmt.Sprintf("select users.*, %s as ledger_points from users", ledger)
+	q := query.New(TableName, KeyName).Select(sql).Where(fmt.Sprintf("coalesce(points,0) != %s", ledger)).Order("id asc")
+
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []*Drift
+	for _, cols := range results {
+		drift = append(drift, &Drift{
+			User:   NewWithColumns(cols),
+			Ledger: resource.ValidateInt(cols["ledger_points"]),
+		})
+	}
+
+	return drift, nil
+}
+
+// Repair sets the user points to the sum of the ledger, which is authoritative.
+func (d *Drift) Repair() error {
+	_, err := query.Exec("update users set points=$1 where id=$2", d.Ledger, d.User.ID)
+	return err
+}
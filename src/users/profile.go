@@ -0,0 +1,97 @@
+package users
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// This file contains the public profile fields for users.
+
+var (
+	// GitHub usernames are alphanumeric with single hyphens, up to 39 characters
+	githubRx = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9]|-[a-zA-Z0-9]){0,38}$`)
+
+	// Mastodon handles are @user@instance.domain
+	mastodonRx = regexp.MustCompile(`^@?([a-zA-Z0-9_]{1,30})@([a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)+)$`)
+)
+
+// MaxTitle and MaxText are the maximum lengths for the profile title and bio.
+const (
+	MaxTitle = 100
+	MaxText  = 5000
+)
+
+// ValidateProfile checks and normalises the profile fields in params,
+// it returns an error suitable for display to the user if a field is invalid.
+func ValidateProfile(params map[string]string) error {
+
+	if len(params["title"]) > MaxTitle {
+		return fmt.Errorf("Sorry, your title must be less than %d characters", MaxTitle)
+	}
+
+	if len(params["text"]) > MaxText {
+		return fmt.Errorf("Sorry, your about text must be less than %d characters", MaxText)
+	}
+
+	if v, ok := params["website"]; ok {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			u, err := url.Parse(v)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("Sorry, your website must be a full http or https url")
+			}
+		}
+		params["website"] = v
+	}
+
+	if v, ok := params["github"]; ok {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "@")
+		if v != "" && !githubRx.MatchString(v) {
+			return fmt.Errorf("Sorry, %s is not a valid GitHub username", v)
+		}
+		params["github"] = v
+	}
+
+	if v, ok := params["mastodon"]; ok {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			m := mastodonRx.FindStringSubmatch(v)
+			if m == nil {
+				return fmt.Errorf("Sorry, your Mastodon handle should look like @name@example.social")
+			}
+			v = fmt.Sprintf("@%s@%s", m[1], strings.ToLower(m[2]))
+		}
+		params["mastodon"] = v
+	}
+
+	return nil
+}
+
+// GitHubURL returns the url of the user's GitHub profile (if any).
+func (u *User) GitHubURL() string {
+	if u.GitHub == "" {
+		return ""
+	}
+	return "https://github.com/" + u.GitHub
+}
+
+// MastodonURL returns the url of the user's Mastodon profile (if any).
+func (u *User) MastodonURL() string {
+	m := mastodonRx.FindStringSubmatch(u.Mastodon)
+	if m == nil {
+		return ""
+	}
+	return fmt.Sprintf("https://%s/@%s", m[2], m[1])
+}
+
+// AvatarURL returns the url of the user's generated avatar.
+func (u *User) AvatarURL() string {
+	return fmt.Sprintf("/users/%d/avatar.svg", u.ID)
+}
+
+// HasAbout returns true if the user has filled in any of their about section.
+func (u *User) HasAbout() bool {
+	return u.Title != "" || u.Text != "" || u.Website != "" || u.GitHub != "" || u.Mastodon != ""
+}
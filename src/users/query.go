@@ -20,7 +20,12 @@ const (
 
 // AllowedParams returns an array of acceptable params in update
 func AllowedParams() []string {
-	return []string{"name", "summary", "email", "text", "title", "password_hash"}
+	return []string{"name", "summary", "email", "text", "title", "website", "github", "mastodon"}
+}
+
+// AllowedParamsAdmin returns the cols editable by admins, who may also set the role and status of users
+func AllowedParamsAdmin() []string {
+	return append(AllowedParams(), "role", "status")
 }
 
 // NewWithColumns creates a new user instance and fills it with data from the database cols provided.
@@ -34,11 +39,26 @@ func NewWithColumns(cols map[string]interface{}) *User {
 	user.Email = resource.ValidateString(cols["email"])
 	user.Name = resource.ValidateString(cols["name"])
 	user.PasswordHash = resource.ValidateString(cols["password_hash"])
+	user.PasswordResetAt = resource.ValidateTime(cols["password_reset_at"])
+	user.SessionsAt = resource.ValidateTime(cols["sessions_at"])
 	user.Points = resource.ValidateInt(cols["points"])
 	user.Role = resource.ValidateInt(cols["role"])
 	user.Summary = resource.ValidateString(cols["summary"])
 	user.Text = resource.ValidateString(cols["text"])
 	user.Title = resource.ValidateString(cols["title"])
+	user.Website = resource.ValidateString(cols["website"])
+	user.GitHub = resource.ValidateString(cols["github"])
+	user.Mastodon = resource.ValidateString(cols["mastodon"])
+	user.ExportToken = resource.ValidateString(cols["export_token"])
+	user.ExportAt = resource.ValidateTime(cols["export_at"])
+	user.FeedToken = resource.ValidateString(cols["feed_token"])
+	user.DeleteAt = resource.ValidateTime(cols["delete_at"])
+	user.InvitedBy = resource.ValidateInt(cols["invited_by"])
+	user.SuspendedUntil = resource.ValidateTime(cols["suspended_until"])
+	user.SuspendedReason = resource.ValidateString(cols["suspended_reason"])
+	user.ShadowBanned = resource.ValidateBoolean(cols["shadow_banned"])
+	user.Supporter = resource.ValidateBoolean(cols["supporter"])
+	user.SupporterTier = resource.ValidateInt(cols["supporter_tier"])
 
 	return user
 }
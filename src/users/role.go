@@ -1,6 +1,8 @@
 package users
 
 import (
+	"fmt"
+
 	"github.com/fragmenta/query"
 	"github.com/fragmenta/view/helpers"
 
@@ -9,7 +11,8 @@ import (
 
 // This file contains functions related to authorisation and roles.
 
-// User roles
+// User roles - Editors are moderators, who may moderate stories and comments
+// but may not change users, points or roles.
 const (
 	Anon   = 0
 	Editor = 10
@@ -22,12 +25,22 @@ func (u *User) RoleOptions() []helpers.Option {
 	var options []helpers.Option
 
 	options = append(options, helpers.Option{Id: Reader, Name: "Reader"})
-	options = append(options, helpers.Option{Id: Editor, Name: "Editor"})
+	options = append(options, helpers.Option{Id: Editor, Name: "Moderator"})
 	options = append(options, helpers.Option{Id: Admin, Name: "Administrator"})
 
 	return options
 }
 
+// ValidRole returns true if this role value is one of the RoleOptions.
+func ValidRole(role string) bool {
+	for _, o := range New().RoleOptions() {
+		if fmt.Sprintf("%d", o.Id) == role {
+			return true
+		}
+	}
+	return false
+}
+
 // RoleDisplay returns the string representation of the Role status
 func (u *User) RoleDisplay() string {
 	for _, o := range u.RoleOptions() {
@@ -48,6 +61,16 @@ func (u *User) Admin() bool {
 	return u.Role == Admin
 }
 
+// Editor returns true if this user is an Editor (a moderator).
+func (u *User) Editor() bool {
+	return u.Role == Editor
+}
+
+// CanModerate returns true if this user may moderate stories and comments.
+func (u *User) CanModerate() bool {
+	return u.Role == Editor || u.Role == Admin
+}
+
 // Reader returns true if this user is an Reader.
 func (u *User) Reader() bool {
 	return u.Role == Reader
@@ -91,6 +114,16 @@ func MockAnon() *User {
 	return &User{Role: Anon, Email: "anon@example.com"}
 }
 
+// MockReader returns a mock user for testing with Role Reader.
+func MockReader() *User {
+	return &User{Role: Reader, Email: "reader@example.com", Base: resource.Base{ID: 3}}
+}
+
+// MockEditor returns a mock user for testing with Role Editor.
+func MockEditor() *User {
+	return &User{Role: Editor, Email: "editor@example.com", Base: resource.Base{ID: 2}}
+}
+
 // MockAdmin returns a mock user for testing with Role Admin.
 func MockAdmin() *User {
 	return &User{Role: Admin, Email: "admin@example.com", Base: resource.Base{ID: 1}}
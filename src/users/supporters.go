@@ -0,0 +1,85 @@
+package users
+
+import (
+	"github.com/fragmenta/query"
+
+	"github.com/kennygrant/gohackernews/src/lib/karma"
+)
+
+// This file contains functions related to supporters, who fund the site with a membership.
+
+// Supporter tiers, users are given the highest tier of the memberships they pay for.
+const (
+	TierNone      = 0
+	TierSupporter = 10
+	TierPatron    = 20
+)
+
+// Tiers returns the tiers of membership which may be bought, from lowest to highest.
+func Tiers() []int64 {
+	return []int64{TierSupporter, TierPatron}
+}
+
+// TierName returns the name of the tier, which is used as the product name when paying for it.
+func TierName(tier int64) string {
+	switch tier {
+	case TierSupporter:
+		return "supporter"
+	case TierPatron:
+		return "patron"
+	}
+	return ""
+}
+
+// TierDisplay returns the name of the tier for display.
+func TierDisplay(tier int64) string {
+	switch tier {
+	case TierSupporter:
+		return "Supporter"
+	case TierPatron:
+		return "Patron"
+	}
+	return ""
+}
+
+// SupporterDisplay returns the tier of this user for display, or an empty string if they are not a supporter.
+func (u *User) SupporterDisplay() string {
+	if !u.Supporter {
+		return ""
+	}
+	return TierDisplay(u.SupporterTier)
+}
+
+// StoryLimit returns the number of stories this user may submit in a day (0 for no limit).
+func (u *User) StoryLimit() int64 {
+	return karma.Current.StoryLimit(u.Supporter)
+}
+
+// CanSubmitToday returns true if this user has stories left in their daily allowance.
+func (u *User) CanSubmitToday() bool {
+	if u.Admin() {
+		return true
+	}
+	count, err := query.New("stories", "user_id").Where("user_id=?", u.ID).Where("created_at > current_timestamp - interval '1 day'").Count()
+	if err != nil {
+		return false
+	}
+	return karma.Current.StoryAllowed(count, u.Supporter)
+}
+
+// SetSupporter sets the supporter tier of the user with this id, on the user and on the
+// stories and comments which show it next to their name. Tier 0 removes the badge.
+func SetSupporter(id int64, tier int64) error {
+	_, err := query.Exec("update users set supporter=$1, supporter_tier=$2 where id=$3", tier > TierNone, tier, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = query.Exec("update stories set user_supporter=$1 where user_id=$2 and user_supporter<>$1", tier, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = query.Exec("update comments set user_supporter=$1 where user_id=$2 and user_supporter<>$1", tier, id)
+	return err
+}
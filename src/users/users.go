@@ -24,6 +24,38 @@ type User struct {
 	Text    string
 	Title   string
 
+	// Website, GitHub and Mastodon are shown in the about section of the profile
+	Website  string
+	GitHub   string
+	Mastodon string
+
 	PasswordHash    string
 	PasswordResetAt time.Time
+
+	// SessionsAt is the time sessions were last revoked, sessions started before this are invalid
+	SessionsAt time.Time
+
+	// ExportToken and ExportAt record the last personal data export
+	ExportToken string
+	ExportAt    time.Time
+
+	// FeedToken identifies the user in the urls of their private feeds
+	FeedToken string
+
+	// DeleteAt is the time a deletion requested by the user takes effect
+	DeleteAt time.Time
+
+	// InvitedBy is the id of the user who invited this user (if any)
+	InvitedBy int64
+
+	// SuspendedUntil and SuspendedReason are set when an admin suspends the user
+	SuspendedUntil  time.Time
+	SuspendedReason string
+
+	// ShadowBanned users see their own stories and comments, but nobody else does
+	ShadowBanned bool
+
+	// Supporter is set while the user pays for a membership, SupporterTier is the highest tier they pay for
+	Supporter     bool
+	SupporterTier int64
 }
@@ -2,11 +2,15 @@
 package users
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fragmenta/query"
 
 	"github.com/kennygrant/gohackernews/src/lib/resource"
+	"github.com/kennygrant/gohackernews/src/lib/status"
 )
 
 var testName = "foo"
@@ -113,6 +117,384 @@ func TestQuery(t *testing.T) {
 
 }
 
+// TestPointEvents tests changes to points are recorded in the ledger, reversed and reconciled
+func TestPointEvents(t *testing.T) {
+
+	id, err := New().Create(map[string]string{"name": "points_test", "status": "100", "points": "0"})
+	if err != nil {
+		t.Fatalf("users: Create user failed :%s", err)
+	}
+
+	err = AddPoints(id, 3, ReasonStoryUpvoted, 1, 0)
+	if err != nil {
+		t.Fatalf("users: AddPoints failed :%s", err)
+	}
+	err = AddPoints(id, -1, ReasonDownvoteCost, 1, 0)
+	if err != nil {
+		t.Fatalf("users: AddPoints failed :%s", err)
+	}
+
+	user, err := Find(id)
+	if err != nil {
+		t.Fatalf("users: points user find failed")
+	}
+	if user.Points != 2 {
+		t.Fatalf("users: AddPoints points wrong got:%d want:%d", user.Points, 2)
+	}
+
+	events, err := FindPointEvents(user.PointEvents())
+	if err != nil || len(events) != 2 {
+		t.Fatalf("users: point events not found :%s", err)
+	}
+
+	// Reversing the newest event should restore its points
+	err = events[0].Reverse()
+	if err != nil {
+		t.Fatalf("users: Reverse failed :%s", err)
+	}
+	user, _ = Find(id)
+	if user.Points != 3 {
+		t.Fatalf("users: Reverse points wrong got:%d want:%d", user.Points, 3)
+	}
+
+	// An event may be reversed only once, and a reversal may not be reversed
+	err = events[0].Reverse()
+	if err == nil {
+		t.Fatalf("users: Reverse twice succeeded")
+	}
+	events, err = FindPointEvents(user.PointEvents())
+	if err != nil || len(events) != 3 || events[0].ReversedID != events[1].ID {
+		t.Fatalf("users: reversal not found :%v", err)
+	}
+	err = events[0].Reverse()
+	if err == nil {
+		t.Fatalf("users: Reverse of reversal succeeded")
+	}
+	user, _ = Find(id)
+	if user.Points != 3 {
+		t.Fatalf("users: Reverse points wrong got:%d want:%d", user.Points, 3)
+	}
+
+	// Points changed outside the ledger are reported as drift and repaired
+	query.ExecSQL("update users set points=10 where id=$1", id)
+	drift, err := FindDrift()
+	if err != nil {
+		t.Fatalf("users: FindDrift failed :%s", err)
+	}
+	found := false
+	for _, d := range drift {
+		if d.User.ID == id {
+			found = true
+			if d.Ledger != 3 || d.Difference() != 7 {
+				t.Fatalf("users: drift wrong got:%d %d", d.Ledger, d.Difference())
+			}
+			err = d.Repair()
+			if err != nil {
+				t.Fatalf("users: Repair failed :%s", err)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("users: drift not found for user")
+	}
+	user, _ = Find(id)
+	if user.Points != 3 {
+		t.Fatalf("users: Repair points wrong got:%d want:%d", user.Points, 3)
+	}
+
+	query.ExecSQL("delete from point_events where user_id=$1", id)
+	user.Destroy()
+}
+
+// TestInvites tests invites are created, used once and recorded in the invite tree
+func TestInvites(t *testing.T) {
+
+	inviterID, err := New().Create(map[string]string{"name": "inviter_test", "status": "100"})
+	if err != nil {
+		t.Fatalf("users: Create user failed :%s", err)
+	}
+	inviter, err := Find(inviterID)
+	if err != nil {
+		t.Fatalf("users: Create user find failed")
+	}
+
+	token := "0123456789abcdef0123456789abcdef"
+	err = inviter.CreateInvite(token, "")
+	if err != nil {
+		t.Fatalf("users: CreateInvite failed :%s", err)
+	}
+
+	invite, err := FindInvite(token)
+	if err != nil {
+		t.Fatalf("users: FindInvite failed :%s", err)
+	}
+
+	// Invites are claimed before registration, and may only be claimed once
+	err = invite.Claim()
+	if err != nil {
+		t.Fatalf("users: Claim invite failed :%s", err)
+	}
+	err = invite.Claim()
+	if err == nil {
+		t.Fatalf("users: Claim invite claimed twice")
+	}
+
+	id, err := New().Create(map[string]string{"name": "invited_test", "status": "100"})
+	if err != nil {
+		t.Fatalf("users: Create user failed :%s", err)
+	}
+	err = invite.Accept(id)
+	if err != nil {
+		t.Fatalf("users: Accept invite failed :%s", err)
+	}
+
+	// Invites may only be used once
+	_, err = FindInvite(token)
+	if err == nil {
+		t.Fatalf("users: FindInvite found used invite")
+	}
+	err = invite.Accept(id)
+	if err == nil {
+		t.Fatalf("users: Accept invite accepted twice")
+	}
+
+	invited, err := Find(id)
+	if err != nil || invited.InvitedBy != inviterID {
+		t.Fatalf("users: invited user has wrong inviter :%v", err)
+	}
+
+	ids, err := inviter.InvitedUserIDs()
+	if err != nil || len(ids) != 1 || ids[0] != id {
+		t.Fatalf("users: InvitedUserIDs wrong got:%v", ids)
+	}
+
+	count, err := inviter.SuspendInvited()
+	if err != nil || count != 2 {
+		t.Fatalf("users: SuspendInvited wrong got:%d :%v", count, err)
+	}
+	invited, _ = Find(id)
+	if invited.Status != status.Suspended {
+		t.Fatalf("users: SuspendInvited did not suspend invited user")
+	}
+
+	query.ExecSQL("delete from invites where user_id=$1", inviterID)
+	invited.Destroy()
+	inviter.Destroy()
+}
+
+// TestSuspendUsers tests suspended users are found as suspended until their suspension expires
+func TestSuspendUsers(t *testing.T) {
+
+	id, err := New().Create(map[string]string{"name": "suspend_test", "status": "100"})
+	if err != nil {
+		t.Fatalf("users: Create user failed :%s", err)
+	}
+	user, err := Find(id)
+	if err != nil {
+		t.Fatalf("users: Create user find failed")
+	}
+
+	err = user.Suspend(time.Now().Add(time.Hour), "spam")
+	if err != nil {
+		t.Fatalf("users: Suspend failed :%s", err)
+	}
+	user, _ = Find(id)
+	if !user.Suspended() || user.SuspendedReason != "spam" {
+		t.Fatalf("users: Suspend did not suspend user")
+	}
+
+	// Suspensions in the past have expired
+	err = user.Suspend(time.Now().Add(-time.Hour), "spam")
+	if err != nil {
+		t.Fatalf("users: Suspend failed :%s", err)
+	}
+	user, _ = Find(id)
+	if user.Suspended() || !user.SuspensionExpired() {
+		t.Fatalf("users: expired suspension still in force")
+	}
+
+	// Suspensions without an end last until lifted
+	err = user.Suspend(time.Time{}, "")
+	if err != nil {
+		t.Fatalf("users: Suspend failed :%s", err)
+	}
+	user, _ = Find(id)
+	if !user.Suspended() {
+		t.Fatalf("users: indefinite suspension not in force")
+	}
+
+	err = user.Unsuspend()
+	if err != nil {
+		t.Fatalf("users: Unsuspend failed :%s", err)
+	}
+	user, _ = Find(id)
+	if user.Suspended() || user.Status != status.Published {
+		t.Fatalf("users: Unsuspend did not lift suspension")
+	}
+
+	err = user.SetShadowBanned(true)
+	if err != nil {
+		t.Fatalf("users: SetShadowBanned failed :%s", err)
+	}
+	user, _ = Find(id)
+	if !user.ShadowBanned || New().CanSee(user) || !user.CanSee(user) {
+		t.Fatalf("users: shadow banned user visible to others")
+	}
+
+	user.Destroy()
+}
+
+// TestAnonymiseUsers tests content is reassigned to the deleted user on deletion
+func TestAnonymiseUsers(t *testing.T) {
+
+	id, err := New().Create(map[string]string{"name": "anon_test", "status": "100"})
+	if err != nil {
+		t.Fatalf("users: Create user failed :%s", err)
+	}
+	user, err := Find(id)
+	if err != nil {
+		t.Fatalf("users: Create user find failed")
+	}
+
+	_, err = query.ExecSQL("INSERT INTO stories (name,user_id,user_name,points) VALUES('anon story',$1,'anon_test',1);", id)
+	if err != nil {
+		t.Fatalf("users: error inserting story :%s", err)
+	}
+
+	err = user.Anonymise()
+	if err != nil {
+		t.Fatalf("users: Anonymise user failed :%s", err)
+	}
+
+	// The user should be gone, but the story should remain
+	_, err = Find(id)
+	if err == nil {
+		t.Fatalf("users: Anonymise did not remove user")
+	}
+
+	results, err := query.New("stories", "id").Where("name=?", "anon story").Results()
+	if err != nil || len(results) != 1 {
+		t.Fatalf("users: Anonymise story not found :%s", err)
+	}
+	if results[0]["user_name"] != DeletedName {
+		t.Fatalf("users: Anonymise story not reassigned got:%v", results[0]["user_name"])
+	}
+
+	query.ExecSQL("delete from stories where name='anon story';")
+
+	// Remove the placeholder so that counts below are unaffected
+	deleted, err := FindDeleted()
+	if err != nil {
+		t.Fatalf("users: deleted user not found :%s", err)
+	}
+	if deleted.Role != Anon {
+		t.Fatalf("users: deleted user has role:%d", deleted.Role)
+	}
+	deleted.Destroy()
+
+	// The name of the placeholder is reserved, whatever the case or spacing
+	for _, name := range []string{DeletedName, " [Deleted] "} {
+		if !ReservedName(name) {
+			t.Fatalf("users: name %q should be reserved", name)
+		}
+	}
+	if ReservedName("deleted") {
+		t.Fatalf("users: name deleted should not be reserved")
+	}
+}
+
+// TestPurgeUsers tests purged content is removed, replies are kept under tombstones
+// and the points the user's votes gave to authors are reversed in the ledger
+func TestPurgeUsers(t *testing.T) {
+
+	spammerID, err := New().Create(map[string]string{"name": "purge_spammer", "status": "100"})
+	if err != nil {
+		t.Fatalf("users: Create user failed :%s", err)
+	}
+	authorID, err := New().Create(map[string]string{"name": "purge_author", "status": "100", "points": "0"})
+	if err != nil {
+		t.Fatalf("users: Create user failed :%s", err)
+	}
+
+	// The author has a story upvoted by the spammer, and replies to a comment by the spammer
+	storyID, err := query.New("stories", "id").Insert(map[string]string{"name": "purge story", "user_id": fmt.Sprintf("%d", authorID), "points": "2"})
+	if err != nil {
+		t.Fatalf("users: error inserting story :%s", err)
+	}
+	err = AddPoints(authorID, 1, ReasonStoryUpvoted, storyID, 0)
+	if err != nil {
+		t.Fatalf("users: error adding points :%s", err)
+	}
+	_, err = query.Exec("insert into votes VALUES(now(),NULL,$1,$2,'',1)", storyID, spammerID)
+	if err != nil {
+		t.Fatalf("users: error inserting vote :%s", err)
+	}
+
+	insertComment := func(userID, parentID int64, text string) int64 {
+		id, err := query.New("comments", "id").Insert(map[string]string{"story_id": fmt.Sprintf("%d", storyID), "parent_id": fmt.Sprintf("%d", parentID), "user_id": fmt.Sprintf("%d", userID), "text": text, "points": "1"})
+		if err != nil {
+			t.Fatalf("users: error inserting comment :%s", err)
+		}
+		return id
+	}
+	repliedID := insertComment(spammerID, 0, "spam with reply")
+	replyID := insertComment(authorID, repliedID, "reply")
+	spamID := insertComment(spammerID, 0, "spam")
+
+	spammer, err := Find(spammerID)
+	if err != nil {
+		t.Fatalf("users: Create user find failed")
+	}
+	err = spammer.Purge()
+	if err != nil {
+		t.Fatalf("users: Purge user failed :%s", err)
+	}
+
+	// The comment without replies is removed, the other is kept as a tombstone above the reply
+	count, err := query.New("comments", "id").Where("id=?", spamID).Count()
+	if err != nil || count != 0 {
+		t.Fatalf("users: Purge did not remove comment :%s", err)
+	}
+	results, err := query.New("comments", "id").WhereIn("id", []int64{repliedID, replyID}).Order("id asc").Results()
+	if err != nil || len(results) != 2 {
+		t.Fatalf("users: Purge removed replies :%s", err)
+	}
+	if results[0]["user_name"] != DeletedName || results[0]["text"] != "" || results[0]["deleted_at"] == nil {
+		t.Fatalf("users: Purge did not keep tombstone got:%v", results[0])
+	}
+
+	// The author loses the point given by the spammer's vote, and their points match their ledger
+	author, err := Find(authorID)
+	if err != nil || author.Points != 0 {
+		t.Fatalf("users: Purge did not reverse points got:%d :%s", author.Points, err)
+	}
+	drift, err := FindDrift()
+	if err != nil {
+		t.Fatalf("users: FindDrift failed :%s", err)
+	}
+	for _, d := range drift {
+		if d.User.ID == authorID {
+			t.Fatalf("users: Purge left points out of the ledger got:%d", d.Difference())
+		}
+	}
+
+	// The placeholder may not be purged
+	deleted, err := FindDeleted()
+	if err != nil {
+		t.Fatalf("users: deleted user not found :%s", err)
+	}
+	err = deleted.Purge()
+	if err == nil {
+		t.Fatalf("users: Purge of placeholder allowed")
+	}
+
+	query.ExecSQL("delete from comments where story_id=$1", storyID)
+	query.ExecSQL("delete from stories where id=$1", storyID)
+	query.ExecSQL("delete from point_events where user_id=$1", authorID)
+	author.Destroy()
+	deleted.Destroy()
+}
+
 // Test Destroy method
 func TestDestroyUsers(t *testing.T) {
 
@@ -141,9 +523,59 @@ func TestDestroyUsers(t *testing.T) {
 
 }
 
+// TestValidateProfile tests profile fields are validated and normalised
+func TestValidateProfile(t *testing.T) {
+	params := map[string]string{
+		"website":  " https://example.com ",
+		"github":   "@kennygrant",
+		"mastodon": "name@Example.Social",
+	}
+	err := ValidateProfile(params)
+	if err != nil {
+		t.Fatalf("users: ValidateProfile failed :%s", err)
+	}
+	if params["website"] != "https://example.com" || params["github"] != "kennygrant" || params["mastodon"] != "@name@example.social" {
+		t.Fatalf("users: ValidateProfile did not normalise got:%v", params)
+	}
+
+	invalid := []map[string]string{
+		{"website": "javascript:alert(1)"},
+		{"website": "example.com"},
+		{"github": "bad name"},
+		{"github": "-dash"},
+		{"mastodon": "@name"},
+	}
+	for _, p := range invalid {
+		if ValidateProfile(p) == nil {
+			t.Fatalf("users: ValidateProfile accepted invalid params:%v", p)
+		}
+	}
+
+	user := &User{GitHub: "kennygrant", Mastodon: "@name@example.social"}
+	if user.GitHubURL() != "https://github.com/kennygrant" || user.MastodonURL() != "https://example.social/@name" {
+		t.Fatalf("users: profile urls wrong got:%s %s", user.GitHubURL(), user.MastodonURL())
+	}
+}
+
 // TestAllowedParams should always return some params
 func TestAllowedParams(t *testing.T) {
 	if len(AllowedParams()) == 0 {
 		t.Fatalf("users: no allowed params")
 	}
+
+	// Passwords, points and roles are never set from user params
+	for _, p := range AllowedParams() {
+		if p == "password_hash" || p == "points" || p == "role" {
+			t.Fatalf("users: %s should not be an allowed param", p)
+		}
+	}
+
+	// Admins may also set roles, which must be one of those offered
+	allowed := strings.Join(AllowedParamsAdmin(), " ")
+	if !strings.Contains(allowed, "role") || !strings.Contains(allowed, "status") {
+		t.Fatalf("users: admins should be allowed to set role and status got:%v", allowed)
+	}
+	if !ValidRole("10") || ValidRole("0") || ValidRole("admin") {
+		t.Fatalf("users: ValidRole wrong")
+	}
 }
@@ -1,42 +1,76 @@
 package users
 
-/*
-  Proposed voting rules - for now these are relaxed
-  2 points - submit, comment
-  10 points - upvote (they start with 10 points)
-  50 points - downvote
-  100 points - flag
+import (
+	"github.com/fragmenta/query"
 
-	karma is collected for comment upvotes *only* not for story upvotes
-	karma is sacrificed in negative actions - flagging and downvoting
-*/
+	"github.com/kennygrant/gohackernews/src/lib/karma"
+)
+
+// Thresholds for privileges, and the rewards and costs of votes,
+// are defined by the karma policy, which is loaded from config.
+//
+//	karma is sacrificed in negative actions - flagging and downvoting
+//	new accounts are on probation, and cannot downvote or flag
 
 // CanSubmit returns true if this user can submit.
 func (u *User) CanSubmit() bool {
-	return u.Points > 2
+	return karma.Current.Allows(karma.Submit, u.Points, u.CreatedAt)
 }
 
 // CanComment returns true if this user can comment.
 func (u *User) CanComment() bool {
-	return u.Points > 0
+	return karma.Current.Allows(karma.Comment, u.Points, u.CreatedAt)
 }
 
 // CanUpvote returns true if this user can upvote.
 func (u *User) CanUpvote() bool {
-	return u.Points > 2
+	return karma.Current.Allows(karma.Upvote, u.Points, u.CreatedAt)
 }
 
 // CanDownvote returns true if this user can downvote.
 func (u *User) CanDownvote() bool {
-	return u.Points > 20
+	return karma.Current.Allows(karma.Downvote, u.Points, u.CreatedAt)
 }
 
 // CanStyle returns true if this user can style text in comments/stories.
 func (u *User) CanStyle() bool {
-	return u.Points > 30
+	return karma.Current.Allows(karma.Style, u.Points, u.CreatedAt)
 }
 
 // CanFlag returns true if this user can flag.
 func (u *User) CanFlag() bool {
-	return u.Points > 50
+	return karma.Current.Allows(karma.Flag, u.Points, u.CreatedAt)
+}
+
+// CanInvite returns true if this user can invite other users.
+func (u *User) CanInvite() bool {
+	return u.Admin() || karma.Current.Allows(karma.Invite, u.Points, u.CreatedAt)
+}
+
+// OnProbation returns true if this user's account is too new to downvote or flag.
+func (u *User) OnProbation() bool {
+	return karma.Current.OnProbation(u.CreatedAt)
+}
+
+// Privileges returns the privileges this user has unlocked, for display.
+func (u *User) Privileges() []karma.Level {
+	return karma.Current.Levels(u.Points, u.CreatedAt)
+}
+
+// CanVoteToday returns true if this user has votes left in their daily allowance.
+func (u *User) CanVoteToday() bool {
+	count, err := query.New("votes", "user_id").Where("user_id=?", u.ID).Where("created_at > current_timestamp - interval '1 day'").Count()
+	if err != nil {
+		return false
+	}
+	return karma.Current.VoteAllowed(count)
+}
+
+// CanFlagToday returns true if this user has flags left in their daily allowance.
+func (u *User) CanFlagToday() bool {
+	count, err := query.New("flags", "user_id").Where("user_id=?", u.ID).Where("created_at > current_timestamp - interval '1 day'").Count()
+	if err != nil {
+		return false
+	}
+	return karma.Current.FlagAllowed(count)
 }